@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Compile-time check to ensure APIKeyAuthenticator implements Authenticator
+var _ Authenticator = (*APIKeyAuthenticator)(nil)
+
+// APIKey is a static key. Either Key or its SHA256 is configured; prefer SHA256 so that
+// configuration files do not hold usable keys.
+type APIKey struct {
+	// Name is the subject of the key's identity
+	Name   string   `mapstructure:"name" yaml:"name"`
+	Key    string   `mapstructure:"key" yaml:"key,omitempty"`
+	SHA256 string   `mapstructure:"sha256" yaml:"sha256,omitempty"`
+	Groups []string `mapstructure:"groups" yaml:"groups,omitempty"`
+}
+
+type apiKey struct {
+	name   string
+	hash   []byte
+	groups []string
+}
+
+// APIKeyAuthenticator authenticates static API keys
+type APIKeyAuthenticator struct {
+	keys []apiKey
+}
+
+// NewAPIKeyAuthenticator creates an authenticator for the given keys
+func NewAPIKeyAuthenticator(keys []APIKey) (*APIKeyAuthenticator, error) {
+	a := &APIKeyAuthenticator{}
+	for i, k := range keys {
+		if k.Name == "" {
+			return nil, fmt.Errorf("API key %d: name is required", i)
+		}
+
+		var hash []byte
+		switch {
+		case k.Key != "" && k.SHA256 != "":
+			return nil, fmt.Errorf("API key %s: only one of key and sha256 may be set", k.Name)
+		case k.Key != "":
+			sum := sha256.Sum256([]byte(k.Key))
+			hash = sum[:]
+		case k.SHA256 != "":
+			decoded, err := hex.DecodeString(strings.TrimSpace(k.SHA256))
+			if err != nil || len(decoded) != sha256.Size {
+				return nil, fmt.Errorf("API key %s: sha256 must be a hex-encoded SHA-256 hash", k.Name)
+			}
+			hash = decoded
+		default:
+			return nil, fmt.Errorf("API key %s: key or sha256 is required", k.Name)
+		}
+		a.keys = append(a.keys, apiKey{name: k.Name, hash: hash, groups: k.Groups})
+	}
+	return a, nil
+}
+
+// Authenticate returns the identity of a configured key, or ErrUnknownToken
+func (a *APIKeyAuthenticator) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	sum := sha256.Sum256([]byte(token))
+	for _, k := range a.keys {
+		if subtle.ConstantTimeCompare(sum[:], k.hash) == 1 {
+			return &Identity{Subject: k.name, Groups: k.groups}, nil
+		}
+	}
+	return nil, ErrUnknownToken
+}
@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Role is what an identity may do in a namespace. Each role includes the ones below it.
+type Role int
+
+const (
+	// None grants nothing
+	None Role = iota
+	// Viewer may read triggers, dead letters, stats and jobs
+	Viewer
+	// Editor may also change triggers, redrive dead letters, submit, cancel and retry jobs,
+	// and claim and run jobs as a worker
+	Editor
+	// Admin may also purge dead letters
+	Admin
+)
+
+// AllNamespaces is the namespace of a binding that applies to every namespace
+const AllNamespaces = "*"
+
+// String returns the name of the role
+func (r Role) String() string {
+	switch r {
+	case Viewer:
+		return "viewer"
+	case Editor:
+		return "editor"
+	case Admin:
+		return "admin"
+	default:
+		return "none"
+	}
+}
+
+// ParseRole parses viewer, editor or admin
+func ParseRole(s string) (Role, error) {
+	switch s {
+	case "viewer":
+		return Viewer, nil
+	case "editor":
+		return Editor, nil
+	case "admin":
+		return Admin, nil
+	default:
+		return None, fmt.Errorf("unknown role %q, want viewer, editor or admin", s)
+	}
+}
+
+// Identity is an authenticated caller
+type Identity struct {
+	// Subject is the JWT subject or the name of the API key
+	Subject string
+	// Groups are the JWT's groups or the groups of the API key
+	Groups []string
+}
+
+// ErrUnknownToken is returned by an authenticator for tokens it does not handle, so that
+// the next authenticator is tried
+var ErrUnknownToken = errors.New("unknown token")
+
+// Authenticator verifies bearer tokens
+type Authenticator interface {
+	// Authenticate returns the identity of a token, ErrUnknownToken if the token is not
+	// one the authenticator handles, or another error if the token is invalid
+	Authenticate(ctx context.Context, token string) (*Identity, error)
+}
+
+// Binding grants a role in a namespace to a subject or to the members of a group
+type Binding struct {
+	Subject string `mapstructure:"subject" yaml:"subject,omitempty"`
+	Group   string `mapstructure:"group" yaml:"group,omitempty"`
+	// Namespace is the namespace the role applies to, or * for every namespace
+	Namespace string `mapstructure:"namespace" yaml:"namespace"`
+	Role      string `mapstructure:"role" yaml:"role"`
+}
+
+type binding struct {
+	Binding
+	role Role
+}
+
+// Policy maps identities to per-namespace roles
+type Policy struct {
+	bindings []binding
+}
+
+// NewPolicy creates a policy from bindings
+func NewPolicy(bindings []Binding) (*Policy, error) {
+	p := &Policy{}
+	for i, b := range bindings {
+		if (b.Subject == "") == (b.Group == "") {
+			return nil, fmt.Errorf("binding %d: exactly one of subject and group is required", i)
+		}
+		if b.Namespace == "" {
+			return nil, fmt.Errorf("binding %d: namespace is required", i)
+		}
+		role, err := ParseRole(b.Role)
+		if err != nil {
+			return nil, fmt.Errorf("binding %d: %w", i, err)
+		}
+		p.bindings = append(p.bindings, binding{Binding: b, role: role})
+	}
+	return p, nil
+}
+
+// Role returns the highest role the identity has in a namespace
+func (p *Policy) Role(id *Identity, namespace string) Role {
+	role := None
+	for _, b := range p.bindings {
+		if b.Namespace != AllNamespaces && b.Namespace != namespace {
+			continue
+		}
+		if b.role > role && b.matches(id) {
+			role = b.role
+		}
+	}
+	return role
+}
+
+// matches reports whether the binding applies to the identity
+func (b binding) matches(id *Identity) bool {
+	if b.Subject != "" {
+		return b.Subject == id.Subject
+	}
+	for _, group := range id.Groups {
+		if group == b.Group {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying an identity
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the identity of the caller, if the RPC was authenticated
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(contextKey{}).(*Identity)
+	return id, ok
+}
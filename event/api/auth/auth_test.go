@@ -0,0 +1,313 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "event/api/proto"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// writeJWKS writes a JWKS with the public key of key under kid and returns its path
+func writeJWKS(t *testing.T, dir, kid string, key *ecdsa.PrivateKey) string {
+	t.Helper()
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	set := map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "EC",
+			"kid": kid,
+			"use": "sig",
+			"crv": "P-256",
+			"x":   encode(key.X.FillBytes(make([]byte, 32))),
+			"y":   encode(key.Y.FillBytes(make([]byte, 32))),
+		}},
+	}
+	content, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "jwks.json")
+	if err := os.WriteFile(file, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func sign(t *testing.T, key interface{}, method jwt.SigningMethod, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	key := newKey(t)
+	file := writeJWKS(t, dir, "k1", key)
+
+	a, err := NewJWTAuthenticator(file, WithIssuer("https://idp.example.com"), WithAudience("triggerd"))
+	if err != nil {
+		t.Fatalf("NewJWTAuthenticator() error = %v", err)
+	}
+
+	claims := func(overrides jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"sub":    "alice",
+			"iss":    "https://idp.example.com",
+			"aud":    "triggerd",
+			"exp":    time.Now().Add(time.Hour).Unix(),
+			"groups": []string{"sales-team"},
+		}
+		for k, v := range overrides {
+			c[k] = v
+		}
+		return c
+	}
+
+	id, err := a.Authenticate(ctx, sign(t, key, jwt.SigningMethodES256, "k1", claims(nil)))
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if id.Subject != "alice" || len(id.Groups) != 1 || id.Groups[0] != "sales-team" {
+		t.Errorf("Authenticate() = %+v", id)
+	}
+
+	invalid := map[string]string{
+		"expired":        sign(t, key, jwt.SigningMethodES256, "k1", claims(jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()})),
+		"no expiry":      sign(t, key, jwt.SigningMethodES256, "k1", claims(jwt.MapClaims{"exp": nil})),
+		"wrong issuer":   sign(t, key, jwt.SigningMethodES256, "k1", claims(jwt.MapClaims{"iss": "https://evil.example.com"})),
+		"wrong audience": sign(t, key, jwt.SigningMethodES256, "k1", claims(jwt.MapClaims{"aud": "other"})),
+		"unknown key":    sign(t, newKey(t), jwt.SigningMethodES256, "k2", claims(nil)),
+		"wrong key":      sign(t, newKey(t), jwt.SigningMethodES256, "k1", claims(nil)),
+		"symmetric":      sign(t, []byte("secret"), jwt.SigningMethodHS256, "k1", claims(nil)),
+	}
+	for name, token := range invalid {
+		if _, err := a.Authenticate(ctx, token); err == nil || err == ErrUnknownToken {
+			t.Errorf("%s: Authenticate() error = %v, want an invalid token error", name, err)
+		}
+	}
+
+	if _, err := a.Authenticate(ctx, "not-a-jwt"); err != ErrUnknownToken {
+		t.Errorf("Authenticate() error = %v, want ErrUnknownToken", err)
+	}
+
+	// A rotated key is picked up from the changed file
+	rotated := newKey(t)
+	writeJWKS(t, dir, "k2", rotated)
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Authenticate(ctx, sign(t, rotated, jwt.SigningMethodES256, "k2", claims(nil))); err != nil {
+		t.Errorf("Authenticate() with rotated key error = %v", err)
+	}
+}
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	ctx := context.Background()
+	sum := sha256.Sum256([]byte("hashed-secret"))
+
+	a, err := NewAPIKeyAuthenticator([]APIKey{
+		{Name: "ci", Key: "plain-secret"},
+		{Name: "ops-bot", SHA256: hex.EncodeToString(sum[:]), Groups: []string{"ops"}},
+	})
+	if err != nil {
+		t.Fatalf("NewAPIKeyAuthenticator() error = %v", err)
+	}
+
+	if id, err := a.Authenticate(ctx, "plain-secret"); err != nil || id.Subject != "ci" {
+		t.Errorf("Authenticate() = %+v, %v", id, err)
+	}
+	if id, err := a.Authenticate(ctx, "hashed-secret"); err != nil || id.Subject != "ops-bot" || id.Groups[0] != "ops" {
+		t.Errorf("Authenticate() = %+v, %v", id, err)
+	}
+	if _, err := a.Authenticate(ctx, "wrong"); err != ErrUnknownToken {
+		t.Errorf("Authenticate() error = %v, want ErrUnknownToken", err)
+	}
+
+	for _, keys := range [][]APIKey{
+		{{Key: "no-name"}},
+		{{Name: "none"}},
+		{{Name: "both", Key: "a", SHA256: hex.EncodeToString(sum[:])}},
+		{{Name: "short", SHA256: "abcd"}},
+	} {
+		if _, err := NewAPIKeyAuthenticator(keys); err == nil {
+			t.Errorf("NewAPIKeyAuthenticator(%+v) error = nil", keys)
+		}
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	policy, err := NewPolicy([]Binding{
+		{Subject: "alice", Namespace: "sales", Role: "editor"},
+		{Group: "sales-team", Namespace: "sales", Role: "viewer"},
+		{Group: "sre", Namespace: AllNamespaces, Role: "viewer"},
+		{Subject: "root", Namespace: AllNamespaces, Role: "admin"},
+	})
+	if err != nil {
+		t.Fatalf("NewPolicy() error = %v", err)
+	}
+
+	tests := []struct {
+		id        Identity
+		namespace string
+		want      Role
+	}{
+		{Identity{Subject: "alice", Groups: []string{"sales-team"}}, "sales", Editor},
+		{Identity{Subject: "alice"}, "billing", None},
+		{Identity{Subject: "bob", Groups: []string{"sales-team"}}, "sales", Viewer},
+		{Identity{Subject: "carol", Groups: []string{"sre"}}, "billing", Viewer},
+		{Identity{Subject: "root"}, "anything", Admin},
+		{Identity{Subject: "mallory"}, "sales", None},
+	}
+	for _, tt := range tests {
+		if got := policy.Role(&tt.id, tt.namespace); got != tt.want {
+			t.Errorf("Role(%s, %s) = %s, want %s", tt.id.Subject, tt.namespace, got, tt.want)
+		}
+	}
+
+	for _, bindings := range [][]Binding{
+		{{Namespace: "sales", Role: "viewer"}},
+		{{Subject: "a", Group: "b", Namespace: "sales", Role: "viewer"}},
+		{{Subject: "a", Role: "viewer"}},
+		{{Subject: "a", Namespace: "sales", Role: "owner"}},
+	} {
+		if _, err := NewPolicy(bindings); err == nil {
+			t.Errorf("NewPolicy(%+v) error = nil", bindings)
+		}
+	}
+}
+
+func TestGuard_UnaryInterceptor(t *testing.T) {
+	keys, err := NewAPIKeyAuthenticator([]APIKey{
+		{Name: "viewer", Key: "viewer-key"},
+		{Name: "editor", Key: "editor-key"},
+		{Name: "root", Key: "root-key"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy, err := NewPolicy([]Binding{
+		{Subject: "viewer", Namespace: "sales", Role: "viewer"},
+		{Subject: "editor", Namespace: "sales", Role: "editor"},
+		{Subject: "root", Namespace: AllNamespaces, Role: "admin"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptor := NewGuard(policy, keys).UnaryInterceptor()
+
+	tests := []struct {
+		name   string
+		md     metadata.MD
+		method string
+		req    interface{}
+		want   codes.Code
+	}{
+		{"no token", nil, "/api.TriggerService/ListTriggers", &pb.ListTriggersRequest{Namespace: "sales"}, codes.Unauthenticated},
+		{"invalid token", metadata.Pairs("authorization", "Bearer nope"), "/api.TriggerService/ListTriggers", &pb.ListTriggersRequest{Namespace: "sales"}, codes.Unauthenticated},
+		{"viewer lists", metadata.Pairs("authorization", "Bearer viewer-key"), "/api.TriggerService/ListTriggers", &pb.ListTriggersRequest{Namespace: "sales"}, codes.OK},
+		{"api key header", metadata.Pairs(APIKeyHeader, "viewer-key"), "/api.TriggerService/ListTriggers", &pb.ListTriggersRequest{Namespace: "sales"}, codes.OK},
+		{"viewer in other namespace", metadata.Pairs("authorization", "Bearer viewer-key"), "/api.TriggerService/ListTriggers", &pb.ListTriggersRequest{Namespace: "billing"}, codes.PermissionDenied},
+		{"viewer removes", metadata.Pairs("authorization", "Bearer viewer-key"), "/api.TriggerService/RemoveTrigger", &pb.RemoveTriggerRequest{Namespace: "sales", Id: "t"}, codes.PermissionDenied},
+		{"editor removes", metadata.Pairs("authorization", "Bearer editor-key"), "/api.TriggerService/RemoveTrigger", &pb.RemoveTriggerRequest{Namespace: "sales", Id: "t"}, codes.OK},
+		{"editor adds to own namespace", metadata.Pairs("authorization", "Bearer editor-key"), "/api.TriggerService/AddTrigger", &pb.AddTriggerRequest{Trigger: &pb.Trigger{Namespace: "sales"}}, codes.OK},
+		{"editor adds to other namespace", metadata.Pairs("authorization", "Bearer editor-key"), "/api.TriggerService/AddTrigger", &pb.AddTriggerRequest{Trigger: &pb.Trigger{Namespace: "billing"}}, codes.PermissionDenied},
+		{"editor submits job", metadata.Pairs("authorization", "Bearer editor-key"), "/api.JobService/SubmitJob", &pb.SubmitJobRequest{Job: &pb.Job{Namespace: "sales"}}, codes.OK},
+		{"editor claims job in own namespace", metadata.Pairs("authorization", "Bearer editor-key"), "/api.JobService/ClaimJob", &pb.ClaimJobRequest{Namespace: "sales", JobType: "resize_image"}, codes.OK},
+		{"editor claims job in any namespace", metadata.Pairs("authorization", "Bearer editor-key"), "/api.JobService/ClaimJob", &pb.ClaimJobRequest{JobType: "resize_image"}, codes.PermissionDenied},
+		{"admin claims job in any namespace", metadata.Pairs("authorization", "Bearer root-key"), "/api.JobService/ClaimJob", &pb.ClaimJobRequest{JobType: "resize_image"}, codes.OK},
+		{"viewer reports job result", metadata.Pairs("authorization", "Bearer viewer-key"), "/api.JobService/ReportJobResult", &pb.ReportJobResultRequest{Namespace: "sales", JobId: "a"}, codes.PermissionDenied},
+		{"editor sends heartbeat", metadata.Pairs("authorization", "Bearer editor-key"), "/api.JobService/HeartbeatJob", &pb.HeartbeatJobRequest{Namespace: "sales", JobId: "a"}, codes.OK},
+		{"editor purges", metadata.Pairs("authorization", "Bearer editor-key"), "/api.TriggerService/PurgeDeadLetters", &pb.PurgeDeadLettersRequest{Namespace: "sales"}, codes.PermissionDenied},
+		{"admin purges", metadata.Pairs("authorization", "Bearer root-key"), "/api.TriggerService/PurgeDeadLetters", &pb.PurgeDeadLettersRequest{Namespace: "sales"}, codes.OK},
+		{"missing namespace", metadata.Pairs("authorization", "Bearer root-key"), "/api.TriggerService/ListTriggers", &pb.ListTriggersRequest{}, codes.InvalidArgument},
+		{"viewer gets namespace", metadata.Pairs("authorization", "Bearer viewer-key"), "/api.TriggerService/GetNamespace", &pb.GetNamespaceRequest{Namespace: "sales"}, codes.OK},
+		{"editor creates namespace", metadata.Pairs("authorization", "Bearer editor-key"), "/api.TriggerService/CreateNamespace", &pb.CreateNamespaceRequest{Namespace: &pb.Namespace{Name: "sales"}}, codes.PermissionDenied},
+		{"admin creates namespace", metadata.Pairs("authorization", "Bearer root-key"), "/api.TriggerService/CreateNamespace", &pb.CreateNamespaceRequest{Namespace: &pb.Namespace{Name: "sales"}}, codes.OK},
+		{"unlisted method", metadata.Pairs("authorization", "Bearer editor-key"), "/api.Other/Method", &pb.ListTriggersRequest{Namespace: "sales"}, codes.PermissionDenied},
+		{"unlisted method as admin", metadata.Pairs("authorization", "Bearer root-key"), "/api.Other/Method", &pb.ListTriggersRequest{}, codes.OK},
+		{"health check without token", nil, "/grpc.health.v1.Health/Check", &pb.ListTriggersRequest{}, codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			var caller *Identity
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				caller, _ = FromContext(ctx)
+				return "ok", nil
+			}
+
+			_, err := interceptor(ctx, tt.req, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("interceptor() code = %s (%v), want %s", got, err, tt.want)
+			}
+			if tt.want == codes.OK && caller == nil && !public[tt.method] {
+				t.Error("handler context has no identity")
+			}
+		})
+	}
+}
+
+// fakeStream is a server stream whose only request is req
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+	req *pb.WatchJobRequest
+}
+
+func (s *fakeStream) Context() context.Context { return s.ctx }
+
+func (s *fakeStream) RecvMsg(m interface{}) error {
+	*m.(*pb.WatchJobRequest) = pb.WatchJobRequest{Namespace: s.req.Namespace, JobId: s.req.JobId}
+	return nil
+}
+
+func TestGuard_StreamInterceptor(t *testing.T) {
+	keys, _ := NewAPIKeyAuthenticator([]APIKey{{Name: "viewer", Key: "viewer-key"}})
+	policy, _ := NewPolicy([]Binding{{Subject: "viewer", Namespace: "sales", Role: "viewer"}})
+	interceptor := NewGuard(policy, keys).StreamInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/api.JobService/WatchJob", IsServerStream: true}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer viewer-key"))
+
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		return stream.RecvMsg(&pb.WatchJobRequest{})
+	}
+
+	for namespace, want := range map[string]codes.Code{"sales": codes.OK, "billing": codes.PermissionDenied} {
+		stream := &fakeStream{ctx: ctx, req: &pb.WatchJobRequest{Namespace: namespace, JobId: "j"}}
+		if got := status.Code(interceptor(nil, stream, info, handler)); got != want {
+			t.Errorf("WatchJob in %s: code = %s, want %s", namespace, got, want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+
+	pb "event/api/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// APIKeyHeader is the metadata key an API key can be sent in instead of a bearer token
+const APIKeyHeader = "x-api-key"
+
+// rules holds the role each RPC requires in the namespace of its request.
+// RPCs that are not listed require the admin role in every namespace, such as listing,
+// creating and deleting namespaces, which sets their quotas.
+var rules = map[string]Role{
+	"/api.TriggerService/ListTriggers":       Viewer,
+	"/api.TriggerService/AddTrigger":         Editor,
+	"/api.TriggerService/UpdateTrigger":      Editor,
+	"/api.TriggerService/RemoveTrigger":      Editor,
+	"/api.TriggerService/ListDeadLetters":    Viewer,
+	"/api.TriggerService/GetDeadLetter":      Viewer,
+	"/api.TriggerService/RedriveDeadLetters": Editor,
+	"/api.TriggerService/PurgeDeadLetters":   Admin,
+	"/api.TriggerService/DryRunTrigger":      Viewer,
+	"/api.TriggerService/GetTriggerStats":    Viewer,
+	"/api.TriggerService/GetNamespace":       Viewer,
+	"/api.JobService/SubmitJob":              Editor,
+	"/api.JobService/GetJob":                 Viewer,
+	"/api.JobService/ListJobs":               Viewer,
+	"/api.JobService/CancelJob":              Editor,
+	"/api.JobService/RetryJob":               Editor,
+	"/api.JobService/WatchJob":               Viewer,
+	"/api.JobService/ClaimJob":               Editor,
+	"/api.JobService/HeartbeatJob":           Editor,
+	"/api.JobService/ReportJobResult":        Editor,
+}
+
+// anyNamespace holds the RPCs whose requests may leave the namespace empty to act on
+// every namespace. They require their role in every namespace in that case.
+var anyNamespace = map[string]bool{
+	"/api.JobService/ClaimJob": true,
+}
+
+// public holds the RPCs that are served without authentication: health checks, which
+// probes make without credentials, and reflection, which only describes the API
+var public = map[string]bool{
+	"/grpc.health.v1.Health/Check":                                   true,
+	"/grpc.health.v1.Health/Watch":                                   true,
+	"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo":      true,
+	"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo": true,
+}
+
+// Guard authenticates the callers of RPCs and authorizes them against a policy
+type Guard struct {
+	policy         *Policy
+	authenticators []Authenticator
+}
+
+// NewGuard creates a guard that tries the authenticators in order
+func NewGuard(policy *Policy, authenticators ...Authenticator) *Guard {
+	return &Guard{
+		policy:         policy,
+		authenticators: authenticators,
+	}
+}
+
+// UnaryInterceptor returns an interceptor that authorizes unary RPCs
+func (g *Guard) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if public[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		id, err := g.authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := g.authorize(id, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		return handler(NewContext(ctx, id), req)
+	}
+}
+
+// StreamInterceptor returns an interceptor that authorizes streaming RPCs. The caller is
+// authenticated when the stream opens and authorized against its first request.
+func (g *Guard) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if public[info.FullMethod] {
+			return handler(srv, ss)
+		}
+		id, err := g.authenticate(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &guardedStream{
+			ServerStream: ss,
+			ctx:          NewContext(ss.Context(), id),
+			authorize: func(req interface{}) error {
+				return g.authorize(id, info.FullMethod, req)
+			},
+		})
+	}
+}
+
+// authenticate returns the identity of the token in the RPC's metadata
+func (g *Guard) authenticate(ctx context.Context) (*Identity, error) {
+	token := tokenFromMetadata(ctx)
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token or API key")
+	}
+
+	for _, a := range g.authenticators {
+		id, err := a.Authenticate(ctx, token)
+		if errors.Is(err, ErrUnknownToken) {
+			continue
+		}
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+		}
+		return id, nil
+	}
+	return nil, status.Error(codes.Unauthenticated, "invalid token")
+}
+
+// authorize checks that the identity has the role the method requires in the request's namespace
+func (g *Guard) authorize(id *Identity, method string, req interface{}) error {
+	name := path.Base(method)
+
+	required, ok := rules[method]
+	if !ok {
+		if g.policy.Role(id, AllNamespaces) < Admin {
+			return status.Errorf(codes.PermissionDenied, "%s requires the admin role in every namespace", name)
+		}
+		return nil
+	}
+
+	namespace := requestNamespace(req)
+	if namespace == "" && anyNamespace[method] {
+		namespace = AllNamespaces
+	}
+	if namespace == "" {
+		return status.Errorf(codes.InvalidArgument, "%s requires a namespace", name)
+	}
+	if role := g.policy.Role(id, namespace); role < required {
+		return status.Errorf(codes.PermissionDenied, "%s requires the %s role in namespace %q, but %s has %s",
+			name, required, namespace, id.Subject, describeRole(role))
+	}
+	return nil
+}
+
+func describeRole(role Role) string {
+	if role == None {
+		return "no role"
+	}
+	return "the " + role.String() + " role"
+}
+
+// requestNamespace returns the namespace a request acts on: that of the trigger or job it
+// carries, or its namespace field
+func requestNamespace(req interface{}) string {
+	if r, ok := req.(interface{ GetTrigger() *pb.Trigger }); ok && r.GetTrigger() != nil {
+		return r.GetTrigger().GetNamespace()
+	}
+	if r, ok := req.(interface{ GetJob() *pb.Job }); ok && r.GetJob() != nil {
+		return r.GetJob().GetNamespace()
+	}
+	if r, ok := req.(interface{ GetNamespace() string }); ok {
+		return r.GetNamespace()
+	}
+	return ""
+}
+
+// tokenFromMetadata returns the bearer token of the authorization header, or the API key header
+func tokenFromMetadata(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, value := range md.Get("authorization") {
+		scheme, token, found := strings.Cut(value, " ")
+		if found && strings.EqualFold(scheme, "bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	if keys := md.Get(APIKeyHeader); len(keys) > 0 {
+		return strings.TrimSpace(keys[0])
+	}
+	return ""
+}
+
+// guardedStream authorizes the first request received on a stream
+type guardedStream struct {
+	grpc.ServerStream
+	ctx        context.Context
+	authorize  func(req interface{}) error
+	authorized bool
+}
+
+func (s *guardedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *guardedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if !s.authorized {
+		if err := s.authorize(m); err != nil {
+			return err
+		}
+		s.authorized = true
+	}
+	return nil
+}
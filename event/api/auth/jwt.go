@@ -0,0 +1,292 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// DefaultGroupsClaim is the JWT claim that holds the caller's groups
+	DefaultGroupsClaim = "groups"
+	// DefaultLeeway is the clock skew allowed when checking exp, nbf and iat
+	DefaultLeeway = 30 * time.Second
+)
+
+// signingMethods are the accepted JWT algorithms. Symmetric algorithms and "none" are
+// rejected, since the keys come from a public JWKS.
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// Compile-time check to ensure JWTAuthenticator implements Authenticator
+var _ Authenticator = (*JWTAuthenticator)(nil)
+
+// JWTAuthenticator authenticates JWTs signed with the keys of a JWKS file. The file is
+// read again when a token names a key ID it does not know and the file has changed,
+// so keys can be rotated without a restart.
+type JWTAuthenticator struct {
+	file        string
+	issuer      string
+	audience    string
+	groupsClaim string
+	leeway      time.Duration
+
+	keys    map[string]interface{}
+	modTime time.Time
+	mu      sync.Mutex
+}
+
+// JWTOption is a function that configures a JWTAuthenticator
+type JWTOption func(*JWTAuthenticator)
+
+// WithIssuer requires tokens to have the given iss claim
+func WithIssuer(issuer string) JWTOption {
+	return func(a *JWTAuthenticator) {
+		a.issuer = issuer
+	}
+}
+
+// WithAudience requires tokens to have the given audience in their aud claim
+func WithAudience(audience string) JWTOption {
+	return func(a *JWTAuthenticator) {
+		a.audience = audience
+	}
+}
+
+// WithGroupsClaim sets the claim that holds the caller's groups
+func WithGroupsClaim(claim string) JWTOption {
+	return func(a *JWTAuthenticator) {
+		a.groupsClaim = claim
+	}
+}
+
+// WithLeeway sets the clock skew allowed when checking time claims
+func WithLeeway(leeway time.Duration) JWTOption {
+	return func(a *JWTAuthenticator) {
+		a.leeway = leeway
+	}
+}
+
+// NewJWTAuthenticator creates an authenticator that verifies tokens with the keys in a
+// JWKS file
+func NewJWTAuthenticator(jwksFile string, options ...JWTOption) (*JWTAuthenticator, error) {
+	a := &JWTAuthenticator{
+		file:        jwksFile,
+		groupsClaim: DefaultGroupsClaim,
+		leeway:      DefaultLeeway,
+	}
+	for _, option := range options {
+		option(a)
+	}
+
+	info, err := os.Stat(jwksFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS: %w", err)
+	}
+	if err := a.load(info.ModTime()); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Authenticate verifies a JWT and returns its subject and groups. Tokens that are not
+// JWTs are left to other authenticators.
+func (a *JWTAuthenticator) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	if strings.Count(token, ".") != 2 {
+		return nil, ErrUnknownToken
+	}
+
+	parserOptions := []jwt.ParserOption{
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(a.leeway),
+	}
+	if a.issuer != "" {
+		parserOptions = append(parserOptions, jwt.WithIssuer(a.issuer))
+	}
+	if a.audience != "" {
+		parserOptions = append(parserOptions, jwt.WithAudience(a.audience))
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.NewParser(parserOptions...).ParseWithClaims(token, claims, a.key); err != nil {
+		return nil, err
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	return &Identity{Subject: subject, Groups: stringList(claims[a.groupsClaim])}, nil
+}
+
+// key returns the verification key of a token by its key ID. A token without a key ID is
+// accepted only if the JWKS has a single key.
+func (a *JWTAuthenticator) key(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if key := a.lookup(kid); key != nil {
+		return key, nil
+	}
+	if info, err := os.Stat(a.file); err == nil && !info.ModTime().Equal(a.modTime) {
+		if err := a.load(info.ModTime()); err != nil {
+			return nil, err
+		}
+		if key := a.lookup(kid); key != nil {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key ID %q", kid)
+}
+
+func (a *JWTAuthenticator) lookup(kid string) interface{} {
+	if kid == "" && len(a.keys) == 1 {
+		for _, key := range a.keys {
+			return key
+		}
+	}
+	return a.keys[kid]
+}
+
+// jwk is a JSON Web Key with the members of RSA, EC and OKP public keys
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// load reads the JWKS file. It must be called with mu held, or before the authenticator is used.
+func (a *JWTAuthenticator) load(modTime time.Time) error {
+	content, err := os.ReadFile(a.file)
+	if err != nil {
+		return fmt.Errorf("failed to read JWKS: %w", err)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(content, &set); err != nil {
+		return fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return fmt.Errorf("JWKS key %d (%q): %w", i, k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return errors.New("JWKS has no signing keys")
+	}
+
+	a.keys = keys
+	a.modTime = modTime
+	return nil
+}
+
+// publicKey converts a JWK to an RSA, ECDSA or Ed25519 public key
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid n: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, errors.New("invalid e")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x: %w", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid x")
+		}
+		return ed25519.PublicKey(x), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// stringList converts a claim that is a string or a list of strings
+func stringList(claim interface{}) []string {
+	switch v := claim.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var list []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,466 @@
+// Package gateway serves the TriggerService, and the JobService RPCs of remote workers, as an
+// HTTP/JSON API with resource-style routes.
+// Every request is forwarded to the gRPC API, so authentication, authorization and metrics
+// apply to both in the same way.
+package gateway
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"event/api/auth"
+	pb "event/api/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// MaxBodySize is the largest request body the gateway reads
+const MaxBodySize = 1 << 20
+
+// OpenAPISpec is the OpenAPI document of the gateway, served at /openapi.yaml
+//
+//go:embed trigger_openapi_spec.yaml
+var OpenAPISpec []byte
+
+var (
+	marshaler   = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+	unmarshaler = protojson.UnmarshalOptions{}
+)
+
+// Gateway translates HTTP/JSON requests to TriggerService and JobService RPCs
+type Gateway struct {
+	client         pb.TriggerServiceClient
+	jobs           pb.JobServiceClient
+	mux            *http.ServeMux
+	allowedOrigins map[string]bool
+}
+
+// Option is a function that configures a Gateway
+type Option func(*Gateway)
+
+// WithAllowedOrigins lets browsers on the given origins, e.g. http://localhost:3000, call
+// the gateway. "*" allows every origin.
+func WithAllowedOrigins(origins ...string) Option {
+	return func(g *Gateway) {
+		for _, origin := range origins {
+			g.allowedOrigins[strings.TrimSuffix(origin, "/")] = true
+		}
+	}
+}
+
+// New creates a Gateway that calls the TriggerService and JobService over conn
+func New(conn grpc.ClientConnInterface, options ...Option) *Gateway {
+	g := &Gateway{
+		client:         pb.NewTriggerServiceClient(conn),
+		jobs:           pb.NewJobServiceClient(conn),
+		mux:            http.NewServeMux(),
+		allowedOrigins: map[string]bool{},
+	}
+
+	for _, option := range options {
+		option(g)
+	}
+
+	g.mux.HandleFunc("GET /openapi.yaml", serveSpec)
+	g.mux.HandleFunc("GET /v1/namespaces", g.listNamespaces)
+	g.mux.HandleFunc("POST /v1/namespaces", g.createNamespace)
+	g.mux.HandleFunc("GET /v1/namespaces/{namespace}", g.getNamespace)
+	g.mux.HandleFunc("DELETE /v1/namespaces/{namespace}", g.deleteNamespace)
+	g.mux.HandleFunc("GET /v1/namespaces/{namespace}/triggers", g.listTriggers)
+	g.mux.HandleFunc("POST /v1/namespaces/{namespace}/triggers", g.addTrigger)
+	g.mux.HandleFunc("GET /v1/namespaces/{namespace}/triggers/{id}", g.getTrigger)
+	g.mux.HandleFunc("PUT /v1/namespaces/{namespace}/triggers/{id}", g.updateTrigger)
+	g.mux.HandleFunc("DELETE /v1/namespaces/{namespace}/triggers/{id}", g.removeTrigger)
+	g.mux.HandleFunc("GET /v1/namespaces/{namespace}/triggers/{id}/stats", g.getTriggerStats)
+	g.mux.HandleFunc("POST /v1/namespaces/{namespace}/triggers/{id}/dry-run", g.dryRunTrigger)
+	g.mux.HandleFunc("POST /v1/namespaces/{namespace}/dry-run", g.dryRunTrigger)
+	g.mux.HandleFunc("GET /v1/namespaces/{namespace}/dead-letters", g.listDeadLetters)
+	g.mux.HandleFunc("GET /v1/namespaces/{namespace}/dead-letters/{id}", g.getDeadLetter)
+	g.mux.HandleFunc("POST /v1/namespaces/{namespace}/dead-letters/redrive", g.redriveDeadLetters)
+	g.mux.HandleFunc("POST /v1/namespaces/{namespace}/dead-letters/purge", g.purgeDeadLetters)
+	g.mux.HandleFunc("POST /v1/jobs/claim", g.claimJob)
+	g.mux.HandleFunc("POST /v1/namespaces/{namespace}/jobs/claim", g.claimJob)
+	g.mux.HandleFunc("POST /v1/namespaces/{namespace}/jobs/{id}/heartbeat", g.heartbeatJob)
+	g.mux.HandleFunc("POST /v1/namespaces/{namespace}/jobs/{id}/result", g.reportJobResult)
+
+	return g
+}
+
+// ServeHTTP serves a request
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if origin := r.Header.Get("Origin"); origin != "" && (g.allowedOrigins["*"] || g.allowedOrigins[origin]) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, "+auth.APIKeyHeader)
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	g.mux.ServeHTTP(w, r)
+}
+
+func serveSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(OpenAPISpec)
+}
+
+func (g *Gateway) listNamespaces(w http.ResponseWriter, r *http.Request) {
+	resp, err := g.client.ListNamespaces(outgoing(r), &pb.ListNamespacesRequest{})
+	respond(w, http.StatusOK, resp, err)
+}
+
+func (g *Gateway) createNamespace(w http.ResponseWriter, r *http.Request) {
+	namespace := &pb.Namespace{}
+	if err := decode(r, namespace); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp, err := g.client.CreateNamespace(outgoing(r), &pb.CreateNamespaceRequest{Namespace: namespace})
+	if err == nil {
+		w.Header().Set("Location", "/v1/namespaces/"+url.PathEscape(namespace.Name))
+	}
+	respond(w, http.StatusCreated, resp, err)
+}
+
+func (g *Gateway) getNamespace(w http.ResponseWriter, r *http.Request) {
+	resp, err := g.client.GetNamespace(outgoing(r), &pb.GetNamespaceRequest{Namespace: r.PathValue("namespace")})
+	respond(w, http.StatusOK, resp, err)
+}
+
+func (g *Gateway) deleteNamespace(w http.ResponseWriter, r *http.Request) {
+	req := &pb.DeleteNamespaceRequest{Namespace: r.PathValue("namespace")}
+	if value := r.URL.Query().Get("delete_triggers"); value != "" {
+		deleteTriggers, err := strconv.ParseBool(value)
+		if err != nil {
+			writeError(w, status.Errorf(codes.InvalidArgument, "invalid delete_triggers %q", value))
+			return
+		}
+		req.DeleteTriggers = deleteTriggers
+	}
+
+	resp, err := g.client.DeleteNamespace(outgoing(r), req)
+	respond(w, http.StatusOK, resp, err)
+}
+
+func (g *Gateway) listTriggers(w http.ResponseWriter, r *http.Request) {
+	resp, err := g.client.ListTriggers(outgoing(r), &pb.ListTriggersRequest{Namespace: r.PathValue("namespace")})
+	respond(w, http.StatusOK, resp, err)
+}
+
+// getTrigger has no RPC of its own; it returns the trigger from ListTriggers
+func (g *Gateway) getTrigger(w http.ResponseWriter, r *http.Request) {
+	namespace, id := r.PathValue("namespace"), r.PathValue("id")
+	resp, err := g.client.ListTriggers(outgoing(r), &pb.ListTriggersRequest{Namespace: namespace})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	for _, trigger := range resp.Triggers {
+		if trigger.Id == id {
+			respond(w, http.StatusOK, trigger, nil)
+			return
+		}
+	}
+	writeError(w, status.Errorf(codes.NotFound, "trigger %s not found in namespace %s", id, namespace))
+}
+
+func (g *Gateway) addTrigger(w http.ResponseWriter, r *http.Request) {
+	trigger := &pb.Trigger{}
+	if err := decode(r, trigger); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := fill(&trigger.Namespace, r.PathValue("namespace"), "namespace"); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp, err := g.client.AddTrigger(outgoing(r), &pb.AddTriggerRequest{Trigger: trigger})
+	if err == nil {
+		w.Header().Set("Location", fmt.Sprintf("/v1/namespaces/%s/triggers/%s", url.PathEscape(trigger.Namespace), url.PathEscape(trigger.Id)))
+	}
+	respond(w, http.StatusCreated, resp, err)
+}
+
+func (g *Gateway) updateTrigger(w http.ResponseWriter, r *http.Request) {
+	trigger := &pb.Trigger{}
+	if err := decode(r, trigger); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := fill(&trigger.Namespace, r.PathValue("namespace"), "namespace"); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := fill(&trigger.Id, r.PathValue("id"), "id"); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp, err := g.client.UpdateTrigger(outgoing(r), &pb.UpdateTriggerRequest{Trigger: trigger})
+	respond(w, http.StatusOK, resp, err)
+}
+
+func (g *Gateway) removeTrigger(w http.ResponseWriter, r *http.Request) {
+	resp, err := g.client.RemoveTrigger(outgoing(r), &pb.RemoveTriggerRequest{
+		Namespace: r.PathValue("namespace"),
+		Id:        r.PathValue("id"),
+	})
+	respond(w, http.StatusOK, resp, err)
+}
+
+func (g *Gateway) getTriggerStats(w http.ResponseWriter, r *http.Request) {
+	resp, err := g.client.GetTriggerStats(outgoing(r), &pb.GetTriggerStatsRequest{
+		Namespace: r.PathValue("namespace"),
+		TriggerId: r.PathValue("id"),
+	})
+	respond(w, http.StatusOK, resp, err)
+}
+
+// dryRunRequest is the body of a dry run. Unlike in DryRunTriggerRequest, the event may be
+// given as a JSON object instead of a string holding one.
+type dryRunRequest struct {
+	Trigger json.RawMessage `json:"trigger"`
+	Event   json.RawMessage `json:"event"`
+}
+
+// dryRunTrigger dry-runs the saved trigger named in the path, or else the trigger in the body
+func (g *Gateway) dryRunTrigger(w http.ResponseWriter, r *http.Request) {
+	body, err := readBody(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var dryRun dryRunRequest
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &dryRun); err != nil {
+			writeError(w, status.Errorf(codes.InvalidArgument, "invalid request body: %v", err))
+			return
+		}
+	}
+
+	req := &pb.DryRunTriggerRequest{
+		Namespace: r.PathValue("namespace"),
+		TriggerId: r.PathValue("id"),
+	}
+	var event string
+	if err := json.Unmarshal(dryRun.Event, &event); err == nil {
+		req.Event = event
+	} else {
+		req.Event = string(dryRun.Event)
+	}
+	if req.TriggerId == "" {
+		if len(dryRun.Trigger) == 0 || string(dryRun.Trigger) == "null" {
+			writeError(w, status.Error(codes.InvalidArgument, "trigger is required"))
+			return
+		}
+		req.Trigger = &pb.Trigger{}
+		if err := unmarshaler.Unmarshal(dryRun.Trigger, req.Trigger); err != nil {
+			writeError(w, status.Errorf(codes.InvalidArgument, "invalid trigger: %v", err))
+			return
+		}
+		if err := fill(&req.Trigger.Namespace, req.Namespace, "namespace"); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	resp, err := g.client.DryRunTrigger(outgoing(r), req)
+	respond(w, http.StatusOK, resp, err)
+}
+
+func (g *Gateway) listDeadLetters(w http.ResponseWriter, r *http.Request) {
+	req := &pb.ListDeadLettersRequest{
+		Namespace: r.PathValue("namespace"),
+		TriggerId: r.URL.Query().Get("trigger_id"),
+	}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		n, err := strconv.ParseInt(limit, 10, 32)
+		if err != nil {
+			writeError(w, status.Errorf(codes.InvalidArgument, "invalid limit %q", limit))
+			return
+		}
+		req.Limit = int32(n)
+	}
+
+	resp, err := g.client.ListDeadLetters(outgoing(r), req)
+	respond(w, http.StatusOK, resp, err)
+}
+
+func (g *Gateway) getDeadLetter(w http.ResponseWriter, r *http.Request) {
+	resp, err := g.client.GetDeadLetter(outgoing(r), &pb.GetDeadLetterRequest{
+		Namespace: r.PathValue("namespace"),
+		Id:        r.PathValue("id"),
+	})
+	respond(w, http.StatusOK, resp, err)
+}
+
+func (g *Gateway) redriveDeadLetters(w http.ResponseWriter, r *http.Request) {
+	req := &pb.RedriveDeadLettersRequest{}
+	if err := decode(r, req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := fill(&req.Namespace, r.PathValue("namespace"), "namespace"); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp, err := g.client.RedriveDeadLetters(outgoing(r), req)
+	respond(w, http.StatusOK, resp, err)
+}
+
+func (g *Gateway) purgeDeadLetters(w http.ResponseWriter, r *http.Request) {
+	req := &pb.PurgeDeadLettersRequest{}
+	if err := decode(r, req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := fill(&req.Namespace, r.PathValue("namespace"), "namespace"); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp, err := g.client.PurgeDeadLetters(outgoing(r), req)
+	respond(w, http.StatusOK, resp, err)
+}
+
+// claimJob claims a job of the namespace in the path, or of any namespace without one
+func (g *Gateway) claimJob(w http.ResponseWriter, r *http.Request) {
+	req := &pb.ClaimJobRequest{}
+	if err := decode(r, req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if namespace := r.PathValue("namespace"); namespace != "" {
+		if err := fill(&req.Namespace, namespace, "namespace"); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	resp, err := g.jobs.ClaimJob(outgoing(r), req)
+	respond(w, http.StatusOK, resp, err)
+}
+
+func (g *Gateway) heartbeatJob(w http.ResponseWriter, r *http.Request) {
+	req := &pb.HeartbeatJobRequest{}
+	if err := decode(r, req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := fill(&req.Namespace, r.PathValue("namespace"), "namespace"); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := fill(&req.JobId, r.PathValue("id"), "job_id"); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp, err := g.jobs.HeartbeatJob(outgoing(r), req)
+	respond(w, http.StatusOK, resp, err)
+}
+
+func (g *Gateway) reportJobResult(w http.ResponseWriter, r *http.Request) {
+	req := &pb.ReportJobResultRequest{}
+	if err := decode(r, req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := fill(&req.Namespace, r.PathValue("namespace"), "namespace"); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := fill(&req.JobId, r.PathValue("id"), "job_id"); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp, err := g.jobs.ReportJobResult(outgoing(r), req)
+	respond(w, http.StatusOK, resp, err)
+}
+
+// outgoing returns the context of an RPC made for r, carrying the caller's credentials
+func outgoing(r *http.Request) context.Context {
+	md := metadata.MD{}
+	if authorization := r.Header.Get("Authorization"); authorization != "" {
+		md.Set("authorization", authorization)
+	}
+	if key := r.Header.Get(auth.APIKeyHeader); key != "" {
+		md.Set(auth.APIKeyHeader, key)
+	}
+	return metadata.NewOutgoingContext(r.Context(), md)
+}
+
+// readBody reads the body of r, up to MaxBodySize
+func readBody(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodySize+1))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to read request body: %v", err)
+	}
+	if len(body) > MaxBodySize {
+		return nil, status.Errorf(codes.InvalidArgument, "request body is larger than %d bytes", MaxBodySize)
+	}
+	return body, nil
+}
+
+// decode reads the JSON body of r into m. An empty body leaves m empty.
+func decode(r *http.Request, m proto.Message) error {
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+	if len(body) == 0 {
+		return nil
+	}
+	if err := unmarshaler.Unmarshal(body, m); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid request body: %v", err)
+	}
+	return nil
+}
+
+// fill sets an empty field of the body to its value from the path, and rejects a body
+// that names a different resource than the path
+func fill(field *string, value, name string) error {
+	if *field == "" {
+		*field = value
+		return nil
+	}
+	if *field != value {
+		return status.Errorf(codes.InvalidArgument, "%s %q in the body does not match %q in the path", name, *field, value)
+	}
+	return nil
+}
+
+// respond writes resp as JSON with code, or the error of the RPC
+func respond(w http.ResponseWriter, code int, resp proto.Message, err error) {
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	body, err := marshaler.Marshal(resp)
+	if err != nil {
+		writeError(w, status.Errorf(codes.Internal, "failed to encode response: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(body)
+}
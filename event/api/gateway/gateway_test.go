@@ -0,0 +1,562 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pb "event/api/proto"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"gopkg.in/yaml.v3"
+)
+
+// fakeService records the last request it received and answers from a fixed set of triggers
+type fakeService struct {
+	pb.UnimplementedTriggerServiceServer
+	request       proto.Message
+	authorization string
+}
+
+func (s *fakeService) record(ctx context.Context, req proto.Message) {
+	s.request = req
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.authorization = strings.Join(md.Get("authorization"), ",")
+}
+
+func (s *fakeService) ListTriggers(ctx context.Context, req *pb.ListTriggersRequest) (*pb.ListTriggersResponse, error) {
+	s.record(ctx, req)
+	if req.Namespace == "secret" {
+		return nil, status.Error(codes.PermissionDenied, "no role in namespace secret")
+	}
+	return &pb.ListTriggersResponse{Triggers: []*pb.Trigger{{Id: "t1", Namespace: req.Namespace, Enabled: true}}}, nil
+}
+
+func (s *fakeService) AddTrigger(ctx context.Context, req *pb.AddTriggerRequest) (*pb.AddTriggerResponse, error) {
+	s.record(ctx, req)
+	return &pb.AddTriggerResponse{Trigger: req.Trigger}, nil
+}
+
+func (s *fakeService) UpdateTrigger(ctx context.Context, req *pb.UpdateTriggerRequest) (*pb.UpdateTriggerResponse, error) {
+	s.record(ctx, req)
+	return &pb.UpdateTriggerResponse{Trigger: req.Trigger}, nil
+}
+
+func (s *fakeService) RemoveTrigger(ctx context.Context, req *pb.RemoveTriggerRequest) (*pb.RemoveTriggerResponse, error) {
+	s.record(ctx, req)
+	return &pb.RemoveTriggerResponse{Success: true}, nil
+}
+
+func (s *fakeService) ListDeadLetters(ctx context.Context, req *pb.ListDeadLettersRequest) (*pb.ListDeadLettersResponse, error) {
+	s.record(ctx, req)
+	return &pb.ListDeadLettersResponse{}, nil
+}
+
+func (s *fakeService) GetDeadLetter(ctx context.Context, req *pb.GetDeadLetterRequest) (*pb.GetDeadLetterResponse, error) {
+	s.record(ctx, req)
+	return nil, status.Errorf(codes.NotFound, "dead letter %s not found", req.Id)
+}
+
+func (s *fakeService) RedriveDeadLetters(ctx context.Context, req *pb.RedriveDeadLettersRequest) (*pb.RedriveDeadLettersResponse, error) {
+	s.record(ctx, req)
+	return &pb.RedriveDeadLettersResponse{Redriven: int32(len(req.Ids))}, nil
+}
+
+func (s *fakeService) PurgeDeadLetters(ctx context.Context, req *pb.PurgeDeadLettersRequest) (*pb.PurgeDeadLettersResponse, error) {
+	s.record(ctx, req)
+	return nil, status.Error(codes.Unauthenticated, "missing credentials")
+}
+
+func (s *fakeService) DryRunTrigger(ctx context.Context, req *pb.DryRunTriggerRequest) (*pb.DryRunTriggerResponse, error) {
+	s.record(ctx, req)
+	return &pb.DryRunTriggerResponse{Matched: true}, nil
+}
+
+func (s *fakeService) GetTriggerStats(ctx context.Context, req *pb.GetTriggerStatsRequest) (*pb.GetTriggerStatsResponse, error) {
+	s.record(ctx, req)
+	return &pb.GetTriggerStatsResponse{Stats: &pb.TriggerStats{Namespace: req.Namespace, TriggerId: req.TriggerId, Fired: 3}}, nil
+}
+
+func (s *fakeService) ListNamespaces(ctx context.Context, req *pb.ListNamespacesRequest) (*pb.ListNamespacesResponse, error) {
+	s.record(ctx, req)
+	return &pb.ListNamespacesResponse{Namespaces: []*pb.Namespace{{Name: "sales", TriggerCount: 1}}}, nil
+}
+
+func (s *fakeService) GetNamespace(ctx context.Context, req *pb.GetNamespaceRequest) (*pb.GetNamespaceResponse, error) {
+	s.record(ctx, req)
+	return &pb.GetNamespaceResponse{Namespace: &pb.Namespace{Name: req.Namespace, TriggerCount: 1}}, nil
+}
+
+func (s *fakeService) CreateNamespace(ctx context.Context, req *pb.CreateNamespaceRequest) (*pb.CreateNamespaceResponse, error) {
+	s.record(ctx, req)
+	return &pb.CreateNamespaceResponse{Namespace: req.Namespace}, nil
+}
+
+func (s *fakeService) DeleteNamespace(ctx context.Context, req *pb.DeleteNamespaceRequest) (*pb.DeleteNamespaceResponse, error) {
+	s.record(ctx, req)
+	if !req.DeleteTriggers {
+		return nil, status.Errorf(codes.FailedPrecondition, "namespace %s has 1 triggers", req.Namespace)
+	}
+	return &pb.DeleteNamespaceResponse{DeletedTriggers: 1}, nil
+}
+
+// fakeJobService serves the worker RPCs of the JobService, recording requests in its fakeService
+type fakeJobService struct {
+	pb.UnimplementedJobServiceServer
+	*fakeService
+}
+
+func (s *fakeJobService) ClaimJob(ctx context.Context, req *pb.ClaimJobRequest) (*pb.ClaimJobResponse, error) {
+	s.record(ctx, req)
+	if req.Namespace == "idle" {
+		return &pb.ClaimJobResponse{}, nil
+	}
+	return &pb.ClaimJobResponse{Job: &pb.Job{JobId: "j1", JobType: req.JobType, Namespace: "media", Status: "started", LeaseOwner: req.WorkerId}}, nil
+}
+
+func (s *fakeJobService) HeartbeatJob(ctx context.Context, req *pb.HeartbeatJobRequest) (*pb.HeartbeatJobResponse, error) {
+	s.record(ctx, req)
+	if req.WorkerId != "w1" {
+		st, _ := status.New(codes.Aborted, "job lease lost").WithDetails(&errdetails.ErrorInfo{Reason: "LEASE_LOST", Domain: "jobs"})
+		return nil, st.Err()
+	}
+	return &pb.HeartbeatJobResponse{Job: &pb.Job{JobId: req.JobId, Namespace: req.Namespace, Progress: req.Progress}}, nil
+}
+
+func (s *fakeJobService) ReportJobResult(ctx context.Context, req *pb.ReportJobResultRequest) (*pb.ReportJobResultResponse, error) {
+	s.record(ctx, req)
+	return &pb.ReportJobResultResponse{Job: &pb.Job{JobId: req.JobId, Namespace: req.Namespace, Status: "completed", Result: req.Result}}, nil
+}
+
+// newGateway serves service over an in-memory connection and returns a gateway to it
+func newGateway(t *testing.T, service *fakeService, options ...Option) *Gateway {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	pb.RegisterTriggerServiceServer(grpcServer, service)
+	pb.RegisterJobServiceServer(grpcServer, &fakeJobService{fakeService: service})
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return New(conn, options...)
+}
+
+func TestGateway(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		wantStatus  int
+		wantRequest proto.Message
+		wantBody    string
+	}{
+		{
+			name:        "list triggers",
+			method:      http.MethodGet,
+			path:        "/v1/namespaces/sales/triggers",
+			wantStatus:  http.StatusOK,
+			wantRequest: &pb.ListTriggersRequest{Namespace: "sales"},
+			wantBody:    `"triggers":[`,
+		},
+		{
+			name:        "get trigger",
+			method:      http.MethodGet,
+			path:        "/v1/namespaces/sales/triggers/t1",
+			wantStatus:  http.StatusOK,
+			wantRequest: &pb.ListTriggersRequest{Namespace: "sales"},
+			wantBody:    `"id":"t1"`,
+		},
+		{
+			name:       "get missing trigger",
+			method:     http.MethodGet,
+			path:       "/v1/namespaces/sales/triggers/t2",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `"status":"NotFound"`,
+		},
+		{
+			name:       "permission denied",
+			method:     http.MethodGet,
+			path:       "/v1/namespaces/secret/triggers",
+			wantStatus: http.StatusForbidden,
+			wantBody:   `"message":"no role in namespace secret"`,
+		},
+		{
+			name:        "add trigger",
+			method:      http.MethodPost,
+			path:        "/v1/namespaces/sales/triggers",
+			body:        `{"id": "t2", "event_type": "created", "retryCount": 2}`,
+			wantStatus:  http.StatusCreated,
+			wantRequest: &pb.AddTriggerRequest{Trigger: &pb.Trigger{Id: "t2", Namespace: "sales", EventType: "created", RetryCount: 2}},
+			wantBody:    `"event_type":"created"`,
+		},
+		{
+			name:       "add trigger to another namespace",
+			method:     http.MethodPost,
+			path:       "/v1/namespaces/sales/triggers",
+			body:       `{"id": "t2", "namespace": "billing"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "add invalid trigger",
+			method:     http.MethodPost,
+			path:       "/v1/namespaces/sales/triggers",
+			body:       `{"id": 2}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `"status":"InvalidArgument"`,
+		},
+		{
+			name:        "update trigger",
+			method:      http.MethodPut,
+			path:        "/v1/namespaces/sales/triggers/t1",
+			body:        `{"enabled": false, "criteria": "event.amount > 10"}`,
+			wantStatus:  http.StatusOK,
+			wantRequest: &pb.UpdateTriggerRequest{Trigger: &pb.Trigger{Id: "t1", Namespace: "sales", Criteria: "event.amount > 10"}},
+		},
+		{
+			name:       "update trigger with another id",
+			method:     http.MethodPut,
+			path:       "/v1/namespaces/sales/triggers/t1",
+			body:       `{"id": "t2"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "remove trigger",
+			method:      http.MethodDelete,
+			path:        "/v1/namespaces/sales/triggers/t1",
+			wantStatus:  http.StatusOK,
+			wantRequest: &pb.RemoveTriggerRequest{Namespace: "sales", Id: "t1"},
+			wantBody:    `"success":true`,
+		},
+		{
+			name:        "trigger stats",
+			method:      http.MethodGet,
+			path:        "/v1/namespaces/sales/triggers/t1/stats",
+			wantStatus:  http.StatusOK,
+			wantRequest: &pb.GetTriggerStatsRequest{Namespace: "sales", TriggerId: "t1"},
+			wantBody:    `"fired":"3"`,
+		},
+		{
+			name:        "dry run saved trigger",
+			method:      http.MethodPost,
+			path:        "/v1/namespaces/sales/triggers/t1/dry-run",
+			body:        `{"event": {"event_type": "created"}}`,
+			wantStatus:  http.StatusOK,
+			wantRequest: &pb.DryRunTriggerRequest{Namespace: "sales", TriggerId: "t1", Event: `{"event_type": "created"}`},
+			wantBody:    `"matched":true`,
+		},
+		{
+			name:        "dry run inline trigger",
+			method:      http.MethodPost,
+			path:        "/v1/namespaces/sales/dry-run",
+			body:        `{"trigger": {"id": "draft"}, "event": "{\"event_type\": \"created\"}"}`,
+			wantStatus:  http.StatusOK,
+			wantRequest: &pb.DryRunTriggerRequest{Namespace: "sales", Trigger: &pb.Trigger{Id: "draft", Namespace: "sales"}, Event: `{"event_type": "created"}`},
+		},
+		{
+			name:       "dry run without trigger",
+			method:     http.MethodPost,
+			path:       "/v1/namespaces/sales/dry-run",
+			body:       `{"event": {}}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "list dead letters",
+			method:      http.MethodGet,
+			path:        "/v1/namespaces/sales/dead-letters?trigger_id=t1&limit=5",
+			wantStatus:  http.StatusOK,
+			wantRequest: &pb.ListDeadLettersRequest{Namespace: "sales", TriggerId: "t1", Limit: 5},
+			wantBody:    `"dead_letters":[]`,
+		},
+		{
+			name:       "list dead letters with invalid limit",
+			method:     http.MethodGet,
+			path:       "/v1/namespaces/sales/dead-letters?limit=many",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "get dead letter",
+			method:      http.MethodGet,
+			path:        "/v1/namespaces/sales/dead-letters/dl1",
+			wantStatus:  http.StatusNotFound,
+			wantRequest: &pb.GetDeadLetterRequest{Namespace: "sales", Id: "dl1"},
+		},
+		{
+			name:        "redrive dead letters",
+			method:      http.MethodPost,
+			path:        "/v1/namespaces/sales/dead-letters/redrive",
+			body:        `{"ids": ["dl1", "dl2"]}`,
+			wantStatus:  http.StatusOK,
+			wantRequest: &pb.RedriveDeadLettersRequest{Namespace: "sales", Ids: []string{"dl1", "dl2"}},
+			wantBody:    `"redriven":2`,
+		},
+		{
+			name:        "purge dead letters unauthenticated",
+			method:      http.MethodPost,
+			path:        "/v1/namespaces/sales/dead-letters/purge",
+			wantStatus:  http.StatusUnauthorized,
+			wantRequest: &pb.PurgeDeadLettersRequest{Namespace: "sales"},
+		},
+		{
+			name:        "list namespaces",
+			method:      http.MethodGet,
+			path:        "/v1/namespaces",
+			wantStatus:  http.StatusOK,
+			wantRequest: &pb.ListNamespacesRequest{},
+			wantBody:    `"trigger_count":1`,
+		},
+		{
+			name:        "get namespace",
+			method:      http.MethodGet,
+			path:        "/v1/namespaces/sales",
+			wantStatus:  http.StatusOK,
+			wantRequest: &pb.GetNamespaceRequest{Namespace: "sales"},
+			wantBody:    `"name":"sales"`,
+		},
+		{
+			name:        "create namespace",
+			method:      http.MethodPost,
+			path:        "/v1/namespaces",
+			body:        `{"name": "sales", "owner": "team-sales", "quota": {"max_triggers": 100}}`,
+			wantStatus:  http.StatusCreated,
+			wantRequest: &pb.CreateNamespaceRequest{Namespace: &pb.Namespace{Name: "sales", Owner: "team-sales", Quota: &pb.NamespaceQuota{MaxTriggers: 100}}},
+			wantBody:    `"max_triggers":100`,
+		},
+		{
+			name:        "delete namespace with triggers",
+			method:      http.MethodDelete,
+			path:        "/v1/namespaces/sales",
+			wantStatus:  http.StatusBadRequest,
+			wantRequest: &pb.DeleteNamespaceRequest{Namespace: "sales"},
+			wantBody:    `"status":"FailedPrecondition"`,
+		},
+		{
+			name:        "delete namespace and triggers",
+			method:      http.MethodDelete,
+			path:        "/v1/namespaces/sales?delete_triggers=true",
+			wantStatus:  http.StatusOK,
+			wantRequest: &pb.DeleteNamespaceRequest{Namespace: "sales", DeleteTriggers: true},
+			wantBody:    `"deleted_triggers":1`,
+		},
+		{
+			name:       "invalid delete_triggers",
+			method:     http.MethodDelete,
+			path:       "/v1/namespaces/sales?delete_triggers=maybe",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "claim job",
+			method:      http.MethodPost,
+			path:        "/v1/jobs/claim",
+			body:        `{"job_type": "resize_image", "worker_id": "w1", "lease_ttl_ms": 30000}`,
+			wantStatus:  http.StatusOK,
+			wantRequest: &pb.ClaimJobRequest{JobType: "resize_image", WorkerId: "w1", LeaseTtlMs: 30000},
+			wantBody:    `"lease_owner":"w1"`,
+		},
+		{
+			name:        "claim job in namespace",
+			method:      http.MethodPost,
+			path:        "/v1/namespaces/idle/jobs/claim",
+			body:        `{"job_type": "resize_image", "worker_id": "w1"}`,
+			wantStatus:  http.StatusOK,
+			wantRequest: &pb.ClaimJobRequest{JobType: "resize_image", Namespace: "idle", WorkerId: "w1"},
+			wantBody:    `"job":null`,
+		},
+		{
+			name:        "send job heartbeat",
+			method:      http.MethodPost,
+			path:        "/v1/namespaces/media/jobs/j1/heartbeat",
+			body:        `{"worker_id": "w1", "progress": 40}`,
+			wantStatus:  http.StatusOK,
+			wantRequest: &pb.HeartbeatJobRequest{Namespace: "media", JobId: "j1", WorkerId: "w1", Progress: 40},
+			wantBody:    `"progress":40`,
+		},
+		{
+			name:        "send job heartbeat without lease",
+			method:      http.MethodPost,
+			path:        "/v1/namespaces/media/jobs/j1/heartbeat",
+			body:        `{"worker_id": "w2"}`,
+			wantStatus:  http.StatusConflict,
+			wantRequest: &pb.HeartbeatJobRequest{Namespace: "media", JobId: "j1", WorkerId: "w2"},
+			wantBody:    `"reason":"LEASE_LOST"`,
+		},
+		{
+			name:       "send heartbeat for another job",
+			method:     http.MethodPost,
+			path:       "/v1/namespaces/media/jobs/j1/heartbeat",
+			body:       `{"job_id": "j2", "worker_id": "w1"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "report job result",
+			method:      http.MethodPost,
+			path:        "/v1/namespaces/media/jobs/j1/result",
+			body:        `{"worker_id": "w1", "result": {"output": "a.png"}}`,
+			wantStatus:  http.StatusOK,
+			wantRequest: &pb.ReportJobResultRequest{Namespace: "media", JobId: "j1", WorkerId: "w1", Result: &structpb.Struct{Fields: map[string]*structpb.Value{"output": structpb.NewStringValue("a.png")}}},
+			wantBody:    `"status":"completed"`,
+		},
+		{
+			name:       "wrong method",
+			method:     http.MethodPatch,
+			path:       "/v1/namespaces/sales/triggers/t1",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &fakeService{}
+			gateway := newGateway(t, service)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer editor-key")
+			rec := httptest.NewRecorder()
+			gateway.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantBody != "" && !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", rec.Body, tt.wantBody)
+			}
+			if tt.wantRequest != nil {
+				if !proto.Equal(service.request, tt.wantRequest) {
+					t.Errorf("request = %v, want %v", service.request, tt.wantRequest)
+				}
+				if service.authorization != "Bearer editor-key" {
+					t.Errorf("authorization = %q, want the caller's", service.authorization)
+				}
+			}
+			if rec.Code >= 400 && rec.Code != http.StatusMethodNotAllowed {
+				var body errorBody
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Message == "" {
+					t.Errorf("error body = %s, want code, status and message", rec.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestGateway_Location(t *testing.T) {
+	gateway := newGateway(t, &fakeService{})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/namespaces/sales/triggers", strings.NewReader(`{"id": "big orders"}`))
+	rec := httptest.NewRecorder()
+	gateway.ServeHTTP(rec, req)
+
+	if got, want := rec.Header().Get("Location"), "/v1/namespaces/sales/triggers/big%20orders"; got != want {
+		t.Errorf("Location = %q, want %q", got, want)
+	}
+}
+
+func TestGateway_CORS(t *testing.T) {
+	gateway := newGateway(t, &fakeService{}, WithAllowedOrigins("http://localhost:3000/"))
+
+	tests := []struct {
+		name       string
+		origin     string
+		wantStatus int
+		wantAllow  string
+	}{
+		{"allowed origin", "http://localhost:3000", http.StatusNoContent, "http://localhost:3000"},
+		{"other origin", "http://evil.example", http.StatusMethodNotAllowed, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/v1/namespaces/sales/triggers", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			rec := httptest.NewRecorder()
+			gateway.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantAllow {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantAllow)
+			}
+		})
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		code codes.Code
+		want int
+	}{
+		{codes.OK, http.StatusOK},
+		{codes.InvalidArgument, http.StatusBadRequest},
+		{codes.Unauthenticated, http.StatusUnauthorized},
+		{codes.PermissionDenied, http.StatusForbidden},
+		{codes.NotFound, http.StatusNotFound},
+		{codes.AlreadyExists, http.StatusConflict},
+		{codes.ResourceExhausted, http.StatusTooManyRequests},
+		{codes.Unimplemented, http.StatusNotImplemented},
+		{codes.Unavailable, http.StatusServiceUnavailable},
+		{codes.DeadlineExceeded, http.StatusGatewayTimeout},
+		{codes.Code(99), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		if got := HTTPStatus(tt.code); got != tt.want {
+			t.Errorf("HTTPStatus(%v) = %d, want %d", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	var spec struct {
+		Paths map[string]map[string]interface{} `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(OpenAPISpec, &spec); err != nil {
+		t.Fatalf("spec is not YAML: %v", err)
+	}
+
+	routes := map[string][]string{
+		"/v1/namespaces":                                   {"get", "post"},
+		"/v1/namespaces/{namespace}":                       {"get", "delete"},
+		"/v1/namespaces/{namespace}/triggers":              {"get", "post"},
+		"/v1/namespaces/{namespace}/triggers/{id}":         {"get", "put", "delete"},
+		"/v1/namespaces/{namespace}/triggers/{id}/stats":   {"get"},
+		"/v1/namespaces/{namespace}/triggers/{id}/dry-run": {"post"},
+		"/v1/namespaces/{namespace}/dry-run":               {"post"},
+		"/v1/namespaces/{namespace}/dead-letters":          {"get"},
+		"/v1/namespaces/{namespace}/dead-letters/{id}":     {"get"},
+		"/v1/namespaces/{namespace}/dead-letters/redrive":  {"post"},
+		"/v1/namespaces/{namespace}/dead-letters/purge":    {"post"},
+		"/v1/jobs/claim":                                   {"post"},
+		"/v1/namespaces/{namespace}/jobs/claim":            {"post"},
+		"/v1/namespaces/{namespace}/jobs/{id}/heartbeat":   {"post"},
+		"/v1/namespaces/{namespace}/jobs/{id}/result":      {"post"},
+	}
+	for path, methods := range routes {
+		for _, method := range methods {
+			if _, ok := spec.Paths[path][method]; !ok {
+				t.Errorf("spec does not document %s %s", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	pb "event/api/proto"
+	"event/data"
+	"event/handlers/deadletter"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ListDeadLetters lists events whose delivery failed after all retries
+func (s *TriggerServer) ListDeadLetters(ctx context.Context, req *pb.ListDeadLettersRequest) (*pb.ListDeadLettersResponse, error) {
+	if s.deadLetters == nil {
+		return nil, status.Error(codes.Unimplemented, "dead-letter queue is not configured")
+	}
+
+	letters, err := s.deadLetters.List(ctx, deadletter.Filter{
+		Namespace: req.Namespace,
+		TriggerID: req.TriggerId,
+		Limit:     int(req.Limit),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list dead letters: %v", err)
+	}
+
+	pbLetters := make([]*pb.DeadLetter, 0, len(letters))
+	for _, dl := range letters {
+		pbLetters = append(pbLetters, convertToPbDeadLetter(dl))
+	}
+
+	return &pb.ListDeadLettersResponse{
+		DeadLetters: pbLetters,
+	}, nil
+}
+
+// GetDeadLetter returns a dead letter with every delivery attempt
+func (s *TriggerServer) GetDeadLetter(ctx context.Context, req *pb.GetDeadLetterRequest) (*pb.GetDeadLetterResponse, error) {
+	if s.deadLetters == nil {
+		return nil, status.Error(codes.Unimplemented, "dead-letter queue is not configured")
+	}
+
+	dl, err := s.deadLetters.Get(ctx, req.Namespace, req.Id)
+	if errors.Is(err, deadletter.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "dead letter %s not found in namespace %s", req.Id, req.Namespace)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get dead letter: %v", err)
+	}
+
+	return &pb.GetDeadLetterResponse{
+		DeadLetter: convertToPbDeadLetter(dl),
+	}, nil
+}
+
+const (
+	// defaultRedriveLimit is how many dead letters RedriveDeadLetters redrives when the
+	// request sets no limit
+	defaultRedriveLimit = 100
+	// maxRedriveLimit caps the limit of RedriveDeadLetters, so that a call stays short
+	maxRedriveLimit = 1000
+)
+
+// RedriveDeadLetters delivers dead-lettered events again using the triggers' current
+// definitions. It redrives up to the request's limit and reports how many matching dead
+// letters are left for later calls.
+func (s *TriggerServer) RedriveDeadLetters(ctx context.Context, req *pb.RedriveDeadLettersRequest) (*pb.RedriveDeadLettersResponse, error) {
+	if s.deadLetters == nil || s.dispatcher == nil {
+		return nil, status.Error(codes.Unimplemented, "dead-letter queue is not configured")
+	}
+	limit := int(req.Limit)
+	if limit < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit must not be negative")
+	}
+	if limit == 0 {
+		limit = defaultRedriveLimit
+	}
+	limit = min(limit, maxRedriveLimit)
+
+	filter := deadletter.Filter{
+		Namespace: req.Namespace,
+		TriggerID: req.TriggerId,
+		IDs:       req.Ids,
+	}
+	total, err := s.deadLetters.Count(ctx, filter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to count dead letters: %v", err)
+	}
+	if len(req.Ids) > 0 && total == 0 {
+		return nil, status.Errorf(codes.NotFound, "no matching dead letters in namespace %s", req.Namespace)
+	}
+	filter.Limit = limit
+	letters, err := s.deadLetters.List(ctx, filter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list dead letters: %v", err)
+	}
+
+	// A redrive that has started is not cancelled with the call, as it would leave its dead
+	// letter claimed; a cancelled call only stops starting further redrives
+	redriveCtx := context.WithoutCancel(ctx)
+	resp := &pb.RedriveDeadLettersResponse{}
+	attempted := 0
+	for _, dl := range letters {
+		if ctx.Err() != nil {
+			break
+		}
+		attempted++
+
+		trigger := s.findTrigger(dl.Namespace, dl.TriggerID)
+		if trigger == nil {
+			resp.Failed++
+			resp.FailedIds = append(resp.FailedIds, dl.ID)
+			continue
+		}
+
+		if err := s.dispatcher.Redrive(redriveCtx, trigger, dl); err != nil {
+			resp.Failed++
+			resp.FailedIds = append(resp.FailedIds, dl.ID)
+			continue
+		}
+		resp.Redriven++
+	}
+	resp.Pending = int32(max(total-attempted, 0))
+
+	return resp, nil
+}
+
+// PurgeDeadLetters removes dead letters without delivering them
+func (s *TriggerServer) PurgeDeadLetters(ctx context.Context, req *pb.PurgeDeadLettersRequest) (*pb.PurgeDeadLettersResponse, error) {
+	if s.deadLetters == nil {
+		return nil, status.Error(codes.Unimplemented, "dead-letter queue is not configured")
+	}
+	if req.Namespace == "" {
+		return nil, status.Error(codes.InvalidArgument, "namespace is required")
+	}
+
+	purged, err := s.deadLetters.Purge(ctx, deadletter.Filter{
+		Namespace: req.Namespace,
+		TriggerID: req.TriggerId,
+		IDs:       req.Ids,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to purge dead letters: %v", err)
+	}
+
+	return &pb.PurgeDeadLettersResponse{
+		Purged: int32(purged),
+	}, nil
+}
+
+// findTrigger returns the current definition of a trigger, or nil if it no longer exists
+func (s *TriggerServer) findTrigger(namespace, id string) *data.Trigger {
+	for _, t := range s.store.GetTriggers(namespace) {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+func convertToPbDeadLetter(dl *data.DeadLetter) *pb.DeadLetter {
+	event, _ := json.Marshal(dl.Event)
+
+	attempts := make([]*pb.DeliveryAttempt, 0, len(dl.Attempts))
+	for _, a := range dl.Attempts {
+		attempts = append(attempts, &pb.DeliveryAttempt{
+			Attempt:      int32(a.Attempt),
+			StatusCode:   int32(a.StatusCode),
+			ResponseBody: a.ResponseBody,
+			Error:        a.Error,
+			StartedAt:    timestamppb.New(a.StartedAt),
+			DurationMs:   a.Duration.Milliseconds(),
+		})
+	}
+
+	return &pb.DeadLetter{
+		Id:           dl.ID,
+		Namespace:    dl.Namespace,
+		TriggerId:    dl.TriggerID,
+		ActionIndex:  int32(dl.ActionIndex),
+		ActionType:   dl.ActionType,
+		Event:        string(event),
+		Attempts:     attempts,
+		LastError:    dl.LastError,
+		RedriveCount: int32(dl.RedriveCount),
+		CreatedAt:    timestamppb.New(dl.CreatedAt),
+		UpdatedAt:    timestamppb.New(dl.UpdatedAt),
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	pb "event/api/proto"
+	"event/data"
+	"event/handlers/actions"
+	"event/handlers/deadletter"
+	"event/handlers/dispatch"
+)
+
+// okAction is a test action that always succeeds
+type okAction struct{}
+
+func (okAction) Execute(ctx context.Context, trigger *data.Trigger, event *data.Event) (data.DeliveryAttempt, error) {
+	return data.DeliveryAttempt{}, nil
+}
+
+func TestTriggerServer_RedriveDeadLettersLimit(t *testing.T) {
+	ctx := context.Background()
+	registry := actions.NewRegistry()
+	registry.Register("ok", func(config map[string]interface{}) (actions.Action, error) {
+		return okAction{}, nil
+	})
+	deadLetters := deadletter.NewMemoryStore()
+	store := newTriggerStore(&data.Trigger{ID: "t1", Namespace: "sales", Actions: []data.ActionConfig{{Type: "ok"}}})
+	s := NewTriggerServer(store, WithDeadLetters(deadLetters, dispatch.NewDispatcher(registry, deadLetters, dispatch.WithBackoff(0, 0))))
+
+	created := time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		dl := &data.DeadLetter{ID: fmt.Sprintf("dl%d", i), Namespace: "sales", TriggerID: "t1", ActionType: "ok", CreatedAt: created.Add(time.Duration(i) * time.Minute)}
+		if err := deadLetters.Add(ctx, dl); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	resp, err := s.RedriveDeadLetters(ctx, &pb.RedriveDeadLettersRequest{Namespace: "sales", Limit: 2})
+	if err != nil {
+		t.Fatalf("RedriveDeadLetters() error = %v", err)
+	}
+	if resp.Redriven != 2 || resp.Failed != 0 || resp.Pending != 1 {
+		t.Errorf("RedriveDeadLetters() = %d redriven, %d failed, %d pending, want 2, 0, 1", resp.Redriven, resp.Failed, resp.Pending)
+	}
+
+	// A cancelled call starts no redrive and leaves the dead letter unclaimed
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	resp, err = s.RedriveDeadLetters(cancelled, &pb.RedriveDeadLettersRequest{Namespace: "sales"})
+	if err != nil {
+		t.Fatalf("RedriveDeadLetters() error = %v", err)
+	}
+	if resp.Redriven != 0 || resp.Pending != 1 {
+		t.Errorf("cancelled RedriveDeadLetters() = %d redriven, %d pending, want 0, 1", resp.Redriven, resp.Pending)
+	}
+	dl, err := deadLetters.Get(ctx, "sales", "dl2")
+	if err != nil || !dl.RedriveUntil.IsZero() {
+		t.Errorf("dead letter after cancelled redrive = %+v, %v, want unclaimed", dl, err)
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	pb "event/api/proto"
+	"event/data"
+	"event/handlers/triggers"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DryRunTrigger evaluates a trigger against a sample event and renders its actions without running them
+func (s *TriggerServer) DryRunTrigger(ctx context.Context, req *pb.DryRunTriggerRequest) (*pb.DryRunTriggerResponse, error) {
+	if s.actions == nil {
+		return nil, status.Error(codes.Unimplemented, "action registry is not configured")
+	}
+	if req.Event == "" {
+		return nil, status.Error(codes.InvalidArgument, "event is required")
+	}
+
+	var trigger *data.Trigger
+	if req.Trigger != nil {
+		t, err := convertToDataTrigger(req.Trigger)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid trigger: %v", err)
+		}
+		trigger = t
+	} else {
+		trigger = s.findTrigger(req.Namespace, req.TriggerId)
+		if trigger == nil {
+			return nil, status.Errorf(codes.NotFound, "trigger %s not found in namespace %s", req.TriggerId, req.Namespace)
+		}
+	}
+
+	var event data.Event
+	if err := json.Unmarshal([]byte(req.Event), &event); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid event: %v", err)
+	}
+
+	// Draft triggers are usually disabled, so the criteria are evaluated as if the trigger were enabled
+	candidate := *trigger
+	candidate.Enabled = true
+	matched, err := triggers.MatchTrigger(&candidate, &event)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to evaluate criteria: %v", err)
+	}
+
+	resp := &pb.DryRunTriggerResponse{Matched: matched}
+	for _, p := range s.actions.Preview(ctx, trigger, &event) {
+		resp.Actions = append(resp.Actions, &pb.ActionPreview{
+			Type:    p.Type,
+			Target:  p.Target,
+			Method:  p.Method,
+			Headers: p.Headers,
+			Body:    p.Body,
+			Error:   p.Error,
+		})
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,331 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	pb "event/api/proto"
+	"event/data"
+	"event/handlers/jobs"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// DefaultWatchInterval is how often WatchJob checks a job for changes
+const DefaultWatchInterval = time.Second
+
+// JobServer implements the JobService gRPC server
+type JobServer struct {
+	pb.UnimplementedJobServiceServer
+	orchestrator  *jobs.Orchestrator
+	watchInterval time.Duration
+}
+
+// JobServerOption is a function that configures a JobServer
+type JobServerOption func(*JobServer)
+
+// WithWatchInterval sets how often WatchJob checks a job for changes
+func WithWatchInterval(interval time.Duration) JobServerOption {
+	return func(s *JobServer) {
+		s.watchInterval = interval
+	}
+}
+
+// NewJobServer creates a new JobServer
+func NewJobServer(orchestrator *jobs.Orchestrator, options ...JobServerOption) *JobServer {
+	s := &JobServer{
+		orchestrator:  orchestrator,
+		watchInterval: DefaultWatchInterval,
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	return s
+}
+
+// SubmitJob submits a job, which starts once its dependencies have completed
+func (s *JobServer) SubmitJob(ctx context.Context, req *pb.SubmitJobRequest) (*pb.SubmitJobResponse, error) {
+	if req.Job == nil {
+		return nil, status.Error(codes.InvalidArgument, "job is required")
+	}
+
+	job, err := s.orchestrator.Submit(ctx, convertToDataJob(req.Job))
+	if err != nil {
+		return nil, jobStatusError(err)
+	}
+
+	return &pb.SubmitJobResponse{
+		Job: convertToPbJob(job),
+	}, nil
+}
+
+// GetJob returns a job
+func (s *JobServer) GetJob(ctx context.Context, req *pb.GetJobRequest) (*pb.GetJobResponse, error) {
+	job, err := s.orchestrator.Store().Get(ctx, req.Namespace, req.JobId)
+	if err != nil {
+		return nil, jobStatusError(err)
+	}
+
+	return &pb.GetJobResponse{
+		Job: convertToPbJob(job),
+	}, nil
+}
+
+// ListJobs lists the jobs of a namespace, oldest first
+func (s *JobServer) ListJobs(ctx context.Context, req *pb.ListJobsRequest) (*pb.ListJobsResponse, error) {
+	if req.Namespace == "" {
+		return nil, status.Error(codes.InvalidArgument, "namespace is required")
+	}
+
+	list, err := s.orchestrator.Store().List(ctx, jobs.Filter{
+		Namespace:   req.Namespace,
+		Statuses:    req.Statuses,
+		JobType:     req.JobType,
+		TriggeredBy: req.TriggeredBy,
+		Limit:       int(req.Limit),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list jobs: %v", err)
+	}
+
+	pbJobs := make([]*pb.Job, 0, len(list))
+	for _, job := range list {
+		pbJobs = append(pbJobs, convertToPbJob(job))
+	}
+
+	return &pb.ListJobsResponse{
+		Jobs: pbJobs,
+	}, nil
+}
+
+// CancelJob cancels a pending or started job and fails its dependents
+func (s *JobServer) CancelJob(ctx context.Context, req *pb.CancelJobRequest) (*pb.CancelJobResponse, error) {
+	job, err := s.orchestrator.Cancel(ctx, req.Namespace, req.JobId, req.Reason)
+	if err != nil {
+		return nil, jobStatusError(err)
+	}
+
+	return &pb.CancelJobResponse{
+		Job: convertToPbJob(job),
+	}, nil
+}
+
+// RetryJob resubmits a failed or canceled job together with the dependents it failed
+func (s *JobServer) RetryJob(ctx context.Context, req *pb.RetryJobRequest) (*pb.RetryJobResponse, error) {
+	job, err := s.orchestrator.Retry(ctx, req.Namespace, req.JobId)
+	if err != nil {
+		return nil, jobStatusError(err)
+	}
+
+	return &pb.RetryJobResponse{
+		Job: convertToPbJob(job),
+	}, nil
+}
+
+// WatchJob streams the job every time it changes, until it has finished.
+// The current state is always sent first.
+func (s *JobServer) WatchJob(req *pb.WatchJobRequest, stream pb.JobService_WatchJobServer) error {
+	ctx := stream.Context()
+	ticker := time.NewTicker(s.watchInterval)
+	defer ticker.Stop()
+
+	var lastVersion int64 = -1
+	for {
+		job, err := s.orchestrator.Store().Get(ctx, req.Namespace, req.JobId)
+		if err != nil {
+			return jobStatusError(err)
+		}
+
+		if job.Version != lastVersion {
+			if err := stream.Send(convertToPbJob(job)); err != nil {
+				return err
+			}
+			lastVersion = job.Version
+		}
+		if job.IsFinished() {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ClaimJob leases the oldest started job of a type that no worker holds. The response has
+// no job when none is waiting.
+func (s *JobServer) ClaimJob(ctx context.Context, req *pb.ClaimJobRequest) (*pb.ClaimJobResponse, error) {
+	job, err := s.orchestrator.Claim(ctx, jobs.ClaimRequest{
+		JobType:   req.JobType,
+		Namespace: req.Namespace,
+		WorkerID:  req.WorkerId,
+		TTL:       time.Duration(req.LeaseTtlMs) * time.Millisecond,
+	})
+	if errors.Is(err, jobs.ErrNoJobs) {
+		return &pb.ClaimJobResponse{}, nil
+	}
+	if err != nil {
+		return nil, jobStatusError(err)
+	}
+
+	return &pb.ClaimJobResponse{
+		Job: convertToPbJob(job),
+	}, nil
+}
+
+// HeartbeatJob extends the lease of a claimed job and records its progress
+func (s *JobServer) HeartbeatJob(ctx context.Context, req *pb.HeartbeatJobRequest) (*pb.HeartbeatJobResponse, error) {
+	job, err := s.orchestrator.Heartbeat(ctx, req.Namespace, req.JobId, req.WorkerId, int(req.Progress),
+		time.Duration(req.LeaseTtlMs)*time.Millisecond)
+	if err != nil {
+		return nil, jobStatusError(err)
+	}
+
+	return &pb.HeartbeatJobResponse{
+		Job: convertToPbJob(job),
+	}, nil
+}
+
+// ReportJobResult completes a claimed job with its result, or fails it with its error
+func (s *JobServer) ReportJobResult(ctx context.Context, req *pb.ReportJobResultRequest) (*pb.ReportJobResultResponse, error) {
+	var result map[string]interface{}
+	if req.Result != nil {
+		result = req.Result.AsMap()
+	}
+	var jobErr *data.JobError
+	if req.Error != nil {
+		jobErr = &data.JobError{
+			Code:    req.Error.Code,
+			Message: req.Error.Message,
+		}
+		if req.Error.Details != nil {
+			jobErr.Details = req.Error.Details.AsMap()
+		}
+	}
+
+	job, err := s.orchestrator.ReportResult(ctx, req.Namespace, req.JobId, req.WorkerId, result, jobErr)
+	if err != nil {
+		return nil, jobStatusError(err)
+	}
+
+	return &pb.ReportJobResultResponse{
+		Job: convertToPbJob(job),
+	}, nil
+}
+
+// jobStatusError maps orchestrator errors to gRPC status errors
+func jobStatusError(err error) error {
+	switch {
+	case errors.Is(err, jobs.ErrCanceled):
+		return leaseStatusError(err, jobs.ReasonCanceled)
+	case errors.Is(err, jobs.ErrLeaseLost):
+		return leaseStatusError(err, jobs.ReasonLeaseLost)
+	case errors.Is(err, jobs.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, jobs.ErrExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, jobs.ErrInvalidJob):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, jobs.ErrInvalidTransition):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	default:
+		return status.Errorf(codes.Internal, "job operation failed: %v", err)
+	}
+}
+
+// leaseStatusError returns an ABORTED error whose ErrorInfo carries the reason, so that
+// remote workers can tell a canceled job from a lost lease
+func leaseStatusError(err error, reason string) error {
+	st := status.New(codes.Aborted, err.Error())
+	if detailed, detailsErr := st.WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: "jobs"}); detailsErr == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+func convertToPbJob(j *data.Job) *pb.Job {
+	job := &pb.Job{
+		JobId:          j.JobID,
+		JobType:        j.JobType,
+		Namespace:      j.Namespace,
+		Status:         j.Status,
+		Inputs:         toStruct(j.Inputs),
+		Result:         toStruct(j.Result),
+		CreatedAt:      toTimestamp(j.CreatedAt),
+		StartedAt:      toTimestamp(j.StartedAt),
+		CompletedAt:    toTimestamp(j.CompletedAt),
+		UpdatedAt:      toTimestamp(j.UpdatedAt),
+		Retries:        int32(j.Retries),
+		MaxRetries:     int32(j.MaxRetries),
+		DependsOn:      j.DependsOn,
+		TriggeredBy:    j.TriggeredBy,
+		LeaseOwner:     j.LeaseOwner,
+		LeaseExpiresAt: toTimestamp(j.LeaseExpiresAt),
+		Progress:       int32(j.Progress),
+	}
+	if j.Error != nil {
+		job.Error = &pb.JobError{
+			Code:      j.Error.Code,
+			Message:   j.Error.Message,
+			Details:   toStruct(j.Error.Details),
+			Timestamp: toTimestamp(j.Error.Timestamp),
+		}
+	}
+	return job
+}
+
+// convertToDataJob converts a submitted job. Only the fields a client may set are copied;
+// the orchestrator owns status, timestamps and leases.
+func convertToDataJob(j *pb.Job) *data.Job {
+	return &data.Job{
+		JobID:       j.JobId,
+		JobType:     j.JobType,
+		Namespace:   j.Namespace,
+		Inputs:      j.Inputs.AsMap(),
+		MaxRetries:  int(j.MaxRetries),
+		DependsOn:   j.DependsOn,
+		TriggeredBy: j.TriggeredBy,
+	}
+}
+
+// toStruct converts a map to a Struct, returning nil for nil maps and values that do not convert.
+// Maps decoded from MongoDB hold bson.M and bson.A values, which are normalized through JSON.
+func toStruct(m map[string]interface{}) *structpb.Struct {
+	if m == nil {
+		return nil
+	}
+	if s, err := structpb.NewStruct(m); err == nil {
+		return s
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil
+	}
+	s := &structpb.Struct{}
+	if err := protojson.Unmarshal(b, s); err != nil {
+		return nil
+	}
+	return s
+}
+
+// toTimestamp converts a time to a Timestamp, returning nil for the zero time
+func toTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
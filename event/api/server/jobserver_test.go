@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	pb "event/api/proto"
+	"event/handlers/jobs"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// newJobClient serves a JobServer over an in-memory connection
+func newJobClient(t *testing.T, orchestrator *jobs.Orchestrator) pb.JobServiceClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	pb.RegisterJobServiceServer(grpcServer, NewJobServer(orchestrator, WithWatchInterval(10*time.Millisecond)))
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewJobServiceClient(conn)
+}
+
+func TestJobServer_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	orchestrator := jobs.NewOrchestrator(jobs.NewMemoryStore(), nil)
+	client := newJobClient(t, orchestrator)
+
+	inputs, _ := structpb.NewStruct(map[string]interface{}{"size": "800x600"})
+	first, err := client.SubmitJob(ctx, &pb.SubmitJobRequest{Job: &pb.Job{JobType: "resize_image", Namespace: "media", Inputs: inputs, TriggeredBy: "evt1"}})
+	if err != nil {
+		t.Fatalf("SubmitJob() error = %v", err)
+	}
+	if first.Job.JobId == "" || first.Job.Status != "started" {
+		t.Fatalf("submitted job = %q %s, want generated ID and started", first.Job.JobId, first.Job.Status)
+	}
+
+	second, err := client.SubmitJob(ctx, &pb.SubmitJobRequest{Job: &pb.Job{JobType: "upload", Namespace: "media", DependsOn: []string{first.Job.JobId}}})
+	if err != nil {
+		t.Fatalf("SubmitJob() error = %v", err)
+	}
+
+	list, err := client.ListJobs(ctx, &pb.ListJobsRequest{Namespace: "media", TriggeredBy: "evt1"})
+	if err != nil {
+		t.Fatalf("ListJobs() error = %v", err)
+	}
+	if len(list.Jobs) != 1 || list.Jobs[0].Inputs.AsMap()["size"] != "800x600" {
+		t.Errorf("ListJobs(triggered_by) = %v", list.Jobs)
+	}
+
+	// Cancelling the first job fails its dependent; retrying it resets both
+	if _, err := client.CancelJob(ctx, &pb.CancelJobRequest{Namespace: "media", JobId: first.Job.JobId}); err != nil {
+		t.Fatalf("CancelJob() error = %v", err)
+	}
+	got, err := client.GetJob(ctx, &pb.GetJobRequest{Namespace: "media", JobId: second.Job.JobId})
+	if err != nil {
+		t.Fatalf("GetJob() error = %v", err)
+	}
+	if got.Job.Status != "failed" || got.Job.Error.GetCode() != jobs.ErrCodeUpstreamFailed {
+		t.Errorf("dependent = %s %v, want failed with %s", got.Job.Status, got.Job.Error, jobs.ErrCodeUpstreamFailed)
+	}
+
+	retried, err := client.RetryJob(ctx, &pb.RetryJobRequest{Namespace: "media", JobId: first.Job.JobId})
+	if err != nil {
+		t.Fatalf("RetryJob() error = %v", err)
+	}
+	if retried.Job.Status != "started" || retried.Job.Error != nil {
+		t.Errorf("retried job = %s %v, want started without error", retried.Job.Status, retried.Job.Error)
+	}
+	got, _ = client.GetJob(ctx, &pb.GetJobRequest{Namespace: "media", JobId: second.Job.JobId})
+	if got.Job.Status != "pending" {
+		t.Errorf("dependent after retry = %s, want pending", got.Job.Status)
+	}
+
+	// Retrying a running job is rejected
+	_, err = client.RetryJob(ctx, &pb.RetryJobRequest{Namespace: "media", JobId: first.Job.JobId})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("RetryJob(started) code = %v, want FailedPrecondition", status.Code(err))
+	}
+	_, err = client.GetJob(ctx, &pb.GetJobRequest{Namespace: "media", JobId: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("GetJob(missing) code = %v, want NotFound", status.Code(err))
+	}
+}
+
+func TestJobServer_WatchJob(t *testing.T) {
+	ctx := context.Background()
+	orchestrator := jobs.NewOrchestrator(jobs.NewMemoryStore(), nil)
+	client := newJobClient(t, orchestrator)
+
+	resp, err := client.SubmitJob(ctx, &pb.SubmitJobRequest{Job: &pb.Job{JobId: "a", JobType: "resize_image", Namespace: "media"}})
+	if err != nil {
+		t.Fatalf("SubmitJob() error = %v", err)
+	}
+
+	stream, err := client.WatchJob(ctx, &pb.WatchJobRequest{Namespace: "media", JobId: resp.Job.JobId})
+	if err != nil {
+		t.Fatalf("WatchJob() error = %v", err)
+	}
+
+	// The current state is sent first
+	job, err := stream.Recv()
+	if err != nil || job.Status != "started" {
+		t.Fatalf("first Recv() = %v, %v, want started", job, err)
+	}
+
+	if _, err := orchestrator.Claim(ctx, jobs.ClaimRequest{JobType: "resize_image", WorkerID: "w1"}); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if _, err := orchestrator.Heartbeat(ctx, "media", "a", "w1", 60, 0); err != nil {
+		t.Fatalf("Heartbeat() error = %v", err)
+	}
+	if _, err := orchestrator.ReportResult(ctx, "media", "a", "w1", map[string]interface{}{"ok": true}, nil); err != nil {
+		t.Fatalf("ReportResult() error = %v", err)
+	}
+
+	var last *pb.Job
+	for {
+		job, err := stream.Recv()
+		if err != nil {
+			break // io.EOF once the job has finished
+		}
+		last = job
+	}
+	if last == nil || last.Status != "completed" || last.Result.AsMap()["ok"] != true {
+		t.Errorf("last streamed job = %v, want completed with result", last)
+	}
+}
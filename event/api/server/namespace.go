@@ -0,0 +1,217 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	pb "event/api/proto"
+	"event/data"
+	"event/handlers/namespaces"
+	"event/handlers/triggers"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ListNamespaces lists the created namespaces and the namespaces that only have triggers,
+// ordered by name
+func (s *TriggerServer) ListNamespaces(ctx context.Context, req *pb.ListNamespacesRequest) (*pb.ListNamespacesResponse, error) {
+	if s.namespaces == nil {
+		return nil, status.Error(codes.Unimplemented, "namespaces are not configured")
+	}
+
+	created, err := s.namespaces.List(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list namespaces: %v", err)
+	}
+
+	counts := map[string]int{}
+	for _, trigger := range s.store.GetAllTriggers() {
+		counts[trigger.Namespace]++
+	}
+
+	resp := &pb.ListNamespacesResponse{}
+	for _, namespace := range created {
+		resp.Namespaces = append(resp.Namespaces, s.convertToPbNamespace(namespace, counts[namespace.Name]))
+		delete(counts, namespace.Name)
+	}
+	for name, count := range counts {
+		resp.Namespaces = append(resp.Namespaces, s.convertToPbNamespace(&data.Namespace{Name: name}, count))
+	}
+	sort.Slice(resp.Namespaces, func(i, j int) bool {
+		return resp.Namespaces[i].Name < resp.Namespaces[j].Name
+	})
+
+	return resp, nil
+}
+
+// GetNamespace returns a namespace with its quota and number of triggers
+func (s *TriggerServer) GetNamespace(ctx context.Context, req *pb.GetNamespaceRequest) (*pb.GetNamespaceResponse, error) {
+	if s.namespaces == nil {
+		return nil, status.Error(codes.Unimplemented, "namespaces are not configured")
+	}
+	if req.Namespace == "" {
+		return nil, status.Error(codes.InvalidArgument, "namespace is required")
+	}
+
+	count := len(s.store.GetTriggers(req.Namespace))
+	namespace, err := s.namespaces.Get(ctx, req.Namespace)
+	switch {
+	case errors.Is(err, namespaces.ErrNotFound) && count > 0:
+		namespace = &data.Namespace{Name: req.Namespace}
+	case errors.Is(err, namespaces.ErrNotFound):
+		return nil, status.Errorf(codes.NotFound, "namespace %s not found", req.Namespace)
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "failed to get namespace: %v", err)
+	}
+
+	return &pb.GetNamespaceResponse{
+		Namespace: s.convertToPbNamespace(namespace, count),
+	}, nil
+}
+
+// CreateNamespace creates a namespace. A namespace that already has triggers can be created
+// to give it metadata and a quota.
+func (s *TriggerServer) CreateNamespace(ctx context.Context, req *pb.CreateNamespaceRequest) (*pb.CreateNamespaceResponse, error) {
+	if s.namespaces == nil {
+		return nil, status.Error(codes.Unimplemented, "namespaces are not configured")
+	}
+	if req.Namespace == nil {
+		return nil, status.Error(codes.InvalidArgument, "namespace is required")
+	}
+	if err := namespaces.ValidateName(req.Namespace.Name); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	namespace := convertToDataNamespace(req.Namespace)
+	if err := namespaces.ValidateQuota(namespace.Quota); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid quota: %v", err)
+	}
+	namespace.CreatedAt = time.Now().UTC()
+
+	err := s.namespaces.Create(ctx, namespace)
+	if errors.Is(err, namespaces.ErrExists) {
+		return nil, status.Errorf(codes.AlreadyExists, "namespace %s already exists", namespace.Name)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create namespace: %v", err)
+	}
+
+	return &pb.CreateNamespaceResponse{
+		Namespace: s.convertToPbNamespace(namespace, len(s.store.GetTriggers(namespace.Name))),
+	}, nil
+}
+
+// DeleteNamespace deletes a namespace. Its triggers are deleted if requested; otherwise a
+// namespace with triggers is not deleted.
+func (s *TriggerServer) DeleteNamespace(ctx context.Context, req *pb.DeleteNamespaceRequest) (*pb.DeleteNamespaceResponse, error) {
+	if s.namespaces == nil {
+		return nil, status.Error(codes.Unimplemented, "namespaces are not configured")
+	}
+	if req.Namespace == "" {
+		return nil, status.Error(codes.InvalidArgument, "namespace is required")
+	}
+
+	triggers := s.store.GetTriggers(req.Namespace)
+	if len(triggers) > 0 && !req.DeleteTriggers {
+		return nil, status.Errorf(codes.FailedPrecondition, "namespace %s has %d triggers; delete them or set delete_triggers", req.Namespace, len(triggers))
+	}
+
+	// Delete the triggers first, so that a failure leaves the namespace and its quota in place
+	resp := &pb.DeleteNamespaceResponse{}
+	for _, trigger := range triggers {
+		if err := s.store.DeleteTrigger(ctx, req.Namespace, trigger.ID); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to delete trigger %s: %v", trigger.ID, err)
+		}
+		resp.DeletedTriggers++
+	}
+
+	err := s.namespaces.Delete(ctx, req.Namespace)
+	if errors.Is(err, namespaces.ErrNotFound) && len(triggers) == 0 {
+		return nil, status.Errorf(codes.NotFound, "namespace %s not found", req.Namespace)
+	}
+	if err != nil && !errors.Is(err, namespaces.ErrNotFound) {
+		return nil, status.Errorf(codes.Internal, "failed to delete namespace: %v", err)
+	}
+
+	return resp, nil
+}
+
+// checkQuota checks a trigger that is about to be saved against its namespace's quota. It
+// returns the max_triggers limit that saveTrigger enforces atomically, or zero if the limit
+// was checked against the cached triggers or there is none.
+func (s *TriggerServer) checkQuota(ctx context.Context, trigger *data.Trigger) (int, error) {
+	if s.namespaces == nil {
+		return 0, nil
+	}
+
+	namespace, err := s.namespaces.Get(ctx, trigger.Namespace)
+	if err != nil && !errors.Is(err, namespaces.ErrNotFound) {
+		return 0, status.Errorf(codes.Internal, "failed to get namespace: %v", err)
+	}
+	quota := namespaces.EffectiveQuota(namespace, s.quota)
+
+	// The cached triggers may miss concurrent saves, so stores that can count their triggers
+	// enforce max_triggers when saving
+	maxTriggers := 0
+	if _, ok := s.store.(triggers.LimitedSaver); ok {
+		maxTriggers, quota.MaxTriggers = quota.MaxTriggers, 0
+	}
+
+	if err := namespaces.CheckQuota(quota, trigger, s.store.GetTriggers(trigger.Namespace)); err != nil {
+		return 0, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return maxTriggers, nil
+}
+
+// saveTrigger saves a trigger. Unless maxTriggers is zero, a new trigger is only saved while
+// its namespace has fewer than maxTriggers triggers, and a ResourceExhausted error is
+// returned otherwise.
+func (s *TriggerServer) saveTrigger(ctx context.Context, trigger *data.Trigger, maxTriggers int) error {
+	limited, ok := s.store.(triggers.LimitedSaver)
+	if !ok || maxTriggers <= 0 {
+		return s.store.SaveTrigger(ctx, trigger.Namespace, trigger.ID, trigger)
+	}
+	err := limited.SaveTriggerWithLimit(ctx, trigger.Namespace, trigger.ID, trigger, maxTriggers)
+	if errors.Is(err, triggers.ErrTriggerLimit) {
+		return status.Errorf(codes.ResourceExhausted, "%v: %v", namespaces.ErrQuotaExceeded, err)
+	}
+	return err
+}
+
+func (s *TriggerServer) convertToPbNamespace(namespace *data.Namespace, triggerCount int) *pb.Namespace {
+	return &pb.Namespace{
+		Name:           namespace.Name,
+		Owner:          namespace.Owner,
+		Description:    namespace.Description,
+		Labels:         namespace.Labels,
+		Quota:          convertToPbQuota(namespace.Quota),
+		CreatedAt:      toTimestamp(namespace.CreatedAt),
+		EffectiveQuota: convertToPbQuota(namespaces.EffectiveQuota(namespace, s.quota)),
+		TriggerCount:   int32(triggerCount),
+	}
+}
+
+func convertToDataNamespace(namespace *pb.Namespace) *data.Namespace {
+	return &data.Namespace{
+		Name:        namespace.Name,
+		Owner:       namespace.Owner,
+		Description: namespace.Description,
+		Labels:      namespace.Labels,
+		Quota: data.NamespaceQuota{
+			MaxTriggers:         int(namespace.GetQuota().GetMaxTriggers()),
+			MaxCriteriaLength:   int(namespace.GetQuota().GetMaxCriteriaLength()),
+			MaxActionsPerMinute: int(namespace.GetQuota().GetMaxActionsPerMinute()),
+		},
+	}
+}
+
+func convertToPbQuota(quota data.NamespaceQuota) *pb.NamespaceQuota {
+	return &pb.NamespaceQuota{
+		MaxTriggers:         int32(quota.MaxTriggers),
+		MaxCriteriaLength:   int32(quota.MaxCriteriaLength),
+		MaxActionsPerMinute: int32(quota.MaxActionsPerMinute),
+	}
+}
@@ -0,0 +1,242 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	pb "event/api/proto"
+	"event/data"
+	"event/handlers/namespaces"
+	"event/handlers/triggers"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// triggerStore is an in-memory trigger store that applies saves and deletes immediately
+type triggerStore struct {
+	healthStore
+	mu       sync.Mutex
+	triggers map[string]map[string]*data.Trigger
+}
+
+func newTriggerStore(triggers ...*data.Trigger) *triggerStore {
+	s := &triggerStore{triggers: map[string]map[string]*data.Trigger{}}
+	for _, trigger := range triggers {
+		s.SaveTrigger(context.Background(), trigger.Namespace, trigger.ID, trigger)
+	}
+	return s
+}
+
+func (s *triggerStore) GetTriggers(namespace string) []*data.Trigger {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var triggers []*data.Trigger
+	for _, trigger := range s.triggers[namespace] {
+		triggers = append(triggers, trigger)
+	}
+	sort.Slice(triggers, func(i, j int) bool { return triggers[i].ID < triggers[j].ID })
+	return triggers
+}
+
+func (s *triggerStore) GetAllTriggers() []*data.Trigger {
+	s.mu.Lock()
+	namespaces := make([]string, 0, len(s.triggers))
+	for namespace := range s.triggers {
+		namespaces = append(namespaces, namespace)
+	}
+	s.mu.Unlock()
+
+	var triggers []*data.Trigger
+	for _, namespace := range namespaces {
+		triggers = append(triggers, s.GetTriggers(namespace)...)
+	}
+	return triggers
+}
+
+func (s *triggerStore) SaveTrigger(ctx context.Context, namespace, name string, trigger *data.Trigger) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.triggers[namespace] == nil {
+		s.triggers[namespace] = map[string]*data.Trigger{}
+	}
+	s.triggers[namespace][name] = trigger
+	return nil
+}
+
+func (s *triggerStore) DeleteTrigger(ctx context.Context, namespace, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.triggers[namespace], name)
+	if len(s.triggers[namespace]) == 0 {
+		delete(s.triggers, namespace)
+	}
+	return nil
+}
+
+func TestTriggerServer_Namespaces(t *testing.T) {
+	ctx := context.Background()
+	store := newTriggerStore(
+		&data.Trigger{ID: "t1", Namespace: "sales"},
+		&data.Trigger{ID: "t2", Namespace: "sales"},
+		&data.Trigger{ID: "t1", Namespace: "billing"},
+	)
+	s := NewTriggerServer(store, WithNamespaces(namespaces.NewMemoryStore(), data.NamespaceQuota{MaxTriggers: 10}))
+
+	created, err := s.CreateNamespace(ctx, &pb.CreateNamespaceRequest{Namespace: &pb.Namespace{
+		Name:   "sales",
+		Owner:  "team-sales",
+		Labels: map[string]string{"tier": "gold"},
+		Quota:  &pb.NamespaceQuota{MaxCriteriaLength: 100},
+	}})
+	if err != nil {
+		t.Fatalf("CreateNamespace() error = %v", err)
+	}
+	if got := created.Namespace; got.TriggerCount != 2 || got.CreatedAt == nil ||
+		got.EffectiveQuota.MaxTriggers != 10 || got.EffectiveQuota.MaxCriteriaLength != 100 {
+		t.Errorf("CreateNamespace() = %v, want 2 triggers, a creation time and the merged quota", got)
+	}
+
+	errorTests := []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{"create twice", func() error {
+			_, err := s.CreateNamespace(ctx, &pb.CreateNamespaceRequest{Namespace: &pb.Namespace{Name: "sales"}})
+			return err
+		}, codes.AlreadyExists},
+		{"create invalid name", func() error {
+			_, err := s.CreateNamespace(ctx, &pb.CreateNamespaceRequest{Namespace: &pb.Namespace{Name: "a/b"}})
+			return err
+		}, codes.InvalidArgument},
+		{"create negative quota", func() error {
+			_, err := s.CreateNamespace(ctx, &pb.CreateNamespaceRequest{Namespace: &pb.Namespace{Name: "x", Quota: &pb.NamespaceQuota{MaxTriggers: -1}}})
+			return err
+		}, codes.InvalidArgument},
+		{"get unknown", func() error {
+			_, err := s.GetNamespace(ctx, &pb.GetNamespaceRequest{Namespace: "unknown"})
+			return err
+		}, codes.NotFound},
+		{"delete with triggers", func() error {
+			_, err := s.DeleteNamespace(ctx, &pb.DeleteNamespaceRequest{Namespace: "billing"})
+			return err
+		}, codes.FailedPrecondition},
+		{"delete unknown", func() error {
+			_, err := s.DeleteNamespace(ctx, &pb.DeleteNamespaceRequest{Namespace: "unknown"})
+			return err
+		}, codes.NotFound},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); status.Code(err) != tt.want {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// Namespaces that only have triggers are listed and can be read
+	got, err := s.GetNamespace(ctx, &pb.GetNamespaceRequest{Namespace: "billing"})
+	if err != nil || got.Namespace.TriggerCount != 1 || got.Namespace.CreatedAt != nil {
+		t.Errorf("GetNamespace(billing) = %v, %v, want an uncreated namespace with 1 trigger", got, err)
+	}
+	list, err := s.ListNamespaces(ctx, &pb.ListNamespacesRequest{})
+	if err != nil {
+		t.Fatalf("ListNamespaces() error = %v", err)
+	}
+	var names []string
+	for _, namespace := range list.Namespaces {
+		names = append(names, namespace.Name)
+	}
+	if strings.Join(names, ",") != "billing,sales" {
+		t.Errorf("ListNamespaces() = %v, want billing,sales", names)
+	}
+
+	deleted, err := s.DeleteNamespace(ctx, &pb.DeleteNamespaceRequest{Namespace: "sales", DeleteTriggers: true})
+	if err != nil || deleted.DeletedTriggers != 2 {
+		t.Fatalf("DeleteNamespace(sales) = %v, %v, want 2 deleted triggers", deleted, err)
+	}
+	if _, err := s.GetNamespace(ctx, &pb.GetNamespaceRequest{Namespace: "sales"}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetNamespace(sales) after delete error = %v, want NotFound", err)
+	}
+}
+
+func TestTriggerServer_Quota(t *testing.T) {
+	ctx := context.Background()
+	store := newTriggerStore(
+		&data.Trigger{ID: "t1", Namespace: "sales"},
+		&data.Trigger{ID: "t2", Namespace: "sales"},
+	)
+	namespaceStore := namespaces.NewMemoryStore()
+	namespaceStore.Create(ctx, &data.Namespace{Name: "sales", Quota: data.NamespaceQuota{MaxTriggers: 2}})
+	s := NewTriggerServer(store, WithNamespaces(namespaceStore, data.NamespaceQuota{MaxTriggers: 1, MaxCriteriaLength: 20}))
+
+	tests := []struct {
+		name    string
+		trigger *pb.Trigger
+		update  bool
+		want    codes.Code
+	}{
+		{"namespace is full", &pb.Trigger{Id: "t3", Namespace: "sales"}, false, codes.ResourceExhausted},
+		{"update in a full namespace", &pb.Trigger{Id: "t1", Namespace: "sales", Criteria: "true"}, true, codes.OK},
+		{"criteria too long", &pb.Trigger{Id: "t1", Namespace: "sales", Criteria: strings.Repeat("x", 21)}, true, codes.ResourceExhausted},
+		{"default quota", &pb.Trigger{Id: "t1", Namespace: "billing"}, false, codes.OK},
+		{"default quota exceeded", &pb.Trigger{Id: "t2", Namespace: "billing"}, false, codes.ResourceExhausted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.update {
+				_, err = s.UpdateTrigger(ctx, &pb.UpdateTriggerRequest{Trigger: tt.trigger})
+			} else {
+				_, err = s.AddTrigger(ctx, &pb.AddTriggerRequest{Trigger: tt.trigger})
+			}
+			if status.Code(err) != tt.want {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// limitedStore counts its triggers when saving, but its GetTriggers is always stale, like a
+// watch cache that has not seen concurrent saves yet
+type limitedStore struct {
+	*triggerStore
+}
+
+func (s *limitedStore) GetTriggers(namespace string) []*data.Trigger { return nil }
+
+func (s *limitedStore) SaveTriggerWithLimit(ctx context.Context, namespace, name string, trigger *data.Trigger, maxTriggers int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.triggers[namespace][name]; !ok && len(s.triggers[namespace]) >= maxTriggers {
+		return fmt.Errorf("%w: namespace %s is full", triggers.ErrTriggerLimit, namespace)
+	}
+	if s.triggers[namespace] == nil {
+		s.triggers[namespace] = map[string]*data.Trigger{}
+	}
+	s.triggers[namespace][name] = trigger
+	return nil
+}
+
+func TestTriggerServer_QuotaEnforcedByStore(t *testing.T) {
+	ctx := context.Background()
+	store := &limitedStore{newTriggerStore(&data.Trigger{ID: "t1", Namespace: "sales"})}
+	s := NewTriggerServer(store, WithNamespaces(namespaces.NewMemoryStore(), data.NamespaceQuota{MaxTriggers: 1}))
+
+	if _, err := s.AddTrigger(ctx, &pb.AddTriggerRequest{Trigger: &pb.Trigger{Id: "t2", Namespace: "sales"}}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("AddTrigger() in a full namespace error = %v, want ResourceExhausted", err)
+	}
+	if _, err := s.UpdateTrigger(ctx, &pb.UpdateTriggerRequest{Trigger: &pb.Trigger{Id: "t1", Namespace: "sales"}}); err != nil {
+		t.Errorf("UpdateTrigger() in a full namespace error = %v", err)
+	}
+}
@@ -2,46 +2,319 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
 	"net"
+	"sync"
+	"time"
 
+	"event/api/auth"
 	pb "event/api/proto"
 	"event/data"
+	"event/handlers/actions"
+	"event/handlers/aggregation"
+	"event/handlers/deadletter"
+	"event/handlers/dispatch"
+	"event/handlers/namespaces"
+	"event/handlers/scheduler"
+	"event/handlers/sequence"
+	"event/handlers/throttle"
 	"event/handlers/triggers"
+	"event/metrics"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 // TriggerServer implements the TriggerService gRPC server
 type TriggerServer struct {
 	pb.UnimplementedTriggerServiceServer
-	store triggers.TriggerStore
+	store       triggers.TriggerStore
+	deadLetters deadletter.Store
+	dispatcher  *dispatch.Dispatcher
+	actions     *actions.Registry
+	jobs        *JobServer
+	stats       throttle.StatsStore
+	namespaces  namespaces.Store
+	quota       data.NamespaceQuota
+	guard       *auth.Guard
+	tls         *tls.Config
+	metrics     *metrics.Metrics
+
+	reflection     bool
+	healthInterval time.Duration
+
+	mu         sync.Mutex
+	grpcServer *grpc.Server
+	health     *health.Server
+	stopHealth context.CancelFunc
+	stopped    bool
+	// healthStatus is the last status set by updateHealth
+	healthStatus healthpb.HealthCheckResponse_ServingStatus
+}
+
+// DefaultHealthInterval is how often the health of the trigger store is checked by default
+const DefaultHealthInterval = 5 * time.Second
+
+// ErrServerStopped is returned by Serve when the server was stopped before it started
+var ErrServerStopped = errors.New("server stopped")
+
+// ServerOption is a function that configures a TriggerServer
+type ServerOption func(*TriggerServer)
+
+// WithDeadLetters enables the dead-letter RPCs. The dispatcher is used to redrive dead letters.
+func WithDeadLetters(deadLetters deadletter.Store, dispatcher *dispatch.Dispatcher) ServerOption {
+	return func(s *TriggerServer) {
+		s.deadLetters = deadLetters
+		s.dispatcher = dispatcher
+	}
+}
+
+// WithActionRegistry validates trigger actions against the registry when triggers are saved
+// and enables DryRunTrigger
+func WithActionRegistry(registry *actions.Registry) ServerOption {
+	return func(s *TriggerServer) {
+		s.actions = registry
+	}
+}
+
+// WithJobService serves the JobService next to the TriggerService
+func WithJobService(jobServer *JobServer) ServerOption {
+	return func(s *TriggerServer) {
+		s.jobs = jobServer
+	}
+}
+
+// WithStats enables GetTriggerStats, reading the stats that triggerd instances record in store
+func WithStats(store throttle.StatsStore) ServerOption {
+	return func(s *TriggerServer) {
+		s.stats = store
+	}
+}
+
+// WithNamespaces enables the namespace RPCs and enforces the quota of a trigger's namespace
+// when it is added or updated. Namespaces that were not created, or do not set a limit, get
+// the limits of defaults.
+func WithNamespaces(store namespaces.Store, defaults data.NamespaceQuota) ServerOption {
+	return func(s *TriggerServer) {
+		s.namespaces = store
+		s.quota = defaults
+	}
+}
+
+// WithAuth requires every RPC to carry a bearer token or API key whose identity has the
+// required role in the RPC's namespace
+func WithAuth(guard *auth.Guard) ServerOption {
+	return func(s *TriggerServer) {
+		s.guard = guard
+	}
+}
+
+// WithTLS serves the API over TLS. The configuration decides whether client certificates
+// are required.
+func WithTLS(config *tls.Config) ServerOption {
+	return func(s *TriggerServer) {
+		s.tls = config
+	}
+}
+
+// WithMetrics measures every RPC, including the ones rejected by authentication
+func WithMetrics(m *metrics.Metrics) ServerOption {
+	return func(s *TriggerServer) {
+		s.metrics = m
+	}
+}
+
+// WithReflection serves the gRPC reflection service, so that tools like grpcurl can
+// discover the API
+func WithReflection() ServerOption {
+	return func(s *TriggerServer) {
+		s.reflection = true
+	}
+}
+
+// WithHealthInterval sets how often the health service checks the trigger store
+func WithHealthInterval(interval time.Duration) ServerOption {
+	return func(s *TriggerServer) {
+		if interval > 0 {
+			s.healthInterval = interval
+		}
+	}
 }
 
 // NewTriggerServer creates a new TriggerServer
-func NewTriggerServer(store triggers.TriggerStore) *TriggerServer {
-	return &TriggerServer{
-		store: store,
+func NewTriggerServer(store triggers.TriggerStore, options ...ServerOption) *TriggerServer {
+	s := &TriggerServer{
+		store:          store,
+		healthInterval: DefaultHealthInterval,
 	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	return s
 }
 
-// Start starts the gRPC server
+// Start listens on address and serves the API until Stop is called
 func (s *TriggerServer) Start(address string) error {
 	lis, err := net.Listen("tcp", address)
 	if err != nil {
 		return fmt.Errorf("failed to listen: %v", err)
 	}
 
-	grpcServer := grpc.NewServer()
+	log.Printf("Starting gRPC server on %s", address)
+	return s.Serve(lis)
+}
+
+// Serve serves the API, the grpc.health.v1 health service and, if enabled, reflection on
+// lis until Stop is called. The health service reports SERVING while the trigger store is
+// healthy.
+func (s *TriggerServer) Serve(lis net.Listener) error {
+	var serverOptions []grpc.ServerOption
+	if s.tls != nil {
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(s.tls)))
+	}
+	if s.metrics != nil {
+		serverOptions = append(serverOptions,
+			grpc.ChainUnaryInterceptor(s.metrics.UnaryServerInterceptor()),
+			grpc.ChainStreamInterceptor(s.metrics.StreamServerInterceptor()),
+		)
+	}
+	if s.guard != nil {
+		serverOptions = append(serverOptions,
+			grpc.ChainUnaryInterceptor(s.guard.UnaryInterceptor()),
+			grpc.ChainStreamInterceptor(s.guard.StreamInterceptor()),
+		)
+	}
+
+	grpcServer := grpc.NewServer(serverOptions...)
 	pb.RegisterTriggerServiceServer(grpcServer, s)
+	if s.jobs != nil {
+		pb.RegisterJobServiceServer(grpcServer, s.jobs)
+	}
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	if s.reflection {
+		reflection.Register(grpcServer)
+	}
+	if s.metrics != nil {
+		s.metrics.InitializeGRPC(grpcServer)
+	}
+
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		lis.Close()
+		return ErrServerStopped
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.grpcServer = grpcServer
+	s.health = healthServer
+	s.stopHealth = cancel
+	s.mu.Unlock()
+
+	s.updateHealth(ctx)
+	go s.watchHealth(ctx)
 
-	log.Printf("Starting gRPC server on %s", address)
 	return grpcServer.Serve(lis)
 }
 
+// Stop reports NOT_SERVING, stops accepting connections and waits for in-flight RPCs to
+// finish until ctx is done, when the remaining RPCs are cancelled. It also stops the trigger
+// store's watch. Serve returns once the server stopped.
+func (s *TriggerServer) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopped = true
+	grpcServer, healthServer, stopHealth := s.grpcServer, s.health, s.stopHealth
+	s.mu.Unlock()
+
+	if stopHealth != nil {
+		stopHealth()
+	}
+	if healthServer != nil {
+		healthServer.Shutdown()
+	}
+
+	var err error
+	if grpcServer != nil {
+		done := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			log.Printf("gRPC server did not stop in time, cancelling the remaining RPCs")
+			grpcServer.Stop()
+			<-done
+			err = ctx.Err()
+		}
+	}
+
+	if watcher, ok := s.store.(interface{ StopWatch() }); ok {
+		watcher.StopWatch()
+	}
+	return err
+}
+
+// watchHealth periodically updates the health status until ctx is done
+func (s *TriggerServer) watchHealth(ctx context.Context) {
+	ticker := time.NewTicker(s.healthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.updateHealth(ctx)
+	}
+}
+
+// updateHealth sets the status of the server and of each of its services from the health
+// of the trigger store
+func (s *TriggerServer) updateHealth(ctx context.Context) {
+	status := healthpb.HealthCheckResponse_SERVING
+	if checker, ok := s.store.(triggers.HealthChecker); ok {
+		checkCtx, cancel := context.WithTimeout(ctx, s.healthInterval)
+		err := checker.Healthy(checkCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if s.healthStatus != healthpb.HealthCheckResponse_NOT_SERVING {
+				log.Printf("Trigger store is unhealthy: %v", err)
+			}
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+	}
+	if status == healthpb.HealthCheckResponse_SERVING && s.healthStatus == healthpb.HealthCheckResponse_NOT_SERVING {
+		log.Printf("Trigger store is healthy again")
+	}
+	s.healthStatus = status
+
+	services := []string{"", pb.TriggerService_ServiceDesc.ServiceName}
+	if s.jobs != nil {
+		services = append(services, pb.JobService_ServiceDesc.ServiceName)
+	}
+	for _, service := range services {
+		s.health.SetServingStatus(service, status)
+	}
+}
+
 // ListTriggers lists all triggers under a specified namespace
 func (s *TriggerServer) ListTriggers(ctx context.Context, req *pb.ListTriggersRequest) (*pb.ListTriggersResponse, error) {
 	triggers := s.store.GetTriggers(req.Namespace)
@@ -62,8 +335,22 @@ func (s *TriggerServer) AddTrigger(ctx context.Context, req *pb.AddTriggerReques
 		return nil, status.Error(codes.InvalidArgument, "trigger is required")
 	}
 
-	trigger := convertToDataTrigger(req.Trigger)
-	err := s.store.SaveTrigger(ctx, trigger.Namespace, trigger.ID, trigger)
+	trigger, err := convertToDataTrigger(req.Trigger)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid trigger: %v", err)
+	}
+	if err := s.validateTrigger(trigger); err != nil {
+		return nil, err
+	}
+	maxTriggers, err := s.checkQuota(ctx, trigger)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.saveTrigger(ctx, trigger, maxTriggers)
+	if status.Code(err) == codes.ResourceExhausted {
+		return nil, err
+	}
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to save trigger: %v", err)
 	}
@@ -79,8 +366,22 @@ func (s *TriggerServer) UpdateTrigger(ctx context.Context, req *pb.UpdateTrigger
 		return nil, status.Error(codes.InvalidArgument, "trigger is required")
 	}
 
-	trigger := convertToDataTrigger(req.Trigger)
-	err := s.store.SaveTrigger(ctx, trigger.Namespace, trigger.ID, trigger)
+	trigger, err := convertToDataTrigger(req.Trigger)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid trigger: %v", err)
+	}
+	if err := s.validateTrigger(trigger); err != nil {
+		return nil, err
+	}
+	maxTriggers, err := s.checkQuota(ctx, trigger)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.saveTrigger(ctx, trigger, maxTriggers)
+	if status.Code(err) == codes.ResourceExhausted {
+		return nil, err
+	}
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to update trigger: %v", err)
 	}
@@ -102,30 +403,266 @@ func (s *TriggerServer) RemoveTrigger(ctx context.Context, req *pb.RemoveTrigger
 	}, nil
 }
 
+// validateTrigger checks a trigger before it is saved
+func (s *TriggerServer) validateTrigger(trigger *data.Trigger) error {
+	kinds := 0
+	for _, set := range []bool{trigger.Schedule != nil, trigger.Aggregation != nil, trigger.Sequence != nil} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds > 1 {
+		return status.Error(codes.InvalidArgument, "a trigger can only have one of schedule, aggregation and sequence")
+	}
+
+	if trigger.Schedule != nil {
+		if _, err := scheduler.Parse(trigger.Schedule); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid trigger schedule: %v", err)
+		}
+	}
+	if trigger.Aggregation != nil {
+		if err := aggregation.Validate(trigger.Aggregation); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid trigger aggregation: %v", err)
+		}
+	}
+	if trigger.Sequence != nil {
+		if err := sequence.Validate(trigger.Sequence); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid trigger sequence: %v", err)
+		}
+	}
+	if trigger.Throttle != nil {
+		if err := throttle.Validate(trigger.Throttle); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid trigger throttle: %v", err)
+		}
+	}
+	if err := triggers.ValidateActive(trigger, time.Now()); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid trigger activity: %v", err)
+	}
+	if s.actions != nil {
+		if err := s.actions.Validate(trigger); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid trigger actions: %v", err)
+		}
+	}
+	return nil
+}
+
 // Helper functions to convert between protobuf and data types
 
 func convertToPbTrigger(t *data.Trigger) *pb.Trigger {
+	pbActions := make([]*pb.Action, 0, len(t.Actions))
+	for _, a := range t.Actions {
+		// Action configs come from YAML or from a Struct, so they always convert
+		config, _ := structpb.NewStruct(a.Config)
+		pbActions = append(pbActions, &pb.Action{
+			Type:       a.Type,
+			Config:     config,
+			RetryCount: int32(a.RetryCount),
+			Timeout:    int32(a.Timeout),
+		})
+	}
+
 	return &pb.Trigger{
-		Id:          t.ID,
-		Name:        t.Name,
-		Namespace:   t.Namespace,
-		ObjectType:  t.ObjectType,
-		EventType:   t.EventType,
-		Enabled:     t.Enabled,
-		Criteria:    t.Criteria,
-		Description: t.Description,
+		Id:            t.ID,
+		Name:          t.Name,
+		Namespace:     t.Namespace,
+		ObjectType:    t.ObjectType,
+		EventType:     t.EventType,
+		Enabled:       t.Enabled,
+		Criteria:      t.Criteria,
+		Description:   t.Description,
+		ActionUrl:     t.ActionURL,
+		RetryCount:    int32(t.RetryCount),
+		Timeout:       int32(t.Timeout),
+		Actions:       pbActions,
+		BodyTemplate:  t.BodyTemplate,
+		Schedule:      convertToPbSchedule(t.Schedule),
+		Aggregation:   convertToPbAggregation(t.Aggregation),
+		Sequence:      convertToPbSequence(t.Sequence),
+		Throttle:      convertToPbThrottle(t.Throttle),
+		Priority:      int32(t.Priority),
+		StopOnMatch:   t.StopOnMatch,
+		ActiveFrom:    t.ActiveFrom,
+		ActiveUntil:   t.ActiveUntil,
+		ActiveWindows: convertToPbActiveWindows(t.ActiveWindows),
 	}
 }
 
-func convertToDataTrigger(t *pb.Trigger) *data.Trigger {
+func convertToDataTrigger(t *pb.Trigger) (*data.Trigger, error) {
+	var dataActions []data.ActionConfig
+	for i, a := range t.Actions {
+		if a.Type == "" {
+			return nil, fmt.Errorf("action %d has no type", i)
+		}
+		dataActions = append(dataActions, data.ActionConfig{
+			Type:       a.Type,
+			Config:     a.Config.AsMap(),
+			RetryCount: int(a.RetryCount),
+			Timeout:    int(a.Timeout),
+		})
+	}
+
 	return &data.Trigger{
-		ID:          t.Id,
-		Name:        t.Name,
-		Namespace:   t.Namespace,
-		ObjectType:  t.ObjectType,
-		EventType:   t.EventType,
-		Enabled:     t.Enabled,
-		Criteria:    t.Criteria,
-		Description: t.Description,
+		ID:            t.Id,
+		Name:          t.Name,
+		Namespace:     t.Namespace,
+		ObjectType:    t.ObjectType,
+		EventType:     t.EventType,
+		Enabled:       t.Enabled,
+		Criteria:      t.Criteria,
+		Description:   t.Description,
+		ActionURL:     t.ActionUrl,
+		RetryCount:    int(t.RetryCount),
+		Timeout:       int(t.Timeout),
+		Actions:       dataActions,
+		BodyTemplate:  t.BodyTemplate,
+		Schedule:      convertToDataSchedule(t.Schedule),
+		Aggregation:   convertToDataAggregation(t.Aggregation),
+		Sequence:      convertToDataSequence(t.Sequence),
+		Throttle:      convertToDataThrottle(t.Throttle),
+		Priority:      int(t.Priority),
+		StopOnMatch:   t.StopOnMatch,
+		ActiveFrom:    t.ActiveFrom,
+		ActiveUntil:   t.ActiveUntil,
+		ActiveWindows: convertToDataActiveWindows(t.ActiveWindows),
+	}, nil
+}
+
+func convertToPbSchedule(s *data.Schedule) *pb.Schedule {
+	if s == nil {
+		return nil
+	}
+	return &pb.Schedule{
+		Cron:     s.Cron,
+		Timezone: s.Timezone,
+	}
+}
+
+func convertToDataSchedule(s *pb.Schedule) *data.Schedule {
+	if s == nil {
+		return nil
+	}
+	return &data.Schedule{
+		Cron:     s.Cron,
+		Timezone: s.Timezone,
+	}
+}
+
+func convertToPbAggregation(a *data.Aggregation) *pb.Aggregation {
+	if a == nil {
+		return nil
+	}
+	return &pb.Aggregation{
+		GroupBy:   a.GroupBy,
+		Function:  a.Function,
+		Field:     a.Field,
+		Window:    a.Window,
+		Mode:      a.Mode,
+		Operator:  a.Operator,
+		Threshold: a.Threshold,
+	}
+}
+
+func convertToDataAggregation(a *pb.Aggregation) *data.Aggregation {
+	if a == nil {
+		return nil
+	}
+	return &data.Aggregation{
+		GroupBy:   a.GroupBy,
+		Function:  a.Function,
+		Field:     a.Field,
+		Window:    a.Window,
+		Mode:      a.Mode,
+		Operator:  a.Operator,
+		Threshold: a.Threshold,
+	}
+}
+
+func convertToPbSequence(s *data.Sequence) *pb.Sequence {
+	if s == nil {
+		return nil
+	}
+	steps := make([]*pb.SequenceStep, 0, len(s.Steps))
+	for _, step := range s.Steps {
+		steps = append(steps, &pb.SequenceStep{
+			Name:     step.Name,
+			Criteria: step.Criteria,
+		})
+	}
+	return &pb.Sequence{
+		Steps:   steps,
+		Key:     s.Key,
+		Timeout: s.Timeout,
+		Absent:  s.Absent,
+	}
+}
+
+func convertToDataSequence(s *pb.Sequence) *data.Sequence {
+	if s == nil {
+		return nil
+	}
+	steps := make([]data.SequenceStep, 0, len(s.Steps))
+	for _, step := range s.Steps {
+		steps = append(steps, data.SequenceStep{
+			Name:     step.Name,
+			Criteria: step.Criteria,
+		})
+	}
+	return &data.Sequence{
+		Steps:   steps,
+		Key:     s.Key,
+		Timeout: s.Timeout,
+		Absent:  s.Absent,
+	}
+}
+
+func convertToPbThrottle(t *data.Throttle) *pb.Throttle {
+	if t == nil {
+		return nil
+	}
+	return &pb.Throttle{
+		MaxFires:    int32(t.MaxFires),
+		Interval:    t.Interval,
+		DedupKey:    t.DedupKey,
+		DedupWindow: t.DedupWindow,
+		Cooldown:    t.Cooldown,
+	}
+}
+
+func convertToDataThrottle(t *pb.Throttle) *data.Throttle {
+	if t == nil {
+		return nil
+	}
+	return &data.Throttle{
+		MaxFires:    int(t.MaxFires),
+		Interval:    t.Interval,
+		DedupKey:    t.DedupKey,
+		DedupWindow: t.DedupWindow,
+		Cooldown:    t.Cooldown,
+	}
+}
+
+func convertToPbActiveWindows(windows []data.ActiveWindow) []*pb.ActiveWindow {
+	var pbWindows []*pb.ActiveWindow
+	for _, w := range windows {
+		pbWindows = append(pbWindows, &pb.ActiveWindow{
+			Days:     w.Days,
+			Start:    w.Start,
+			End:      w.End,
+			Timezone: w.Timezone,
+		})
+	}
+	return pbWindows
+}
+
+func convertToDataActiveWindows(windows []*pb.ActiveWindow) []data.ActiveWindow {
+	var dataWindows []data.ActiveWindow
+	for _, w := range windows {
+		dataWindows = append(dataWindows, data.ActiveWindow{
+			Days:     w.Days,
+			Start:    w.Start,
+			End:      w.End,
+			Timezone: w.Timezone,
+		})
 	}
+	return dataWindows
 }
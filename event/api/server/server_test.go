@@ -0,0 +1,191 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "event/api/proto"
+	"event/data"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// healthStore is a trigger store whose health can be switched
+type healthStore struct {
+	unhealthy atomic.Bool
+	watching  atomic.Bool
+}
+
+func (s *healthStore) LoadAll(ctx context.Context) error            { return nil }
+func (s *healthStore) Watch(ctx context.Context)                    { s.watching.Store(true) }
+func (s *healthStore) StopWatch()                                   { s.watching.Store(false) }
+func (s *healthStore) GetTriggers(namespace string) []*data.Trigger { return nil }
+func (s *healthStore) GetAllTriggers() []*data.Trigger              { return nil }
+func (s *healthStore) Close() error                                 { return nil }
+
+func (s *healthStore) SaveTrigger(ctx context.Context, namespace, name string, trigger *data.Trigger) error {
+	return nil
+}
+
+func (s *healthStore) DeleteTrigger(ctx context.Context, namespace, name string) error {
+	return nil
+}
+
+func (s *healthStore) Healthy(ctx context.Context) error {
+	if s.unhealthy.Load() {
+		return errors.New("etcd is unreachable")
+	}
+	return nil
+}
+
+// serve runs a TriggerServer over an in-memory connection and returns a connection to it
+// and a channel that receives the result of Serve
+func serve(t *testing.T, s *TriggerServer) (*grpc.ClientConn, <-chan error) {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(lis) }()
+	t.Cleanup(func() { s.Stop(context.Background()) })
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, served
+}
+
+// waitForStatus polls the health service until service reports want
+func waitForStatus(t *testing.T, client healthpb.HealthClient, service string, want healthpb.HealthCheckResponse_ServingStatus) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err == nil && resp.Status == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("health of %q = %v (%v), want %v", service, resp.GetStatus(), err, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTriggerServer_Health(t *testing.T) {
+	store := &healthStore{}
+	conn, _ := serve(t, NewTriggerServer(store, WithHealthInterval(10*time.Millisecond)))
+	client := healthpb.NewHealthClient(conn)
+
+	waitForStatus(t, client, "", healthpb.HealthCheckResponse_SERVING)
+	waitForStatus(t, client, pb.TriggerService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+
+	store.unhealthy.Store(true)
+	waitForStatus(t, client, "", healthpb.HealthCheckResponse_NOT_SERVING)
+	waitForStatus(t, client, pb.TriggerService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+
+	store.unhealthy.Store(false)
+	waitForStatus(t, client, "", healthpb.HealthCheckResponse_SERVING)
+
+	// JobService is not served, so its health is unknown
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: pb.JobService_ServiceDesc.ServiceName}); status.Code(err) != codes.NotFound {
+		t.Errorf("health of JobService error = %v, want NotFound", err)
+	}
+}
+
+func TestTriggerServer_Reflection(t *testing.T) {
+	conn, _ := serve(t, NewTriggerServer(&healthStore{}, WithReflection()))
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	if err != nil {
+		t.Fatalf("ServerReflectionInfo() error = %v", err)
+	}
+	if err := stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+
+	services := map[string]bool{}
+	for _, service := range resp.GetListServicesResponse().GetService() {
+		services[service.Name] = true
+	}
+	for _, want := range []string{pb.TriggerService_ServiceDesc.ServiceName, "grpc.health.v1.Health"} {
+		if !services[want] {
+			t.Errorf("reflection lists %v, want %s", services, want)
+		}
+	}
+}
+
+func TestTriggerServer_Stop(t *testing.T) {
+	t.Run("graceful", func(t *testing.T) {
+		store := &healthStore{}
+		store.Watch(context.Background())
+		s := NewTriggerServer(store)
+		conn, served := serve(t, s)
+		waitForStatus(t, healthpb.NewHealthClient(conn), "", healthpb.HealthCheckResponse_SERVING)
+
+		if err := s.Stop(context.Background()); err != nil {
+			t.Fatalf("Stop() error = %v", err)
+		}
+		if err := <-served; err != nil {
+			t.Errorf("Serve() error = %v, want nil", err)
+		}
+		if store.watching.Load() {
+			t.Error("Stop() did not stop the store's watch")
+		}
+	})
+
+	t.Run("deadline cancels streams", func(t *testing.T) {
+		s := NewTriggerServer(&healthStore{})
+		conn, served := serve(t, s)
+
+		// A health watch stays open until the server cancels it
+		watch, err := healthpb.NewHealthClient(conn).Watch(context.Background(), &healthpb.HealthCheckRequest{})
+		if err != nil {
+			t.Fatalf("Watch() error = %v", err)
+		}
+		if _, err := watch.Recv(); err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if err := s.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Stop() error = %v, want DeadlineExceeded", err)
+		}
+		select {
+		case <-served:
+		case <-time.After(time.Second):
+			t.Fatal("Serve() did not return after Stop()")
+		}
+	})
+
+	t.Run("before serve", func(t *testing.T) {
+		s := NewTriggerServer(&healthStore{})
+		if err := s.Stop(context.Background()); err != nil {
+			t.Fatalf("Stop() error = %v", err)
+		}
+		if err := s.Serve(bufconn.Listen(1 << 10)); !errors.Is(err, ErrServerStopped) {
+			t.Errorf("Serve() after Stop() error = %v, want ErrServerStopped", err)
+		}
+	})
+}
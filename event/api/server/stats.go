@@ -0,0 +1,44 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	pb "event/api/proto"
+	"event/data"
+	"event/handlers/throttle"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetTriggerStats returns how often a trigger fired and how often its throttle suppressed it.
+// Triggers that never fired have zero stats.
+func (s *TriggerServer) GetTriggerStats(ctx context.Context, req *pb.GetTriggerStatsRequest) (*pb.GetTriggerStatsResponse, error) {
+	if s.stats == nil {
+		return nil, status.Error(codes.Unimplemented, "trigger stats are not configured")
+	}
+	if req.Namespace == "" || req.TriggerId == "" {
+		return nil, status.Error(codes.InvalidArgument, "namespace and trigger_id are required")
+	}
+
+	stats, err := s.stats.Get(ctx, req.Namespace, req.TriggerId)
+	if errors.Is(err, throttle.ErrNotFound) {
+		stats = &data.TriggerStats{Namespace: req.Namespace, TriggerID: req.TriggerId}
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get trigger stats: %v", err)
+	}
+
+	return &pb.GetTriggerStatsResponse{
+		Stats: &pb.TriggerStats{
+			Namespace:        stats.Namespace,
+			TriggerId:        stats.TriggerID,
+			Fired:            stats.Fired,
+			RateLimited:      stats.RateLimited,
+			Deduplicated:     stats.Deduplicated,
+			CoolingDown:      stats.CoolingDown,
+			LastFiredAt:      toTimestamp(stats.LastFiredAt),
+			LastSuppressedAt: toTimestamp(stats.LastSuppressedAt),
+		},
+	}, nil
+}
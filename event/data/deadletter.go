@@ -0,0 +1,30 @@
+package data
+
+import "time"
+
+// DeliveryAttempt records the outcome of a single attempt to run a trigger's action
+type DeliveryAttempt struct {
+	Attempt      int           `json:"attempt" bson:"attempt"`
+	StatusCode   int           `json:"status_code,omitempty" bson:"status_code,omitempty"`
+	ResponseBody string        `json:"response_body,omitempty" bson:"response_body,omitempty"` // Truncated response body snippet
+	Error        string        `json:"error,omitempty" bson:"error,omitempty"`
+	StartedAt    time.Time     `json:"started_at" bson:"started_at"`
+	Duration     time.Duration `json:"duration" bson:"duration"`
+}
+
+// DeadLetter holds an event for which a trigger's action failed after all retries
+type DeadLetter struct {
+	ID           string            `json:"id" bson:"_id"`
+	Namespace    string            `json:"namespace" bson:"namespace"`
+	TriggerID    string            `json:"trigger_id" bson:"trigger_id"`
+	ActionIndex  int               `json:"action_index" bson:"action_index"` // Position of the failed action in the trigger
+	ActionType   string            `json:"action_type" bson:"action_type"`
+	Event        Event             `json:"event" bson:"event"`
+	Attempts     []DeliveryAttempt `json:"attempts" bson:"attempts"`
+	LastError    string            `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	RedriveCount int               `json:"redrive_count,omitempty" bson:"redrive_count,omitempty"`
+	CreatedAt    time.Time         `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at" bson:"updated_at"`
+	// RedriveUntil is set while a redrive holds the dead letter, so that it is not redriven twice
+	RedriveUntil time.Time `json:"redrive_until,omitempty" bson:"redrive_until,omitempty"`
+}
@@ -0,0 +1,26 @@
+package data
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// NewEventID returns a random UUID v4 for use as an event ID
+func NewEventID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("failed to generate event ID: %v", err))
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// NewJobID returns a random job ID of the form job_<24 hex digits>
+func NewJobID() string {
+	var b [12]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("failed to generate job ID: %v", err))
+	}
+	return fmt.Sprintf("job_%x", b)
+}
@@ -2,17 +2,55 @@ package data
 
 import "time"
 
+// Job statuses. A job is pending until all of its dependencies have completed,
+// then started, and finally completed, failed or canceled.
+const (
+	JobStatusPending   = "pending"
+	JobStatusStarted   = "started"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+	JobStatusCanceled  = "canceled"
+)
+
 // Job represents an activity or unit of work in the system
 type Job struct {
-	JobID       string                 `json:"job_id"`
-	Namespace   string                 `json:"namespace"`
-	Status      string                 `json:"status"` // e.g., "started", "completed", "failed"
-	Inputs      map[string]interface{} `json:"inputs,omitempty"`
-	Result      map[string]interface{} `json:"result,omitempty"`
-	CreatedAt   time.Time              `json:"created_at,omitempty"`
-	StartedAt   time.Time              `json:"started_at,omitempty"`
-	CompletedAt time.Time              `json:"completed_at,omitempty"`
-	Retries     int                    `json:"retries,omitempty"`
-	DependsOn   []string               `json:"depends_on,omitempty"`   // Array of task IDs for DAG-style orchestration
-	TriggeredBy string                 `json:"triggered_by,omitempty"` // Event ID that caused the task to start
+	JobID       string                 `json:"job_id" bson:"_id"`
+	JobType     string                 `json:"job_type" bson:"job_type"` // e.g., "resize_image"
+	Namespace   string                 `json:"namespace" bson:"namespace"`
+	Status      string                 `json:"status" bson:"status"` // e.g., "started", "completed", "failed"
+	Inputs      map[string]interface{} `json:"inputs,omitempty" bson:"inputs,omitempty"`
+	Result      map[string]interface{} `json:"result,omitempty" bson:"result,omitempty"`
+	Error       *JobError              `json:"error,omitempty" bson:"error,omitempty"`
+	CreatedAt   time.Time              `json:"created_at,omitempty" bson:"created_at"`
+	StartedAt   time.Time              `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	CompletedAt time.Time              `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+	UpdatedAt   time.Time              `json:"updated_at,omitempty" bson:"updated_at"`
+	Retries     int                    `json:"retries,omitempty" bson:"retries"`
+	MaxRetries  int                    `json:"max_retries,omitempty" bson:"max_retries"`             // Times an expired lease is re-queued before the job fails
+	DependsOn   []string               `json:"depends_on,omitempty" bson:"depends_on,omitempty"`     // Array of task IDs for DAG-style orchestration
+	TriggeredBy string                 `json:"triggered_by,omitempty" bson:"triggered_by,omitempty"` // Event ID that caused the task to start
+	// Lease of the worker running a started job. A job with no lease, or an expired one, can be claimed.
+	LeaseOwner     string    `json:"lease_owner,omitempty" bson:"lease_owner,omitempty"`
+	LeaseExpiresAt time.Time `json:"lease_expires_at,omitempty" bson:"lease_expires_at,omitempty"`
+	Progress       int       `json:"progress,omitempty" bson:"progress"` // Percentage reported by the worker
+	// Version is incremented on every update and guards against concurrent modification
+	Version int64 `json:"version" bson:"version"`
+}
+
+// JobError describes why a job failed
+type JobError struct {
+	Code      string                 `json:"code" bson:"code"` // e.g., "IMG_TOO_LARGE"
+	Message   string                 `json:"message" bson:"message"`
+	Details   map[string]interface{} `json:"details,omitempty" bson:"details,omitempty"`
+	Timestamp time.Time              `json:"timestamp" bson:"timestamp"`
+}
+
+// IsFinished reports whether the job has completed, failed or been canceled
+func (j *Job) IsFinished() bool {
+	return j.Status == JobStatusCompleted || j.Status == JobStatusFailed || j.Status == JobStatusCanceled
+}
+
+// Error implements the error interface so that workers can return a JobError from their handlers
+func (e *JobError) Error() string {
+	return e.Code + ": " + e.Message
 }
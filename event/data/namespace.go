@@ -0,0 +1,26 @@
+package data
+
+import "time"
+
+// Namespace describes a tenant whose triggers share a key prefix in etcd
+type Namespace struct {
+	Name        string            `json:"name" yaml:"name"`
+	Owner       string            `json:"owner,omitempty" yaml:"owner,omitempty"`
+	Description string            `json:"description,omitempty" yaml:"description,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	// Quota overrides the default quota. Limits that are zero fall back to the default.
+	Quota     NamespaceQuota `json:"quota,omitempty" yaml:"quota,omitempty"`
+	CreatedAt time.Time      `json:"created_at" yaml:"created_at"`
+}
+
+// NamespaceQuota limits the triggers of a namespace. Zero means no limit.
+// Example: at most 100 triggers with criteria up to 2000 characters that run at most 600
+// actions per minute together is {MaxTriggers: 100, MaxCriteriaLength: 2000, MaxActionsPerMinute: 600}.
+type NamespaceQuota struct {
+	MaxTriggers int `json:"max_triggers,omitempty" yaml:"max_triggers,omitempty" mapstructure:"max_triggers"`
+	// MaxCriteriaLength limits the length of the criteria and sequence step criteria
+	MaxCriteriaLength int `json:"max_criteria_length,omitempty" yaml:"max_criteria_length,omitempty" mapstructure:"max_criteria_length"`
+	// MaxActionsPerMinute limits how many actions the enabled triggers may run per minute
+	// together, as bounded by their throttles' max_fires and interval
+	MaxActionsPerMinute int `json:"max_actions_per_minute,omitempty" yaml:"max_actions_per_minute,omitempty" mapstructure:"max_actions_per_minute"`
+}
@@ -0,0 +1,22 @@
+package data
+
+import "time"
+
+// TriggerStats counts how often a trigger fired and how often a firing was suppressed
+// by its throttle
+type TriggerStats struct {
+	Namespace string `json:"namespace" bson:"namespace"`
+	TriggerID string `json:"trigger_id" bson:"trigger_id"`
+	Fired     int64  `json:"fired" bson:"fired"`
+	// RateLimited, Deduplicated and CoolingDown count the suppressed firings by reason
+	RateLimited      int64     `json:"rate_limited" bson:"rate_limited"`
+	Deduplicated     int64     `json:"deduplicated" bson:"deduplicated"`
+	CoolingDown      int64     `json:"cooling_down" bson:"cooling_down"`
+	LastFiredAt      time.Time `json:"last_fired_at,omitempty" bson:"last_fired_at,omitempty"`
+	LastSuppressedAt time.Time `json:"last_suppressed_at,omitempty" bson:"last_suppressed_at,omitempty"`
+}
+
+// Suppressed returns the number of suppressed firings
+func (s *TriggerStats) Suppressed() int64 {
+	return s.RateLimited + s.Deduplicated + s.CoolingDown
+}
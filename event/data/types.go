@@ -34,6 +34,37 @@ type Event struct {
 	} `json:"nats_meta"`
 }
 
+// ToMap returns a map representation of the event that matches its JSON field names.
+// It is the data that trigger criteria and action templates are evaluated against.
+func (e *Event) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"event_id":      e.ID,
+		"event_type":    e.EventType,
+		"event_version": e.EventVersion,
+		"namespace":     e.Namespace,
+		"object_type":   e.ObjectType,
+		"object_id":     e.ObjectID,
+		"timestamp":     e.Timestamp,
+		"actor": map[string]interface{}{
+			"type": e.Actor.Type,
+			"id":   e.Actor.ID,
+		},
+		"context": map[string]interface{}{
+			"request_id": e.Context.RequestID,
+			"trace_id":   e.Context.TraceID,
+		},
+		"payload": map[string]interface{}{
+			"before": e.Payload.Before,
+			"after":  e.Payload.After,
+		},
+		"nats_meta": map[string]interface{}{
+			"stream":      e.NatsMeta.Stream,
+			"sequence":    e.NatsMeta.Sequence,
+			"received_at": e.NatsMeta.ReceivedAt,
+		},
+	}
+}
+
 type Trigger struct {
 	ID         string `json:"id" yaml:"id"`
 	Name       string `json:"name" yaml:"name"`
@@ -46,6 +77,159 @@ type Trigger struct {
 	Criteria    string `json:"criteria" yaml:"criteria"`
 	Description string `json:"description,omitempty" yaml:"description,omitempty"`
 	Enabled     bool   `json:"enabled" yaml:"enabled"`
+	// ActionURL is the webhook endpoint that matching events are sent to.
+	// It is shorthand for a single webhook action and is run before any Actions.
+	ActionURL  string `json:"action_url,omitempty" yaml:"action_url,omitempty"`
+	RetryCount int    `json:"retry_count,omitempty" yaml:"retry_count,omitempty"` // Number of retry attempts on failure
+	Timeout    int    `json:"timeout,omitempty" yaml:"timeout,omitempty"`         // Request timeout in seconds
+	// Actions are run in order when the trigger fires
+	Actions []ActionConfig `json:"actions,omitempty" yaml:"actions,omitempty"`
+	// BodyTemplate replaces the event as the body sent to ActionURL.
+	// It is a Go template rendered against the event, e.g. {"text": {{ json .object_id }}}
+	BodyTemplate string `json:"body_template,omitempty" yaml:"body_template,omitempty"`
+	// Schedule makes the trigger fire at fixed times instead of on incoming events.
+	// Each firing is a synthetic schedule.tick event that the criteria are evaluated against.
+	Schedule *Schedule `json:"schedule,omitempty" yaml:"schedule,omitempty"`
+	// Aggregation makes the trigger fire when the events matching its criteria cross a
+	// threshold within a time window, instead of on every matching event
+	Aggregation *Aggregation `json:"aggregation,omitempty" yaml:"aggregation,omitempty"`
+	// Sequence makes the trigger fire when events for the same key match its steps in order,
+	// or when the last step does not follow in time
+	Sequence *Sequence `json:"sequence,omitempty" yaml:"sequence,omitempty"`
+	// Throttle limits how often the trigger fires. Suppressed firings are counted in its stats.
+	Throttle *Throttle `json:"throttle,omitempty" yaml:"throttle,omitempty"`
+	// Priority orders the evaluation of a namespace's triggers, higher first. Triggers of the
+	// same priority are evaluated in the order of their IDs.
+	Priority int `json:"priority,omitempty" yaml:"priority,omitempty"`
+	// StopOnMatch skips the triggers after this one when it matches an event
+	StopOnMatch bool `json:"stop_on_match,omitempty" yaml:"stop_on_match,omitempty"`
+	// ActiveFrom and ActiveUntil limit when the trigger is evaluated, as RFC 3339 times.
+	// A trigger is deleted from etcd once its ActiveUntil has passed.
+	ActiveFrom  string `json:"active_from,omitempty" yaml:"active_from,omitempty"`
+	ActiveUntil string `json:"active_until,omitempty" yaml:"active_until,omitempty"`
+	// ActiveWindows limit the trigger to recurring times, e.g. business hours. The trigger is
+	// evaluated within any of its windows, and at any time without windows.
+	ActiveWindows []ActiveWindow `json:"active_windows,omitempty" yaml:"active_windows,omitempty"`
+	// ActivePeriod is the parsed ActiveFrom, ActiveUntil and ActiveWindows. It is set when
+	// the trigger is loaded or validated, so that they are not parsed on every event.
+	ActivePeriod ActivePeriod `json:"-" yaml:"-"`
+}
+
+// ActivePeriod is the parsed active time of a trigger
+type ActivePeriod interface {
+	// Contains reports whether the trigger is evaluated at a time
+	Contains(at time.Time) bool
+	// Until returns when the trigger stops being evaluated, or the zero time if it does not
+	Until() time.Time
+}
+
+// ActiveWindow is a recurring time in which a trigger is evaluated.
+// Example: weekdays from 9 to 17 in Berlin is
+// {Days: ["mon-fri"], Start: "09:00", End: "17:00", Timezone: "Europe/Berlin"}.
+type ActiveWindow struct {
+	// Days are days of the week such as mon or sunday, or ranges such as mon-fri.
+	// The window recurs every day without days.
+	Days []string `json:"days,omitempty" yaml:"days,omitempty"`
+	// Start and End are times of day as HH:MM, defaulting to 00:00 and 24:00. End is
+	// exclusive. A window whose End is not after its Start ends on the next day.
+	Start string `json:"start,omitempty" yaml:"start,omitempty"`
+	End   string `json:"end,omitempty" yaml:"end,omitempty"`
+	// Timezone is the IANA time zone of the window, defaults to UTC
+	Timezone string `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+}
+
+// Throttle limits how often a trigger fires, across all triggerd instances.
+// Example: at most 10 webhooks per minute, one per customer per hour, and none within 5s of
+// the last is {MaxFires: 10, Interval: "1m", DedupKey: "event.payload.after.customer_id",
+// DedupWindow: "1h", Cooldown: "5s"}.
+type Throttle struct {
+	// MaxFires is how often the trigger may fire per Interval, in fixed windows aligned to it
+	MaxFires int    `json:"max_fires,omitempty" yaml:"max_fires,omitempty"`
+	Interval string `json:"interval,omitempty" yaml:"interval,omitempty"`
+	// DedupKey is an expression over `event`. The trigger fires at most once per value
+	// within DedupWindow.
+	DedupKey    string `json:"dedup_key,omitempty" yaml:"dedup_key,omitempty"`
+	DedupWindow string `json:"dedup_window,omitempty" yaml:"dedup_window,omitempty"`
+	// Cooldown is how long the trigger does not fire after it fired, e.g. 30s
+	Cooldown string `json:"cooldown,omitempty" yaml:"cooldown,omitempty"`
+}
+
+// ScheduleTickEventType is the event type of the synthetic events that fire scheduled triggers
+const ScheduleTickEventType = "schedule.tick"
+
+// AggregationThresholdEventType is the event type of the synthetic events that fire
+// aggregation triggers
+const AggregationThresholdEventType = "aggregation.threshold"
+
+// Aggregation describes a threshold over the events matching a trigger within a time window.
+// Example: more than 5 payment.failed events for the same customer within 10 minutes is
+// {GroupBy: "event.payload.after.customer_id", Window: "10m", Operator: ">", Threshold: 5}.
+type Aggregation struct {
+	// GroupBy is an expression over `event` whose value keeps a separate window per group.
+	// All events share one window when it is empty.
+	GroupBy string `json:"group_by,omitempty" yaml:"group_by,omitempty"`
+	// Function is one of count (default), sum, min or max
+	Function string `json:"function,omitempty" yaml:"function,omitempty"`
+	// Field is an expression over `event` with the number that sum, min and max aggregate
+	Field string `json:"field,omitempty" yaml:"field,omitempty"`
+	// Window is the length of the window, e.g. 10m or 24h
+	Window string `json:"window" yaml:"window"`
+	// Mode is sliding (default), a window ending at each event, or tumbling, fixed
+	// consecutive windows aligned to the window length
+	Mode string `json:"mode,omitempty" yaml:"mode,omitempty"`
+	// Operator compares the aggregate to Threshold: >= (default), >, <= or <
+	Operator  string  `json:"operator,omitempty" yaml:"operator,omitempty"`
+	Threshold float64 `json:"threshold" yaml:"threshold"`
+}
+
+// Event types of the synthetic events that fire sequence triggers
+const (
+	// SequenceMatchedEventType fires a sequence whose steps all occurred in time
+	SequenceMatchedEventType = "sequence.matched"
+	// SequenceAbsentEventType fires an absence sequence whose last step did not occur in time
+	SequenceAbsentEventType = "sequence.absent"
+)
+
+// Sequence describes events that must follow each other for the same correlation key.
+// Example: order.created with no order.paid for the same order within 24h is
+// {Steps: [{Name: "created", ...}, {Name: "paid", ...}], Timeout: "24h", Absent: true}.
+type Sequence struct {
+	// Steps are matched in order. Step criteria can refer to the events of earlier steps
+	// as steps.<name>, e.g. event.actor.id != steps.reset.actor.id
+	Steps []SequenceStep `json:"steps" yaml:"steps"`
+	// Key is an expression over `event` that correlates the steps, defaults to event.object_id
+	Key string `json:"key,omitempty" yaml:"key,omitempty"`
+	// Timeout is how long after the first step the sequence must complete, e.g. 5m or 24h
+	Timeout string `json:"timeout" yaml:"timeout"`
+	// Absent fires the trigger when the last step does not occur within Timeout,
+	// instead of when it does
+	Absent bool `json:"absent,omitempty" yaml:"absent,omitempty"`
+}
+
+// SequenceStep is one step of a sequence
+type SequenceStep struct {
+	// Name identifies the step in later steps' criteria
+	Name string `json:"name" yaml:"name"`
+	// Criteria is an expression over `event` and `steps` that must be true for the step to match
+	Criteria string `json:"criteria" yaml:"criteria"`
+}
+
+// Schedule describes when a scheduled trigger fires
+type Schedule struct {
+	// Cron is a five-field cron expression (minute hour day-of-month month day-of-week)
+	// or a descriptor such as @daily or @every 15m
+	Cron string `json:"cron" yaml:"cron"`
+	// Timezone is the IANA time zone the cron expression is evaluated in, defaults to UTC
+	Timezone string `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+}
+
+// ActionConfig describes an action that is run when a trigger fires.
+// Config is specific to the action type, e.g. {"url": "https://..."} for a webhook.
+type ActionConfig struct {
+	Type       string                 `json:"type" yaml:"type"` // e.g., "webhook", "nats", "grpc"
+	Config     map[string]interface{} `json:"config,omitempty" yaml:"config,omitempty"`
+	RetryCount int                    `json:"retry_count,omitempty" yaml:"retry_count,omitempty"` // Defaults to the trigger's retry_count
+	Timeout    int                    `json:"timeout,omitempty" yaml:"timeout,omitempty"`         // Timeout in seconds, defaults to the trigger's timeout
 }
 
 // ToYAML marshals the trigger to YAML
@@ -0,0 +1,187 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"event/data"
+)
+
+// Action performs the work of a trigger when it fires.
+// Execute reports failure through its error, which the dispatcher uses for
+// retries and dead-lettering. The returned attempt may carry action specific
+// details such as an HTTP status code and response body snippet.
+type Action interface {
+	Execute(ctx context.Context, trigger *data.Trigger, event *data.Event) (data.DeliveryAttempt, error)
+}
+
+// Preview is the request an action would make for an event
+type Preview struct {
+	Type    string            // Action type
+	Target  string            // URL, subject or gRPC target
+	Method  string            // HTTP or gRPC method, if any
+	Headers map[string]string // Request headers or metadata
+	Body    string            // Rendered request body
+	Error   string            // Set if the action could not be built or rendered
+}
+
+// Previewer is implemented by actions that can render their request without sending it
+type Previewer interface {
+	Preview(ctx context.Context, trigger *data.Trigger, event *data.Event) (Preview, error)
+}
+
+// Factory creates an Action from the type specific part of its configuration
+type Factory func(config map[string]interface{}) (Action, error)
+
+// Registry maps action types to the factories that build them
+type Registry struct {
+	factories map[string]Factory
+	mu        sync.RWMutex
+}
+
+// NewRegistry creates an empty action registry
+func NewRegistry() *Registry {
+	return &Registry{
+		factories: make(map[string]Factory),
+	}
+}
+
+// Register adds a factory for an action type, replacing any existing one
+func (r *Registry) Register(actionType string, factory Factory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.factories[actionType] = factory
+}
+
+// Types returns the registered action types in alphabetical order
+func (r *Registry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	types := make([]string, 0, len(r.factories))
+	for t := range r.factories {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Build creates the Action described by cfg
+func (r *Registry) Build(cfg data.ActionConfig) (Action, error) {
+	r.mu.RLock()
+	factory, ok := r.factories[cfg.Type]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown action type %q", cfg.Type)
+	}
+
+	action, err := factory(cfg.Config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s action: %w", cfg.Type, err)
+	}
+	return action, nil
+}
+
+// Validate checks that every action of the trigger can be built
+func (r *Registry) Validate(trigger *data.Trigger) error {
+	for i, cfg := range ForTrigger(trigger) {
+		if _, err := r.Build(cfg); err != nil {
+			return fmt.Errorf("action %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Preview renders every action of the trigger for the event without executing any of them.
+// Errors are reported per action so that one broken template does not hide the others.
+func (r *Registry) Preview(ctx context.Context, trigger *data.Trigger, event *data.Event) []Preview {
+	configs := ForTrigger(trigger)
+	previews := make([]Preview, 0, len(configs))
+	for _, cfg := range configs {
+		preview := Preview{Type: cfg.Type}
+
+		action, err := r.Build(cfg)
+		if err != nil {
+			preview.Error = err.Error()
+			previews = append(previews, preview)
+			continue
+		}
+
+		previewer, ok := action.(Previewer)
+		if !ok {
+			preview.Error = fmt.Sprintf("%s actions cannot be previewed", cfg.Type)
+			previews = append(previews, preview)
+			continue
+		}
+
+		rendered, err := previewer.Preview(ctx, trigger, event)
+		if err != nil {
+			preview.Error = err.Error()
+		} else {
+			rendered.Type = cfg.Type
+			preview = rendered
+		}
+		previews = append(previews, preview)
+	}
+	return previews
+}
+
+// ForTrigger returns the actions to run for a trigger.
+// A trigger's action_url and body_template are treated as a webhook action that runs first.
+func ForTrigger(trigger *data.Trigger) []data.ActionConfig {
+	if trigger.ActionURL == "" {
+		return trigger.Actions
+	}
+
+	config := map[string]interface{}{"url": trigger.ActionURL}
+	if trigger.BodyTemplate != "" {
+		config["body"] = trigger.BodyTemplate
+	}
+
+	configs := make([]data.ActionConfig, 0, len(trigger.Actions)+1)
+	configs = append(configs, data.ActionConfig{
+		Type:   WebhookType,
+		Config: config,
+	})
+	return append(configs, trigger.Actions...)
+}
+
+// permanentError marks an error that retrying will not fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the dispatcher does not retry the action
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// decodeConfig converts a generic action configuration into a typed struct
+func decodeConfig(config map[string]interface{}, v interface{}) error {
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("failed to decode config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package actions
+
+import (
+	"reflect"
+	"testing"
+
+	"event/data"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewDefaultRegistry(Dependencies{})
+
+	if got := registry.Types(); !reflect.DeepEqual(got, []string{GRPCType, NotifyType, WebhookType}) {
+		t.Errorf("Types() = %v, want [grpc notify webhook]", got)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     data.ActionConfig
+		wantErr bool
+	}{
+		{
+			name: "webhook",
+			cfg:  data.ActionConfig{Type: WebhookType, Config: map[string]interface{}{"url": "http://localhost/hook"}},
+		},
+		{
+			name: "grpc",
+			cfg:  data.ActionConfig{Type: GRPCType, Config: map[string]interface{}{"target": "localhost:50051", "method": "pkg.Service/Method"}},
+		},
+		{
+			name:    "grpc with bad method",
+			cfg:     data.ActionConfig{Type: GRPCType, Config: map[string]interface{}{"target": "localhost:50051", "method": "Method"}},
+			wantErr: true,
+		},
+		{
+			name:    "nats without connection",
+			cfg:     data.ActionConfig{Type: NATSType},
+			wantErr: true,
+		},
+		{
+			name:    "unknown type",
+			cfg:     data.ActionConfig{Type: "carrier-pigeon"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := registry.Build(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Build() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestForTrigger(t *testing.T) {
+	trigger := &data.Trigger{
+		ActionURL: "http://localhost/hook",
+		Actions: []data.ActionConfig{
+			{Type: NATSType, Config: map[string]interface{}{"subject": "alerts"}},
+		},
+	}
+
+	configs := ForTrigger(trigger)
+	if len(configs) != 2 {
+		t.Fatalf("ForTrigger() returned %d actions, want 2", len(configs))
+	}
+	if configs[0].Type != WebhookType || configs[0].Config["url"] != "http://localhost/hook" {
+		t.Errorf("ForTrigger()[0] = %+v, want webhook to action_url", configs[0])
+	}
+	if configs[1].Type != NATSType {
+		t.Errorf("ForTrigger()[1] = %+v, want nats", configs[1])
+	}
+}
+
+func TestDeriveEvent(t *testing.T) {
+	event := newTestEvent()
+	event.NatsMeta.Sequence = 42
+
+	derived := DeriveEvent(event, "order.flagged")
+	if derived.ID == event.ID || derived.ID == "" {
+		t.Errorf("derived event ID = %q, want a new ID", derived.ID)
+	}
+	if derived.EventType != "order.flagged" {
+		t.Errorf("derived EventType = %s, want order.flagged", derived.EventType)
+	}
+	if derived.ObjectID != event.ObjectID || derived.Namespace != event.Namespace {
+		t.Errorf("derived event lost object identity: %+v", derived)
+	}
+	if derived.Actor.ID != "triggerd" || derived.NatsMeta.Sequence != 0 {
+		t.Errorf("derived event actor = %+v, nats_meta = %+v", derived.Actor, derived.NatsMeta)
+	}
+	if event.EventType != "order.created" {
+		t.Errorf("original event was modified")
+	}
+}
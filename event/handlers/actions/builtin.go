@@ -0,0 +1,39 @@
+package actions
+
+import (
+	"net/http"
+
+	"event/handlers/secrets"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Dependencies holds the shared clients used by the built-in actions.
+// Actions whose dependency is nil are not registered.
+type Dependencies struct {
+	HTTPClient *http.Client
+	Secrets    secrets.Store
+	NATS       *nats.Conn
+	// NotificationURL is the base URL of the notification service used by notify actions
+	// that do not set their own url
+	NotificationURL string
+	// Jobs submits the jobs of start_job actions
+	Jobs JobSubmitter
+}
+
+// NewDefaultRegistry creates a registry with the built-in webhook, nats, grpc, notify and start_job actions
+func NewDefaultRegistry(deps Dependencies) *Registry {
+	r := NewRegistry()
+
+	r.Register(WebhookType, NewWebhookFactory(deps.HTTPClient, deps.Secrets))
+	if deps.NATS != nil {
+		r.Register(NATSType, NewNATSFactory(deps.NATS))
+	}
+	r.Register(GRPCType, NewGRPCFactory())
+	r.Register(NotifyType, NewNotifyFactory(deps.HTTPClient, deps.NotificationURL))
+	if deps.Jobs != nil {
+		r.Register(StartJobType, NewStartJobFactory(deps.Jobs))
+	}
+
+	return r
+}
@@ -0,0 +1,311 @@
+package actions
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"event/data"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// GRPCType is the action type for generic gRPC unary calls
+const GRPCType = "grpc"
+
+// GRPCConfig is the configuration of a grpc action
+type GRPCConfig struct {
+	Target   string            `json:"target"`             // host:port of the server
+	Method   string            `json:"method"`             // Full method name, e.g. "package.Service/Method"
+	TLS      bool              `json:"tls,omitempty"`      // Use TLS with the system roots instead of plaintext
+	Metadata map[string]string `json:"metadata,omitempty"` // Outgoing request metadata
+}
+
+// Compile-time check to ensure GRPCAction implements Action and Previewer
+var (
+	_ Action    = (*GRPCAction)(nil)
+	_ Previewer = (*GRPCAction)(nil)
+)
+
+// GRPCAction calls a unary gRPC method with the matched event as its request.
+// The method's request and response types are resolved through server reflection,
+// and the event's JSON fields are mapped onto the request message by name.
+type GRPCAction struct {
+	config  GRPCConfig
+	service string
+	method  string
+	pool    *grpcPool
+}
+
+// NewGRPCFactory returns a factory for grpc actions. Connections and resolved
+// method descriptors are shared between all actions built by the factory.
+func NewGRPCFactory() Factory {
+	pool := &grpcPool{
+		conns:   make(map[string]*grpc.ClientConn),
+		methods: make(map[string]protoreflect.MethodDescriptor),
+	}
+
+	return func(config map[string]interface{}) (Action, error) {
+		var cfg GRPCConfig
+		if err := decodeConfig(config, &cfg); err != nil {
+			return nil, err
+		}
+		if cfg.Target == "" {
+			return nil, fmt.Errorf("target is required")
+		}
+
+		service, method, ok := strings.Cut(strings.TrimPrefix(cfg.Method, "/"), "/")
+		if !ok || service == "" || method == "" {
+			return nil, fmt.Errorf("method must have the form package.Service/Method")
+		}
+
+		return &GRPCAction{
+			config:  cfg,
+			service: service,
+			method:  method,
+			pool:    pool,
+		}, nil
+	}
+}
+
+// Execute invokes the configured method. The gRPC status code is recorded as the attempt's status code.
+func (a *GRPCAction) Execute(ctx context.Context, trigger *data.Trigger, event *data.Event) (data.DeliveryAttempt, error) {
+	var attempt data.DeliveryAttempt
+
+	conn, err := a.pool.conn(a.config.Target, a.config.TLS)
+	if err != nil {
+		return attempt, err
+	}
+
+	md, err := a.pool.method(ctx, conn, a.config.Target, a.service, a.method)
+	if err != nil {
+		return attempt, err
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return attempt, Permanent(fmt.Errorf("failed to marshal event: %w", err))
+	}
+	req := dynamicpb.NewMessage(md.Input())
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(body, req); err != nil {
+		return attempt, Permanent(fmt.Errorf("failed to map event onto %s: %w", md.Input().FullName(), err))
+	}
+	resp := dynamicpb.NewMessage(md.Output())
+
+	if len(a.config.Metadata) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(a.config.Metadata))
+	}
+
+	err = conn.Invoke(ctx, "/"+a.service+"/"+a.method, req, resp)
+	attempt.StatusCode = int(status.Code(err))
+	if err != nil {
+		switch status.Code(err) {
+		case codes.InvalidArgument, codes.NotFound, codes.PermissionDenied, codes.Unauthenticated, codes.Unimplemented:
+			return attempt, Permanent(err)
+		}
+		return attempt, err
+	}
+
+	if out, err := protojson.Marshal(resp); err == nil {
+		if len(out) > maxResponseSnippet {
+			out = out[:maxResponseSnippet]
+		}
+		attempt.ResponseBody = string(out)
+	}
+
+	return attempt, nil
+}
+
+// Preview returns the method and metadata of the call and the event JSON the request is mapped from.
+// The request type is not resolved, so no connection to the target is made.
+func (a *GRPCAction) Preview(ctx context.Context, trigger *data.Trigger, event *data.Event) (Preview, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return Preview{}, fmt.Errorf("failed to marshal event: %w", err)
+	}
+	return Preview{
+		Target:  a.config.Target,
+		Method:  a.service + "/" + a.method,
+		Headers: a.config.Metadata,
+		Body:    string(body),
+	}, nil
+}
+
+// grpcPool caches client connections per target and method descriptors per target and method
+type grpcPool struct {
+	conns   map[string]*grpc.ClientConn
+	methods map[string]protoreflect.MethodDescriptor
+	mu      sync.Mutex
+}
+
+// conn returns a cached connection to target, creating it if needed
+func (p *grpcPool) conn(target string, useTLS bool) (*grpc.ClientConn, error) {
+	key := fmt.Sprintf("%s|%t", target, useTLS)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if conn, ok := p.conns[key]; ok {
+		return conn, nil
+	}
+
+	creds := insecure.NewCredentials()
+	if useTLS {
+		creds = credentials.NewTLS(&tls.Config{})
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client for %s: %w", target, err)
+	}
+
+	p.conns[key] = conn
+	return conn, nil
+}
+
+// method returns the descriptor of service/method on target, resolving it through server reflection
+func (p *grpcPool) method(ctx context.Context, conn *grpc.ClientConn, target, service, method string) (protoreflect.MethodDescriptor, error) {
+	key := target + "/" + service + "/" + method
+
+	p.mu.Lock()
+	md, ok := p.methods[key]
+	p.mu.Unlock()
+	if ok {
+		return md, nil
+	}
+
+	files, err := resolveService(ctx, conn, service)
+	if err != nil {
+		return nil, err
+	}
+
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, Permanent(fmt.Errorf("service %s not found: %w", service, err))
+	}
+	sd, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, Permanent(fmt.Errorf("%s is not a service", service))
+	}
+	md = sd.Methods().ByName(protoreflect.Name(method))
+	if md == nil {
+		return nil, Permanent(fmt.Errorf("method %s not found on service %s", method, service))
+	}
+	if md.IsStreamingClient() || md.IsStreamingServer() {
+		return nil, Permanent(fmt.Errorf("method %s/%s is not unary", service, method))
+	}
+
+	p.mu.Lock()
+	p.methods[key] = md
+	p.mu.Unlock()
+
+	return md, nil
+}
+
+// resolveService fetches the file descriptors that define service and all their dependencies
+func resolveService(ctx context.Context, conn *grpc.ClientConn, service string) (*protoregistry.Files, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start server reflection: %w", err)
+	}
+	defer stream.CloseSend()
+
+	fetched := make(map[string]*descriptorpb.FileDescriptorProto)
+	request := func(req *rpb.ServerReflectionRequest) error {
+		if err := stream.Send(req); err != nil {
+			return fmt.Errorf("server reflection request failed: %w", err)
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return fmt.Errorf("server reflection request failed: %w", err)
+		}
+		if errResp := resp.GetErrorResponse(); errResp != nil {
+			return Permanent(fmt.Errorf("server reflection: %s", errResp.ErrorMessage))
+		}
+		for _, raw := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			fd := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(raw, fd); err != nil {
+				return fmt.Errorf("failed to decode file descriptor: %w", err)
+			}
+			fetched[fd.GetName()] = fd
+		}
+		return nil
+	}
+
+	err = request(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: service},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch any dependencies the server did not send, falling back to the compiled-in registry
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+	var walk func(name string) error
+	walk = func(name string) error {
+		if seen[name] {
+			return nil
+		}
+		seen[name] = true
+
+		fd, ok := fetched[name]
+		if !ok {
+			if global, err := protoregistry.GlobalFiles.FindFileByPath(name); err == nil {
+				fd = protodesc.ToFileDescriptorProto(global)
+			} else {
+				err := request(&rpb.ServerReflectionRequest{
+					MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{FileByFilename: name},
+				})
+				if err != nil {
+					return err
+				}
+				if fd, ok = fetched[name]; !ok {
+					return fmt.Errorf("server reflection did not return %s", name)
+				}
+			}
+		}
+
+		for _, dep := range fd.GetDependency() {
+			if err := walk(dep); err != nil {
+				return err
+			}
+		}
+		set.File = append(set.File, fd)
+		return nil
+	}
+
+	roots := make([]string, 0, len(fetched))
+	for name := range fetched {
+		roots = append(roots, name)
+	}
+	for _, name := range roots {
+		if err := walk(name); err != nil {
+			return nil, err
+		}
+	}
+
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build descriptors for %s: %w", service, err)
+	}
+	return files, nil
+}
@@ -0,0 +1,83 @@
+package actions
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"event/data"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// startHealthServer starts a gRPC server with the health and reflection services
+func startHealthServer(t *testing.T) (string, *health.Server) {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	server := grpc.NewServer()
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	reflection.Register(server)
+
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	return lis.Addr().String(), healthServer
+}
+
+func TestGRPCAction(t *testing.T) {
+	target, healthServer := startHealthServer(t)
+	factory := NewGRPCFactory()
+
+	action, err := factory(map[string]interface{}{
+		"target": target,
+		"method": "grpc.health.v1.Health/Check",
+	})
+	if err != nil {
+		t.Fatalf("factory error = %v", err)
+	}
+
+	attempt, err := action.Execute(context.Background(), &data.Trigger{ID: "t1"}, newTestEvent())
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.Contains(attempt.ResponseBody, `"SERVING"`) {
+		t.Errorf("ResponseBody = %s, want SERVING status", attempt.ResponseBody)
+	}
+
+	// The cached method descriptor is reused for later calls
+	healthServer.Shutdown()
+	attempt, err = action.Execute(context.Background(), &data.Trigger{ID: "t1"}, newTestEvent())
+	if err != nil {
+		t.Fatalf("Execute() after shutdown error = %v", err)
+	}
+	if !strings.Contains(attempt.ResponseBody, `"NOT_SERVING"`) {
+		t.Errorf("ResponseBody = %s, want NOT_SERVING status", attempt.ResponseBody)
+	}
+}
+
+func TestGRPCAction_UnknownMethod(t *testing.T) {
+	target, _ := startHealthServer(t)
+
+	action, err := NewGRPCFactory()(map[string]interface{}{
+		"target": target,
+		"method": "grpc.health.v1.Health/Missing",
+	})
+	if err != nil {
+		t.Fatalf("factory error = %v", err)
+	}
+
+	_, err = action.Execute(context.Background(), &data.Trigger{ID: "t1"}, newTestEvent())
+	if err == nil || !IsPermanent(err) {
+		t.Errorf("Execute() error = %v, want permanent error", err)
+	}
+}
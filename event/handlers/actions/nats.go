@@ -0,0 +1,129 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"event/data"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSType is the action type for publishing a derived event to NATS
+const NATSType = "nats"
+
+// CausationHeader is the NATS header naming the trigger, as namespace/id, that published a
+// derived event. triggerd does not evaluate such an event against that trigger again.
+const CausationHeader = "Triggerd-Caused-By"
+
+// Publisher publishes messages to NATS; *nats.Conn implements it
+type Publisher interface {
+	PublishMsg(msg *nats.Msg) error
+	FlushWithContext(ctx context.Context) error
+}
+
+// NATSConfig is the configuration of a nats action. At least one of Subject and EventType
+// must be set, as the derived event would otherwise be the matched event republished.
+type NATSConfig struct {
+	// Subject defaults to event.<namespace>.<object_type>.<event_type> of the derived event
+	Subject string `json:"subject,omitempty"`
+	// EventType of the derived event, defaults to the matched event's type
+	EventType string `json:"event_type,omitempty"`
+}
+
+// Compile-time check to ensure NATSAction implements Action and Previewer
+var (
+	_ Action    = (*NATSAction)(nil)
+	_ Previewer = (*NATSAction)(nil)
+)
+
+// NATSAction publishes an event derived from the matched event to a NATS subject
+type NATSAction struct {
+	config NATSConfig
+	conn   Publisher
+}
+
+// NewNATSFactory returns a factory for nats actions publishing on conn
+func NewNATSFactory(conn Publisher) Factory {
+	return func(config map[string]interface{}) (Action, error) {
+		var cfg NATSConfig
+		if err := decodeConfig(config, &cfg); err != nil {
+			return nil, err
+		}
+		if cfg.Subject == "" && cfg.EventType == "" {
+			return nil, fmt.Errorf("subject or event_type is required")
+		}
+
+		return &NATSAction{
+			config: cfg,
+			conn:   conn,
+		}, nil
+	}
+}
+
+// Execute publishes the derived event and waits for the server to acknowledge it
+func (a *NATSAction) Execute(ctx context.Context, trigger *data.Trigger, event *data.Event) (data.DeliveryAttempt, error) {
+	var attempt data.DeliveryAttempt
+
+	msg, err := a.message(trigger, event)
+	if err != nil {
+		return attempt, Permanent(err)
+	}
+
+	if err := a.conn.PublishMsg(msg); err != nil {
+		return attempt, fmt.Errorf("failed to publish to %s: %w", msg.Subject, err)
+	}
+	if err := a.conn.FlushWithContext(ctx); err != nil {
+		return attempt, fmt.Errorf("failed to flush NATS connection: %w", err)
+	}
+
+	return attempt, nil
+}
+
+// Preview renders the derived event and the subject it would be published to
+func (a *NATSAction) Preview(ctx context.Context, trigger *data.Trigger, event *data.Event) (Preview, error) {
+	msg, err := a.message(trigger, event)
+	if err != nil {
+		return Preview{}, err
+	}
+	return Preview{
+		Target:  msg.Subject,
+		Headers: map[string]string{CausationHeader: msg.Header.Get(CausationHeader)},
+		Body:    string(msg.Data),
+	}, nil
+}
+
+// message returns the message carrying the derived event, marked as caused by the trigger
+func (a *NATSAction) message(trigger *data.Trigger, event *data.Event) (*nats.Msg, error) {
+	derived := DeriveEvent(event, a.config.EventType)
+	body, err := json.Marshal(derived)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	subject := a.config.Subject
+	if subject == "" {
+		subject = fmt.Sprintf("event.%s.%s.%s", derived.Namespace, derived.ObjectType, derived.EventType)
+	}
+	msg := nats.NewMsg(subject)
+	msg.Data = body
+	msg.Header.Set(CausationHeader, trigger.Namespace+"/"+trigger.ID)
+	return msg, nil
+}
+
+// DeriveEvent returns a copy of event with a new ID and timestamp, published by triggerd.
+// If eventType is empty the original event type is kept.
+func DeriveEvent(event *data.Event, eventType string) *data.Event {
+	derived := *event
+	derived.ID = data.NewEventID()
+	derived.Timestamp = time.Now().UTC()
+	derived.Actor.Type = "system"
+	derived.Actor.ID = "triggerd"
+	derived.NatsMeta = data.Event{}.NatsMeta
+	if eventType != "" {
+		derived.EventType = eventType
+	}
+	return &derived
+}
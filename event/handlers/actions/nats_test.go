@@ -0,0 +1,59 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"event/data"
+
+	"github.com/nats-io/nats.go"
+)
+
+// recordingPublisher keeps the messages published to it
+type recordingPublisher struct {
+	msgs []*nats.Msg
+}
+
+func (p *recordingPublisher) PublishMsg(msg *nats.Msg) error {
+	p.msgs = append(p.msgs, msg)
+	return nil
+}
+
+func (p *recordingPublisher) FlushWithContext(ctx context.Context) error {
+	return nil
+}
+
+func TestNATSAction(t *testing.T) {
+	publisher := &recordingPublisher{}
+	factory := NewNATSFactory(publisher)
+
+	// Without a subject or event type the action would republish the matched event
+	if _, err := factory(map[string]interface{}{}); err == nil {
+		t.Error("factory() with neither subject nor event_type succeeded, want an error")
+	}
+
+	action, err := factory(map[string]interface{}{"event_type": "order.flagged"})
+	if err != nil {
+		t.Fatalf("factory() error = %v", err)
+	}
+	trigger := &data.Trigger{ID: "t1", Namespace: "sales"}
+	if _, err := action.Execute(context.Background(), trigger, newTestEvent()); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if len(publisher.msgs) != 1 {
+		t.Fatalf("published %d messages, want 1", len(publisher.msgs))
+	}
+	msg := publisher.msgs[0]
+	if msg.Subject != "event.sales.order.order.flagged" {
+		t.Errorf("subject = %s, want event.sales.order.order.flagged", msg.Subject)
+	}
+	if got := msg.Header.Get(CausationHeader); got != "sales/t1" {
+		t.Errorf("%s = %q, want sales/t1", CausationHeader, got)
+	}
+	var derived data.Event
+	if err := json.Unmarshal(msg.Data, &derived); err != nil || derived.EventType != "order.flagged" {
+		t.Errorf("published event = %+v, %v, want an order.flagged event", derived, err)
+	}
+}
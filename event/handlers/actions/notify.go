@@ -0,0 +1,279 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"event/data"
+)
+
+// NotifyType is the action type for creating in-app notifications in the notification service
+const NotifyType = "notify"
+
+// NotifyConfig is the configuration of a notify action.
+// Every string field except URL is a template rendered against the matched event.
+type NotifyConfig struct {
+	URL        string            `json:"url,omitempty"` // Base URL of the notification service, defaults to Dependencies.NotificationURL
+	Title      string            `json:"title"`
+	Message    string            `json:"message,omitempty"`
+	Recipients []NotifyRecipient `json:"recipients"`
+	Priority   string            `json:"priority,omitempty"` // Defaults to "normal"
+	Labels     []string          `json:"labels,omitempty"`
+	GroupID    string            `json:"group_id,omitempty"`
+	AppName    string            `json:"app_name,omitempty"`
+}
+
+// NotifyRecipient is a recipient template; recipients whose ID renders empty are skipped
+type NotifyRecipient struct {
+	Type string `json:"type"` // e.g., "user", "group"
+	ID   string `json:"id"`
+}
+
+// notification is the request body of POST /api/notifications.
+// It mirrors notification/types.Notification.
+type notification struct {
+	Timestamp  time.Time         `json:"timestamp"`
+	Title      string            `json:"title"`
+	Message    string            `json:"message"`
+	Priority   string            `json:"priority"`
+	Recipients []NotifyRecipient `json:"recipients"`
+	Labels     []string          `json:"labels,omitempty"`
+	AppName    string            `json:"appName,omitempty"`
+	GroupID    string            `json:"groupId,omitempty"`
+}
+
+// notifyTemplates holds the parsed templates of a notify action
+type notifyTemplates struct {
+	title      *template.Template
+	message    *template.Template
+	priority   *template.Template
+	groupID    *template.Template
+	appName    *template.Template
+	labels     []*template.Template
+	recipients []struct{ typ, id *template.Template }
+}
+
+// Compile-time check to ensure NotifyAction implements Action and Previewer
+var (
+	_ Action    = (*NotifyAction)(nil)
+	_ Previewer = (*NotifyAction)(nil)
+)
+
+// NotifyAction creates an in-app notification for the matched event
+type NotifyAction struct {
+	url        string
+	templates  notifyTemplates
+	httpClient *http.Client
+}
+
+// NewNotifyFactory returns a factory for notify actions. defaultURL is used when an
+// action does not set its own url. If httpClient is nil, http.DefaultClient is used.
+func NewNotifyFactory(httpClient *http.Client, defaultURL string) Factory {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return func(config map[string]interface{}) (Action, error) {
+		var cfg NotifyConfig
+		if err := decodeConfig(config, &cfg); err != nil {
+			return nil, err
+		}
+		if cfg.URL == "" {
+			cfg.URL = defaultURL
+		}
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("url is required")
+		}
+		if cfg.Title == "" {
+			return nil, fmt.Errorf("title is required")
+		}
+		if len(cfg.Recipients) == 0 {
+			return nil, fmt.Errorf("at least one recipient is required")
+		}
+		if cfg.Priority == "" {
+			cfg.Priority = "normal"
+		}
+
+		templates, err := parseNotifyTemplates(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		return &NotifyAction{
+			url:        strings.TrimSuffix(cfg.URL, "/"),
+			templates:  templates,
+			httpClient: httpClient,
+		}, nil
+	}
+}
+
+// Execute renders the notification and posts it to the notification service
+func (a *NotifyAction) Execute(ctx context.Context, trigger *data.Trigger, event *data.Event) (data.DeliveryAttempt, error) {
+	var attempt data.DeliveryAttempt
+
+	n, err := a.render(event)
+	if err != nil {
+		return attempt, Permanent(err)
+	}
+	if len(n.Recipients) == 0 {
+		return attempt, Permanent(fmt.Errorf("no recipients for event %s", event.ID))
+	}
+
+	body, err := json.Marshal(n)
+	if err != nil {
+		return attempt, Permanent(fmt.Errorf("failed to marshal notification: %w", err))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url+"/api/notifications", bytes.NewReader(body))
+	if err != nil {
+		return attempt, Permanent(fmt.Errorf("failed to create request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return attempt, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSnippet))
+	attempt.StatusCode = resp.StatusCode
+	attempt.ResponseBody = string(snippet)
+
+	switch {
+	case resp.StatusCode == http.StatusCreated:
+		return attempt, nil
+	case resp.StatusCode == http.StatusBadRequest:
+		return attempt, Permanent(fmt.Errorf("notification rejected: %s", snippet))
+	default:
+		return attempt, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+}
+
+// Preview renders the notification that would be created for the event
+func (a *NotifyAction) Preview(ctx context.Context, trigger *data.Trigger, event *data.Event) (Preview, error) {
+	n, err := a.render(event)
+	if err != nil {
+		return Preview{}, err
+	}
+	body, err := json.Marshal(n)
+	if err != nil {
+		return Preview{}, fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	return Preview{
+		Target:  a.url + "/api/notifications",
+		Method:  http.MethodPost,
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    string(body),
+	}, nil
+}
+
+// render builds the notification for an event from the action's templates
+func (a *NotifyAction) render(event *data.Event) (*notification, error) {
+	t := a.templates
+	n := &notification{Timestamp: time.Now()}
+
+	fields := []struct {
+		tmpl *template.Template
+		dst  *string
+	}{
+		{t.title, &n.Title},
+		{t.message, &n.Message},
+		{t.priority, &n.Priority},
+		{t.groupID, &n.GroupID},
+		{t.appName, &n.AppName},
+	}
+	for _, f := range fields {
+		out, err := renderTemplate(f.tmpl, event)
+		if err != nil {
+			return nil, err
+		}
+		*f.dst = strings.TrimSpace(out)
+	}
+
+	for _, tmpl := range t.labels {
+		label, err := renderTemplate(tmpl, event)
+		if err != nil {
+			return nil, err
+		}
+		if label = strings.TrimSpace(label); label != "" {
+			n.Labels = append(n.Labels, label)
+		}
+	}
+
+	for _, r := range t.recipients {
+		typ, err := renderTemplate(r.typ, event)
+		if err != nil {
+			return nil, err
+		}
+		id, err := renderTemplate(r.id, event)
+		if err != nil {
+			return nil, err
+		}
+		if id = strings.TrimSpace(id); id != "" {
+			n.Recipients = append(n.Recipients, NotifyRecipient{Type: strings.TrimSpace(typ), ID: id})
+		}
+	}
+
+	return n, nil
+}
+
+// parseNotifyTemplates parses every template of a notify configuration
+func parseNotifyTemplates(cfg NotifyConfig) (notifyTemplates, error) {
+	var (
+		t   notifyTemplates
+		err error
+	)
+
+	fields := []struct {
+		name string
+		text string
+		dst  **template.Template
+	}{
+		{"title", cfg.Title, &t.title},
+		{"message", cfg.Message, &t.message},
+		{"priority", cfg.Priority, &t.priority},
+		{"group_id", cfg.GroupID, &t.groupID},
+		{"app_name", cfg.AppName, &t.appName},
+	}
+	for _, f := range fields {
+		if *f.dst, err = parseTemplate(f.name, f.text); err != nil {
+			return t, err
+		}
+	}
+
+	for i, label := range cfg.Labels {
+		tmpl, err := parseTemplate(fmt.Sprintf("labels[%d]", i), label)
+		if err != nil {
+			return t, err
+		}
+		t.labels = append(t.labels, tmpl)
+	}
+
+	for i, r := range cfg.Recipients {
+		if r.ID == "" {
+			return t, fmt.Errorf("recipient %d has no id", i)
+		}
+		if r.Type == "" {
+			r.Type = "user"
+		}
+		typ, err := parseTemplate(fmt.Sprintf("recipients[%d].type", i), r.Type)
+		if err != nil {
+			return t, err
+		}
+		id, err := parseTemplate(fmt.Sprintf("recipients[%d].id", i), r.ID)
+		if err != nil {
+			return t, err
+		}
+		t.recipients = append(t.recipients, struct{ typ, id *template.Template }{typ, id})
+	}
+
+	return t, nil
+}
@@ -0,0 +1,154 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// newNotificationServer returns a stand-in for the notification service that records created notifications
+func newNotificationServer(t *testing.T, status int) (*httptest.Server, *[]notification) {
+	t.Helper()
+
+	var received []notification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/notifications" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var n notification
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received = append(received, n)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &received
+}
+
+func TestNotifyAction_RendersTemplates(t *testing.T) {
+	server, received := newNotificationServer(t, http.StatusCreated)
+
+	action, err := NewNotifyFactory(nil, server.URL)(map[string]interface{}{
+		"title":    "Order {{ .object_id }} created",
+		"message":  "Amount: {{ .payload.after.amount }}",
+		"priority": "{{ if gt .payload.after.amount 1000.0 }}high{{ else }}normal{{ end }}",
+		"labels":   []interface{}{"sales", "{{ .event_type }}", "{{ .payload.after.missing }}"},
+		"group_id": "order-{{ .object_id }}",
+		"recipients": []interface{}{
+			map[string]interface{}{"id": "{{ .payload.after.owner }}"},
+			map[string]interface{}{"type": "group", "id": "{{ .payload.after.team }}"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("factory error = %v", err)
+	}
+
+	event := newTestEvent()
+	event.Payload.After["amount"] = 1500.0
+	event.Payload.After["owner"] = "alice"
+	if _, err := action.Execute(context.Background(), nil, event); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if len(*received) != 1 {
+		t.Fatalf("received %d notifications, want 1", len(*received))
+	}
+	n := (*received)[0]
+	if n.Title != "Order o1 created" || n.Message != "Amount: 1500" {
+		t.Errorf("title/message = %q/%q", n.Title, n.Message)
+	}
+	if n.Priority != "high" {
+		t.Errorf("priority = %q, want high", n.Priority)
+	}
+	if !reflect.DeepEqual(n.Labels, []string{"sales", "order.created"}) {
+		t.Errorf("labels = %v, want [sales order.created]", n.Labels)
+	}
+	if n.GroupID != "order-o1" {
+		t.Errorf("groupId = %q, want order-o1", n.GroupID)
+	}
+	// The group recipient renders empty and is skipped
+	if !reflect.DeepEqual(n.Recipients, []NotifyRecipient{{Type: "user", ID: "alice"}}) {
+		t.Errorf("recipients = %+v, want [user alice]", n.Recipients)
+	}
+}
+
+func TestNotifyAction_Errors(t *testing.T) {
+	config := func(url string) map[string]interface{} {
+		return map[string]interface{}{
+			"url":        url,
+			"title":      "Order {{ .object_id }}",
+			"recipients": []interface{}{map[string]interface{}{"id": "{{ .payload.after.owner }}"}},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		status        int
+		owner         interface{}
+		wantPermanent bool
+	}{
+		{name: "created", status: http.StatusCreated, owner: "alice"},
+		{name: "rejected", status: http.StatusBadRequest, owner: "alice", wantPermanent: true},
+		{name: "unavailable", status: http.StatusServiceUnavailable, owner: "alice"},
+		{name: "no recipients", status: http.StatusCreated, wantPermanent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newNotificationServer(t, tt.status)
+			action, err := NewNotifyFactory(nil, "")(config(server.URL))
+			if err != nil {
+				t.Fatalf("factory error = %v", err)
+			}
+
+			event := newTestEvent()
+			if tt.owner != nil {
+				event.Payload.After["owner"] = tt.owner
+			}
+			attempt, err := action.Execute(context.Background(), nil, event)
+			if tt.status == http.StatusCreated && tt.owner != nil {
+				if err != nil {
+					t.Fatalf("Execute() error = %v", err)
+				}
+				if attempt.StatusCode != http.StatusCreated {
+					t.Errorf("StatusCode = %d, want 201", attempt.StatusCode)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Execute() expected error, got nil")
+			}
+			if IsPermanent(err) != tt.wantPermanent {
+				t.Errorf("IsPermanent() = %v, want %v", IsPermanent(err), tt.wantPermanent)
+			}
+		})
+	}
+}
+
+func TestNotifyFactory_Validation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+	}{
+		{name: "no url", config: map[string]interface{}{"title": "t", "recipients": []interface{}{map[string]interface{}{"id": "u"}}}},
+		{name: "no title", config: map[string]interface{}{"url": "http://n", "recipients": []interface{}{map[string]interface{}{"id": "u"}}}},
+		{name: "no recipients", config: map[string]interface{}{"url": "http://n", "title": "t"}},
+		{name: "bad template", config: map[string]interface{}{"url": "http://n", "title": "{{ .object_id", "recipients": []interface{}{map[string]interface{}{"id": "u"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewNotifyFactory(nil, "")(tt.config); err == nil {
+				t.Error("factory expected error, got nil")
+			}
+		})
+	}
+}
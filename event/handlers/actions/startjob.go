@@ -0,0 +1,168 @@
+package actions
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"event/data"
+	"event/handlers/jobs"
+	"event/handlers/triggers"
+)
+
+// StartJobType is the action type for starting a job from a matched event
+const StartJobType = "start_job"
+
+// JobSubmitter submits jobs. It is implemented by *jobs.Orchestrator.
+type JobSubmitter interface {
+	Submit(ctx context.Context, job *data.Job) (*data.Job, error)
+}
+
+// StartJobConfig is the configuration of a start_job action
+type StartJobConfig struct {
+	JobType string `json:"job_type"`
+	// Inputs maps each input name to an expression over `event`, as used in trigger criteria
+	Inputs     map[string]string `json:"inputs,omitempty"`
+	MaxRetries int               `json:"max_retries,omitempty"`
+	// Dedupe is an expression over `event`. Events for which it evaluates to the same value
+	// start the job only once per namespace and job type.
+	Dedupe string `json:"dedupe,omitempty"`
+}
+
+// Compile-time check to ensure StartJobAction implements Action and Previewer
+var (
+	_ Action    = (*StartJobAction)(nil)
+	_ Previewer = (*StartJobAction)(nil)
+)
+
+// StartJobAction creates a job whose inputs are taken from the matched event
+type StartJobAction struct {
+	config    StartJobConfig
+	submitter JobSubmitter
+}
+
+// NewStartJobFactory returns a factory for start_job actions submitting to submitter
+func NewStartJobFactory(submitter JobSubmitter) Factory {
+	return func(config map[string]interface{}) (Action, error) {
+		var cfg StartJobConfig
+		if err := decodeConfig(config, &cfg); err != nil {
+			return nil, err
+		}
+		if cfg.JobType == "" {
+			return nil, fmt.Errorf("job_type is required")
+		}
+		if cfg.MaxRetries < 0 {
+			return nil, fmt.Errorf("max_retries must not be negative")
+		}
+
+		// Compile every expression up front so broken triggers are rejected when saved
+		for name, expression := range cfg.Inputs {
+			if err := triggers.CompileExpression(expression); err != nil {
+				return nil, fmt.Errorf("input %q: %w", name, err)
+			}
+		}
+		if cfg.Dedupe != "" {
+			if err := triggers.CompileExpression(cfg.Dedupe); err != nil {
+				return nil, fmt.Errorf("dedupe: %w", err)
+			}
+		}
+
+		return &StartJobAction{
+			config:    cfg,
+			submitter: submitter,
+		}, nil
+	}
+}
+
+// Execute submits the job. A job that was already started for the same dedupe key is not
+// submitted again, and the action succeeds.
+func (a *StartJobAction) Execute(ctx context.Context, trigger *data.Trigger, event *data.Event) (data.DeliveryAttempt, error) {
+	var attempt data.DeliveryAttempt
+
+	job, err := a.job(event)
+	if err != nil {
+		return attempt, Permanent(err)
+	}
+
+	submitted, err := a.submitter.Submit(ctx, job)
+	switch {
+	case errors.Is(err, jobs.ErrExists) && a.config.Dedupe != "":
+		attempt.ResponseBody = fmt.Sprintf("duplicate of job %s", job.JobID)
+		return attempt, nil
+	case errors.Is(err, jobs.ErrInvalidJob):
+		return attempt, Permanent(err)
+	case err != nil:
+		return attempt, fmt.Errorf("failed to submit %s job: %w", job.JobType, err)
+	}
+
+	attempt.ResponseBody = fmt.Sprintf("job %s %s", submitted.JobID, submitted.Status)
+	return attempt, nil
+}
+
+// Preview renders the job that would be submitted
+func (a *StartJobAction) Preview(ctx context.Context, trigger *data.Trigger, event *data.Event) (Preview, error) {
+	job, err := a.job(event)
+	if err != nil {
+		return Preview{}, err
+	}
+
+	body, err := json.Marshal(job)
+	if err != nil {
+		return Preview{}, fmt.Errorf("failed to marshal job: %w", err)
+	}
+	return Preview{Target: job.JobType, Body: string(body)}, nil
+}
+
+// job builds the job for an event, evaluating its inputs and dedupe key
+func (a *StartJobAction) job(event *data.Event) (*data.Job, error) {
+	inputs := make(map[string]interface{}, len(a.config.Inputs))
+	for name, expression := range a.config.Inputs {
+		value, err := triggers.EvaluateExpression(expression, event)
+		if err != nil {
+			return nil, fmt.Errorf("input %q: %w", name, err)
+		}
+		inputs[name] = value
+	}
+
+	job := &data.Job{
+		JobType:     a.config.JobType,
+		Namespace:   event.Namespace,
+		Inputs:      inputs,
+		MaxRetries:  a.config.MaxRetries,
+		TriggeredBy: event.ID,
+	}
+
+	if a.config.Dedupe != "" {
+		key, err := triggers.EvaluateExpression(a.config.Dedupe, event)
+		if err != nil {
+			return nil, fmt.Errorf("dedupe: %w", err)
+		}
+		id, err := dedupeJobID(job.Namespace, job.JobType, key)
+		if err != nil {
+			return nil, err
+		}
+		job.JobID = id
+	}
+
+	return job, nil
+}
+
+// dedupeJobID derives a stable job ID from the namespace, job type and dedupe key,
+// so that the store rejects a second job for the same key. Map keys are encoded in sorted order.
+func dedupeJobID(namespace, jobType string, key interface{}) (string, error) {
+	raw, err := json.Marshal(key)
+	if err != nil {
+		return "", fmt.Errorf("dedupe: failed to encode key: %w", err)
+	}
+
+	h := sha256.New()
+	h.Write([]byte(namespace))
+	h.Write([]byte{0})
+	h.Write([]byte(jobType))
+	h.Write([]byte{0})
+	h.Write(raw)
+	return "job_" + hex.EncodeToString(h.Sum(nil))[:24], nil
+}
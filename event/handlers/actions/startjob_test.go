@@ -0,0 +1,95 @@
+package actions
+
+import (
+	"context"
+	"testing"
+
+	"event/data"
+	"event/handlers/jobs"
+)
+
+func TestStartJobAction_MapsEvent(t *testing.T) {
+	ctx := context.Background()
+	orchestrator := jobs.NewOrchestrator(jobs.NewMemoryStore(), nil)
+
+	action, err := NewStartJobFactory(orchestrator)(map[string]interface{}{
+		"job_type": "invoice",
+		"inputs": map[string]interface{}{
+			"order_id": "event.object_id",
+			"amount":   "event.payload.after.amount * 2",
+		},
+	})
+	if err != nil {
+		t.Fatalf("factory error = %v", err)
+	}
+
+	if _, err := action.Execute(ctx, nil, newTestEvent()); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	list, err := orchestrator.Store().List(ctx, jobs.Filter{Namespace: "sales", TriggeredBy: "evt1"})
+	if err != nil || len(list) != 1 {
+		t.Fatalf("List() = %v, %v, want one job", list, err)
+	}
+	job := list[0]
+	if job.JobType != "invoice" || job.Status != data.JobStatusStarted {
+		t.Errorf("job = %s %s, want started invoice", job.JobType, job.Status)
+	}
+	if job.Inputs["order_id"] != "o1" || job.Inputs["amount"] != 3000 {
+		t.Errorf("inputs = %v, want order_id o1 and amount 3000", job.Inputs)
+	}
+}
+
+func TestStartJobAction_Dedupe(t *testing.T) {
+	ctx := context.Background()
+	orchestrator := jobs.NewOrchestrator(jobs.NewMemoryStore(), nil)
+
+	action, err := NewStartJobFactory(orchestrator)(map[string]interface{}{
+		"job_type": "invoice",
+		"dedupe":   "event.object_id",
+	})
+	if err != nil {
+		t.Fatalf("factory error = %v", err)
+	}
+
+	first := newTestEvent()
+	second := newTestEvent()
+	second.ID = "evt2"
+	other := newTestEvent()
+	other.ID = "evt3"
+	other.ObjectID = "o2"
+
+	for _, event := range []*data.Event{first, second, other} {
+		if _, err := action.Execute(ctx, nil, event); err != nil {
+			t.Fatalf("Execute(%s) error = %v", event.ID, err)
+		}
+	}
+
+	list, _ := orchestrator.Store().List(ctx, jobs.Filter{Namespace: "sales"})
+	if len(list) != 2 {
+		t.Fatalf("jobs = %d, want 2 (one per object)", len(list))
+	}
+	if list[0].TriggeredBy == "evt2" || list[1].TriggeredBy == "evt2" {
+		t.Errorf("duplicate event evt2 started a job")
+	}
+}
+
+func TestStartJobFactory_Validation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+	}{
+		{"missing job type", map[string]interface{}{}},
+		{"bad input", map[string]interface{}{"job_type": "a", "inputs": map[string]interface{}{"x": "event.("}}},
+		{"bad dedupe", map[string]interface{}{"job_type": "a", "dedupe": "event.)"}},
+		{"negative retries", map[string]interface{}{"job_type": "a", "max_retries": -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewStartJobFactory(nil)(tt.config); err == nil {
+				t.Error("factory error = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package actions
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"event/data"
+)
+
+// noValue is what text/template prints for missing map keys
+const noValue = "<no value>"
+
+// templateFuncs are the helpers available to every action template
+var templateFuncs = template.FuncMap{
+	"json":       toJSON,
+	"default":    defaultValue,
+	"field":      field,
+	"formatTime": formatTime,
+	"now":        func() time.Time { return time.Now().UTC() },
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"trim":       strings.TrimSpace,
+}
+
+// parseTemplate parses an action template. Templates are rendered against the
+// event's map representation, e.g. {{ .payload.after.amount }} or {{ .object_id }}.
+func parseTemplate(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Option("missingkey=zero").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	return t, nil
+}
+
+// renderTemplate renders t against the event. Missing fields render as empty strings.
+func renderTemplate(t *template.Template, event *data.Event) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, event.ToMap()); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", t.Name(), err)
+	}
+	return strings.ReplaceAll(buf.String(), noValue, ""), nil
+}
+
+// toJSON encodes v as JSON, e.g. {{ json .payload.after }} or {{ json .object_id }} for a quoted string
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// defaultValue returns value, or def if value is missing or empty: {{ default "n/a" .payload.after.note }}
+func defaultValue(def, value interface{}) interface{} {
+	if isEmpty(value) {
+		return def
+	}
+	return value
+}
+
+// isEmpty reports whether v is nil, or the zero value or an empty collection
+func isEmpty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.String:
+		return rv.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return rv.IsNil()
+	}
+	return rv.IsZero()
+}
+
+// field looks up a dotted path such as "payload.after.amount" in the template data.
+// It returns nil if any part of the path is missing: {{ field . "payload.before.status" }}
+func field(root interface{}, path string) interface{} {
+	current := root
+	for _, key := range strings.Split(path, ".") {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			current = v[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil
+			}
+			current = v[i]
+		default:
+			return nil
+		}
+	}
+	return current
+}
+
+// formatTime formats a time.Time, an RFC 3339 string or Unix seconds with a Go layout.
+// The layout may also be one of "rfc3339", "date", "time" or "unix".
+func formatTime(layout string, value interface{}) (string, error) {
+	var t time.Time
+	switch v := value.(type) {
+	case time.Time:
+		t = v
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return "", fmt.Errorf("formatTime: %w", err)
+		}
+		t = parsed
+	case float64:
+		t = time.Unix(int64(v), 0).UTC()
+	case int64:
+		t = time.Unix(v, 0).UTC()
+	case int:
+		t = time.Unix(int64(v), 0).UTC()
+	case nil:
+		return "", nil
+	default:
+		return "", fmt.Errorf("formatTime: unsupported value of type %T", value)
+	}
+
+	switch layout {
+	case "rfc3339":
+		layout = time.RFC3339
+	case "date":
+		layout = time.DateOnly
+	case "time":
+		layout = time.TimeOnly
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10), nil
+	}
+	return t.Format(layout), nil
+}
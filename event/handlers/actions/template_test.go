@@ -0,0 +1,152 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"event/data"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	event := newTestEvent()
+	event.Timestamp = time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	event.Payload.After["customer"] = map[string]interface{}{"name": "Ada"}
+	event.Payload.After["items"] = []interface{}{"book", "pen"}
+
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{name: "field access", text: "{{ .payload.after.amount }}", want: "1500"},
+		{name: "missing field", text: "[{{ .payload.after.missing }}]", want: "[]"},
+		{name: "json string", text: "{{ json .object_id }}", want: `"o1"`},
+		{name: "json object", text: "{{ json .payload.after.customer }}", want: `{"name":"Ada"}`},
+		{name: "default used", text: `{{ default "n/a" .payload.after.note }}`, want: "n/a"},
+		{name: "default not used", text: `{{ default "n/a" .object_id }}`, want: "o1"},
+		{name: "field helper", text: `{{ field . "payload.after.customer.name" }}`, want: "Ada"},
+		{name: "field helper index", text: `{{ field . "payload.after.items.1" }}`, want: "pen"},
+		{name: "field helper missing", text: `{{ default "none" (field . "payload.before.status") }}`, want: "none"},
+		{name: "format time", text: `{{ formatTime "2006-01-02 15:04" .timestamp }}`, want: "2024-03-01 12:30"},
+		{name: "format time alias", text: `{{ formatTime "date" .timestamp }}`, want: "2024-03-01"},
+		{name: "format unix", text: `{{ formatTime "unix" .timestamp }}`, want: "1709296200"},
+		{name: "upper", text: `{{ upper .namespace }}`, want: "SALES"},
+		{name: "bad time", text: `{{ formatTime "date" .object_id }}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := parseTemplate("test", tt.text)
+			if err != nil {
+				t.Fatalf("parseTemplate() error = %v", err)
+			}
+			got, err := renderTemplate(tmpl, event)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("renderTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTemplate_Invalid(t *testing.T) {
+	for _, text := range []string{"{{ .object_id", "{{ unknownFunc .object_id }}"} {
+		if _, err := parseTemplate("test", text); err == nil {
+			t.Errorf("parseTemplate(%q) expected error, got nil", text)
+		}
+	}
+}
+
+func TestWebhookAction_Templates(t *testing.T) {
+	var (
+		gotPath   string
+		gotHeader string
+		gotBody   []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotHeader = r.Header.Get("X-Object")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	action, err := NewWebhookFactory(nil, nil)(map[string]interface{}{
+		"url":     server.URL + "/hooks/{{ .namespace }}",
+		"headers": map[string]interface{}{"X-Object": "{{ .object_type }}/{{ .object_id }}"},
+		"body":    `{"text": {{ json (printf "Order %s: %v" .object_id .payload.after.amount) }}}`,
+	})
+	if err != nil {
+		t.Fatalf("factory error = %v", err)
+	}
+
+	trigger := &data.Trigger{ID: "t1", Namespace: "sales"}
+	if _, err := action.Execute(context.Background(), trigger, newTestEvent()); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if gotPath != "/hooks/sales" {
+		t.Errorf("path = %q, want /hooks/sales", gotPath)
+	}
+	if gotHeader != "order/o1" {
+		t.Errorf("X-Object = %q, want order/o1", gotHeader)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(gotBody, &body); err != nil {
+		t.Fatalf("body is not JSON: %v (%s)", err, gotBody)
+	}
+	if body["text"] != "Order o1: 1500" {
+		t.Errorf("body text = %q, want %q", body["text"], "Order o1: 1500")
+	}
+}
+
+func TestRegistry_Preview(t *testing.T) {
+	registry := NewDefaultRegistry(Dependencies{NotificationURL: "http://notifications"})
+	trigger := &data.Trigger{
+		ID:           "t1",
+		Namespace:    "sales",
+		ActionURL:    "https://example.com/{{ .object_id }}",
+		BodyTemplate: `{"amount": {{ .payload.after.amount }}}`,
+		Actions: []data.ActionConfig{
+			{Type: NotifyType, Config: map[string]interface{}{
+				"title":      "Order {{ .object_id }}",
+				"recipients": []interface{}{map[string]interface{}{"id": "u1"}},
+			}},
+			{Type: "carrier-pigeon"},
+		},
+	}
+
+	previews := registry.Preview(context.Background(), trigger, newTestEvent())
+	if len(previews) != 3 {
+		t.Fatalf("Preview() returned %d previews, want 3", len(previews))
+	}
+
+	webhook := previews[0]
+	if webhook.Type != WebhookType || webhook.Target != "https://example.com/o1" || webhook.Body != `{"amount": 1500}` {
+		t.Errorf("webhook preview = %+v", webhook)
+	}
+	if webhook.Headers["X-Trigger-ID"] != "t1" {
+		t.Errorf("webhook X-Trigger-ID = %q, want t1", webhook.Headers["X-Trigger-ID"])
+	}
+
+	notify := previews[1]
+	if notify.Type != NotifyType || notify.Target != "http://notifications/api/notifications" || notify.Error != "" {
+		t.Errorf("notify preview = %+v", notify)
+	}
+	var n notification
+	if err := json.Unmarshal([]byte(notify.Body), &n); err != nil || n.Title != "Order o1" {
+		t.Errorf("notify body = %s (err %v)", notify.Body, err)
+	}
+
+	if previews[2].Error == "" {
+		t.Error("unknown action preview has no error")
+	}
+}
@@ -0,0 +1,197 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"event/data"
+	"event/handlers/secrets"
+	"event/signature"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	// WebhookType is the action type for HTTP webhooks
+	WebhookType = "webhook"
+	// maxResponseSnippet is the number of response body bytes kept per attempt
+	maxResponseSnippet = 1024
+)
+
+// WebhookConfig is the configuration of a webhook action.
+// URL, header values and Body are templates rendered against the matched event.
+type WebhookConfig struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method,omitempty"` // Defaults to POST
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body replaces the default JSON encoded event, e.g. {"text": {{ json .object_id }}}
+	Body string `json:"body,omitempty"`
+}
+
+// Compile-time check to ensure WebhookAction implements Action and Previewer
+var (
+	_ Action    = (*WebhookAction)(nil)
+	_ Previewer = (*WebhookAction)(nil)
+)
+
+// WebhookAction sends the matched event, or its rendered body template, to an HTTP endpoint
+type WebhookAction struct {
+	config     WebhookConfig
+	url        *template.Template
+	headers    map[string]*template.Template
+	body       *template.Template
+	httpClient *http.Client
+	secrets    secrets.Store
+}
+
+// NewWebhookFactory returns a factory for webhook actions. If httpClient is nil,
+// http.DefaultClient is used. When secretStore is set, deliveries are signed with
+// the trigger's or namespace's active secrets.
+func NewWebhookFactory(httpClient *http.Client, secretStore secrets.Store) Factory {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return func(config map[string]interface{}) (Action, error) {
+		var cfg WebhookConfig
+		if err := decodeConfig(config, &cfg); err != nil {
+			return nil, err
+		}
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("url is required")
+		}
+		if cfg.Method == "" {
+			cfg.Method = http.MethodPost
+		}
+
+		w := &WebhookAction{
+			config:     cfg,
+			headers:    make(map[string]*template.Template, len(cfg.Headers)),
+			httpClient: httpClient,
+			secrets:    secretStore,
+		}
+
+		var err error
+		if w.url, err = parseTemplate("url", cfg.URL); err != nil {
+			return nil, err
+		}
+		for key, value := range cfg.Headers {
+			if w.headers[key], err = parseTemplate("headers."+key, value); err != nil {
+				return nil, err
+			}
+		}
+		if cfg.Body != "" {
+			if w.body, err = parseTemplate("body", cfg.Body); err != nil {
+				return nil, err
+			}
+		}
+
+		return w, nil
+	}
+}
+
+// Preview renders the request the webhook would send, without the signature header
+func (w *WebhookAction) Preview(ctx context.Context, trigger *data.Trigger, event *data.Event) (Preview, error) {
+	url, headers, body, err := w.render(trigger, event)
+	if err != nil {
+		return Preview{}, err
+	}
+	return Preview{
+		Target:  url,
+		Method:  w.config.Method,
+		Headers: headers,
+		Body:    string(body),
+	}, nil
+}
+
+// render renders the URL, headers and body of the request for an event
+func (w *WebhookAction) render(trigger *data.Trigger, event *data.Event) (string, map[string]string, []byte, error) {
+	url, err := renderTemplate(w.url, event)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	for key, tmpl := range w.headers {
+		value, err := renderTemplate(tmpl, event)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		headers[key] = value
+	}
+	headers["X-Trigger-ID"] = trigger.ID
+	headers["X-Event-ID"] = event.ID
+
+	var body []byte
+	if w.body != nil {
+		rendered, err := renderTemplate(w.body, event)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		body = []byte(rendered)
+	} else if body, err = json.Marshal(event); err != nil {
+		return "", nil, nil, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	return strings.TrimSpace(url), headers, body, nil
+}
+
+// Execute sends the event to the configured URL. Any non-2xx response is treated as a failure;
+// 4xx responses other than 408 and 429 are not retried.
+func (w *WebhookAction) Execute(ctx context.Context, trigger *data.Trigger, event *data.Event) (data.DeliveryAttempt, error) {
+	var attempt data.DeliveryAttempt
+
+	url, headers, body, err := w.render(trigger, event)
+	if err != nil {
+		return attempt, Permanent(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, w.config.Method, url, bytes.NewReader(body))
+	if err != nil {
+		return attempt, Permanent(fmt.Errorf("failed to create request: %w", err))
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	// The receiver can continue the trace of the event with the W3C traceparent header
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	if w.secrets != nil {
+		active, err := w.secrets.Secrets(ctx, trigger.Namespace, trigger.ID)
+		if err != nil {
+			return attempt, fmt.Errorf("failed to load signing secrets: %w", err)
+		}
+		if len(active) > 0 {
+			req.Header.Set(signature.Header, signature.Sign(body, time.Now(), active...))
+		}
+	}
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return attempt, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSnippet))
+	attempt.StatusCode = resp.StatusCode
+	attempt.ResponseBody = string(snippet)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return attempt, nil
+	}
+
+	err = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return attempt, Permanent(err)
+	}
+	return attempt, err
+}
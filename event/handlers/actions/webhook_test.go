@@ -0,0 +1,153 @@
+package actions
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"event/data"
+	"event/handlers/secrets"
+	"event/signature"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestEvent() *data.Event {
+	event := &data.Event{
+		ID:         "evt1",
+		EventType:  "order.created",
+		Namespace:  "sales",
+		ObjectType: "order",
+		ObjectID:   "o1",
+	}
+	event.Payload.After = map[string]interface{}{"amount": 1500}
+	return event
+}
+
+func TestWebhookAction_Signing(t *testing.T) {
+	var (
+		gotHeader string
+		gotBody   []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get(signature.Header)
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	store := secrets.NewMemoryStore()
+	ctx := context.Background()
+	action, err := NewWebhookFactory(nil, store)(map[string]interface{}{"url": server.URL})
+	if err != nil {
+		t.Fatalf("factory error = %v", err)
+	}
+	trigger := &data.Trigger{ID: "t1", Namespace: "sales"}
+
+	// Without secrets the delivery is unsigned
+	if _, err := action.Execute(ctx, trigger, newTestEvent()); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if gotHeader != "" {
+		t.Errorf("unsigned delivery has signature header %q", gotHeader)
+	}
+
+	// During rotation both the old and new secret verify the delivery
+	store.Rotate(ctx, "sales", "", "old-secret")
+	store.Rotate(ctx, "sales", "", "new-secret")
+	attempt, err := action.Execute(ctx, trigger, newTestEvent())
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if attempt.StatusCode != http.StatusNoContent {
+		t.Errorf("StatusCode = %d, want 204", attempt.StatusCode)
+	}
+	for _, secret := range []string{"old-secret", "new-secret"} {
+		if err := signature.Verify(gotHeader, gotBody, signature.DefaultTolerance, secret); err != nil {
+			t.Errorf("Verify() with %s error = %v", secret, err)
+		}
+	}
+
+	// Once the rotation ends the old secret no longer verifies
+	store.Revoke(ctx, "sales", "")
+	if _, err := action.Execute(ctx, trigger, newTestEvent()); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if err := signature.Verify(gotHeader, gotBody, signature.DefaultTolerance, "old-secret"); err != signature.ErrMismatch {
+		t.Errorf("Verify() with revoked secret error = %v, want ErrMismatch", err)
+	}
+}
+
+func TestWebhookAction_TraceContext(t *testing.T) {
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+
+	var gotTraceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTraceparent = r.Header.Get("traceparent")
+	}))
+	defer server.Close()
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	action, err := NewWebhookFactory(nil, nil)(map[string]interface{}{"url": server.URL})
+	if err != nil {
+		t.Fatalf("factory error = %v", err)
+	}
+	if _, err := action.Execute(ctx, &data.Trigger{ID: "t1", Namespace: "sales"}, newTestEvent()); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if want := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"; gotTraceparent != want {
+		t.Errorf("traceparent = %q, want %q", gotTraceparent, want)
+	}
+}
+
+func TestWebhookAction_StatusCodes(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		wantErr       bool
+		wantPermanent bool
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "server error", status: http.StatusBadGateway, wantErr: true},
+		{name: "too many requests", status: http.StatusTooManyRequests, wantErr: true},
+		{name: "bad request", status: http.StatusBadRequest, wantErr: true, wantPermanent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			action, _ := NewWebhookFactory(nil, nil)(map[string]interface{}{"url": server.URL})
+			_, err := action.Execute(context.Background(), &data.Trigger{ID: "t1"}, newTestEvent())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if IsPermanent(err) != tt.wantPermanent {
+				t.Errorf("IsPermanent() = %v, want %v", IsPermanent(err), tt.wantPermanent)
+			}
+		})
+	}
+}
+
+func TestWebhookFactory_RequiresURL(t *testing.T) {
+	if _, err := NewWebhookFactory(nil, nil)(map[string]interface{}{}); err == nil {
+		t.Error("factory expected error for missing url")
+	}
+}
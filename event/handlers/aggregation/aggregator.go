@@ -0,0 +1,392 @@
+package aggregation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"event/data"
+	"event/handlers/triggers"
+)
+
+const (
+	// DefaultBuckets is the default number of buckets a sliding window is divided into.
+	// Sliding windows are accurate to the length of one bucket.
+	DefaultBuckets = 20
+	// ActorID is the actor of threshold events
+	ActorID = "triggerd"
+)
+
+// Aggregation functions
+const (
+	FunctionCount = "count"
+	FunctionSum   = "sum"
+	FunctionMin   = "min"
+	FunctionMax   = "max"
+)
+
+// Window modes
+const (
+	ModeSliding  = "sliding"
+	ModeTumbling = "tumbling"
+)
+
+// Bucket holds the aggregates of the events in one slice of a window
+type Bucket struct {
+	Start time.Time `json:"start" bson:"start"`
+	Count int64     `json:"count" bson:"count"`
+	Sum   float64   `json:"sum" bson:"sum"`
+	Min   float64   `json:"min" bson:"min"`
+	Max   float64   `json:"max" bson:"max"`
+}
+
+// Validate checks an aggregation before its trigger is saved
+func Validate(a *data.Aggregation) error {
+	_, err := newSpec(a, DefaultBuckets)
+	return err
+}
+
+// spec is a parsed aggregation
+type spec struct {
+	config   data.Aggregation
+	window   time.Duration
+	width    time.Duration // bucket length
+	function string
+	operator string
+}
+
+func newSpec(a *data.Aggregation, buckets int) (*spec, error) {
+	window, err := time.ParseDuration(a.Window)
+	if err != nil {
+		return nil, fmt.Errorf("invalid window %q: %w", a.Window, err)
+	}
+	if window <= 0 {
+		return nil, fmt.Errorf("window must be positive")
+	}
+
+	s := &spec{
+		config:   *a,
+		window:   window,
+		width:    window,
+		function: a.Function,
+		operator: a.Operator,
+	}
+
+	switch a.Mode {
+	case "", ModeSliding:
+		s.width = window / time.Duration(buckets)
+		if s.width < time.Second {
+			s.width = time.Second
+		}
+	case ModeTumbling:
+	default:
+		return nil, fmt.Errorf("unknown mode %q", a.Mode)
+	}
+
+	switch a.Function {
+	case "":
+		s.function = FunctionCount
+	case FunctionCount:
+	case FunctionSum, FunctionMin, FunctionMax:
+		if a.Field == "" {
+			return nil, fmt.Errorf("field is required for %s", a.Function)
+		}
+	default:
+		return nil, fmt.Errorf("unknown function %q", a.Function)
+	}
+
+	switch a.Operator {
+	case "":
+		s.operator = ">="
+	case ">=", ">", "<=", "<":
+	default:
+		return nil, fmt.Errorf("unknown operator %q", a.Operator)
+	}
+
+	if a.GroupBy != "" {
+		if err := triggers.CompileExpression(a.GroupBy); err != nil {
+			return nil, fmt.Errorf("group_by: %w", err)
+		}
+	}
+	if a.Field != "" {
+		if err := triggers.CompileExpression(a.Field); err != nil {
+			return nil, fmt.Errorf("field: %w", err)
+		}
+	}
+
+	return s, nil
+}
+
+// maxAttempts is how often Observe re-reads a window that changed while it was clearing it
+const maxAttempts = 5
+
+// Aggregator keeps windowed aggregates for aggregation triggers in a Store
+type Aggregator struct {
+	store   Store
+	buckets int
+
+	// specs holds the parsed aggregation of each trigger by namespace/ID, so that an edited
+	// trigger replaces its entry
+	specs map[string]cachedSpec
+	mu    sync.Mutex
+}
+
+// cachedSpec is a parsed aggregation and the config it was parsed from
+type cachedSpec struct {
+	config data.Aggregation
+	spec   *spec
+}
+
+// Option is a function that configures an Aggregator
+type Option func(*Aggregator)
+
+// WithBuckets sets the number of buckets a sliding window is divided into
+func WithBuckets(n int) Option {
+	return func(a *Aggregator) {
+		a.buckets = n
+	}
+}
+
+// NewAggregator creates an aggregator that keeps its windows in store
+func NewAggregator(store Store, options ...Option) *Aggregator {
+	a := &Aggregator{
+		store:   store,
+		buckets: DefaultBuckets,
+		specs:   make(map[string]cachedSpec),
+	}
+
+	for _, option := range options {
+		option(a)
+	}
+
+	return a
+}
+
+// Observe adds an event that matched the criteria of an aggregation trigger to its window.
+// If the aggregate crosses the threshold, the group's window is cleared and the threshold
+// event that fires the trigger is returned. Otherwise the returned event is nil. When
+// replicas share the store, only the one that clears the window returns the threshold event.
+func (a *Aggregator) Observe(ctx context.Context, trigger *data.Trigger, event *data.Event) (*data.Event, error) {
+	if trigger.Aggregation == nil {
+		return nil, fmt.Errorf("trigger %s has no aggregation", trigger.ID)
+	}
+	s, err := a.spec(trigger)
+	if err != nil {
+		return nil, err
+	}
+
+	key := ""
+	if trigger.Aggregation.GroupBy != "" {
+		value, err := triggers.EvaluateExpression(trigger.Aggregation.GroupBy, event)
+		if err != nil {
+			return nil, fmt.Errorf("group_by: %w", err)
+		}
+		if value != nil {
+			key = fmt.Sprint(value)
+		}
+	}
+
+	value := 1.0
+	if trigger.Aggregation.Field != "" {
+		raw, err := triggers.EvaluateExpression(trigger.Aggregation.Field, event)
+		if err != nil {
+			return nil, fmt.Errorf("field: %w", err)
+		}
+		var ok bool
+		if value, ok = triggers.ToNumber(raw); !ok {
+			return nil, fmt.Errorf("field: %v (%T) is not a number", raw, raw)
+		}
+	}
+
+	at := event.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	group := Group{
+		ID:        groupID(trigger, s, key),
+		Namespace: trigger.Namespace,
+		TriggerID: trigger.ID,
+		Key:       key,
+	}
+	bucket := at.Truncate(s.width)
+	window, err := a.store.Add(ctx, group, bucket, value, bucket.Add(s.window))
+	if err != nil {
+		return nil, err
+	}
+	a.removeExpired(ctx, s, window, at)
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		aggregate, count, start := s.aggregate(window, at)
+		if count == 0 || !crosses(s.operator, aggregate, trigger.Aggregation.Threshold) {
+			return nil, nil
+		}
+
+		// Another replica may have added a value since, or cleared the window and fired
+		cleared, err := a.store.Delete(ctx, window)
+		if err != nil {
+			return nil, err
+		}
+		if cleared {
+			return thresholdEvent(trigger, s, event, key, aggregate, count, start, at), nil
+		}
+		window, err = a.store.Get(ctx, group.ID)
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("window of group %q changed %d times while it was being cleared", key, maxAttempts)
+}
+
+// spec returns the parsed aggregation of a trigger, parsing it on first use and whenever
+// the trigger's aggregation changed
+func (a *Aggregator) spec(trigger *data.Trigger) (*spec, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := trigger.Namespace + "/" + trigger.ID
+	if cached, ok := a.specs[key]; ok && cached.config == *trigger.Aggregation {
+		return cached.spec, nil
+	}
+	s, err := newSpec(trigger.Aggregation, a.buckets)
+	if err != nil {
+		return nil, err
+	}
+	a.specs[key] = cachedSpec{config: *trigger.Aggregation, spec: s}
+	return s, nil
+}
+
+// Prune drops the parsed aggregations of triggers that are not among the loaded triggers,
+// so that deleted triggers do not stay cached
+func (a *Aggregator) Prune(loaded []*data.Trigger) {
+	keep := make(map[string]bool, len(loaded))
+	for _, trigger := range loaded {
+		keep[trigger.Namespace+"/"+trigger.ID] = true
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for key := range a.specs {
+		if !keep[key] {
+			delete(a.specs, key)
+		}
+	}
+}
+
+// removeExpired drops the buckets that have left the window. Expired buckets are not
+// counted either way, so a failure is only logged.
+func (a *Aggregator) removeExpired(ctx context.Context, s *spec, window *Window, at time.Time) {
+	first := s.windowStart(at)
+	var expired []time.Time
+	for _, b := range window.Buckets {
+		if b.Start.Before(first) {
+			expired = append(expired, b.Start)
+		}
+	}
+	if len(expired) == 0 {
+		return
+	}
+	if err := a.store.RemoveBuckets(ctx, window.ID, expired); err != nil {
+		log.Printf("Failed to remove expired buckets of aggregation window %s: %v", window.ID, err)
+	}
+}
+
+// aggregate returns the aggregate and number of events in the window at the given time,
+// together with the start of the window
+func (s *spec) aggregate(window *Window, at time.Time) (float64, int64, time.Time) {
+	start := s.windowStart(at)
+	var (
+		count  int64
+		result float64
+	)
+	for _, b := range window.Buckets {
+		if b.Start.Before(start) || b.Start.After(at) {
+			continue
+		}
+		switch s.function {
+		case FunctionCount:
+			result += float64(b.Count)
+		case FunctionSum:
+			result += b.Sum
+		case FunctionMin:
+			if count == 0 || b.Min < result {
+				result = b.Min
+			}
+		case FunctionMax:
+			if count == 0 || b.Max > result {
+				result = b.Max
+			}
+		}
+		count += b.Count
+	}
+	return result, count, start
+}
+
+// windowStart returns the start of the first bucket in the window at the given time
+func (s *spec) windowStart(at time.Time) time.Time {
+	current := at.Truncate(s.width)
+	if s.width == s.window {
+		return current
+	}
+	return current.Add(s.width - s.window)
+}
+
+// crosses reports whether value crosses the threshold
+func crosses(operator string, value, threshold float64) bool {
+	switch operator {
+	case ">":
+		return value > threshold
+	case "<=":
+		return value <= threshold
+	case "<":
+		return value < threshold
+	default:
+		return value >= threshold
+	}
+}
+
+// thresholdEvent returns the synthetic event that fires an aggregation trigger
+func thresholdEvent(trigger *data.Trigger, s *spec, last *data.Event, key string, value float64, count int64, start, at time.Time) *data.Event {
+	event := &data.Event{
+		ID:           data.NewEventID(),
+		EventType:    data.AggregationThresholdEventType,
+		EventVersion: "1.0.0",
+		Namespace:    trigger.Namespace,
+		ObjectType:   last.ObjectType,
+		ObjectID:     last.ObjectID,
+		Timestamp:    at.UTC(),
+	}
+	event.Actor.Type = "system"
+	event.Actor.ID = ActorID
+	event.Context = last.Context
+	event.Payload.After = map[string]interface{}{
+		"trigger_id":   trigger.ID,
+		"group":        key,
+		"function":     s.function,
+		"value":        value,
+		"count":        count,
+		"threshold":    trigger.Aggregation.Threshold,
+		"window":       s.config.Window,
+		"window_start": start.UTC().Format(time.RFC3339),
+		"window_end":   at.UTC().Format(time.RFC3339),
+		"last_event":   last.ToMap(),
+	}
+	return event
+}
+
+// groupID returns the ID of the window of a group. It contains a hash of the aggregation and
+// bucket length, so that windows counted under an earlier aggregation are not reused.
+func groupID(trigger *data.Trigger, s *spec, key string) string {
+	config, _ := json.Marshal(s.config)
+	sum := sha256.Sum256(fmt.Appendf(config, "/%d", s.width))
+	return trigger.Namespace + "/" + trigger.ID + "/" + hex.EncodeToString(sum[:8]) + "/" + key
+}
@@ -0,0 +1,235 @@
+package aggregation
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"event/data"
+)
+
+var base = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func paymentFailed(customer string, amount float64, at time.Duration) *data.Event {
+	event := &data.Event{
+		ID:         data.NewEventID(),
+		EventType:  "payment.failed",
+		Namespace:  "billing",
+		ObjectType: "payment",
+		ObjectID:   "p1",
+		Timestamp:  base.Add(at),
+	}
+	event.Payload.After = map[string]interface{}{"customer_id": customer, "amount": amount}
+	return event
+}
+
+func TestAggregator_Count(t *testing.T) {
+	ctx := context.Background()
+	trigger := &data.Trigger{ID: "failures", Namespace: "billing", Enabled: true, Aggregation: &data.Aggregation{
+		GroupBy:   "event.payload.after.customer_id",
+		Window:    "10m",
+		Operator:  ">",
+		Threshold: 2,
+	}}
+	a := NewAggregator(NewMemoryStore())
+
+	// Two failures for c1 and one for c2 stay below the threshold
+	for _, event := range []*data.Event{
+		paymentFailed("c1", 10, 0),
+		paymentFailed("c2", 10, time.Minute),
+		paymentFailed("c1", 10, 2*time.Minute),
+	} {
+		fired, err := a.Observe(ctx, trigger, event)
+		if err != nil || fired != nil {
+			t.Fatalf("Observe() = %v, %v, want no threshold event", fired, err)
+		}
+	}
+
+	// The first c1 failure leaves the sliding window before the third arrives
+	if fired, _ := a.Observe(ctx, trigger, paymentFailed("c1", 10, 11*time.Minute)); fired != nil {
+		t.Fatalf("Observe() fired with an expired event in the window")
+	}
+
+	fired, err := a.Observe(ctx, trigger, paymentFailed("c1", 10, 11*time.Minute+30*time.Second))
+	if err != nil || fired == nil {
+		t.Fatalf("Observe() = %v, %v, want threshold event", fired, err)
+	}
+	if fired.EventType != data.AggregationThresholdEventType || fired.Payload.After["group"] != "c1" || fired.Payload.After["value"] != 3.0 {
+		t.Errorf("threshold event = %s %v", fired.EventType, fired.Payload.After)
+	}
+
+	// Firing clears the group's window
+	if fired, _ := a.Observe(ctx, trigger, paymentFailed("c1", 10, 13*time.Minute)); fired != nil {
+		t.Errorf("Observe() fired again right after the window was cleared")
+	}
+}
+
+func TestAggregator_TumblingSum(t *testing.T) {
+	ctx := context.Background()
+	trigger := &data.Trigger{ID: "volume", Namespace: "billing", Enabled: true, Aggregation: &data.Aggregation{
+		Function:  FunctionSum,
+		Field:     "event.payload.after.amount",
+		Window:    "1h",
+		Mode:      ModeTumbling,
+		Threshold: 100,
+	}}
+	a := NewAggregator(NewMemoryStore())
+
+	// 60 in the 12:00 window and 60 in the 13:00 window never add up
+	for _, event := range []*data.Event{
+		paymentFailed("c1", 60, 50*time.Minute),
+		paymentFailed("c2", 60, 70*time.Minute),
+	} {
+		if fired, err := a.Observe(ctx, trigger, event); err != nil || fired != nil {
+			t.Fatalf("Observe() = %v, %v, want no threshold event", fired, err)
+		}
+	}
+
+	fired, err := a.Observe(ctx, trigger, paymentFailed("c3", 50, 80*time.Minute))
+	if err != nil || fired == nil {
+		t.Fatalf("Observe() = %v, %v, want threshold event", fired, err)
+	}
+	if fired.Payload.After["value"] != 110.0 || fired.Payload.After["window_start"] != "2024-03-01T13:00:00Z" {
+		t.Errorf("threshold event payload = %v", fired.Payload.After)
+	}
+}
+
+func TestAggregator_SharedStore(t *testing.T) {
+	ctx := context.Background()
+	trigger := &data.Trigger{ID: "failures", Namespace: "billing", Enabled: true, Aggregation: &data.Aggregation{
+		GroupBy:   "event.payload.after.customer_id",
+		Window:    "10m",
+		Threshold: 2,
+	}}
+	store := NewMemoryStore()
+	replica1, replica2 := NewAggregator(store), NewAggregator(store)
+
+	// Each replica sees one of the failures, and together they cross the threshold
+	if fired, err := replica1.Observe(ctx, trigger, paymentFailed("c1", 10, 0)); err != nil || fired != nil {
+		t.Fatalf("Observe() = %v, %v, want no threshold event", fired, err)
+	}
+	fired, err := replica2.Observe(ctx, trigger, paymentFailed("c1", 10, time.Minute))
+	if err != nil || fired == nil {
+		t.Fatalf("Observe() = %v, %v, want threshold event", fired, err)
+	}
+	if fired.Payload.After["count"] != int64(2) {
+		t.Errorf("threshold event count = %v, want 2", fired.Payload.After["count"])
+	}
+
+	// A changed aggregation starts from an empty window
+	changed := *trigger
+	changed.Aggregation = &data.Aggregation{GroupBy: "event.payload.after.customer_id", Window: "5m", Threshold: 2}
+	replica1.Observe(ctx, trigger, paymentFailed("c2", 10, 2*time.Minute))
+	if fired, _ := replica2.Observe(ctx, &changed, paymentFailed("c2", 10, 3*time.Minute)); fired != nil {
+		t.Errorf("window of a changed aggregation counted events of the old one")
+	}
+}
+
+func TestAggregator_ConcurrentReplicas(t *testing.T) {
+	ctx := context.Background()
+	trigger := &data.Trigger{ID: "failures", Namespace: "billing", Enabled: true, Aggregation: &data.Aggregation{
+		Window:    "10m",
+		Threshold: 10,
+	}}
+	store := NewMemoryStore()
+	replicas := []*Aggregator{NewAggregator(store), NewAggregator(store)}
+
+	var (
+		mu    sync.Mutex
+		fired []*data.Event
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			event, err := replicas[i%2].Observe(ctx, trigger, paymentFailed("c1", 10, time.Duration(i)*time.Millisecond))
+			if err != nil {
+				t.Errorf("Observe() error = %v", err)
+			}
+			if event != nil {
+				mu.Lock()
+				fired = append(fired, event)
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Every event is counted by exactly one firing or is still in the window
+	var counted int64
+	for _, event := range fired {
+		count := event.Payload.After["count"].(int64)
+		if count < 10 {
+			t.Errorf("threshold event counted %d events, want at least 10", count)
+		}
+		counted += count
+	}
+	if window, err := store.Get(ctx, groupID(trigger, mustSpec(t, trigger.Aggregation), "")); err == nil {
+		for _, b := range window.Buckets {
+			counted += b.Count
+		}
+	}
+	if len(fired) == 0 || counted != 100 {
+		t.Errorf("%d threshold events counted %d of 100 events", len(fired), counted)
+	}
+}
+
+func TestAggregator_SpecCacheIsBounded(t *testing.T) {
+	ctx := context.Background()
+	a := NewAggregator(NewMemoryStore())
+	trigger := &data.Trigger{ID: "failures", Namespace: "billing", Enabled: true}
+
+	// Every edit of the trigger replaces its cached aggregation
+	for i := 1; i <= 3; i++ {
+		trigger.Aggregation = &data.Aggregation{Window: "10m", Threshold: float64(i)}
+		if _, err := a.Observe(ctx, trigger, paymentFailed("c1", 10, 0)); err != nil {
+			t.Fatalf("Observe() error = %v", err)
+		}
+	}
+	if len(a.specs) != 1 {
+		t.Errorf("cached %d aggregations after edits, want 1", len(a.specs))
+	}
+
+	// Deleted triggers are pruned
+	a.Prune(nil)
+	if len(a.specs) != 0 {
+		t.Errorf("cached %d aggregations after pruning, want 0", len(a.specs))
+	}
+}
+
+func mustSpec(t *testing.T, a *data.Aggregation) *spec {
+	t.Helper()
+	s, err := newSpec(a, DefaultBuckets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		aggregation data.Aggregation
+		wantErr     bool
+	}{
+		{"count", data.Aggregation{Window: "10m", Threshold: 5}, false},
+		{"sum", data.Aggregation{Function: FunctionSum, Field: "event.payload.after.amount", Window: "1h", Mode: ModeTumbling}, false},
+		{"missing window", data.Aggregation{Threshold: 5}, true},
+		{"negative window", data.Aggregation{Window: "-1m"}, true},
+		{"sum without field", data.Aggregation{Function: FunctionSum, Window: "1h"}, true},
+		{"unknown function", data.Aggregation{Function: "avg", Window: "1h"}, true},
+		{"unknown mode", data.Aggregation{Mode: "hopping", Window: "1h"}, true},
+		{"unknown operator", data.Aggregation{Operator: "==", Window: "1h"}, true},
+		{"bad group_by", data.Aggregation{GroupBy: "event.(", Window: "1h"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(&tt.aggregation); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package aggregation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultCollection is the default MongoDB collection for aggregation windows
+const DefaultCollection = "aggregation_windows"
+
+// Compile-time check to ensure MongoStore implements Store
+var _ Store = (*MongoStore)(nil)
+
+// MongoStore is a window store backed by a MongoDB collection with one document per group.
+// Values are added with a single upsert that increments the bucket, so replicas sharing the
+// collection see every event.
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoStore creates a new MongoDB-backed window store and ensures its indexes exist
+func NewMongoStore(ctx context.Context, db *mongo.Database, collectionName string) (*MongoStore, error) {
+	if collectionName == "" {
+		collectionName = DefaultCollection
+	}
+	collection := db.Collection(collectionName)
+
+	// Windows without recent values are removed by MongoDB
+	indexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	if _, err := collection.Indexes().CreateMany(ctx, indexModels); err != nil {
+		return nil, fmt.Errorf("failed to create aggregation indexes: %w", err)
+	}
+
+	return &MongoStore{
+		collection: collection,
+	}, nil
+}
+
+// Add adds a value to the bucket starting at start and returns the updated window
+func (s *MongoStore) Add(ctx context.Context, group Group, start time.Time, value float64, expires time.Time) (*Window, error) {
+	bucket := "buckets." + bucketKey(start)
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"namespace":  group.Namespace,
+			"trigger_id": group.TriggerID,
+			"key":        group.Key,
+		},
+		"$set": bson.M{bucket + ".start": start},
+		"$inc": bson.M{
+			bucket + ".count": 1,
+			bucket + ".sum":   value,
+			"version":         1,
+		},
+		"$min": bson.M{bucket + ".min": value},
+		"$max": bson.M{
+			bucket + ".max": value,
+			"expires_at":    expires,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var window Window
+	err := s.collection.FindOneAndUpdate(ctx, bson.M{"_id": group.ID}, update, opts).Decode(&window)
+	if mongo.IsDuplicateKeyError(err) {
+		// Another replica created the window at the same time; it exists now
+		err = s.collection.FindOneAndUpdate(ctx, bson.M{"_id": group.ID}, update, opts).Decode(&window)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to add to aggregation window: %w", err)
+	}
+	return &window, nil
+}
+
+// Get returns the window of a group
+func (s *MongoStore) Get(ctx context.Context, id string) (*Window, error) {
+	var window Window
+	err := s.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&window)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get aggregation window: %w", err)
+	}
+	return &window, nil
+}
+
+// Delete removes a window if its version is unchanged since it was read
+func (s *MongoStore) Delete(ctx context.Context, window *Window) (bool, error) {
+	result, err := s.collection.DeleteOne(ctx, bson.M{"_id": window.ID, "version": window.Version})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete aggregation window: %w", err)
+	}
+	return result.DeletedCount == 1, nil
+}
+
+// RemoveBuckets drops buckets that have left a window
+func (s *MongoStore) RemoveBuckets(ctx context.Context, id string, starts []time.Time) error {
+	unset := bson.M{}
+	for _, start := range starts {
+		unset["buckets."+bucketKey(start)] = ""
+	}
+	if _, err := s.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$unset": unset}); err != nil {
+		return fmt.Errorf("failed to remove expired aggregation buckets: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package aggregation
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when a group has no window
+var ErrNotFound = errors.New("aggregation window not found")
+
+// Group identifies the window of one group of an aggregation trigger
+type Group struct {
+	// ID is namespace/trigger ID/aggregation hash/group key. A changed aggregation starts
+	// new windows, and those of the old one expire.
+	ID        string `json:"id" bson:"_id"`
+	Namespace string `json:"namespace" bson:"namespace"`
+	TriggerID string `json:"trigger_id" bson:"trigger_id"`
+	Key       string `json:"key" bson:"key"`
+}
+
+// Window is the stored window of one group
+type Window struct {
+	Group `bson:",inline"`
+	// Buckets holds the buckets of the window by their start in Unix seconds
+	Buckets map[string]Bucket `json:"buckets" bson:"buckets"`
+	// Version is incremented by every added value, so that only one replica clears a
+	// window that crossed its threshold
+	Version   int64     `json:"version" bson:"version"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+}
+
+// bucketKey returns the key of the bucket starting at start in Window.Buckets
+func bucketKey(start time.Time) string {
+	return strconv.FormatInt(start.Unix(), 10)
+}
+
+// Store keeps the windows of aggregation triggers. Every operation is atomic, so that
+// replicas sharing a store count every event once and fire once per crossing.
+type Store interface {
+	// Add adds a value to the bucket starting at start, creating the window and bucket if
+	// needed, and returns the updated window. The window is removed after expires unless a
+	// later value extends it.
+	Add(ctx context.Context, group Group, start time.Time, value float64, expires time.Time) (*Window, error)
+
+	// Get returns the window of a group, or ErrNotFound
+	Get(ctx context.Context, id string) (*Window, error)
+
+	// Delete removes a window if its version is unchanged since it was read.
+	// It reports whether the window was removed by this call.
+	Delete(ctx context.Context, window *Window) (bool, error)
+
+	// RemoveBuckets drops buckets that have left a window
+	RemoveBuckets(ctx context.Context, id string, starts []time.Time) error
+}
+
+// Compile-time check to ensure MemoryStore implements Store
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore is an in-memory window store, intended for tests and a single triggerd instance.
+// Windows that expired before the start of an added bucket are dropped on every add.
+type MemoryStore struct {
+	windows map[string]*Window
+	mu      sync.Mutex
+}
+
+// NewMemoryStore creates an empty in-memory window store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		windows: make(map[string]*Window),
+	}
+}
+
+// Add adds a value to the bucket starting at start and returns the updated window
+func (s *MemoryStore) Add(ctx context.Context, group Group, start time.Time, value float64, expires time.Time) (*Window, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, w := range s.windows {
+		if !w.ExpiresAt.After(start) {
+			delete(s.windows, id)
+		}
+	}
+
+	w, ok := s.windows[group.ID]
+	if !ok {
+		w = &Window{Group: group, Buckets: make(map[string]Bucket)}
+		s.windows[group.ID] = w
+	}
+	key := bucketKey(start)
+	b, ok := w.Buckets[key]
+	if !ok {
+		b = Bucket{Start: start, Min: value, Max: value}
+	}
+	b.Count++
+	b.Sum += value
+	b.Min = min(b.Min, value)
+	b.Max = max(b.Max, value)
+	w.Buckets[key] = b
+	w.Version++
+	if expires.After(w.ExpiresAt) {
+		w.ExpiresAt = expires
+	}
+	return clone(w), nil
+}
+
+// Get returns the window of a group
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Window, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.windows[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clone(w), nil
+}
+
+// Delete removes a window if its version is unchanged since it was read
+func (s *MemoryStore) Delete(ctx context.Context, window *Window) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.windows[window.ID]
+	if !ok || w.Version != window.Version {
+		return false, nil
+	}
+	delete(s.windows, window.ID)
+	return true, nil
+}
+
+// RemoveBuckets drops buckets that have left a window
+func (s *MemoryStore) RemoveBuckets(ctx context.Context, id string, starts []time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.windows[id]
+	if !ok {
+		return nil
+	}
+	for _, start := range starts {
+		delete(w.Buckets, bucketKey(start))
+	}
+	return nil
+}
+
+// clone copies a window, so that callers cannot change the stored one
+func clone(w *Window) *Window {
+	c := *w
+	c.Buckets = make(map[string]Bucket, len(w.Buckets))
+	for key, b := range w.Buckets {
+		c.Buckets[key] = b
+	}
+	return &c
+}
@@ -0,0 +1,165 @@
+package deadletter
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"event/data"
+)
+
+// Compile-time check to ensure MemoryStore implements Store
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore is an in-memory dead-letter store, intended for tests and local development
+type MemoryStore struct {
+	letters map[string]*data.DeadLetter // id -> DeadLetter
+	mu      sync.RWMutex
+}
+
+// NewMemoryStore creates a new in-memory dead-letter store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		letters: make(map[string]*data.DeadLetter),
+	}
+}
+
+// Add stores a new dead letter
+func (s *MemoryStore) Add(ctx context.Context, dl *data.DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.letters[dl.ID] = clone(dl)
+	return nil
+}
+
+// Get returns a dead letter by namespace and ID
+func (s *MemoryStore) Get(ctx context.Context, namespace, id string) (*data.DeadLetter, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	dl, ok := s.letters[id]
+	if !ok || dl.Namespace != namespace {
+		return nil, ErrNotFound
+	}
+	return clone(dl), nil
+}
+
+// List returns the dead letters matching the filter, oldest first
+func (s *MemoryStore) List(ctx context.Context, filter Filter) ([]*data.DeadLetter, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var letters []*data.DeadLetter
+	for _, dl := range s.letters {
+		if matches(dl, filter) {
+			letters = append(letters, clone(dl))
+		}
+	}
+
+	sort.Slice(letters, func(i, j int) bool {
+		return letters[i].CreatedAt.Before(letters[j].CreatedAt)
+	})
+
+	if filter.Limit > 0 && len(letters) > filter.Limit {
+		letters = letters[:filter.Limit]
+	}
+	return letters, nil
+}
+
+// Count returns how many dead letters match the filter, ignoring its limit
+func (s *MemoryStore) Count(ctx context.Context, filter Filter) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, dl := range s.letters {
+		if matches(dl, filter) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Update replaces an existing dead letter
+func (s *MemoryStore) Update(ctx context.Context, dl *data.DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.letters[dl.ID]; !ok {
+		return ErrNotFound
+	}
+	s.letters[dl.ID] = clone(dl)
+	return nil
+}
+
+// Claim marks a dead letter as being redriven until the given time and returns it
+func (s *MemoryStore) Claim(ctx context.Context, namespace, id string, now, until time.Time) (*data.DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dl, ok := s.letters[id]
+	if !ok || dl.Namespace != namespace {
+		return nil, ErrNotFound
+	}
+	if dl.RedriveUntil.After(now) {
+		return nil, ErrClaimed
+	}
+	dl.RedriveUntil = until
+	return clone(dl), nil
+}
+
+// Delete removes a dead letter
+func (s *MemoryStore) Delete(ctx context.Context, namespace, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dl, ok := s.letters[id]
+	if !ok || dl.Namespace != namespace {
+		return ErrNotFound
+	}
+	delete(s.letters, id)
+	return nil
+}
+
+// Purge removes all dead letters matching the filter and returns how many were removed
+func (s *MemoryStore) Purge(ctx context.Context, filter Filter) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for id, dl := range s.letters {
+		if matches(dl, filter) {
+			delete(s.letters, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// clone copies a dead letter, so that callers cannot change the stored one
+func clone(dl *data.DeadLetter) *data.DeadLetter {
+	c := *dl
+	c.Attempts = append([]data.DeliveryAttempt(nil), dl.Attempts...)
+	return &c
+}
+
+// matches reports whether a dead letter satisfies the filter
+func matches(dl *data.DeadLetter, filter Filter) bool {
+	if dl.Namespace != filter.Namespace {
+		return false
+	}
+	if filter.TriggerID != "" && dl.TriggerID != filter.TriggerID {
+		return false
+	}
+	if len(filter.IDs) == 0 {
+		return true
+	}
+	for _, id := range filter.IDs {
+		if dl.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package deadletter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"event/data"
+)
+
+func newTestStore(t *testing.T) *MemoryStore {
+	t.Helper()
+
+	store := NewMemoryStore()
+	now := time.Now()
+	letters := []*data.DeadLetter{
+		{ID: "dl1", Namespace: "sales", TriggerID: "t1", CreatedAt: now.Add(-3 * time.Minute)},
+		{ID: "dl2", Namespace: "sales", TriggerID: "t2", CreatedAt: now.Add(-2 * time.Minute)},
+		{ID: "dl3", Namespace: "sales", TriggerID: "t1", CreatedAt: now.Add(-1 * time.Minute)},
+		{ID: "dl4", Namespace: "core", TriggerID: "t1", CreatedAt: now},
+	}
+	for _, dl := range letters {
+		if err := store.Add(context.Background(), dl); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	return store
+}
+
+func TestMemoryStore_List(t *testing.T) {
+	store := newTestStore(t)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{
+			name:   "namespace",
+			filter: Filter{Namespace: "sales"},
+			want:   []string{"dl1", "dl2", "dl3"},
+		},
+		{
+			name:   "trigger",
+			filter: Filter{Namespace: "sales", TriggerID: "t1"},
+			want:   []string{"dl1", "dl3"},
+		},
+		{
+			name:   "ids",
+			filter: Filter{Namespace: "sales", IDs: []string{"dl3", "dl4"}},
+			want:   []string{"dl3"},
+		},
+		{
+			name:   "limit",
+			filter: Filter{Namespace: "sales", Limit: 1},
+			want:   []string{"dl1"},
+		},
+		{
+			name:   "unknown namespace",
+			filter: Filter{Namespace: "nonexistent"},
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			letters, err := store.List(context.Background(), tt.filter)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(letters) != len(tt.want) {
+				t.Fatalf("List() returned %d dead letters, want %d", len(letters), len(tt.want))
+			}
+			for i, dl := range letters {
+				if dl.ID != tt.want[i] {
+					t.Errorf("List()[%d] = %s, want %s", i, dl.ID, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStore_GetDelete(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	if _, err := store.Get(ctx, "core", "dl1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() from wrong namespace error = %v, want ErrNotFound", err)
+	}
+
+	dl, err := store.Get(ctx, "sales", "dl1")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if dl.TriggerID != "t1" {
+		t.Errorf("Get() TriggerID = %s, want t1", dl.TriggerID)
+	}
+
+	if err := store.Delete(ctx, "sales", "dl1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get(ctx, "sales", "dl1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryStore_Purge(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	purged, err := store.Purge(ctx, Filter{Namespace: "sales", TriggerID: "t1"})
+	if err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if purged != 2 {
+		t.Errorf("Purge() = %d, want 2", purged)
+	}
+
+	letters, _ := store.List(ctx, Filter{Namespace: "sales"})
+	if len(letters) != 1 || letters[0].ID != "dl2" {
+		t.Errorf("List() after Purge() = %v, want only dl2", letters)
+	}
+
+	// Other namespaces are untouched
+	if _, err := store.Get(ctx, "core", "dl4"); err != nil {
+		t.Errorf("Get() core/dl4 error = %v", err)
+	}
+}
+
+func TestMemoryStore_Claim(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	dl, err := store.Claim(ctx, "sales", "dl1", now, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if _, err := store.Claim(ctx, "sales", "dl1", now, now.Add(time.Minute)); !errors.Is(err, ErrClaimed) {
+		t.Errorf("second Claim() error = %v, want ErrClaimed", err)
+	}
+	if _, err := store.Claim(ctx, "core", "dl1", now, now.Add(time.Minute)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Claim() from wrong namespace error = %v, want ErrNotFound", err)
+	}
+
+	// Changing the returned dead letter does not change the stored one
+	dl.RedriveCount = 5
+	dl.Attempts = append(dl.Attempts, data.DeliveryAttempt{Attempt: 1})
+	stored, _ := store.Get(ctx, "sales", "dl1")
+	if stored.RedriveCount != 0 || len(stored.Attempts) != 0 {
+		t.Errorf("stored dead letter changed to %+v", stored)
+	}
+
+	// An expired claim can be taken over
+	if _, err := store.Claim(ctx, "sales", "dl1", now.Add(2*time.Minute), now.Add(3*time.Minute)); err != nil {
+		t.Errorf("Claim() after the claim expired error = %v", err)
+	}
+}
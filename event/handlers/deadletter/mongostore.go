@@ -0,0 +1,162 @@
+package deadletter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"event/data"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultCollection is the default MongoDB collection for dead letters
+const DefaultCollection = "dead_letters"
+
+// Compile-time check to ensure MongoStore implements Store
+var _ Store = (*MongoStore)(nil)
+
+// MongoStore is a dead-letter store backed by a MongoDB collection
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoStore creates a new MongoDB-backed dead-letter store and ensures its indexes exist
+func NewMongoStore(ctx context.Context, db *mongo.Database, collectionName string) (*MongoStore, error) {
+	if collectionName == "" {
+		collectionName = DefaultCollection
+	}
+	// Decode nested documents (inputs, results, payloads) as maps rather than bson.D
+	collection := db.Collection(collectionName, options.Collection().SetBSONOptions(&options.BSONOptions{DefaultDocumentM: true}))
+
+	indexModels := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "trigger_id", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+	}
+	if _, err := collection.Indexes().CreateMany(ctx, indexModels); err != nil {
+		return nil, fmt.Errorf("failed to create dead-letter indexes: %w", err)
+	}
+
+	return &MongoStore{
+		collection: collection,
+	}, nil
+}
+
+// Add stores a new dead letter
+func (s *MongoStore) Add(ctx context.Context, dl *data.DeadLetter) error {
+	if _, err := s.collection.InsertOne(ctx, dl); err != nil {
+		return fmt.Errorf("failed to insert dead letter: %w", err)
+	}
+	return nil
+}
+
+// Get returns a dead letter by namespace and ID
+func (s *MongoStore) Get(ctx context.Context, namespace, id string) (*data.DeadLetter, error) {
+	var dl data.DeadLetter
+	err := s.collection.FindOne(ctx, bson.M{"_id": id, "namespace": namespace}).Decode(&dl)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+	return &dl, nil
+}
+
+// List returns the dead letters matching the filter, oldest first
+func (s *MongoStore) List(ctx context.Context, filter Filter) ([]*data.DeadLetter, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+
+	cursor, err := s.collection.Find(ctx, filterToBSON(filter), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var letters []*data.DeadLetter
+	if err := cursor.All(ctx, &letters); err != nil {
+		return nil, fmt.Errorf("failed to decode dead letters: %w", err)
+	}
+	return letters, nil
+}
+
+// Count returns how many dead letters match the filter, ignoring its limit
+func (s *MongoStore) Count(ctx context.Context, filter Filter) (int, error) {
+	count, err := s.collection.CountDocuments(ctx, filterToBSON(filter))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count dead letters: %w", err)
+	}
+	return int(count), nil
+}
+
+// Claim marks a dead letter as being redriven until the given time and returns it
+func (s *MongoStore) Claim(ctx context.Context, namespace, id string, now, until time.Time) (*data.DeadLetter, error) {
+	// A missing redrive_until is not greater than now either
+	filter := bson.M{"_id": id, "namespace": namespace, "redrive_until": bson.M{"$not": bson.M{"$gt": now}}}
+	update := bson.M{"$set": bson.M{"redrive_until": until}}
+
+	var dl data.DeadLetter
+	err := s.collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&dl)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if _, err := s.Get(ctx, namespace, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrClaimed
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim dead letter: %w", err)
+	}
+	return &dl, nil
+}
+
+// Update replaces an existing dead letter
+func (s *MongoStore) Update(ctx context.Context, dl *data.DeadLetter) error {
+	result, err := s.collection.ReplaceOne(ctx, bson.M{"_id": dl.ID, "namespace": dl.Namespace}, dl)
+	if err != nil {
+		return fmt.Errorf("failed to update dead letter: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes a dead letter
+func (s *MongoStore) Delete(ctx context.Context, namespace, id string) error {
+	result, err := s.collection.DeleteOne(ctx, bson.M{"_id": id, "namespace": namespace})
+	if err != nil {
+		return fmt.Errorf("failed to delete dead letter: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Purge removes all dead letters matching the filter and returns how many were removed
+func (s *MongoStore) Purge(ctx context.Context, filter Filter) (int, error) {
+	result, err := s.collection.DeleteMany(ctx, filterToBSON(filter))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge dead letters: %w", err)
+	}
+	return int(result.DeletedCount), nil
+}
+
+// filterToBSON converts a Filter into a MongoDB query document
+func filterToBSON(filter Filter) bson.M {
+	query := bson.M{"namespace": filter.Namespace}
+	if filter.TriggerID != "" {
+		query["trigger_id"] = filter.TriggerID
+	}
+	if len(filter.IDs) > 0 {
+		query["_id"] = bson.M{"$in": filter.IDs}
+	}
+	return query
+}
@@ -0,0 +1,51 @@
+package deadletter
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"event/data"
+)
+
+// ErrNotFound is returned when a dead letter does not exist
+var ErrNotFound = errors.New("dead letter not found")
+
+// ErrClaimed is returned when another redrive holds a dead letter
+var ErrClaimed = errors.New("dead letter is being redriven")
+
+// Filter narrows down the dead letters returned by List and removed by Purge
+type Filter struct {
+	Namespace string
+	TriggerID string   // Optional, matches all triggers when empty
+	IDs       []string // Optional, matches all dead letters when empty
+	Limit     int      // Optional, no limit when zero
+}
+
+// Store defines the interface for a dead-letter store
+type Store interface {
+	// Add stores a new dead letter
+	Add(ctx context.Context, dl *data.DeadLetter) error
+
+	// Get returns a dead letter by namespace and ID
+	Get(ctx context.Context, namespace, id string) (*data.DeadLetter, error)
+
+	// List returns the dead letters matching the filter, oldest first
+	List(ctx context.Context, filter Filter) ([]*data.DeadLetter, error)
+
+	// Count returns how many dead letters match the filter, ignoring its limit
+	Count(ctx context.Context, filter Filter) (int, error)
+
+	// Claim marks a dead letter as being redriven until the given time and returns it.
+	// It returns ErrClaimed if another redrive holds the dead letter at now.
+	Claim(ctx context.Context, namespace, id string, now, until time.Time) (*data.DeadLetter, error)
+
+	// Update replaces an existing dead letter
+	Update(ctx context.Context, dl *data.DeadLetter) error
+
+	// Delete removes a dead letter
+	Delete(ctx context.Context, namespace, id string) error
+
+	// Purge removes all dead letters matching the filter and returns how many were removed
+	Purge(ctx context.Context, filter Filter) (int, error)
+}
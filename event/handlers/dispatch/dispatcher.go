@@ -0,0 +1,272 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"event/data"
+	"event/handlers/actions"
+	"event/handlers/deadletter"
+	"event/tracing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("event/handlers/dispatch")
+
+const (
+	// DefaultInitialBackoff is the default delay before the first retry
+	DefaultInitialBackoff = 500 * time.Millisecond
+	// DefaultMaxBackoff is the default upper bound for the delay between retries
+	DefaultMaxBackoff = 30 * time.Second
+	// DefaultActionTimeout is used when neither the action nor the trigger sets a timeout
+	DefaultActionTimeout = 10 * time.Second
+	// redriveLease is how long a redrive holds a dead letter before another may take it over
+	redriveLease = 5 * time.Minute
+	// deadLetterTimeout bounds storing a dead letter, which outlives the dispatch's context
+	deadLetterTimeout = 10 * time.Second
+)
+
+// Dispatcher runs the actions of fired triggers with retries and moves
+// events whose actions still fail after all retries to the dead-letter store
+type Dispatcher struct {
+	registry       *actions.Registry
+	deadLetters    deadletter.Store
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	observer       Observer
+}
+
+// Observer is notified of every action attempt and of every event that is dead-lettered
+type Observer interface {
+	// ActionAttempted is called after each attempt to run an action, with the attempt's error if it failed
+	ActionAttempted(trigger *data.Trigger, actionType string, duration time.Duration, err error)
+	// DeadLettered is called when an event is stored as a dead letter after its action failed
+	DeadLettered(trigger *data.Trigger, actionType string)
+}
+
+// Option is a function that configures a Dispatcher
+type Option func(*Dispatcher)
+
+// WithBackoff sets the initial and maximum delay between retries
+func WithBackoff(initial, max time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.initialBackoff = initial
+		d.maxBackoff = max
+	}
+}
+
+// WithObserver reports action attempts and dead letters to observer
+func WithObserver(observer Observer) Option {
+	return func(d *Dispatcher) {
+		d.observer = observer
+	}
+}
+
+// NewDispatcher creates a new Dispatcher that builds actions from registry. deadLetters
+// may be nil, in which case failed actions are only reported to the caller.
+func NewDispatcher(registry *actions.Registry, deadLetters deadletter.Store, options ...Option) *Dispatcher {
+	d := &Dispatcher{
+		registry:       registry,
+		deadLetters:    deadLetters,
+		initialBackoff: DefaultInitialBackoff,
+		maxBackoff:     DefaultMaxBackoff,
+	}
+
+	for _, option := range options {
+		option(d)
+	}
+
+	return d
+}
+
+// Dispatch runs every action of the trigger for the event, in order.
+// Each action is retried independently; an action that fails after all retries
+// is stored as a dead letter and does not stop the remaining actions.
+func (d *Dispatcher) Dispatch(ctx context.Context, trigger *data.Trigger, event *data.Event) error {
+	var errs []error
+	for i, cfg := range actions.ForTrigger(trigger) {
+		if err := d.dispatchAction(ctx, trigger, event, i, cfg); err != nil {
+			errs = append(errs, fmt.Errorf("%s action %d: %w", cfg.Type, i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Redrive runs the failed action of a dead letter again using the trigger's current definition.
+// On success the dead letter is removed; otherwise the new attempts are appended to it.
+// The dead letter is claimed first, so that concurrent redrives do not run the action twice;
+// the losing redrive returns deadletter.ErrClaimed.
+func (d *Dispatcher) Redrive(ctx context.Context, trigger *data.Trigger, dl *data.DeadLetter) error {
+	if d.deadLetters == nil {
+		return fmt.Errorf("dead-letter store is not configured")
+	}
+
+	configs := actions.ForTrigger(trigger)
+	if dl.ActionIndex >= len(configs) || configs[dl.ActionIndex].Type != dl.ActionType {
+		return fmt.Errorf("trigger %s/%s no longer has a %s action at position %d", trigger.Namespace, trigger.ID, dl.ActionType, dl.ActionIndex)
+	}
+	cfg := configs[dl.ActionIndex]
+
+	now := time.Now()
+	claimed, err := d.deadLetters.Claim(ctx, dl.Namespace, dl.ID, now, now.Add(redriveLease))
+	if err != nil {
+		return err
+	}
+
+	attempts, err := d.run(ctx, trigger, &claimed.Event, cfg, len(claimed.Attempts))
+	if err == nil {
+		return d.deadLetters.Delete(ctx, claimed.Namespace, claimed.ID)
+	}
+
+	claimed.Attempts = append(claimed.Attempts, attempts...)
+	claimed.LastError = err.Error()
+	claimed.RedriveCount++
+	claimed.RedriveUntil = time.Time{}
+	claimed.UpdatedAt = time.Now()
+	if updateErr := d.deadLetters.Update(ctx, claimed); updateErr != nil {
+		return fmt.Errorf("redrive failed: %v; failed to update dead letter: %w", err, updateErr)
+	}
+
+	return err
+}
+
+// dispatchAction runs a single action and dead-letters the event if it fails
+func (d *Dispatcher) dispatchAction(ctx context.Context, trigger *data.Trigger, event *data.Event, index int, cfg data.ActionConfig) error {
+	attempts, err := d.run(ctx, trigger, event, cfg, 0)
+	if err == nil {
+		return nil
+	}
+
+	if d.deadLetters != nil {
+		now := time.Now()
+		dl := &data.DeadLetter{
+			ID:          primitive.NewObjectID().Hex(),
+			Namespace:   trigger.Namespace,
+			TriggerID:   trigger.ID,
+			ActionIndex: index,
+			ActionType:  cfg.Type,
+			Event:       *event,
+			Attempts:    attempts,
+			LastError:   err.Error(),
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		// The dead letter is stored even if the dispatch was cancelled, as happens on
+		// shutdown, since the event would otherwise be lost
+		addCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), deadLetterTimeout)
+		dlErr := d.deadLetters.Add(addCtx, dl)
+		cancel()
+		if dlErr != nil {
+			return fmt.Errorf("action failed: %v; failed to store dead letter: %w", err, dlErr)
+		}
+		if d.observer != nil {
+			d.observer.DeadLettered(trigger, cfg.Type)
+		}
+	}
+
+	return err
+}
+
+// run builds the action and executes it up to 1+retries times, returning every attempt made.
+// Attempt numbers continue from offset so redrives extend the existing history.
+func (d *Dispatcher) run(ctx context.Context, trigger *data.Trigger, event *data.Event, cfg data.ActionConfig, offset int) ([]data.DeliveryAttempt, error) {
+	action, err := d.registry.Build(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	retries := cfg.RetryCount
+	if retries == 0 {
+		retries = trigger.RetryCount
+	}
+	timeout := DefaultActionTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	} else if trigger.Timeout > 0 {
+		timeout = time.Duration(trigger.Timeout) * time.Second
+	}
+
+	var (
+		attempts []data.DeliveryAttempt
+		lastErr  error
+	)
+
+	for i := 0; i <= retries; i++ {
+		if i > 0 {
+			if err := d.wait(ctx, i); err != nil {
+				return attempts, fmt.Errorf("%w (last error: %v)", err, lastErr)
+			}
+		}
+
+		start := time.Now()
+		attemptCtx, span := tracer.Start(ctx, "action "+cfg.Type,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(tracing.TriggerAttributes(trigger)...),
+			trace.WithAttributes(
+				attribute.String("event.id", event.ID),
+				attribute.String("action.type", cfg.Type),
+				attribute.Int("action.attempt", offset+i+1),
+			),
+		)
+		attemptCtx, cancel := context.WithTimeout(attemptCtx, timeout)
+		attempt, err := action.Execute(attemptCtx, trigger, event)
+		cancel()
+		attempt.Attempt = offset + i + 1
+		attempt.StartedAt = start
+		attempt.Duration = time.Since(start)
+		if attempt.StatusCode != 0 {
+			span.SetAttributes(attribute.Int("http.response.status_code", attempt.StatusCode))
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		if d.observer != nil {
+			d.observer.ActionAttempted(trigger, cfg.Type, attempt.Duration, err)
+		}
+		if err == nil {
+			attempts = append(attempts, attempt)
+			return attempts, nil
+		}
+
+		attempt.Error = err.Error()
+		attempts = append(attempts, attempt)
+		lastErr = err
+
+		if actions.IsPermanent(err) {
+			break
+		}
+	}
+
+	return attempts, lastErr
+}
+
+// wait sleeps before the given retry using exponential backoff
+func (d *Dispatcher) wait(ctx context.Context, retry int) error {
+	if d.initialBackoff <= 0 {
+		return ctx.Err()
+	}
+
+	delay := d.initialBackoff << (retry - 1)
+	if delay > d.maxBackoff || delay <= 0 {
+		delay = d.maxBackoff
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,345 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"event/data"
+	"event/handlers/actions"
+	"event/handlers/deadletter"
+)
+
+// newTestServer returns a webhook receiver that fails the first `failures` requests
+func newTestServer(t *testing.T, failures int32) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if n <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("receiver unavailable"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &calls
+}
+
+func newTestEvent() *data.Event {
+	event := &data.Event{
+		ID:        "evt1",
+		EventType: "created",
+		Namespace: "sales",
+	}
+	event.Payload.After = map[string]interface{}{"amount": 1500}
+	return event
+}
+
+func TestDispatcher_RetriesThenSucceeds(t *testing.T) {
+	server, calls := newTestServer(t, 2)
+	deadLetters := deadletter.NewMemoryStore()
+	dispatcher := NewDispatcher(actions.NewDefaultRegistry(actions.Dependencies{}), deadLetters, WithBackoff(0, 0))
+
+	trigger := &data.Trigger{ID: "t1", Namespace: "sales", ActionURL: server.URL, RetryCount: 3}
+	if err := dispatcher.Dispatch(context.Background(), trigger, newTestEvent()); err != nil {
+		t.Fatalf("Dispatch() error = %v", err)
+	}
+
+	if *calls != 3 {
+		t.Errorf("webhook called %d times, want 3", *calls)
+	}
+	letters, _ := deadLetters.List(context.Background(), deadletter.Filter{Namespace: "sales"})
+	if len(letters) != 0 {
+		t.Errorf("got %d dead letters, want 0", len(letters))
+	}
+}
+
+func TestDispatcher_DeadLettersAfterRetries(t *testing.T) {
+	server, calls := newTestServer(t, 100)
+	deadLetters := deadletter.NewMemoryStore()
+	dispatcher := NewDispatcher(actions.NewDefaultRegistry(actions.Dependencies{}), deadLetters, WithBackoff(0, 0))
+
+	trigger := &data.Trigger{ID: "t1", Namespace: "sales", ActionURL: server.URL, RetryCount: 2}
+	if err := dispatcher.Dispatch(context.Background(), trigger, newTestEvent()); err == nil {
+		t.Fatal("Dispatch() expected error, got nil")
+	}
+
+	if *calls != 3 {
+		t.Errorf("webhook called %d times, want 3", *calls)
+	}
+
+	letters, _ := deadLetters.List(context.Background(), deadletter.Filter{Namespace: "sales"})
+	if len(letters) != 1 {
+		t.Fatalf("got %d dead letters, want 1", len(letters))
+	}
+	dl := letters[0]
+	if dl.TriggerID != "t1" || dl.Event.ID != "evt1" {
+		t.Errorf("dead letter = %s/%s, want t1/evt1", dl.TriggerID, dl.Event.ID)
+	}
+	if len(dl.Attempts) != 3 {
+		t.Fatalf("dead letter has %d attempts, want 3", len(dl.Attempts))
+	}
+	for i, attempt := range dl.Attempts {
+		if attempt.Attempt != i+1 {
+			t.Errorf("attempt %d numbered %d", i, attempt.Attempt)
+		}
+		if attempt.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("attempt %d status = %d, want 503", i, attempt.StatusCode)
+		}
+		if attempt.ResponseBody != "receiver unavailable" {
+			t.Errorf("attempt %d body = %q", i, attempt.ResponseBody)
+		}
+		if attempt.Error == "" {
+			t.Errorf("attempt %d has no error", i)
+		}
+	}
+}
+
+func TestDispatcher_Redrive(t *testing.T) {
+	server, _ := newTestServer(t, 2)
+	deadLetters := deadletter.NewMemoryStore()
+	dispatcher := NewDispatcher(actions.NewDefaultRegistry(actions.Dependencies{}), deadLetters, WithBackoff(0, 0))
+	ctx := context.Background()
+
+	trigger := &data.Trigger{ID: "t1", Namespace: "sales", ActionURL: server.URL}
+	if err := dispatcher.Dispatch(ctx, trigger, newTestEvent()); err == nil {
+		t.Fatal("Dispatch() expected error, got nil")
+	}
+
+	letters, _ := deadLetters.List(ctx, deadletter.Filter{Namespace: "sales"})
+	if len(letters) != 1 {
+		t.Fatalf("got %d dead letters, want 1", len(letters))
+	}
+	dl := letters[0]
+
+	// The receiver is still failing, so the attempt is appended
+	if err := dispatcher.Redrive(ctx, trigger, dl); err == nil {
+		t.Fatal("Redrive() expected error, got nil")
+	}
+	dl, err := deadLetters.Get(ctx, "sales", dl.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(dl.Attempts) != 2 || dl.Attempts[1].Attempt != 2 || dl.RedriveCount != 1 {
+		t.Errorf("after failed redrive: %d attempts, last numbered %d, redrive count %d",
+			len(dl.Attempts), dl.Attempts[len(dl.Attempts)-1].Attempt, dl.RedriveCount)
+	}
+
+	// The receiver has recovered, so the dead letter is removed
+	if err := dispatcher.Redrive(ctx, trigger, dl); err != nil {
+		t.Fatalf("Redrive() error = %v", err)
+	}
+	if _, err := deadLetters.Get(ctx, "sales", dl.ID); err != deadletter.ErrNotFound {
+		t.Errorf("Get() after successful redrive error = %v, want ErrNotFound", err)
+	}
+}
+
+// blockingAction is a test action that counts its calls and runs until it is released
+type blockingAction struct {
+	calls   int32
+	started chan struct{}
+	release chan struct{}
+}
+
+func (a *blockingAction) Execute(ctx context.Context, trigger *data.Trigger, event *data.Event) (data.DeliveryAttempt, error) {
+	atomic.AddInt32(&a.calls, 1)
+	a.started <- struct{}{}
+	<-a.release
+	return data.DeliveryAttempt{}, nil
+}
+
+func TestDispatcher_ConcurrentRedrive(t *testing.T) {
+	action := &blockingAction{started: make(chan struct{}, 2), release: make(chan struct{})}
+	registry := actions.NewRegistry()
+	registry.Register("block", func(config map[string]interface{}) (actions.Action, error) {
+		return action, nil
+	})
+	deadLetters := deadletter.NewMemoryStore()
+	dispatcher := NewDispatcher(registry, deadLetters, WithBackoff(0, 0))
+	ctx := context.Background()
+
+	trigger := &data.Trigger{ID: "t1", Namespace: "sales", Actions: []data.ActionConfig{{Type: "block"}}}
+	deadLetters.Add(ctx, &data.DeadLetter{ID: "dl1", Namespace: "sales", TriggerID: "t1", ActionType: "block", Event: *newTestEvent()})
+	letters, _ := deadLetters.List(ctx, deadletter.Filter{Namespace: "sales"})
+
+	first := make(chan error)
+	go func() {
+		first <- dispatcher.Redrive(ctx, trigger, letters[0])
+	}()
+	<-action.started
+
+	// The second redrive finds the dead letter claimed by the first
+	if err := dispatcher.Redrive(ctx, trigger, letters[0]); !errors.Is(err, deadletter.ErrClaimed) {
+		t.Errorf("concurrent Redrive() error = %v, want ErrClaimed", err)
+	}
+	close(action.release)
+	if err := <-first; err != nil {
+		t.Fatalf("Redrive() error = %v", err)
+	}
+
+	if calls := atomic.LoadInt32(&action.calls); calls != 1 {
+		t.Errorf("action ran %d times, want 1", calls)
+	}
+	if _, err := deadLetters.Get(ctx, "sales", "dl1"); !errors.Is(err, deadletter.ErrNotFound) {
+		t.Errorf("Get() after redrive error = %v, want ErrNotFound", err)
+	}
+}
+
+// recordingAction is a test action that fails a configurable number of times
+type recordingAction struct {
+	calls    *int
+	failures int
+	err      error
+}
+
+func (a *recordingAction) Execute(ctx context.Context, trigger *data.Trigger, event *data.Event) (data.DeliveryAttempt, error) {
+	*a.calls++
+	if *a.calls <= a.failures {
+		return data.DeliveryAttempt{}, a.err
+	}
+	return data.DeliveryAttempt{}, nil
+}
+
+func TestDispatcher_MultipleActions(t *testing.T) {
+	registry := actions.NewRegistry()
+	var okCalls, failCalls int
+	registry.Register("ok", func(config map[string]interface{}) (actions.Action, error) {
+		return &recordingAction{calls: &okCalls}, nil
+	})
+	registry.Register("fail", func(config map[string]interface{}) (actions.Action, error) {
+		return &recordingAction{calls: &failCalls, failures: 100, err: actions.Permanent(errors.New("bad request"))}, nil
+	})
+
+	deadLetters := deadletter.NewMemoryStore()
+	dispatcher := NewDispatcher(registry, deadLetters, WithBackoff(0, 0))
+
+	trigger := &data.Trigger{
+		ID:         "t1",
+		Namespace:  "sales",
+		RetryCount: 3,
+		Actions: []data.ActionConfig{
+			{Type: "fail"},
+			{Type: "ok"},
+		},
+	}
+	if err := dispatcher.Dispatch(context.Background(), trigger, newTestEvent()); err == nil {
+		t.Fatal("Dispatch() expected error, got nil")
+	}
+
+	// A failing action does not stop the next one, and permanent errors are not retried
+	if okCalls != 1 {
+		t.Errorf("ok action called %d times, want 1", okCalls)
+	}
+	if failCalls != 1 {
+		t.Errorf("fail action called %d times, want 1", failCalls)
+	}
+
+	letters, _ := deadLetters.List(context.Background(), deadletter.Filter{Namespace: "sales"})
+	if len(letters) != 1 {
+		t.Fatalf("got %d dead letters, want 1", len(letters))
+	}
+	if letters[0].ActionIndex != 0 || letters[0].ActionType != "fail" {
+		t.Errorf("dead letter action = %s #%d, want fail #0", letters[0].ActionType, letters[0].ActionIndex)
+	}
+}
+
+func TestDispatcher_UnknownActionType(t *testing.T) {
+	deadLetters := deadletter.NewMemoryStore()
+	dispatcher := NewDispatcher(actions.NewRegistry(), deadLetters, WithBackoff(0, 0))
+
+	trigger := &data.Trigger{ID: "t1", Namespace: "sales", Actions: []data.ActionConfig{{Type: "carrier-pigeon"}}}
+	if err := dispatcher.Dispatch(context.Background(), trigger, newTestEvent()); err == nil {
+		t.Fatal("Dispatch() expected error, got nil")
+	}
+
+	letters, _ := deadLetters.List(context.Background(), deadletter.Filter{Namespace: "sales"})
+	if len(letters) != 1 {
+		t.Fatalf("got %d dead letters, want 1", len(letters))
+	}
+}
+
+// recordingObserver counts the attempts and dead letters reported by a dispatcher
+type recordingObserver struct {
+	attempts, failures, deadLetters int
+}
+
+func (o *recordingObserver) ActionAttempted(trigger *data.Trigger, actionType string, duration time.Duration, err error) {
+	o.attempts++
+	if err != nil {
+		o.failures++
+	}
+}
+
+func (o *recordingObserver) DeadLettered(trigger *data.Trigger, actionType string) {
+	o.deadLetters++
+}
+
+func TestDispatcher_Observer(t *testing.T) {
+	tests := []struct {
+		name            string
+		failures        int32
+		wantAttempts    int
+		wantFailures    int
+		wantDeadLetters int
+	}{
+		{"succeeds after a retry", 1, 2, 1, 0},
+		{"dead-lettered after retries", 100, 3, 3, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTestServer(t, tt.failures)
+			observer := &recordingObserver{}
+			dispatcher := NewDispatcher(actions.NewDefaultRegistry(actions.Dependencies{}), deadletter.NewMemoryStore(),
+				WithBackoff(0, 0), WithObserver(observer))
+
+			trigger := &data.Trigger{ID: "t1", Namespace: "sales", ActionURL: server.URL, RetryCount: 2}
+			dispatcher.Dispatch(context.Background(), trigger, newTestEvent())
+
+			if observer.attempts != tt.wantAttempts || observer.failures != tt.wantFailures || observer.deadLetters != tt.wantDeadLetters {
+				t.Errorf("observed %d attempts, %d failures, %d dead letters, want %d, %d, %d",
+					observer.attempts, observer.failures, observer.deadLetters, tt.wantAttempts, tt.wantFailures, tt.wantDeadLetters)
+			}
+		})
+	}
+}
+
+// cancelAwareStore fails like a database client once its context is done
+type cancelAwareStore struct {
+	*deadletter.MemoryStore
+}
+
+func (s cancelAwareStore) Add(ctx context.Context, dl *data.DeadLetter) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.MemoryStore.Add(ctx, dl)
+}
+
+func TestDispatcher_DeadLettersWhenCancelled(t *testing.T) {
+	server, _ := newTestServer(t, 100)
+	deadLetters := deadletter.NewMemoryStore()
+	dispatcher := NewDispatcher(actions.NewDefaultRegistry(actions.Dependencies{}), cancelAwareStore{deadLetters}, WithBackoff(time.Hour, time.Hour))
+
+	// Shutting down cancels the dispatch while it waits to retry
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	trigger := &data.Trigger{ID: "t1", Namespace: "sales", ActionURL: server.URL, RetryCount: 3}
+	if err := dispatcher.Dispatch(ctx, trigger, newTestEvent()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Dispatch() error = %v, want context.Canceled", err)
+	}
+
+	letters, _ := deadLetters.List(context.Background(), deadletter.Filter{Namespace: "sales"})
+	if len(letters) != 1 || len(letters[0].Attempts) != 1 {
+		t.Fatalf("got %v dead letters, want 1 with 1 attempt", letters)
+	}
+}
@@ -0,0 +1,208 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"event/data"
+)
+
+const (
+	// DefaultLeaseTTL is used when a claim or heartbeat does not set a lease duration
+	DefaultLeaseTTL = 30 * time.Second
+	// ErrCodeLeaseExpired is the error code of jobs whose lease expired after all retries
+	ErrCodeLeaseExpired = "LEASE_EXPIRED"
+)
+
+var (
+	// ErrNoJobs is returned by Claim when no job is waiting for a worker
+	ErrNoJobs = errors.New("no jobs to claim")
+	// ErrLeaseLost is returned when a worker no longer holds the lease of a job
+	ErrLeaseLost = errors.New("job lease lost")
+	// ErrCanceled is returned to the worker holding the lease of a canceled job
+	ErrCanceled = errors.New("job canceled")
+)
+
+// Reasons identify ErrLeaseLost and ErrCanceled in errors that are sent to remote workers,
+// such as the JobService's gRPC errors
+const (
+	ReasonLeaseLost = "LEASE_LOST"
+	ReasonCanceled  = "JOB_CANCELED"
+)
+
+// ClaimRequest describes the jobs a worker is willing to run
+type ClaimRequest struct {
+	JobType   string
+	Namespace string // Optional, claims from every namespace when empty
+	WorkerID  string
+	TTL       time.Duration // Lease duration, defaults to DefaultLeaseTTL
+}
+
+// Claim leases the oldest started job of the requested type that has no live lease.
+// It returns ErrNoJobs if there is none.
+func (o *Orchestrator) Claim(ctx context.Context, req ClaimRequest) (*data.Job, error) {
+	if req.JobType == "" || req.WorkerID == "" {
+		return nil, fmt.Errorf("%w: job_type and worker_id are required", ErrInvalidJob)
+	}
+	ttl := req.TTL
+	if ttl <= 0 {
+		ttl = DefaultLeaseTTL
+	}
+
+	candidates, err := o.store.List(ctx, Filter{
+		Namespace: req.Namespace,
+		JobType:   req.JobType,
+		Statuses:  []string{data.JobStatusStarted},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, candidate := range candidates {
+		if leased(candidate, o.now()) {
+			continue
+		}
+
+		job, err := o.transition(ctx, candidate.Namespace, candidate.JobID, func(job *data.Job) error {
+			if job.Status != data.JobStatusStarted || leased(job, o.now()) {
+				return ErrLeaseLost
+			}
+			job.LeaseOwner = req.WorkerID
+			job.LeaseExpiresAt = o.now().Add(ttl)
+			return nil
+		})
+		if errors.Is(err, ErrLeaseLost) {
+			continue // Claimed by another worker in the meantime
+		}
+		if err != nil {
+			return nil, err
+		}
+		return job, nil
+	}
+
+	return nil, ErrNoJobs
+}
+
+// Heartbeat extends the lease of a job held by workerID and records its progress percentage.
+// It returns ErrCanceled if the job was canceled and ErrLeaseLost if the worker no longer
+// holds the lease; in both cases the worker should stop working on the job.
+func (o *Orchestrator) Heartbeat(ctx context.Context, namespace, id, workerID string, progress int, ttl time.Duration) (*data.Job, error) {
+	if ttl <= 0 {
+		ttl = DefaultLeaseTTL
+	}
+
+	return o.transition(ctx, namespace, id, func(job *data.Job) error {
+		if err := checkLease(job, workerID); err != nil {
+			return err
+		}
+		job.LeaseExpiresAt = o.now().Add(ttl)
+		if progress > job.Progress && progress <= 100 {
+			job.Progress = progress
+		}
+		return nil
+	})
+}
+
+// ReportResult finishes a job held by workerID. The job completes with result if jobErr
+// is nil and fails with jobErr otherwise.
+func (o *Orchestrator) ReportResult(ctx context.Context, namespace, id, workerID string, result map[string]interface{}, jobErr *data.JobError) (*data.Job, error) {
+	job, err := o.store.Get(ctx, namespace, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkLease(job, workerID); err != nil {
+		return nil, err
+	}
+
+	// Complete and Fail re-check the job's state, so a concurrent cancellation still wins
+	if jobErr != nil {
+		return o.Fail(ctx, namespace, id, jobErr)
+	}
+	return o.Complete(ctx, namespace, id, result)
+}
+
+// ExpireLeases re-queues started jobs whose lease has expired, incrementing their retry
+// count. Jobs that have used up MaxRetries fail with LEASE_EXPIRED. It returns the number
+// of expired leases.
+func (o *Orchestrator) ExpireLeases(ctx context.Context) (int, error) {
+	started, err := o.store.List(ctx, Filter{Statuses: []string{data.JobStatusStarted}})
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		expired int
+		errs    []error
+	)
+	for _, candidate := range started {
+		if candidate.LeaseOwner == "" || leased(candidate, o.now()) {
+			continue
+		}
+
+		if candidate.Retries >= candidate.MaxRetries {
+			_, err := o.fail(ctx, candidate.Namespace, candidate.JobID, &data.JobError{
+				Code:    ErrCodeLeaseExpired,
+				Message: fmt.Sprintf("lease of worker %s expired", candidate.LeaseOwner),
+				Details: map[string]interface{}{"worker_id": candidate.LeaseOwner, "retries": candidate.Retries},
+			}, func(job *data.Job) error {
+				if job.LeaseOwner == "" || leased(job, o.now()) {
+					return ErrLeaseLost // Renewed in the meantime
+				}
+				return nil
+			})
+			if errors.Is(err, ErrLeaseLost) {
+				continue
+			}
+			if err != nil && !errors.Is(err, ErrInvalidTransition) {
+				errs = append(errs, err)
+				continue
+			}
+			expired++
+			continue
+		}
+
+		_, err := o.transition(ctx, candidate.Namespace, candidate.JobID, func(job *data.Job) error {
+			if job.Status != data.JobStatusStarted || job.LeaseOwner == "" || leased(job, o.now()) {
+				return ErrLeaseLost // Finished or renewed in the meantime
+			}
+			job.Retries++
+			job.Progress = 0
+			clearLease(job)
+			return nil
+		})
+		if errors.Is(err, ErrLeaseLost) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		expired++
+	}
+
+	return expired, errors.Join(errs...)
+}
+
+// leased reports whether a job has a lease that has not expired
+func leased(job *data.Job, now time.Time) bool {
+	return job.LeaseOwner != "" && now.Before(job.LeaseExpiresAt)
+}
+
+// checkLease returns an error unless workerID holds the lease of a started job
+func checkLease(job *data.Job, workerID string) error {
+	if job.Status == data.JobStatusCanceled {
+		return ErrCanceled
+	}
+	if job.Status != data.JobStatusStarted || job.LeaseOwner != workerID {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// clearLease removes a job's lease
+func clearLease(job *data.Job) {
+	job.LeaseOwner = ""
+	job.LeaseExpiresAt = time.Time{}
+}
@@ -0,0 +1,194 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"event/data"
+)
+
+// newTestOrchestrator returns an orchestrator whose clock is controlled by the returned function
+func newTestOrchestrator(publisher Publisher) (*Orchestrator, func(time.Duration)) {
+	now := time.Date(2025, 4, 16, 14, 0, 0, 0, time.UTC)
+	o := NewOrchestrator(NewMemoryStore(), publisher)
+	o.now = func() time.Time { return now }
+	return o, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestOrchestrator_ClaimAndHeartbeat(t *testing.T) {
+	ctx := context.Background()
+	o, advance := newTestOrchestrator(nil)
+
+	if _, err := o.Submit(ctx, &data.Job{JobID: "a", JobType: "resize_image", Namespace: "media"}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	if _, err := o.Claim(ctx, ClaimRequest{JobType: "other", WorkerID: "w1"}); !errors.Is(err, ErrNoJobs) {
+		t.Errorf("Claim(other type) error = %v, want ErrNoJobs", err)
+	}
+
+	job, err := o.Claim(ctx, ClaimRequest{JobType: "resize_image", WorkerID: "w1", TTL: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if job.LeaseOwner != "w1" {
+		t.Errorf("LeaseOwner = %q, want w1", job.LeaseOwner)
+	}
+
+	// The job is leased, so a second worker gets nothing
+	if _, err := o.Claim(ctx, ClaimRequest{JobType: "resize_image", WorkerID: "w2"}); !errors.Is(err, ErrNoJobs) {
+		t.Errorf("second Claim() error = %v, want ErrNoJobs", err)
+	}
+
+	advance(5 * time.Second)
+	job, err = o.Heartbeat(ctx, "media", "a", "w1", 40, 10*time.Second)
+	if err != nil {
+		t.Fatalf("Heartbeat() error = %v", err)
+	}
+	if job.Progress != 40 || !job.LeaseExpiresAt.Equal(o.now().Add(10*time.Second)) {
+		t.Errorf("after heartbeat: progress %d, lease until %v", job.Progress, job.LeaseExpiresAt)
+	}
+
+	if _, err := o.Heartbeat(ctx, "media", "a", "w2", 50, 0); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Heartbeat(other worker) error = %v, want ErrLeaseLost", err)
+	}
+	if _, err := o.ReportResult(ctx, "media", "a", "w2", nil, nil); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("ReportResult(other worker) error = %v, want ErrLeaseLost", err)
+	}
+
+	job, err = o.ReportResult(ctx, "media", "a", "w1", nil, &data.JobError{Code: "IMG_TOO_LARGE", Message: "Resize failed"})
+	if err != nil {
+		t.Fatalf("ReportResult() error = %v", err)
+	}
+	if job.Status != data.JobStatusFailed || job.Error.Code != "IMG_TOO_LARGE" || job.LeaseOwner != "" {
+		t.Errorf("job = %s %+v lease %q, want failed with IMG_TOO_LARGE and no lease", job.Status, job.Error, job.LeaseOwner)
+	}
+}
+
+func TestOrchestrator_ExpireLeases(t *testing.T) {
+	ctx := context.Background()
+	publisher := &recordingPublisher{}
+	o, advance := newTestOrchestrator(publisher)
+
+	if _, err := o.Submit(ctx, &data.Job{JobID: "a", JobType: "resize_image", Namespace: "media", MaxRetries: 1}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	if _, err := o.Claim(ctx, ClaimRequest{JobType: "resize_image", WorkerID: "w1", TTL: 10 * time.Second}); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	advance(11 * time.Second)
+
+	// First expiry: re-queued and announced again
+	if n, err := o.ExpireLeases(ctx); err != nil || n != 1 {
+		t.Fatalf("ExpireLeases() = %d, %v, want 1, nil", n, err)
+	}
+	job, _ := o.Store().Get(ctx, "media", "a")
+	if job.Status != data.JobStatusStarted || job.Retries != 1 || job.LeaseOwner != "" {
+		t.Errorf("after first expiry: %s, retries %d, lease %q", job.Status, job.Retries, job.LeaseOwner)
+	}
+	if got := publisher.subjects[len(publisher.subjects)-1]; got != "event.media.resize_image.started" {
+		t.Errorf("last subject = %s, want a second started event", got)
+	}
+
+	// The stale worker can no longer report
+	if _, err := o.Claim(ctx, ClaimRequest{JobType: "resize_image", WorkerID: "w2", TTL: 10 * time.Second}); err != nil {
+		t.Fatalf("Claim() after expiry error = %v", err)
+	}
+	if _, err := o.ReportResult(ctx, "media", "a", "w1", nil, nil); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("ReportResult(stale worker) error = %v, want ErrLeaseLost", err)
+	}
+
+	// Second expiry: retries are used up
+	advance(11 * time.Second)
+	if n, err := o.ExpireLeases(ctx); err != nil || n != 1 {
+		t.Fatalf("ExpireLeases() = %d, %v, want 1, nil", n, err)
+	}
+	job, _ = o.Store().Get(ctx, "media", "a")
+	if job.Status != data.JobStatusFailed || job.Error == nil || job.Error.Code != ErrCodeLeaseExpired {
+		t.Errorf("after second expiry: %s %+v, want failed with %s", job.Status, job.Error, ErrCodeLeaseExpired)
+	}
+}
+
+// listHookStore runs afterList once, right after its first List returns
+type listHookStore struct {
+	Store
+	afterList func()
+}
+
+func (s *listHookStore) List(ctx context.Context, filter Filter) ([]*data.Job, error) {
+	jobs, err := s.Store.List(ctx, filter)
+	if s.afterList != nil {
+		s.afterList()
+		s.afterList = nil
+	}
+	return jobs, err
+}
+
+func TestOrchestrator_ExpireLeasesSkipsRenewedLease(t *testing.T) {
+	ctx := context.Background()
+	store := &listHookStore{Store: NewMemoryStore()}
+	now := time.Date(2025, 4, 16, 14, 0, 0, 0, time.UTC)
+	o := NewOrchestrator(store, nil)
+	o.now = func() time.Time { return now }
+
+	if _, err := o.Submit(ctx, &data.Job{JobID: "a", JobType: "resize_image", Namespace: "media"}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if _, err := o.Claim(ctx, ClaimRequest{JobType: "resize_image", WorkerID: "w1", TTL: 10 * time.Second}); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	now = now.Add(11 * time.Second)
+
+	// The worker heartbeats after the expired lease was listed, before the job is failed
+	store.afterList = func() {
+		if _, err := o.Heartbeat(ctx, "media", "a", "w1", 10, 10*time.Second); err != nil {
+			t.Errorf("Heartbeat() error = %v", err)
+		}
+	}
+	if n, err := o.ExpireLeases(ctx); err != nil || n != 0 {
+		t.Fatalf("ExpireLeases() = %d, %v, want 0, nil", n, err)
+	}
+	job, _ := o.Store().Get(ctx, "media", "a")
+	if job.Status != data.JobStatusStarted || job.LeaseOwner != "w1" {
+		t.Errorf("after renewed lease: %s, lease %q, want started by w1", job.Status, job.LeaseOwner)
+	}
+}
+
+func TestOrchestrator_Cancel(t *testing.T) {
+	ctx := context.Background()
+	o, _ := newTestOrchestrator(nil)
+
+	if _, err := o.SubmitAll(ctx, []*data.Job{
+		{JobID: "a", JobType: "resize_image", Namespace: "media"},
+		{JobID: "b", JobType: "upload", Namespace: "media", DependsOn: []string{"a"}},
+	}); err != nil {
+		t.Fatalf("SubmitAll() error = %v", err)
+	}
+	if _, err := o.Claim(ctx, ClaimRequest{JobType: "resize_image", WorkerID: "w1"}); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+
+	job, err := o.Cancel(ctx, "media", "a", "no longer needed")
+	if err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if job.Status != data.JobStatusCanceled || job.Error.Code != ErrCodeCanceled {
+		t.Errorf("canceled job = %s %+v", job.Status, job.Error)
+	}
+
+	if _, err := o.Heartbeat(ctx, "media", "a", "w1", 10, 0); !errors.Is(err, ErrCanceled) {
+		t.Errorf("Heartbeat(canceled) error = %v, want ErrCanceled", err)
+	}
+
+	dependent, _ := o.Store().Get(ctx, "media", "b")
+	if dependent.Status != data.JobStatusFailed || dependent.Error.Code != ErrCodeUpstreamFailed {
+		t.Errorf("dependent = %s %+v, want failed with %s", dependent.Status, dependent.Error, ErrCodeUpstreamFailed)
+	}
+
+	if _, err := o.Cancel(ctx, "media", "a", ""); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("second Cancel() error = %v, want ErrInvalidTransition", err)
+	}
+}
@@ -0,0 +1,149 @@
+package jobs
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+
+	"event/data"
+)
+
+// Compile-time check to ensure MemoryStore implements Store
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore is an in-memory job store, intended for tests and local development
+type MemoryStore struct {
+	jobs map[string]*data.Job // job ID -> Job
+	mu   sync.RWMutex
+}
+
+// NewMemoryStore creates a new in-memory job store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		jobs: make(map[string]*data.Job),
+	}
+}
+
+// Create stores a new job, returning ErrExists if its ID is taken
+func (s *MemoryStore) Create(ctx context.Context, job *data.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[job.JobID]; ok {
+		return ErrExists
+	}
+	s.jobs[job.JobID] = copyJob(job)
+	return nil
+}
+
+// Get returns a job by namespace and ID
+func (s *MemoryStore) Get(ctx context.Context, namespace, id string) (*data.Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok || job.Namespace != namespace {
+		return nil, ErrNotFound
+	}
+	return copyJob(job), nil
+}
+
+// List returns the jobs matching the filter, oldest first
+func (s *MemoryStore) List(ctx context.Context, filter Filter) ([]*data.Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var jobs []*data.Job
+	for _, job := range s.jobs {
+		if matches(job, filter) {
+			jobs = append(jobs, copyJob(job))
+		}
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].JobID < jobs[j].JobID
+		}
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+
+	if filter.Limit > 0 && len(jobs) > filter.Limit {
+		jobs = jobs[:filter.Limit]
+	}
+	return jobs, nil
+}
+
+// Update replaces a job if its version is unchanged since it was read
+func (s *MemoryStore) Update(ctx context.Context, job *data.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.jobs[job.JobID]
+	if !ok || current.Namespace != job.Namespace {
+		return ErrNotFound
+	}
+	if current.Version != job.Version {
+		return ErrConflict
+	}
+
+	job.Version++
+	s.jobs[job.JobID] = copyJob(job)
+	return nil
+}
+
+// Delete removes a job
+func (s *MemoryStore) Delete(ctx context.Context, namespace, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.jobs[id]; ok && job.Namespace == namespace {
+		delete(s.jobs, id)
+	}
+	return nil
+}
+
+// matches reports whether a job matches the filter
+func matches(job *data.Job, filter Filter) bool {
+	if filter.Namespace != "" && job.Namespace != filter.Namespace {
+		return false
+	}
+	if len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, job.Status) {
+		return false
+	}
+	if filter.JobType != "" && job.JobType != filter.JobType {
+		return false
+	}
+	if filter.TriggeredBy != "" && job.TriggeredBy != filter.TriggeredBy {
+		return false
+	}
+	if filter.DependsOn != "" && !slices.Contains(job.DependsOn, filter.DependsOn) {
+		return false
+	}
+	return true
+}
+
+// copyJob returns a copy of job that shares no slices or top-level maps with it
+func copyJob(job *data.Job) *data.Job {
+	c := *job
+	c.DependsOn = slices.Clone(job.DependsOn)
+	c.Inputs = copyMap(job.Inputs)
+	c.Result = copyMap(job.Result)
+	if job.Error != nil {
+		e := *job.Error
+		e.Details = copyMap(job.Error.Details)
+		c.Error = &e
+	}
+	return &c
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,145 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"event/data"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultCollection is the default MongoDB collection for jobs
+const DefaultCollection = "jobs"
+
+// Compile-time check to ensure MongoStore implements Store
+var _ Store = (*MongoStore)(nil)
+
+// MongoStore is a job store backed by a MongoDB collection
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoStore creates a new MongoDB-backed job store and ensures its indexes exist
+func NewMongoStore(ctx context.Context, db *mongo.Database, collectionName string) (*MongoStore, error) {
+	if collectionName == "" {
+		collectionName = DefaultCollection
+	}
+	// Decode nested documents (inputs, results, payloads) as maps rather than bson.D
+	collection := db.Collection(collectionName, options.Collection().SetBSONOptions(&options.BSONOptions{DefaultDocumentM: true}))
+
+	indexModels := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "depends_on", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "triggered_by", Value: 1}},
+		},
+	}
+	if _, err := collection.Indexes().CreateMany(ctx, indexModels); err != nil {
+		return nil, fmt.Errorf("failed to create job indexes: %w", err)
+	}
+
+	return &MongoStore{
+		collection: collection,
+	}, nil
+}
+
+// Create stores a new job, returning ErrExists if its ID is taken
+func (s *MongoStore) Create(ctx context.Context, job *data.Job) error {
+	if _, err := s.collection.InsertOne(ctx, job); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrExists
+		}
+		return fmt.Errorf("failed to insert job: %w", err)
+	}
+	return nil
+}
+
+// Get returns a job by namespace and ID
+func (s *MongoStore) Get(ctx context.Context, namespace, id string) (*data.Job, error) {
+	var job data.Job
+	err := s.collection.FindOne(ctx, bson.M{"_id": id, "namespace": namespace}).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	return &job, nil
+}
+
+// List returns the jobs matching the filter, oldest first
+func (s *MongoStore) List(ctx context.Context, filter Filter) ([]*data.Job, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+
+	cursor, err := s.collection.Find(ctx, filterToBSON(filter), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var jobs []*data.Job
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to decode jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// Update replaces a job if its version is unchanged since it was read
+func (s *MongoStore) Update(ctx context.Context, job *data.Job) error {
+	version := job.Version
+	job.Version++
+
+	result, err := s.collection.ReplaceOne(ctx, bson.M{"_id": job.JobID, "namespace": job.Namespace, "version": version}, job)
+	if err != nil {
+		job.Version = version
+		return fmt.Errorf("failed to update job: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		job.Version = version
+		if _, err := s.Get(ctx, job.Namespace, job.JobID); err != nil {
+			return err
+		}
+		return ErrConflict
+	}
+	return nil
+}
+
+// Delete removes a job
+func (s *MongoStore) Delete(ctx context.Context, namespace, id string) error {
+	if _, err := s.collection.DeleteOne(ctx, bson.M{"_id": id, "namespace": namespace}); err != nil {
+		return fmt.Errorf("failed to delete job: %w", err)
+	}
+	return nil
+}
+
+// filterToBSON converts a Filter into a MongoDB query document
+func filterToBSON(filter Filter) bson.M {
+	query := bson.M{}
+	if filter.Namespace != "" {
+		query["namespace"] = filter.Namespace
+	}
+	if len(filter.Statuses) > 0 {
+		query["status"] = bson.M{"$in": filter.Statuses}
+	}
+	if filter.JobType != "" {
+		query["job_type"] = filter.JobType
+	}
+	if filter.TriggeredBy != "" {
+		query["triggered_by"] = filter.TriggeredBy
+	}
+	if filter.DependsOn != "" {
+		query["depends_on"] = filter.DependsOn
+	}
+	return query
+}
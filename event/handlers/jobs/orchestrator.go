@@ -0,0 +1,546 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"event/data"
+)
+
+const (
+	// DefaultActorID is the actor of the lifecycle events emitted by the orchestrator
+	DefaultActorID = "job_orchestrator"
+	// ErrCodeUpstreamFailed is the error code of jobs failed because a dependency failed or was canceled
+	ErrCodeUpstreamFailed = "UPSTREAM_FAILED"
+	// ErrCodeCanceled is the error code of canceled jobs
+	ErrCodeCanceled = "CANCELED"
+	// maxConflictRetries bounds how often a transition is retried after a concurrent update
+	maxConflictRetries = 5
+)
+
+var (
+	// ErrInvalidJob is returned when a submitted job or batch is malformed
+	ErrInvalidJob = errors.New("invalid job")
+	// ErrInvalidTransition is returned when a job is not in a state that allows the requested change
+	ErrInvalidTransition = errors.New("invalid job state transition")
+)
+
+// Publisher publishes job lifecycle events. *nats.Conn implements it.
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// Orchestrator persists jobs and moves them through their lifecycle, resolving
+// DependsOn as a DAG: a job starts once all of its dependencies have completed,
+// and fails as soon as any of them fails.
+type Orchestrator struct {
+	store     Store
+	publisher Publisher
+	actorID   string
+	now       func() time.Time
+}
+
+// Option is a function that configures an Orchestrator
+type Option func(*Orchestrator)
+
+// WithActorID sets the actor ID of the emitted lifecycle events
+func WithActorID(id string) Option {
+	return func(o *Orchestrator) {
+		o.actorID = id
+	}
+}
+
+// NewOrchestrator creates a new Orchestrator. publisher may be nil, in which case
+// no lifecycle events are emitted.
+func NewOrchestrator(store Store, publisher Publisher, options ...Option) *Orchestrator {
+	o := &Orchestrator{
+		store:     store,
+		publisher: publisher,
+		actorID:   DefaultActorID,
+		now:       func() time.Time { return time.Now().UTC() },
+	}
+
+	for _, option := range options {
+		option(o)
+	}
+
+	return o
+}
+
+// Store returns the orchestrator's job store
+func (o *Orchestrator) Store() Store {
+	return o.store
+}
+
+// Submit stores a new job and starts it if its dependencies have already completed
+func (o *Orchestrator) Submit(ctx context.Context, job *data.Job) (*data.Job, error) {
+	jobs, err := o.SubmitAll(ctx, []*data.Job{job})
+	if err != nil {
+		return nil, err
+	}
+	return jobs[0], nil
+}
+
+// SubmitAll stores a batch of jobs that may depend on each other and on existing jobs.
+// The batch is rejected if a dependency does not exist or the dependencies form a cycle.
+// Jobs are stored in dependency order, and jobs whose dependencies have all completed are started.
+// If a job cannot be stored, the jobs of the batch stored before it are deleted again, so
+// that the caller can resubmit the batch.
+func (o *Orchestrator) SubmitAll(ctx context.Context, jobs []*data.Job) ([]*data.Job, error) {
+	now := o.now()
+	batch := make(map[string]*data.Job, len(jobs))
+	for _, job := range jobs {
+		if job.Namespace == "" || job.JobType == "" {
+			return nil, fmt.Errorf("%w: namespace and job_type are required", ErrInvalidJob)
+		}
+		if job.JobID == "" {
+			job.JobID = data.NewJobID()
+		}
+		if _, dup := batch[job.JobID]; dup {
+			return nil, fmt.Errorf("%w: duplicate job ID %s", ErrInvalidJob, job.JobID)
+		}
+		batch[job.JobID] = job
+
+		resetToPending(job)
+		job.CreatedAt = now
+		job.UpdatedAt = now
+		job.Version = 0
+	}
+
+	ordered, err := o.resolve(ctx, jobs, batch)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, job := range ordered {
+		if err := o.store.Create(ctx, job); err != nil {
+			err = fmt.Errorf("failed to store job %s: %w", job.JobID, err)
+			return nil, errors.Join(err, o.deleteAll(ctx, ordered[:i]))
+		}
+	}
+
+	// Start or fail the jobs whose dependencies are already finished
+	for _, job := range ordered {
+		if err := o.advance(ctx, job.Namespace, job.JobID); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]*data.Job, 0, len(jobs))
+	for _, job := range jobs {
+		current, err := o.store.Get(ctx, job.Namespace, job.JobID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reload job %s: %w", job.JobID, err)
+		}
+		result = append(result, current)
+	}
+	return result, nil
+}
+
+// deleteAll deletes the jobs of a batch that could not be stored completely. It is not
+// cancelled with ctx, as the jobs would otherwise be started without the rest of the batch.
+func (o *Orchestrator) deleteAll(ctx context.Context, jobs []*data.Job) error {
+	ctx = context.WithoutCancel(ctx)
+	var errs []error
+	for _, job := range jobs {
+		if err := o.store.Delete(ctx, job.Namespace, job.JobID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete job %s of the incomplete batch: %w", job.JobID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Complete marks a started job as completed and advances the jobs that depend on it
+func (o *Orchestrator) Complete(ctx context.Context, namespace, id string, result map[string]interface{}) (*data.Job, error) {
+	job, err := o.transition(ctx, namespace, id, func(job *data.Job) error {
+		if job.Status != data.JobStatusStarted {
+			return fmt.Errorf("%w: job %s is %s", ErrInvalidTransition, id, job.Status)
+		}
+		job.Status = data.JobStatusCompleted
+		job.Result = result
+		job.CompletedAt = o.now()
+		job.Progress = 100
+		clearLease(job)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return job, o.advanceDependents(ctx, job)
+}
+
+// Fail marks a job as failed and fails every job that depends on it
+func (o *Orchestrator) Fail(ctx context.Context, namespace, id string, jobErr *data.JobError) (*data.Job, error) {
+	return o.fail(ctx, namespace, id, jobErr, nil)
+}
+
+// fail fails a job with jobErr unless it is finished or check, if set, rejects it. check
+// sees the job as stored, so it can re-check what the caller decided on.
+func (o *Orchestrator) fail(ctx context.Context, namespace, id string, jobErr *data.JobError, check func(job *data.Job) error) (*data.Job, error) {
+	if jobErr == nil {
+		jobErr = &data.JobError{Code: "UNKNOWN", Message: "job failed"}
+	}
+	if jobErr.Timestamp.IsZero() {
+		jobErr.Timestamp = o.now()
+	}
+
+	job, err := o.transition(ctx, namespace, id, func(job *data.Job) error {
+		if job.IsFinished() {
+			return fmt.Errorf("%w: job %s is already %s", ErrInvalidTransition, id, job.Status)
+		}
+		if check != nil {
+			if err := check(job); err != nil {
+				return err
+			}
+		}
+		job.Status = data.JobStatusFailed
+		job.Error = jobErr
+		job.CompletedAt = o.now()
+		clearLease(job)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return job, o.advanceDependents(ctx, job)
+}
+
+// Cancel stops a pending or started job and fails every job that depends on it.
+// The worker running the job learns about the cancellation on its next heartbeat.
+func (o *Orchestrator) Cancel(ctx context.Context, namespace, id, reason string) (*data.Job, error) {
+	if reason == "" {
+		reason = "job canceled"
+	}
+
+	job, err := o.transition(ctx, namespace, id, func(job *data.Job) error {
+		if job.IsFinished() {
+			return fmt.Errorf("%w: job %s is already %s", ErrInvalidTransition, id, job.Status)
+		}
+		job.Status = data.JobStatusCanceled
+		job.Error = &data.JobError{Code: ErrCodeCanceled, Message: reason, Timestamp: o.now()}
+		job.CompletedAt = o.now()
+		clearLease(job)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return job, o.advanceDependents(ctx, job)
+}
+
+// Retry resubmits a failed or canceled job. Dependents that failed because of it are
+// reset to pending as well, so the rest of the graph runs once the job completes.
+func (o *Orchestrator) Retry(ctx context.Context, namespace, id string) (*data.Job, error) {
+	if _, err := o.transition(ctx, namespace, id, func(job *data.Job) error {
+		if job.Status != data.JobStatusFailed && job.Status != data.JobStatusCanceled {
+			return fmt.Errorf("%w: job %s is %s", ErrInvalidTransition, id, job.Status)
+		}
+		resetToPending(job)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := o.resetDependents(ctx, namespace, id); err != nil {
+		return nil, err
+	}
+	if err := o.advance(ctx, namespace, id); err != nil {
+		return nil, err
+	}
+	return o.store.Get(ctx, namespace, id)
+}
+
+// resetDependents resets the jobs that failed because the given job did not complete
+func (o *Orchestrator) resetDependents(ctx context.Context, namespace, id string) error {
+	dependents, err := o.store.List(ctx, Filter{
+		Namespace: namespace,
+		Statuses:  []string{data.JobStatusFailed},
+		DependsOn: id,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list dependents of job %s: %w", id, err)
+	}
+
+	for _, dependent := range dependents {
+		if dependent.Error == nil || dependent.Error.Code != ErrCodeUpstreamFailed {
+			continue
+		}
+		_, err := o.transition(ctx, namespace, dependent.JobID, func(job *data.Job) error {
+			if job.Status != data.JobStatusFailed {
+				return fmt.Errorf("%w: job %s is %s", ErrInvalidTransition, job.JobID, job.Status)
+			}
+			resetToPending(job)
+			return nil
+		})
+		if errors.Is(err, ErrInvalidTransition) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := o.resetDependents(ctx, namespace, dependent.JobID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resetToPending clears the outcome of a finished job so it can run again
+func resetToPending(job *data.Job) {
+	job.Status = data.JobStatusPending
+	job.Result = nil
+	job.Error = nil
+	job.StartedAt = time.Time{}
+	job.CompletedAt = time.Time{}
+	job.Retries = 0
+	job.Progress = 0
+	clearLease(job)
+}
+
+// Reconcile advances every pending job whose dependencies have finished. It recovers
+// from a crash between a job finishing and its dependents being advanced.
+func (o *Orchestrator) Reconcile(ctx context.Context) error {
+	pending, err := o.store.List(ctx, Filter{Statuses: []string{data.JobStatusPending}})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, job := range pending {
+		if err := o.advance(ctx, job.Namespace, job.JobID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// resolve checks that every dependency exists, either in the batch or in the store,
+// and returns the batch in dependency order
+func (o *Orchestrator) resolve(ctx context.Context, jobs []*data.Job, batch map[string]*data.Job) ([]*data.Job, error) {
+	for _, job := range jobs {
+		for _, dep := range job.DependsOn {
+			if dep == job.JobID {
+				return nil, fmt.Errorf("%w: job %s depends on itself", ErrInvalidJob, job.JobID)
+			}
+			if upstream, ok := batch[dep]; ok {
+				if upstream.Namespace != job.Namespace {
+					return nil, fmt.Errorf("%w: job %s depends on %s in another namespace", ErrInvalidJob, job.JobID, dep)
+				}
+				continue
+			}
+			if _, err := o.store.Get(ctx, job.Namespace, dep); err != nil {
+				if errors.Is(err, ErrNotFound) {
+					return nil, fmt.Errorf("%w: dependency %s of job %s does not exist", ErrInvalidJob, dep, job.JobID)
+				}
+				return nil, err
+			}
+		}
+	}
+
+	// Depth-first topological sort over the dependencies within the batch
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(jobs))
+	ordered := make([]*data.Job, 0, len(jobs))
+
+	var visit func(job *data.Job) error
+	visit = func(job *data.Job) error {
+		switch state[job.JobID] {
+		case visiting:
+			return fmt.Errorf("%w: dependency cycle through job %s", ErrInvalidJob, job.JobID)
+		case visited:
+			return nil
+		}
+		state[job.JobID] = visiting
+		for _, dep := range job.DependsOn {
+			if upstream, ok := batch[dep]; ok {
+				if err := visit(upstream); err != nil {
+					return err
+				}
+			}
+		}
+		state[job.JobID] = visited
+		ordered = append(ordered, job)
+		return nil
+	}
+
+	for _, job := range jobs {
+		if err := visit(job); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// advanceDependents advances the pending jobs that depend on a finished job
+func (o *Orchestrator) advanceDependents(ctx context.Context, job *data.Job) error {
+	dependents, err := o.store.List(ctx, Filter{
+		Namespace: job.Namespace,
+		Statuses:  []string{data.JobStatusPending},
+		DependsOn: job.JobID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list dependents of job %s: %w", job.JobID, err)
+	}
+
+	var errs []error
+	for _, dependent := range dependents {
+		if err := o.advance(ctx, dependent.Namespace, dependent.JobID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// advance starts a pending job if all its dependencies have completed, or fails it
+// if any dependency has failed or been canceled. Failures cascade to the job's own dependents.
+func (o *Orchestrator) advance(ctx context.Context, namespace, id string) error {
+	job, err := o.store.Get(ctx, namespace, id)
+	if err != nil {
+		return fmt.Errorf("failed to get job %s: %w", id, err)
+	}
+	if job.Status != data.JobStatusPending {
+		return nil
+	}
+
+	var failedDep string
+	for _, dep := range job.DependsOn {
+		upstream, err := o.store.Get(ctx, namespace, dep)
+		if err != nil {
+			return fmt.Errorf("failed to get dependency %s of job %s: %w", dep, id, err)
+		}
+		switch upstream.Status {
+		case data.JobStatusCompleted:
+			continue
+		case data.JobStatusFailed, data.JobStatusCanceled:
+			failedDep = dep
+		default:
+			return nil // Still waiting
+		}
+		break
+	}
+
+	if failedDep != "" {
+		_, err := o.Fail(ctx, namespace, id, &data.JobError{
+			Code:    ErrCodeUpstreamFailed,
+			Message: fmt.Sprintf("dependency %s did not complete", failedDep),
+			Details: map[string]interface{}{"job_id": failedDep},
+		})
+		if errors.Is(err, ErrInvalidTransition) {
+			return nil // Finished concurrently
+		}
+		return err
+	}
+
+	_, err = o.transition(ctx, namespace, id, func(job *data.Job) error {
+		if job.Status != data.JobStatusPending {
+			return fmt.Errorf("%w: job %s is %s", ErrInvalidTransition, id, job.Status)
+		}
+		job.Status = data.JobStatusStarted
+		job.StartedAt = o.now()
+		return nil
+	})
+	if errors.Is(err, ErrInvalidTransition) {
+		return nil // Started concurrently
+	}
+	return err
+}
+
+// transition applies change to the current version of a job, stores it and emits the
+// lifecycle event for its new status. A job re-queued with a higher retry count emits
+// job.started again. Concurrent updates are retried with fresh state.
+func (o *Orchestrator) transition(ctx context.Context, namespace, id string, change func(job *data.Job) error) (*data.Job, error) {
+	for i := 0; ; i++ {
+		job, err := o.store.Get(ctx, namespace, id)
+		if err != nil {
+			return nil, err
+		}
+		before := *job
+
+		if err := change(job); err != nil {
+			return nil, err
+		}
+		job.UpdatedAt = o.now()
+
+		err = o.store.Update(ctx, job)
+		if errors.Is(err, ErrConflict) && i < maxConflictRetries {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to update job %s: %w", id, err)
+		}
+
+		if job.Status != before.Status || job.Retries > before.Retries {
+			o.emit(&before, job)
+		}
+		return job, nil
+	}
+}
+
+// emit publishes the lifecycle event for a job's status change. Publishing is best effort;
+// the job store is the source of truth.
+func (o *Orchestrator) emit(before, after *data.Job) {
+	if o.publisher == nil || !slices.Contains([]string{data.JobStatusStarted, data.JobStatusCompleted, data.JobStatusFailed, data.JobStatusCanceled}, after.Status) {
+		return
+	}
+
+	event := LifecycleEvent(before, after, o.actorID)
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to marshal lifecycle event of job %s: %v", after.JobID, err)
+		return
+	}
+	if err := o.publisher.Publish(Subject(after.Namespace, after.JobType, after.Status), body); err != nil {
+		log.Printf("Failed to publish lifecycle event of job %s: %v", after.JobID, err)
+	}
+}
+
+// Subject returns the NATS subject of a job lifecycle event: event.<namespace>.<job_type>.<status>
+func Subject(namespace, jobType, status string) string {
+	return fmt.Sprintf("event.%s.%s.%s", namespace, jobType, status)
+}
+
+// LifecycleEvent builds the job.<status> event for a job's transition. The payload's
+// before and after hold the job as it was and as it is now; before is nil for new jobs.
+func LifecycleEvent(before, after *data.Job, actorID string) *data.Event {
+	event := &data.Event{
+		ID:           data.NewEventID(),
+		EventType:    "job." + after.Status,
+		EventVersion: "1.3.0",
+		Namespace:    after.Namespace,
+		ObjectType:   after.JobType,
+		ObjectID:     after.JobID,
+		Timestamp:    after.UpdatedAt,
+	}
+	event.Actor.Type = "system"
+	event.Actor.ID = actorID
+	if before != nil && before.Status != "" {
+		event.Payload.Before = jobToMap(before)
+	}
+	event.Payload.After = jobToMap(after)
+	return event
+}
+
+// jobToMap returns the JSON representation of a job as a map
+func jobToMap(job *data.Job) map[string]interface{} {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return nil
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil
+	}
+	return m
+}
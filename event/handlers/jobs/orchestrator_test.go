@@ -0,0 +1,260 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"event/data"
+)
+
+// recordingPublisher records the subjects and events it is asked to publish
+type recordingPublisher struct {
+	mu       sync.Mutex
+	subjects []string
+	events   []*data.Event
+}
+
+func (p *recordingPublisher) Publish(subject string, body []byte) error {
+	var event data.Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.subjects = append(p.subjects, subject)
+	p.events = append(p.events, &event)
+	return nil
+}
+
+func statusOf(t *testing.T, store Store, id string) string {
+	t.Helper()
+	job, err := store.Get(context.Background(), "media", id)
+	if err != nil {
+		t.Fatalf("Get(%s) error = %v", id, err)
+	}
+	return job.Status
+}
+
+func TestOrchestrator_Chain(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	publisher := &recordingPublisher{}
+	o := NewOrchestrator(store, publisher)
+
+	// Submitted out of order: thumbnail -> resize -> upload
+	_, err := o.SubmitAll(ctx, []*data.Job{
+		{JobID: "upload", JobType: "upload", Namespace: "media", DependsOn: []string{"resize"}},
+		{JobID: "thumbnail", JobType: "thumbnail", Namespace: "media"},
+		{JobID: "resize", JobType: "resize_image", Namespace: "media", DependsOn: []string{"thumbnail"}},
+	})
+	if err != nil {
+		t.Fatalf("SubmitAll() error = %v", err)
+	}
+
+	want := map[string]string{"thumbnail": data.JobStatusStarted, "resize": data.JobStatusPending, "upload": data.JobStatusPending}
+	for id, status := range want {
+		if got := statusOf(t, store, id); got != status {
+			t.Errorf("%s status = %s, want %s", id, got, status)
+		}
+	}
+
+	if _, err := o.Complete(ctx, "media", "thumbnail", map[string]interface{}{"url": "t.png"}); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if got := statusOf(t, store, "resize"); got != data.JobStatusStarted {
+		t.Errorf("resize status = %s, want started", got)
+	}
+
+	if _, err := o.Fail(ctx, "media", "resize", &data.JobError{Code: "IMG_TOO_LARGE", Message: "Resize failed"}); err != nil {
+		t.Fatalf("Fail() error = %v", err)
+	}
+	upload, _ := store.Get(ctx, "media", "upload")
+	if upload.Status != data.JobStatusFailed || upload.Error == nil || upload.Error.Code != ErrCodeUpstreamFailed {
+		t.Errorf("upload = %s %+v, want failed with %s", upload.Status, upload.Error, ErrCodeUpstreamFailed)
+	}
+
+	wantSubjects := []string{
+		"event.media.thumbnail.started",
+		"event.media.thumbnail.completed",
+		"event.media.resize_image.started",
+		"event.media.resize_image.failed",
+		"event.media.upload.failed",
+	}
+	if !reflect.DeepEqual(publisher.subjects, wantSubjects) {
+		t.Errorf("subjects = %v, want %v", publisher.subjects, wantSubjects)
+	}
+
+	completed := publisher.events[1]
+	if completed.EventType != "job.completed" || completed.ObjectType != "thumbnail" || completed.ObjectID != "thumbnail" {
+		t.Errorf("completed event = %s %s/%s", completed.EventType, completed.ObjectType, completed.ObjectID)
+	}
+	if completed.Payload.Before["status"] != data.JobStatusStarted || completed.Payload.After["status"] != data.JobStatusCompleted {
+		t.Errorf("completed payload status %v -> %v", completed.Payload.Before["status"], completed.Payload.After["status"])
+	}
+}
+
+func TestOrchestrator_Diamond(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	o := NewOrchestrator(store, nil)
+
+	_, err := o.SubmitAll(ctx, []*data.Job{
+		{JobID: "a", JobType: "fetch", Namespace: "media"},
+		{JobID: "b", JobType: "left", Namespace: "media", DependsOn: []string{"a"}},
+		{JobID: "c", JobType: "right", Namespace: "media", DependsOn: []string{"a"}},
+		{JobID: "d", JobType: "merge", Namespace: "media", DependsOn: []string{"b", "c"}},
+	})
+	if err != nil {
+		t.Fatalf("SubmitAll() error = %v", err)
+	}
+
+	for _, id := range []string{"a", "b"} {
+		if _, err := o.Complete(ctx, "media", id, nil); err != nil {
+			t.Fatalf("Complete(%s) error = %v", id, err)
+		}
+	}
+	if got := statusOf(t, store, "d"); got != data.JobStatusPending {
+		t.Errorf("d status = %s while c is running, want pending", got)
+	}
+
+	if _, err := o.Complete(ctx, "media", "c", nil); err != nil {
+		t.Fatalf("Complete(c) error = %v", err)
+	}
+	if got := statusOf(t, store, "d"); got != data.JobStatusStarted {
+		t.Errorf("d status = %s, want started", got)
+	}
+
+	// A job submitted later against finished dependencies starts right away
+	job, err := o.Submit(ctx, &data.Job{JobType: "report", Namespace: "media", DependsOn: []string{"a", "c"}})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if job.JobID == "" || job.Status != data.JobStatusStarted {
+		t.Errorf("late job = %q %s, want generated ID and started", job.JobID, job.Status)
+	}
+}
+
+func TestOrchestrator_InvalidSubmissions(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	o := NewOrchestrator(store, nil)
+
+	tests := []struct {
+		name string
+		jobs []*data.Job
+	}{
+		{
+			name: "missing type",
+			jobs: []*data.Job{{JobID: "a", Namespace: "media"}},
+		},
+		{
+			name: "unknown dependency",
+			jobs: []*data.Job{{JobID: "a", JobType: "x", Namespace: "media", DependsOn: []string{"nope"}}},
+		},
+		{
+			name: "self dependency",
+			jobs: []*data.Job{{JobID: "a", JobType: "x", Namespace: "media", DependsOn: []string{"a"}}},
+		},
+		{
+			name: "cycle",
+			jobs: []*data.Job{
+				{JobID: "a", JobType: "x", Namespace: "media", DependsOn: []string{"c"}},
+				{JobID: "b", JobType: "x", Namespace: "media", DependsOn: []string{"a"}},
+				{JobID: "c", JobType: "x", Namespace: "media", DependsOn: []string{"b"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := o.SubmitAll(ctx, tt.jobs); !errors.Is(err, ErrInvalidJob) {
+				t.Errorf("SubmitAll() error = %v, want ErrInvalidJob", err)
+			}
+		})
+	}
+
+	// Nothing from the rejected batches was stored
+	jobs, _ := store.List(ctx, Filter{})
+	if len(jobs) != 0 {
+		t.Errorf("store has %d jobs, want 0", len(jobs))
+	}
+}
+
+func TestOrchestrator_SubmitAllRollsBack(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	o := NewOrchestrator(store, nil)
+	if _, err := o.Submit(ctx, &data.Job{JobID: "taken", JobType: "x", Namespace: "media"}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	// The second job of the batch cannot be stored
+	_, err := o.SubmitAll(ctx, []*data.Job{
+		{JobID: "a", JobType: "x", Namespace: "media"},
+		{JobID: "taken", JobType: "x", Namespace: "media", DependsOn: []string{"a"}},
+	})
+	if !errors.Is(err, ErrExists) {
+		t.Fatalf("SubmitAll() error = %v, want ErrExists", err)
+	}
+
+	// The job stored before it is deleted, the existing job is kept
+	if _, err := store.Get(ctx, "media", "a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(a) error = %v, want ErrNotFound", err)
+	}
+	if _, err := store.Get(ctx, "media", "taken"); err != nil {
+		t.Errorf("Get(taken) error = %v", err)
+	}
+}
+
+func TestOrchestrator_InvalidTransitions(t *testing.T) {
+	ctx := context.Background()
+	o := NewOrchestrator(NewMemoryStore(), nil)
+
+	if _, err := o.SubmitAll(ctx, []*data.Job{
+		{JobID: "a", JobType: "x", Namespace: "media"},
+		{JobID: "b", JobType: "x", Namespace: "media", DependsOn: []string{"a"}},
+	}); err != nil {
+		t.Fatalf("SubmitAll() error = %v", err)
+	}
+
+	if _, err := o.Complete(ctx, "media", "b", nil); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Complete(pending) error = %v, want ErrInvalidTransition", err)
+	}
+	if _, err := o.Complete(ctx, "media", "a", nil); err != nil {
+		t.Fatalf("Complete(a) error = %v", err)
+	}
+	if _, err := o.Fail(ctx, "media", "a", nil); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Fail(completed) error = %v, want ErrInvalidTransition", err)
+	}
+	if _, err := o.Complete(ctx, "media", "missing", nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Complete(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryStore_UpdateConflict(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	if err := store.Create(ctx, &data.Job{JobID: "a", JobType: "x", Namespace: "media"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := store.Create(ctx, &data.Job{JobID: "a", JobType: "x", Namespace: "media"}); !errors.Is(err, ErrExists) {
+		t.Errorf("Create(duplicate) error = %v, want ErrExists", err)
+	}
+
+	first, _ := store.Get(ctx, "media", "a")
+	second, _ := store.Get(ctx, "media", "a")
+
+	first.Status = data.JobStatusStarted
+	if err := store.Update(ctx, first); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	second.Status = data.JobStatusFailed
+	if err := store.Update(ctx, second); !errors.Is(err, ErrConflict) {
+		t.Errorf("stale Update() error = %v, want ErrConflict", err)
+	}
+}
@@ -0,0 +1,47 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+
+	"event/data"
+)
+
+var (
+	// ErrNotFound is returned when a job does not exist
+	ErrNotFound = errors.New("job not found")
+	// ErrExists is returned when a job with the same ID already exists
+	ErrExists = errors.New("job already exists")
+	// ErrConflict is returned when a job was modified since it was read
+	ErrConflict = errors.New("job was modified concurrently")
+)
+
+// Filter narrows down the jobs returned by List
+type Filter struct {
+	Namespace   string   // Optional, matches all namespaces when empty
+	Statuses    []string // Optional, matches all statuses when empty
+	JobType     string   // Optional
+	TriggeredBy string   // Optional
+	DependsOn   string   // Optional, matches jobs that depend on this job ID
+	Limit       int      // Optional, no limit when zero
+}
+
+// Store defines the interface for a job repository.
+// Implementations return copies, so callers may modify the jobs they get back.
+type Store interface {
+	// Create stores a new job, returning ErrExists if its ID is taken
+	Create(ctx context.Context, job *data.Job) error
+
+	// Get returns a job by namespace and ID
+	Get(ctx context.Context, namespace, id string) (*data.Job, error)
+
+	// List returns the jobs matching the filter, oldest first
+	List(ctx context.Context, filter Filter) ([]*data.Job, error)
+
+	// Update replaces a job if its version is unchanged since it was read, returning
+	// ErrConflict otherwise. On success job.Version is incremented.
+	Update(ctx context.Context, job *data.Job) error
+
+	// Delete removes a job. It undoes the jobs of a batch that could not be stored completely.
+	Delete(ctx context.Context, namespace, id string) error
+}
@@ -0,0 +1,116 @@
+package namespaces
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"event/data"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPrefix is the default prefix for namespace keys in etcd. It is kept apart from the
+// trigger prefix, so that a namespace's metadata is never loaded as a trigger.
+const DefaultPrefix = "/namespaces/"
+
+// Compile-time check to ensure EtcdStore implements Store
+var _ Store = (*EtcdStore)(nil)
+
+// EtcdStore is a namespace store backed by etcd. Each namespace is stored as YAML under
+// <prefix><name>.
+type EtcdStore struct {
+	client *clientv3.Client
+	prefix string
+}
+
+// NewEtcdStore creates a new etcd-backed namespace store using an existing client
+func NewEtcdStore(client *clientv3.Client, prefix string) *EtcdStore {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	// Ensure prefix ends with "/"
+	if !strings.HasSuffix(prefix, "/") {
+		prefix = prefix + "/"
+	}
+
+	return &EtcdStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Create stores a new namespace, or returns ErrExists
+func (s *EtcdStore) Create(ctx context.Context, namespace *data.Namespace) error {
+	value, err := yaml.Marshal(namespace)
+	if err != nil {
+		return fmt.Errorf("failed to marshal namespace: %w", err)
+	}
+
+	// Only create the key if it does not exist yet
+	key := s.prefix + namespace.Name
+	resp, err := s.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, string(value))).
+		Commit()
+	if err != nil {
+		return fmt.Errorf("failed to save namespace to etcd: %w", err)
+	}
+	if !resp.Succeeded {
+		return ErrExists
+	}
+	return nil
+}
+
+// Get returns a namespace by name, or ErrNotFound
+func (s *EtcdStore) Get(ctx context.Context, name string) (*data.Namespace, error) {
+	resp, err := s.client.Get(ctx, s.prefix+name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get namespace from etcd: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, ErrNotFound
+	}
+	return parse(resp.Kvs[0].Key, resp.Kvs[0].Value)
+}
+
+// List returns all created namespaces, ordered by name
+func (s *EtcdStore) List(ctx context.Context) ([]*data.Namespace, error) {
+	resp, err := s.client.Get(ctx, s.prefix, clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces from etcd: %w", err)
+	}
+
+	namespaces := make([]*data.Namespace, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		namespace, err := parse(kv.Key, kv.Value)
+		if err != nil {
+			return nil, err
+		}
+		namespaces = append(namespaces, namespace)
+	}
+	return namespaces, nil
+}
+
+// Delete removes a namespace, or returns ErrNotFound
+func (s *EtcdStore) Delete(ctx context.Context, name string) error {
+	resp, err := s.client.Delete(ctx, s.prefix+name)
+	if err != nil {
+		return fmt.Errorf("failed to delete namespace from etcd: %w", err)
+	}
+	if resp.Deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// parse decodes the namespace stored under key
+func parse(key, value []byte) (*data.Namespace, error) {
+	var namespace data.Namespace
+	if err := yaml.Unmarshal(value, &namespace); err != nil {
+		return nil, fmt.Errorf("failed to parse namespace %s: %w", key, err)
+	}
+	return &namespace, nil
+}
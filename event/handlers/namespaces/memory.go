@@ -0,0 +1,76 @@
+package namespaces
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"event/data"
+)
+
+// Compile-time check to ensure MemoryStore implements Store
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore is an in-memory namespace store, intended for tests and local development
+type MemoryStore struct {
+	namespaces map[string]*data.Namespace
+	mu         sync.RWMutex
+}
+
+// NewMemoryStore creates a new in-memory namespace store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		namespaces: make(map[string]*data.Namespace),
+	}
+}
+
+// Create stores a new namespace, or returns ErrExists
+func (s *MemoryStore) Create(ctx context.Context, namespace *data.Namespace) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.namespaces[namespace.Name]; ok {
+		return ErrExists
+	}
+	s.namespaces[namespace.Name] = namespace
+	return nil
+}
+
+// Get returns a namespace by name, or ErrNotFound
+func (s *MemoryStore) Get(ctx context.Context, name string) (*data.Namespace, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	namespace, ok := s.namespaces[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return namespace, nil
+}
+
+// List returns all created namespaces, ordered by name
+func (s *MemoryStore) List(ctx context.Context) ([]*data.Namespace, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	namespaces := make([]*data.Namespace, 0, len(s.namespaces))
+	for _, namespace := range s.namespaces {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Name < namespaces[j].Name
+	})
+	return namespaces, nil
+}
+
+// Delete removes a namespace, or returns ErrNotFound
+func (s *MemoryStore) Delete(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.namespaces[name]; !ok {
+		return ErrNotFound
+	}
+	delete(s.namespaces, name)
+	return nil
+}
@@ -0,0 +1,118 @@
+package namespaces
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"event/data"
+	"event/handlers/actions"
+)
+
+// ErrQuotaExceeded is returned when a trigger does not fit its namespace's quota
+var ErrQuotaExceeded = errors.New("namespace quota exceeded")
+
+// EffectiveQuota returns the quota of a namespace: its own limits, and the defaults for the
+// limits it does not set. A nil namespace has the default quota.
+func EffectiveQuota(namespace *data.Namespace, defaults data.NamespaceQuota) data.NamespaceQuota {
+	quota := defaults
+	if namespace == nil {
+		return quota
+	}
+	if namespace.Quota.MaxTriggers != 0 {
+		quota.MaxTriggers = namespace.Quota.MaxTriggers
+	}
+	if namespace.Quota.MaxCriteriaLength != 0 {
+		quota.MaxCriteriaLength = namespace.Quota.MaxCriteriaLength
+	}
+	if namespace.Quota.MaxActionsPerMinute != 0 {
+		quota.MaxActionsPerMinute = namespace.Quota.MaxActionsPerMinute
+	}
+	return quota
+}
+
+// ValidateQuota checks that no limit of a quota is negative
+func ValidateQuota(quota data.NamespaceQuota) error {
+	if quota.MaxTriggers < 0 || quota.MaxCriteriaLength < 0 || quota.MaxActionsPerMinute < 0 {
+		return fmt.Errorf("quota limits must not be negative")
+	}
+	return nil
+}
+
+// CheckQuota checks whether a trigger fits the quota of its namespace, given the triggers
+// the namespace has. A trigger with the ID of an existing trigger replaces it, so updates
+// do not count towards max_triggers.
+//
+// The action rate of a trigger is bounded only by its throttle: max_fires per interval
+// times its number of actions. While the namespace has an action rate quota, every enabled
+// trigger with actions needs such a throttle, and together they must stay within the quota.
+func CheckQuota(quota data.NamespaceQuota, trigger *data.Trigger, existing []*data.Trigger) error {
+	if quota.MaxCriteriaLength > 0 {
+		criteria := []string{trigger.Criteria}
+		if trigger.Sequence != nil {
+			for _, step := range trigger.Sequence.Steps {
+				criteria = append(criteria, step.Criteria)
+			}
+		}
+		for _, c := range criteria {
+			if len(c) > quota.MaxCriteriaLength {
+				return fmt.Errorf("%w: criteria of %d characters exceed the limit of %d", ErrQuotaExceeded, len(c), quota.MaxCriteriaLength)
+			}
+		}
+	}
+
+	others := make([]*data.Trigger, 0, len(existing))
+	for _, t := range existing {
+		if t.ID != trigger.ID {
+			others = append(others, t)
+		}
+	}
+
+	if quota.MaxTriggers > 0 && len(others) == len(existing) && len(existing) >= quota.MaxTriggers {
+		return fmt.Errorf("%w: namespace %s already has %d triggers, the limit is %d", ErrQuotaExceeded, trigger.Namespace, len(existing), quota.MaxTriggers)
+	}
+
+	if quota.MaxActionsPerMinute > 0 {
+		rate := actionRate(trigger)
+		if rate == 0 {
+			return nil
+		}
+		if math.IsInf(rate, 1) {
+			return fmt.Errorf("%w: namespace %s limits actions to %d per minute, so trigger %s needs a throttle with max_fires and interval",
+				ErrQuotaExceeded, trigger.Namespace, quota.MaxActionsPerMinute, trigger.ID)
+		}
+		for _, t := range others {
+			rate += actionRate(t)
+		}
+		if rate > float64(quota.MaxActionsPerMinute) {
+			return fmt.Errorf("%w: the triggers of namespace %s could run %s actions per minute, the limit is %d",
+				ErrQuotaExceeded, trigger.Namespace, formatRate(rate), quota.MaxActionsPerMinute)
+		}
+	}
+	return nil
+}
+
+// actionRate returns how many actions per minute a trigger can run at most: zero if it is
+// disabled or has no actions, and +Inf if its throttle does not bound its firings
+func actionRate(trigger *data.Trigger) float64 {
+	n := len(actions.ForTrigger(trigger))
+	if !trigger.Enabled || n == 0 {
+		return 0
+	}
+	if trigger.Throttle == nil || trigger.Throttle.MaxFires <= 0 {
+		return math.Inf(1)
+	}
+	interval, err := time.ParseDuration(trigger.Throttle.Interval)
+	if err != nil || interval <= 0 {
+		return math.Inf(1)
+	}
+	return float64(trigger.Throttle.MaxFires*n) / interval.Minutes()
+}
+
+func formatRate(rate float64) string {
+	if math.IsInf(rate, 1) {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%.0f", math.Ceil(rate))
+}
@@ -0,0 +1,149 @@
+package namespaces
+
+import (
+	"errors"
+	"testing"
+
+	"event/data"
+)
+
+func TestEffectiveQuota(t *testing.T) {
+	defaults := data.NamespaceQuota{MaxTriggers: 100, MaxCriteriaLength: 1000}
+
+	if got := EffectiveQuota(nil, defaults); got != defaults {
+		t.Errorf("EffectiveQuota(nil) = %+v, want the defaults", got)
+	}
+
+	namespace := &data.Namespace{Name: "sales", Quota: data.NamespaceQuota{MaxTriggers: 5, MaxActionsPerMinute: 60}}
+	want := data.NamespaceQuota{MaxTriggers: 5, MaxCriteriaLength: 1000, MaxActionsPerMinute: 60}
+	if got := EffectiveQuota(namespace, defaults); got != want {
+		t.Errorf("EffectiveQuota() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCheckQuota(t *testing.T) {
+	throttled := func(id string, maxFires int, interval string) *data.Trigger {
+		return &data.Trigger{
+			ID:         id,
+			Namespace:  "sales",
+			Enabled:    true,
+			ActionURL:  "https://example.com/hook",
+			Throttle:   &data.Throttle{MaxFires: maxFires, Interval: interval},
+			EventType:  "order.created",
+			ObjectType: "Order",
+		}
+	}
+	existing := []*data.Trigger{
+		throttled("t1", 10, "1m"),
+		throttled("t2", 60, "1h"),
+	}
+
+	tests := []struct {
+		name     string
+		quota    data.NamespaceQuota
+		trigger  *data.Trigger
+		existing []*data.Trigger
+		wantErr  bool
+	}{
+		{
+			name:     "no limits",
+			trigger:  &data.Trigger{ID: "t3", Enabled: true, ActionURL: "https://example.com"},
+			existing: existing,
+		},
+		{
+			name:     "below max_triggers",
+			quota:    data.NamespaceQuota{MaxTriggers: 3},
+			trigger:  &data.Trigger{ID: "t3"},
+			existing: existing,
+		},
+		{
+			name:     "at max_triggers",
+			quota:    data.NamespaceQuota{MaxTriggers: 2},
+			trigger:  &data.Trigger{ID: "t3"},
+			existing: existing,
+			wantErr:  true,
+		},
+		{
+			name:     "update at max_triggers",
+			quota:    data.NamespaceQuota{MaxTriggers: 2},
+			trigger:  &data.Trigger{ID: "t1"},
+			existing: existing,
+		},
+		{
+			name:    "criteria too long",
+			quota:   data.NamespaceQuota{MaxCriteriaLength: 10},
+			trigger: &data.Trigger{ID: "t3", Criteria: "event.payload.after.amount > 100"},
+			wantErr: true,
+		},
+		{
+			name:  "sequence step criteria too long",
+			quota: data.NamespaceQuota{MaxCriteriaLength: 10},
+			trigger: &data.Trigger{ID: "t3", Sequence: &data.Sequence{Steps: []data.SequenceStep{
+				{Name: "created", Criteria: "true"},
+				{Name: "paid", Criteria: `event.event_type == "order.paid"`},
+			}}},
+			wantErr: true,
+		},
+		{
+			name:     "within the action rate",
+			quota:    data.NamespaceQuota{MaxActionsPerMinute: 20},
+			trigger:  throttled("t3", 9, "1m"),
+			existing: existing,
+		},
+		{
+			name:     "above the action rate",
+			quota:    data.NamespaceQuota{MaxActionsPerMinute: 20},
+			trigger:  throttled("t3", 10, "1m"),
+			existing: existing,
+			wantErr:  true,
+		},
+		{
+			name:     "replaced trigger does not count",
+			quota:    data.NamespaceQuota{MaxActionsPerMinute: 20},
+			trigger:  throttled("t1", 19, "1m"),
+			existing: existing,
+		},
+		{
+			name:    "unthrottled trigger",
+			quota:   data.NamespaceQuota{MaxActionsPerMinute: 20},
+			trigger: &data.Trigger{ID: "t3", Enabled: true, ActionURL: "https://example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "disabled unthrottled trigger",
+			quota:   data.NamespaceQuota{MaxActionsPerMinute: 20},
+			trigger: &data.Trigger{ID: "t3", ActionURL: "https://example.com"},
+		},
+		{
+			name:    "several actions per firing",
+			quota:   data.NamespaceQuota{MaxActionsPerMinute: 20},
+			trigger: &data.Trigger{ID: "t3", Enabled: true, Actions: []data.ActionConfig{{Type: "webhook"}, {Type: "nats"}, {Type: "log"}}, Throttle: &data.Throttle{MaxFires: 7, Interval: "1m"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckQuota(tt.quota, tt.trigger, tt.existing)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckQuota() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrQuotaExceeded) {
+				t.Errorf("CheckQuota() error = %v, want ErrQuotaExceeded", err)
+			}
+		})
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"sales", "team-a.prod", "A_1"} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("ValidateName(%q) error = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "a/b", "-sales", "sales team"} {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("ValidateName(%q) succeeded, want an error", name)
+		}
+	}
+}
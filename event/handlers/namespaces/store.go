@@ -0,0 +1,47 @@
+// Package namespaces keeps the metadata and quotas of namespaces. A namespace exists once it
+// is created or once a trigger is saved in it; created namespaces carry an owner, labels and
+// a quota that overrides the default quota.
+package namespaces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"event/data"
+)
+
+var (
+	// ErrNotFound is returned when a namespace was not created
+	ErrNotFound = errors.New("namespace not found")
+	// ErrExists is returned when a namespace is created twice
+	ErrExists = errors.New("namespace already exists")
+)
+
+// namePattern matches namespace names, which are a single segment of an etcd key
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,62}$`)
+
+// Store defines the interface for a namespace store
+type Store interface {
+	// Create stores a new namespace, or returns ErrExists
+	Create(ctx context.Context, namespace *data.Namespace) error
+
+	// Get returns a namespace by name, or ErrNotFound
+	Get(ctx context.Context, name string) (*data.Namespace, error)
+
+	// List returns all created namespaces, ordered by name
+	List(ctx context.Context) ([]*data.Namespace, error)
+
+	// Delete removes a namespace, or returns ErrNotFound
+	Delete(ctx context.Context, name string) error
+}
+
+// ValidateName checks a namespace name: up to 63 letters, digits, '_', '.' and '-',
+// starting with a letter or digit
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid namespace name %q: use up to 63 letters, digits, '_', '.' and '-', starting with a letter or digit", name)
+	}
+	return nil
+}
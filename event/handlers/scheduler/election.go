@@ -0,0 +1,191 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+const (
+	// DefaultPrefix is the default prefix of the scheduler's etcd keys
+	DefaultPrefix = "/scheduler/"
+	// DefaultSessionTTL is the default TTL in seconds of the leader's etcd session.
+	// If the leader dies, another instance takes over after at most this long.
+	DefaultSessionTTL = 10
+)
+
+// errSessionExpired is returned when the leader's etcd session ends while it is leading
+var errSessionExpired = errors.New("etcd session expired")
+
+// Election runs a scheduler on exactly one of the instances sharing an etcd prefix
+type Election struct {
+	client     *clientv3.Client
+	prefix     string
+	id         string
+	sessionTTL int
+}
+
+// ElectionOption is a function that configures an Election
+type ElectionOption func(*Election)
+
+// WithSessionTTL sets the TTL in seconds of the leader's etcd session
+func WithSessionTTL(ttl int) ElectionOption {
+	return func(e *Election) {
+		e.sessionTTL = ttl
+	}
+}
+
+// NewElection creates an election among the instances using prefix. id identifies this instance.
+func NewElection(client *clientv3.Client, prefix, id string, options ...ElectionOption) *Election {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix = prefix + "/"
+	}
+
+	e := &Election{
+		client:     client,
+		prefix:     prefix,
+		id:         id,
+		sessionTTL: DefaultSessionTTL,
+	}
+
+	for _, option := range options {
+		option(e)
+	}
+
+	return e
+}
+
+// Run campaigns for leadership and runs the scheduler while this instance leads.
+// If leadership is lost, it campaigns again. Run returns when ctx is done.
+func (e *Election) Run(ctx context.Context, s *Scheduler) {
+	for {
+		err := e.lead(ctx, s)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Scheduler %s is no longer leading: %v", e.id, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// lead waits until this instance is elected and runs the scheduler until leadership ends
+func (e *Election) lead(ctx context.Context, s *Scheduler) error {
+	session, err := concurrency.NewSession(e.client, concurrency.WithTTL(e.sessionTTL), concurrency.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to create etcd session: %w", err)
+	}
+	defer session.Close()
+
+	election := concurrency.NewElection(session, e.prefix+"leader")
+	if err := election.Campaign(ctx, e.id); err != nil {
+		return fmt.Errorf("failed to campaign: %w", err)
+	}
+	log.Printf("Scheduler %s elected leader", e.id)
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-session.Done():
+			cancel()
+		case <-leaderCtx.Done():
+		}
+	}()
+
+	s.Run(leaderCtx, &etcdLedger{
+		client:    e.client,
+		prefix:    e.prefix + "ticks/",
+		leaderKey: election.Key(),
+		leaderRev: election.Rev(),
+	})
+
+	// Hand over right away instead of waiting for the session to expire
+	resignCtx, resignCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer resignCancel()
+	if err := election.Resign(resignCtx); err != nil {
+		log.Printf("Failed to resign scheduler leadership: %v", err)
+	}
+
+	select {
+	case <-session.Done():
+		return errSessionExpired
+	default:
+		return nil
+	}
+}
+
+// Compile-time check to ensure etcdLedger implements Ledger
+var _ Ledger = (*etcdLedger)(nil)
+
+// etcdLedger keeps the last fired tick of each trigger in etcd. Ticks are only claimed while
+// the leader key of the term it was created for still exists, so a deposed leader cannot fire.
+type etcdLedger struct {
+	client    *clientv3.Client
+	prefix    string
+	leaderKey string
+	leaderRev int64
+}
+
+func (l *etcdLedger) key(namespace, id string) string {
+	return l.prefix + namespace + "/" + id
+}
+
+// Last returns the scheduled time of the last tick fired for a trigger
+func (l *etcdLedger) Last(ctx context.Context, namespace, id string) (time.Time, error) {
+	last, _, err := l.get(ctx, namespace, id)
+	return last, err
+}
+
+// Claim records the tick unless that or a later tick was already fired or leadership was lost
+func (l *etcdLedger) Claim(ctx context.Context, namespace, id string, scheduledAt time.Time) (bool, error) {
+	last, modRevision, err := l.get(ctx, namespace, id)
+	if err != nil {
+		return false, err
+	}
+	if !scheduledAt.After(last) {
+		return false, nil
+	}
+
+	resp, err := l.client.Txn(ctx).If(
+		clientv3.Compare(clientv3.CreateRevision(l.leaderKey), "=", l.leaderRev),
+		clientv3.Compare(clientv3.ModRevision(l.key(namespace, id)), "=", modRevision),
+	).Then(
+		clientv3.OpPut(l.key(namespace, id), scheduledAt.UTC().Format(time.RFC3339Nano)),
+	).Commit()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim tick: %w", err)
+	}
+	return resp.Succeeded, nil
+}
+
+// get returns the last tick of a trigger and the revision it was written at
+func (l *etcdLedger) get(ctx context.Context, namespace, id string) (time.Time, int64, error) {
+	resp, err := l.client.Get(ctx, l.key(namespace, id))
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("failed to get last tick: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return time.Time{}, 0, nil
+	}
+
+	kv := resp.Kvs[0]
+	last, err := time.Parse(time.RFC3339Nano, string(kv.Value))
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("invalid last tick %q: %w", kv.Value, err)
+	}
+	return last, kv.ModRevision, nil
+}
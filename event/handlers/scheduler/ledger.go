@@ -0,0 +1,44 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Compile-time check to ensure MemoryLedger implements Ledger
+var _ Ledger = (*MemoryLedger)(nil)
+
+// MemoryLedger is a ledger for a single instance
+type MemoryLedger struct {
+	last map[string]time.Time
+	mu   sync.Mutex
+}
+
+// NewMemoryLedger creates an empty in-memory ledger
+func NewMemoryLedger() *MemoryLedger {
+	return &MemoryLedger{
+		last: make(map[string]time.Time),
+	}
+}
+
+// Last returns the scheduled time of the last tick fired for a trigger
+func (l *MemoryLedger) Last(ctx context.Context, namespace, id string) (time.Time, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.last[namespace+"/"+id], nil
+}
+
+// Claim records the tick unless that or a later tick was already fired
+func (l *MemoryLedger) Claim(ctx context.Context, namespace, id string, scheduledAt time.Time) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := namespace + "/" + id
+	if !scheduledAt.After(l.last[key]) {
+		return false, nil
+	}
+	l.last[key] = scheduledAt
+	return true, nil
+}
@@ -0,0 +1,221 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"event/data"
+	"event/handlers/triggers"
+
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	// DefaultResolution is how often the scheduler checks for due ticks
+	DefaultResolution = time.Second
+	// DefaultCatchUp is how far back a newly elected scheduler fires ticks that were missed
+	// while no instance was leading
+	DefaultCatchUp = 5 * time.Minute
+	// ActorID is the actor of tick events
+	ActorID = "triggerd"
+)
+
+// FireFunc fires a scheduled trigger with a tick event. Its context is not cancelled when
+// the scheduler stops, e.g. because this instance lost leadership.
+type FireFunc func(ctx context.Context, trigger *data.Trigger, event *data.Event)
+
+// Ledger records the ticks that have fired, so that each tick fires once across instances
+type Ledger interface {
+	// Last returns the scheduled time of the last tick fired for a trigger, or the zero time
+	Last(ctx context.Context, namespace, id string) (time.Time, error)
+	// Claim records that the tick scheduled at scheduledAt fires. It returns false if that
+	// or a later tick was already fired.
+	Claim(ctx context.Context, namespace, id string, scheduledAt time.Time) (bool, error)
+}
+
+// Scheduler fires the scheduled triggers of a trigger store
+type Scheduler struct {
+	store      triggers.TriggerStore
+	fire       FireFunc
+	resolution time.Duration
+	catchUp    time.Duration
+	now        func() time.Time
+}
+
+// Option is a function that configures a Scheduler
+type Option func(*Scheduler)
+
+// WithResolution sets how often the scheduler checks for due ticks
+func WithResolution(resolution time.Duration) Option {
+	return func(s *Scheduler) {
+		s.resolution = resolution
+	}
+}
+
+// WithCatchUp sets how far back missed ticks are fired when the scheduler starts
+func WithCatchUp(catchUp time.Duration) Option {
+	return func(s *Scheduler) {
+		s.catchUp = catchUp
+	}
+}
+
+// NewScheduler creates a scheduler for the triggers in store that calls fire for every tick
+func NewScheduler(store triggers.TriggerStore, fire FireFunc, options ...Option) *Scheduler {
+	s := &Scheduler{
+		store:      store,
+		fire:       fire,
+		resolution: DefaultResolution,
+		catchUp:    DefaultCatchUp,
+		now:        time.Now,
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	return s
+}
+
+// Parse returns the cron schedule of a trigger, evaluated in its time zone
+func Parse(schedule *data.Schedule) (cron.Schedule, error) {
+	spec := strings.TrimSpace(schedule.Cron)
+	if spec == "" {
+		return nil, fmt.Errorf("cron is required")
+	}
+	if strings.HasPrefix(spec, "TZ=") || strings.HasPrefix(spec, "CRON_TZ=") {
+		return nil, fmt.Errorf("set the time zone with timezone, not in cron")
+	}
+
+	location := time.UTC
+	if schedule.Timezone != "" {
+		loc, err := time.LoadLocation(schedule.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", schedule.Timezone, err)
+		}
+		location = loc
+	}
+
+	parsed, err := cron.ParseStandard("CRON_TZ=" + location.String() + " " + spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", spec, err)
+	}
+	return parsed, nil
+}
+
+// TickEvent returns the synthetic event that fires a scheduled trigger
+func TickEvent(trigger *data.Trigger, scheduledAt time.Time) *data.Event {
+	event := &data.Event{
+		ID:           data.NewEventID(),
+		EventType:    data.ScheduleTickEventType,
+		EventVersion: "1.0.0",
+		Namespace:    trigger.Namespace,
+		ObjectType:   "schedule",
+		ObjectID:     trigger.ID,
+		Timestamp:    scheduledAt.UTC(),
+	}
+	event.Actor.Type = "system"
+	event.Actor.ID = ActorID
+	event.Payload.After = map[string]interface{}{
+		"trigger_id":   trigger.ID,
+		"cron":         trigger.Schedule.Cron,
+		"timezone":     trigger.Schedule.Timezone,
+		"scheduled_at": scheduledAt.UTC().Format(time.RFC3339),
+	}
+	return event
+}
+
+// entry is the schedule state of one trigger
+type entry struct {
+	spec     data.Schedule
+	schedule cron.Schedule
+	next     time.Time
+}
+
+// Run fires due ticks until ctx is done, claiming every tick in ledger first.
+// Ticks missed before Run was called are fired if they are within the catch-up window.
+func (s *Scheduler) Run(ctx context.Context, ledger Ledger) {
+	ticker := time.NewTicker(s.resolution)
+	defer ticker.Stop()
+
+	entries := make(map[string]*entry)
+	s.tick(ctx, ledger, entries, true)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.tick(ctx, ledger, entries, false)
+		}
+	}
+}
+
+// tick fires every tick that is due. On the first tick of a run, triggers resume from the
+// last tick in the ledger; triggers added later start from now.
+func (s *Scheduler) tick(ctx context.Context, ledger Ledger, entries map[string]*entry, first bool) {
+	now := s.now()
+	seen := make(map[string]bool)
+
+	for _, trigger := range s.store.GetAllTriggers() {
+		if trigger.Schedule == nil || !trigger.Enabled {
+			continue
+		}
+		key := trigger.Namespace + "/" + trigger.ID
+		seen[key] = true
+
+		e, ok := entries[key]
+		if !ok || e.spec != *trigger.Schedule {
+			schedule, err := Parse(trigger.Schedule)
+			if err != nil {
+				log.Printf("Skipping scheduled trigger %s: %v", key, err)
+				entries[key] = &entry{spec: *trigger.Schedule}
+				continue
+			}
+
+			from := now
+			if first {
+				last, err := ledger.Last(ctx, trigger.Namespace, trigger.ID)
+				if err != nil {
+					log.Printf("Failed to read last tick of trigger %s: %v", key, err)
+					continue
+				}
+				if !last.IsZero() {
+					from = last
+					if earliest := now.Add(-s.catchUp); from.Before(earliest) {
+						from = earliest
+					}
+				}
+			}
+
+			e = &entry{spec: *trigger.Schedule, schedule: schedule, next: schedule.Next(from)}
+			entries[key] = e
+		}
+		if e.schedule == nil {
+			continue
+		}
+
+		for !e.next.IsZero() && !e.next.After(now) {
+			scheduledAt := e.next
+			claimed, err := ledger.Claim(ctx, trigger.Namespace, trigger.ID, scheduledAt)
+			if err != nil {
+				// Retry the same tick on the next check
+				log.Printf("Failed to claim tick %s of trigger %s: %v", scheduledAt.Format(time.RFC3339), key, err)
+				break
+			}
+			e.next = e.schedule.Next(scheduledAt)
+			if claimed {
+				// A claimed tick is never fired again, so losing leadership must not cancel
+				// its firing or the deliveries it starts
+				s.fire(context.WithoutCancel(ctx), trigger, TickEvent(trigger, scheduledAt))
+			}
+		}
+	}
+
+	for key := range entries {
+		if !seen[key] {
+			delete(entries, key)
+		}
+	}
+}
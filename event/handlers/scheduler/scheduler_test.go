@@ -0,0 +1,173 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"event/data"
+	"event/handlers/triggers"
+)
+
+// staticStore is a trigger store with a fixed set of triggers
+type staticStore struct {
+	triggers.TriggerStore
+	triggers []*data.Trigger
+}
+
+func (s *staticStore) GetAllTriggers() []*data.Trigger {
+	return s.triggers
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule data.Schedule
+		from     time.Time
+		want     time.Time
+		wantErr  bool
+	}{
+		{
+			name:     "utc by default",
+			schedule: data.Schedule{Cron: "0 2 * * *"},
+			from:     time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+			want:     time.Date(2024, 3, 2, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "time zone",
+			schedule: data.Schedule{Cron: "0 2 * * *", Timezone: "Europe/Berlin"},
+			from:     time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+			want:     time.Date(2024, 3, 2, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "descriptor",
+			schedule: data.Schedule{Cron: "@hourly"},
+			from:     time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC),
+			want:     time.Date(2024, 3, 1, 13, 0, 0, 0, time.UTC),
+		},
+		{name: "empty", schedule: data.Schedule{}, wantErr: true},
+		{name: "invalid cron", schedule: data.Schedule{Cron: "61 * * * *"}, wantErr: true},
+		{name: "invalid time zone", schedule: data.Schedule{Cron: "@daily", Timezone: "Mars/Olympus"}, wantErr: true},
+		{name: "inline time zone", schedule: data.Schedule{Cron: "CRON_TZ=UTC @daily"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(&tt.schedule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := schedule.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduler_FiresEachTickOnce(t *testing.T) {
+	ctx := context.Background()
+	trigger := &data.Trigger{ID: "nightly", Namespace: "billing", Enabled: true, Schedule: &data.Schedule{Cron: "0 2 * * *"}}
+	store := &staticStore{triggers: []*data.Trigger{
+		trigger,
+		{ID: "disabled", Namespace: "billing", Schedule: &data.Schedule{Cron: "* * * * *"}},
+		{ID: "event-driven", Namespace: "billing", Enabled: true},
+	}}
+
+	var fired []*data.Event
+	fire := func(ctx context.Context, trigger *data.Trigger, event *data.Event) {
+		fired = append(fired, event)
+	}
+	ledger := NewMemoryLedger()
+	now := time.Date(2024, 3, 1, 1, 59, 0, 0, time.UTC)
+
+	// Two instances share the ledger; only one of them fires the tick
+	first := NewScheduler(store, fire)
+	first.now = func() time.Time { return now }
+	second := NewScheduler(store, fire)
+	second.now = func() time.Time { return now }
+
+	firstEntries, secondEntries := make(map[string]*entry), make(map[string]*entry)
+	first.tick(ctx, ledger, firstEntries, true)
+	second.tick(ctx, ledger, secondEntries, true)
+	if len(fired) != 0 {
+		t.Fatalf("fired %d ticks before the schedule, want 0", len(fired))
+	}
+
+	now = now.Add(2 * time.Minute)
+	first.tick(ctx, ledger, firstEntries, false)
+	second.tick(ctx, ledger, secondEntries, false)
+	if len(fired) != 1 {
+		t.Fatalf("fired %d ticks, want 1", len(fired))
+	}
+
+	event := fired[0]
+	if event.EventType != data.ScheduleTickEventType || event.Namespace != "billing" || event.ObjectID != "nightly" {
+		t.Errorf("tick event = %s %s/%s", event.EventType, event.Namespace, event.ObjectID)
+	}
+	if want := time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC); !event.Timestamp.Equal(want) {
+		t.Errorf("tick timestamp = %v, want %v", event.Timestamp, want)
+	}
+
+	matched, err := triggers.MatchTrigger(trigger, event)
+	if err != nil || !matched {
+		t.Errorf("MatchTrigger(tick) = %v, %v, want true", matched, err)
+	}
+}
+
+func TestScheduler_FiringOutlivesLeadership(t *testing.T) {
+	leaderCtx, loseLeadership := context.WithCancel(context.Background())
+	store := &staticStore{triggers: []*data.Trigger{
+		{ID: "nightly", Namespace: "billing", Enabled: true, Schedule: &data.Schedule{Cron: "0 2 * * *"}},
+	}}
+
+	var fireCtx context.Context
+	fire := func(ctx context.Context, trigger *data.Trigger, event *data.Event) {
+		fireCtx = ctx
+	}
+	now := time.Date(2024, 3, 1, 1, 59, 0, 0, time.UTC)
+	s := NewScheduler(store, fire)
+	s.now = func() time.Time { return now }
+
+	entries := make(map[string]*entry)
+	ledger := NewMemoryLedger()
+	s.tick(leaderCtx, ledger, entries, true)
+	now = now.Add(2 * time.Minute)
+	s.tick(leaderCtx, ledger, entries, false)
+	if fireCtx == nil {
+		t.Fatal("tick was not fired")
+	}
+
+	// The tick is claimed, so the deliveries it started must keep running
+	loseLeadership()
+	if err := fireCtx.Err(); err != nil {
+		t.Errorf("firing context error = %v after leadership was lost, want nil", err)
+	}
+}
+
+func TestScheduler_CatchUp(t *testing.T) {
+	ctx := context.Background()
+	store := &staticStore{triggers: []*data.Trigger{
+		{ID: "hourly", Namespace: "billing", Enabled: true, Schedule: &data.Schedule{Cron: "@hourly"}},
+	}}
+
+	var fired []time.Time
+	fire := func(ctx context.Context, trigger *data.Trigger, event *data.Event) {
+		fired = append(fired, event.Timestamp)
+	}
+
+	// The previous leader fired 10:00; 11:00 and 12:00 were missed during the handover
+	ledger := NewMemoryLedger()
+	ledger.Claim(ctx, "billing", "hourly", time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC))
+
+	s := NewScheduler(store, fire, WithCatchUp(90*time.Minute))
+	s.now = func() time.Time { return time.Date(2024, 3, 1, 12, 10, 0, 0, time.UTC) }
+	s.tick(ctx, ledger, make(map[string]*entry), true)
+
+	// 11:00 is within the catch-up window, 12:00 is due
+	if len(fired) != 2 || fired[0].Hour() != 11 || fired[1].Hour() != 12 {
+		t.Errorf("fired = %v, want 11:00 and 12:00", fired)
+	}
+}
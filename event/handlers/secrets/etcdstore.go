@@ -0,0 +1,122 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// DefaultSecretPrefix is the default prefix for signing secret keys in etcd.
+// It is kept apart from the trigger prefix so secrets never appear in trigger YAML.
+const DefaultSecretPrefix = "/trigger-secrets/"
+
+// Compile-time check to ensure EtcdStore implements Store
+var _ Store = (*EtcdStore)(nil)
+
+// EtcdStore is a signing secret store backed by etcd.
+// Namespace secrets are stored under <prefix><namespace> and
+// trigger secrets under <prefix><namespace>/<triggerID>.
+type EtcdStore struct {
+	client *clientv3.Client
+	prefix string
+}
+
+// secretRecord is the value stored in etcd for each key
+type secretRecord struct {
+	Secrets []string `json:"secrets"`
+}
+
+// NewEtcdStore creates a new etcd-backed secret store using an existing client
+func NewEtcdStore(client *clientv3.Client, prefix string) *EtcdStore {
+	if prefix == "" {
+		prefix = DefaultSecretPrefix
+	}
+
+	// Ensure prefix ends with "/"
+	if !strings.HasSuffix(prefix, "/") {
+		prefix = prefix + "/"
+	}
+
+	return &EtcdStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Secrets returns the active signing secrets for a trigger, newest first
+func (s *EtcdStore) Secrets(ctx context.Context, namespace, triggerID string) ([]string, error) {
+	if triggerID != "" {
+		secrets, err := s.get(ctx, s.key(namespace, triggerID))
+		if err != nil || len(secrets) > 0 {
+			return secrets, err
+		}
+	}
+	return s.get(ctx, s.key(namespace, ""))
+}
+
+// Rotate makes secret the primary secret and keeps the previous primary active
+func (s *EtcdStore) Rotate(ctx context.Context, namespace, triggerID, secret string) error {
+	if secret == "" {
+		return fmt.Errorf("secret must not be empty")
+	}
+
+	key := s.key(namespace, triggerID)
+	current, err := s.get(ctx, key)
+	if err != nil {
+		return err
+	}
+	return s.put(ctx, key, rotate(current, secret))
+}
+
+// Revoke removes every secret except the primary one
+func (s *EtcdStore) Revoke(ctx context.Context, namespace, triggerID string) error {
+	key := s.key(namespace, triggerID)
+	current, err := s.get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if len(current) <= 1 {
+		return nil
+	}
+	return s.put(ctx, key, current[:1])
+}
+
+// key returns the etcd key for a namespace or trigger
+func (s *EtcdStore) key(namespace, triggerID string) string {
+	if triggerID == "" {
+		return s.prefix + namespace
+	}
+	return s.prefix + namespace + "/" + triggerID
+}
+
+// get reads the secrets stored under key
+func (s *EtcdStore) get(ctx context.Context, key string) ([]string, error) {
+	resp, err := s.client.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secrets from etcd: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+
+	var record secretRecord
+	if err := json.Unmarshal(resp.Kvs[0].Value, &record); err != nil {
+		return nil, fmt.Errorf("failed to parse secrets %s: %w", key, err)
+	}
+	return record.Secrets, nil
+}
+
+// put writes the secrets under key
+func (s *EtcdStore) put(ctx context.Context, key string, secrets []string) error {
+	value, err := json.Marshal(secretRecord{Secrets: secrets})
+	if err != nil {
+		return fmt.Errorf("failed to marshal secrets: %w", err)
+	}
+	if _, err := s.client.Put(ctx, key, string(value)); err != nil {
+		return fmt.Errorf("failed to save secrets to etcd: %w", err)
+	}
+	return nil
+}
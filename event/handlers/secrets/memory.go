@@ -0,0 +1,67 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Compile-time check to ensure MemoryStore implements Store
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore is an in-memory secret store, intended for tests and local development
+type MemoryStore struct {
+	secrets map[string][]string // namespace or namespace/triggerID -> secrets
+	mu      sync.RWMutex
+}
+
+// NewMemoryStore creates a new in-memory secret store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		secrets: make(map[string][]string),
+	}
+}
+
+// Secrets returns the active signing secrets for a trigger, newest first
+func (s *MemoryStore) Secrets(ctx context.Context, namespace, triggerID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if secrets := s.secrets[memoryKey(namespace, triggerID)]; triggerID != "" && len(secrets) > 0 {
+		return secrets, nil
+	}
+	return s.secrets[memoryKey(namespace, "")], nil
+}
+
+// Rotate makes secret the primary secret and keeps the previous primary active
+func (s *MemoryStore) Rotate(ctx context.Context, namespace, triggerID, secret string) error {
+	if secret == "" {
+		return fmt.Errorf("secret must not be empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := memoryKey(namespace, triggerID)
+	s.secrets[key] = rotate(s.secrets[key], secret)
+	return nil
+}
+
+// Revoke removes every secret except the primary one
+func (s *MemoryStore) Revoke(ctx context.Context, namespace, triggerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := memoryKey(namespace, triggerID)
+	if len(s.secrets[key]) > 1 {
+		s.secrets[key] = s.secrets[key][:1]
+	}
+	return nil
+}
+
+func memoryKey(namespace, triggerID string) string {
+	if triggerID == "" {
+		return namespace
+	}
+	return namespace + "/" + triggerID
+}
@@ -0,0 +1,75 @@
+package secrets
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestMemoryStore_Rotation(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	steps := []struct {
+		name   string
+		action func() error
+		want   []string
+	}{
+		{
+			name:   "no secrets",
+			action: func() error { return nil },
+			want:   nil,
+		},
+		{
+			name:   "initial secret",
+			action: func() error { return store.Rotate(ctx, "sales", "", "s1") },
+			want:   []string{"s1"},
+		},
+		{
+			name:   "rotation keeps previous secret",
+			action: func() error { return store.Rotate(ctx, "sales", "", "s2") },
+			want:   []string{"s2", "s1"},
+		},
+		{
+			name:   "only two secrets are active",
+			action: func() error { return store.Rotate(ctx, "sales", "", "s3") },
+			want:   []string{"s3", "s2"},
+		},
+		{
+			name:   "revoke ends the rotation",
+			action: func() error { return store.Revoke(ctx, "sales", "") },
+			want:   []string{"s3"},
+		},
+	}
+
+	for _, step := range steps {
+		if err := step.action(); err != nil {
+			t.Fatalf("%s: error = %v", step.name, err)
+		}
+		got, err := store.Secrets(ctx, "sales", "t1")
+		if err != nil {
+			t.Fatalf("%s: Secrets() error = %v", step.name, err)
+		}
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: Secrets() = %v, want %v", step.name, got, step.want)
+		}
+	}
+}
+
+func TestMemoryStore_TriggerOverridesNamespace(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	store.Rotate(ctx, "sales", "", "namespace-secret")
+	store.Rotate(ctx, "sales", "t1", "trigger-secret")
+
+	got, _ := store.Secrets(ctx, "sales", "t1")
+	if !reflect.DeepEqual(got, []string{"trigger-secret"}) {
+		t.Errorf("Secrets(t1) = %v, want [trigger-secret]", got)
+	}
+
+	got, _ = store.Secrets(ctx, "sales", "t2")
+	if !reflect.DeepEqual(got, []string{"namespace-secret"}) {
+		t.Errorf("Secrets(t2) = %v, want [namespace-secret]", got)
+	}
+}
@@ -0,0 +1,40 @@
+package secrets
+
+import (
+	"context"
+)
+
+// MaxActiveSecrets is the number of secrets kept active during rotation
+const MaxActiveSecrets = 2
+
+// Store defines the interface for a webhook signing secret store.
+// Secrets can be set for a whole namespace or for a single trigger;
+// a trigger's own secrets take precedence over its namespace's.
+type Store interface {
+	// Secrets returns the active signing secrets for a trigger, newest first.
+	// It falls back to the namespace secrets and returns nil when neither is set.
+	Secrets(ctx context.Context, namespace, triggerID string) ([]string, error)
+
+	// Rotate makes secret the primary secret and keeps the previous primary active,
+	// so receivers can switch secrets without rejecting deliveries.
+	// An empty triggerID rotates the namespace secret.
+	Rotate(ctx context.Context, namespace, triggerID, secret string) error
+
+	// Revoke removes every secret except the primary one, ending a rotation.
+	// An empty triggerID revokes the namespace secret.
+	Revoke(ctx context.Context, namespace, triggerID string) error
+}
+
+// rotate returns the active secrets after adding secret as the new primary
+func rotate(current []string, secret string) []string {
+	rotated := []string{secret}
+	for _, s := range current {
+		if len(rotated) == MaxActiveSecrets {
+			break
+		}
+		if s != secret {
+			rotated = append(rotated, s)
+		}
+	}
+	return rotated
+}
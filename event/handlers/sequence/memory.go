@@ -0,0 +1,110 @@
+package sequence
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"event/data"
+)
+
+// Compile-time check to ensure MemoryStore implements Store
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore is an in-memory sequence store, intended for tests and local development
+type MemoryStore struct {
+	instances map[string]*Instance
+	mu        sync.RWMutex
+}
+
+// NewMemoryStore creates a new in-memory sequence store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		instances: make(map[string]*Instance),
+	}
+}
+
+// Get returns an instance by ID
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Instance, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	instance, ok := s.instances[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyInstance(instance), nil
+}
+
+// Create stores a new instance, returning ErrExists if its ID is taken
+func (s *MemoryStore) Create(ctx context.Context, instance *Instance) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.instances[instance.ID]; ok {
+		return ErrExists
+	}
+	s.instances[instance.ID] = copyInstance(instance)
+	return nil
+}
+
+// Update replaces an instance if its version is unchanged since it was read
+func (s *MemoryStore) Update(ctx context.Context, instance *Instance) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.instances[instance.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if current.Version != instance.Version {
+		return ErrConflict
+	}
+	instance.Version++
+	s.instances[instance.ID] = copyInstance(instance)
+	return nil
+}
+
+// Delete removes an instance if its version is unchanged since it was read
+func (s *MemoryStore) Delete(ctx context.Context, instance *Instance) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.instances[instance.ID]
+	if !ok || current.Version != instance.Version {
+		return false, nil
+	}
+	delete(s.instances, instance.ID)
+	return true, nil
+}
+
+// ListExpired returns up to limit instances whose deadline is before the given time, earliest first
+func (s *MemoryStore) ListExpired(ctx context.Context, before time.Time, limit int) ([]*Instance, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var expired []*Instance
+	for _, instance := range s.instances {
+		if instance.Deadline.Before(before) {
+			expired = append(expired, copyInstance(instance))
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].Deadline.Before(expired[j].Deadline)
+	})
+	if limit > 0 && len(expired) > limit {
+		expired = expired[:limit]
+	}
+	return expired, nil
+}
+
+// copyInstance returns a copy of an instance that shares no maps with the original
+func copyInstance(instance *Instance) *Instance {
+	c := *instance
+	c.Steps = make(map[string]*data.Event, len(instance.Steps))
+	for name, event := range instance.Steps {
+		c.Steps[name] = event
+	}
+	return &c
+}
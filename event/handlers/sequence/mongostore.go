@@ -0,0 +1,170 @@
+package sequence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultCollection is the default MongoDB collection for sequence instances
+const DefaultCollection = "sequences"
+
+// Compile-time check to ensure MongoStore implements Store
+var _ Store = (*MongoStore)(nil)
+
+// MongoStore is a sequence store backed by a MongoDB collection
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoStore creates a new MongoDB-backed sequence store and ensures its indexes exist
+func NewMongoStore(ctx context.Context, db *mongo.Database, collectionName string) (*MongoStore, error) {
+	if collectionName == "" {
+		collectionName = DefaultCollection
+	}
+	// Decode nested documents (event payloads) as maps rather than bson.D
+	collection := db.Collection(collectionName, options.Collection().SetBSONOptions(&options.BSONOptions{DefaultDocumentM: true}))
+
+	indexModels := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "deadline", Value: 1}},
+		},
+	}
+	if _, err := collection.Indexes().CreateMany(ctx, indexModels); err != nil {
+		return nil, fmt.Errorf("failed to create sequence indexes: %w", err)
+	}
+
+	return &MongoStore{
+		collection: collection,
+	}, nil
+}
+
+// Get returns an instance by ID
+func (s *MongoStore) Get(ctx context.Context, id string) (*Instance, error) {
+	var instance Instance
+	err := s.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&instance)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sequence instance: %w", err)
+	}
+	plainPayloads(&instance)
+	return &instance, nil
+}
+
+// Create stores a new instance, returning ErrExists if its ID is taken
+func (s *MongoStore) Create(ctx context.Context, instance *Instance) error {
+	if _, err := s.collection.InsertOne(ctx, instance); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrExists
+		}
+		return fmt.Errorf("failed to insert sequence instance: %w", err)
+	}
+	return nil
+}
+
+// Update replaces an instance if its version is unchanged since it was read
+func (s *MongoStore) Update(ctx context.Context, instance *Instance) error {
+	version := instance.Version
+	instance.Version++
+
+	result, err := s.collection.ReplaceOne(ctx, bson.M{"_id": instance.ID, "version": version}, instance)
+	if err != nil {
+		instance.Version = version
+		return fmt.Errorf("failed to update sequence instance: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		instance.Version = version
+		if _, err := s.Get(ctx, instance.ID); err != nil {
+			return err
+		}
+		return ErrConflict
+	}
+	return nil
+}
+
+// Delete removes an instance if its version is unchanged since it was read
+func (s *MongoStore) Delete(ctx context.Context, instance *Instance) (bool, error) {
+	result, err := s.collection.DeleteOne(ctx, bson.M{"_id": instance.ID, "version": instance.Version})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete sequence instance: %w", err)
+	}
+	return result.DeletedCount == 1, nil
+}
+
+// ListExpired returns up to limit instances whose deadline is before the given time, earliest first
+func (s *MongoStore) ListExpired(ctx context.Context, before time.Time, limit int) ([]*Instance, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "deadline", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	cursor, err := s.collection.Find(ctx, bson.M{"deadline": bson.M{"$lt": before}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired sequence instances: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var instances []*Instance
+	if err := cursor.All(ctx, &instances); err != nil {
+		return nil, fmt.Errorf("failed to decode sequence instances: %w", err)
+	}
+	for _, instance := range instances {
+		plainPayloads(instance)
+	}
+	return instances, nil
+}
+
+// plainPayloads turns the documents and arrays nested in the payloads of an instance's step
+// events into map[string]interface{} and []interface{}, as decoded from JSON. Criteria such
+// as has() do not recognize primitive.M and primitive.A.
+func plainPayloads(instance *Instance) {
+	for _, event := range instance.Steps {
+		if event == nil {
+			continue
+		}
+		event.Payload.Before = plainMap(event.Payload.Before)
+		event.Payload.After = plainMap(event.Payload.After)
+	}
+}
+
+func plainMap(m map[string]interface{}) map[string]interface{} {
+	for key, value := range m {
+		m[key] = plainValue(value)
+	}
+	return m
+}
+
+func plainValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case primitive.M:
+		return plainMap(map[string]interface{}(v))
+	case map[string]interface{}:
+		return plainMap(v)
+	case primitive.D:
+		m := make(map[string]interface{}, len(v))
+		for _, e := range v {
+			m[e.Key] = plainValue(e.Value)
+		}
+		return m
+	case primitive.A:
+		return plainSlice([]interface{}(v))
+	case []interface{}:
+		return plainSlice(v)
+	}
+	return value
+}
+
+func plainSlice(s []interface{}) []interface{} {
+	for i, value := range s {
+		s[i] = plainValue(value)
+	}
+	return s
+}
@@ -0,0 +1,41 @@
+package sequence
+
+import (
+	"testing"
+
+	"event/data"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+)
+
+func TestPlainPayloads(t *testing.T) {
+	created := &data.Event{ID: "e1", EventType: "order.created", Namespace: "sales"}
+	created.Payload.After = map[string]interface{}{
+		"address": map[string]interface{}{"city": "Berlin"},
+		"items":   []interface{}{map[string]interface{}{"sku": "a1"}},
+	}
+	instance := &Instance{ID: "sales/t1/o1", Steps: map[string]*data.Event{"created": created}}
+
+	// Decode the instance as MongoStore does
+	raw, err := bson.Marshal(instance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder, err := bson.NewDecoder(bsonrw.NewBSONDocumentReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder.DefaultDocumentM()
+	var decoded Instance
+	if err := decoder.Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+	plainPayloads(&decoded)
+
+	step := data.SequenceStep{Name: "shipped", Criteria: `has(steps.created, "payload.after.address.city") && steps.created.payload.after.items[0].sku == "a1"`}
+	matched, err := matchStep(step, &data.Event{ID: "e2"}, decoded.Steps)
+	if err != nil || !matched {
+		t.Errorf("matchStep() after a BSON round trip = %v, %v, want true", matched, err)
+	}
+}
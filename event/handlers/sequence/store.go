@@ -0,0 +1,60 @@
+package sequence
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"event/data"
+)
+
+var (
+	// ErrNotFound is returned when a sequence instance does not exist
+	ErrNotFound = errors.New("sequence instance not found")
+	// ErrExists is returned when creating an instance whose key is already in progress
+	ErrExists = errors.New("sequence instance already exists")
+	// ErrConflict is returned when an instance was changed since it was read
+	ErrConflict = errors.New("sequence instance was modified concurrently")
+)
+
+// Instance is the progress of a sequence trigger for one correlation key
+type Instance struct {
+	ID        string `json:"id" bson:"_id"` // namespace/trigger ID/key
+	Namespace string `json:"namespace" bson:"namespace"`
+	TriggerID string `json:"trigger_id" bson:"trigger_id"`
+	Key       string `json:"key" bson:"key"`
+	// Sequence is the definition the instance was started with
+	Sequence data.Sequence `json:"sequence" bson:"sequence"`
+	// Steps holds the events of the matched steps by step name
+	Steps map[string]*data.Event `json:"steps" bson:"steps"`
+	// Next is the index of the next step to match
+	Next      int       `json:"next" bson:"next"`
+	StartedAt time.Time `json:"started_at" bson:"started_at"`
+	Deadline  time.Time `json:"deadline" bson:"deadline"`
+	// Version is incremented on every update for optimistic locking
+	Version int64 `json:"version" bson:"version"`
+}
+
+// InstanceID returns the ID of the instance of a trigger for a key
+func InstanceID(namespace, triggerID, key string) string {
+	return namespace + "/" + triggerID + "/" + key
+}
+
+// Store keeps sequence instances durably, so that sequences complete across restarts and replicas
+type Store interface {
+	// Get returns an instance by ID
+	Get(ctx context.Context, id string) (*Instance, error)
+
+	// Create stores a new instance, returning ErrExists if its ID is taken
+	Create(ctx context.Context, instance *Instance) error
+
+	// Update replaces an instance if its version is unchanged since it was read
+	Update(ctx context.Context, instance *Instance) error
+
+	// Delete removes an instance if its version is unchanged since it was read.
+	// It reports whether the instance was removed by this call.
+	Delete(ctx context.Context, instance *Instance) (bool, error)
+
+	// ListExpired returns up to limit instances whose deadline is before the given time
+	ListExpired(ctx context.Context, before time.Time, limit int) ([]*Instance, error)
+}
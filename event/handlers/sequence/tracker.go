@@ -0,0 +1,323 @@
+package sequence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"time"
+
+	"event/data"
+	"event/handlers/triggers"
+)
+
+const (
+	// DefaultKey correlates the steps of a sequence by the object they are about
+	DefaultKey = "event.object_id"
+	// ActorID is the actor of sequence events
+	ActorID = "triggerd"
+
+	// expireBatch is how many expired instances are read at a time
+	expireBatch = 100
+	// maxConflictRetries is how often an event is re-applied when another replica updated
+	// the same instance concurrently
+	maxConflictRetries = 5
+)
+
+// stepName matches step names that can be used as steps.<name> in criteria
+var stepName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Validate checks a sequence before its trigger is saved
+func Validate(seq *data.Sequence) error {
+	timeout, err := time.ParseDuration(seq.Timeout)
+	if err != nil {
+		return fmt.Errorf("invalid timeout %q: %w", seq.Timeout, err)
+	}
+	if timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+
+	if len(seq.Steps) == 0 {
+		return fmt.Errorf("at least one step is required")
+	}
+	if seq.Absent && len(seq.Steps) < 2 {
+		return fmt.Errorf("an absence sequence needs at least two steps")
+	}
+
+	names := make(map[string]bool)
+	for i, step := range seq.Steps {
+		if !stepName.MatchString(step.Name) {
+			return fmt.Errorf("step %d: invalid name %q", i, step.Name)
+		}
+		if names[step.Name] {
+			return fmt.Errorf("step %d: duplicate name %q", i, step.Name)
+		}
+		names[step.Name] = true
+
+		if step.Criteria == "" {
+			return fmt.Errorf("step %s: criteria is required", step.Name)
+		}
+		if err := triggers.CompileExpression(step.Criteria, "steps"); err != nil {
+			return fmt.Errorf("step %s: %w", step.Name, err)
+		}
+	}
+
+	if seq.Key != "" {
+		if err := triggers.CompileExpression(seq.Key); err != nil {
+			return fmt.Errorf("key: %w", err)
+		}
+	}
+	return nil
+}
+
+// Firing is a sequence trigger to fire with a sequence event
+type Firing struct {
+	Trigger *data.Trigger
+	Event   *data.Event
+}
+
+// Tracker advances sequence triggers as events arrive and fires them
+type Tracker struct {
+	store    Store
+	triggers triggers.TriggerStore
+	now      func() time.Time
+}
+
+// NewTracker creates a tracker that keeps its instances in store and looks up the
+// triggers of expired instances in triggerStore
+func NewTracker(store Store, triggerStore triggers.TriggerStore) *Tracker {
+	return &Tracker{
+		store:    store,
+		triggers: triggerStore,
+		now:      time.Now,
+	}
+}
+
+// Observe applies an event to the sequence of a trigger and returns the events that fire it.
+// An instance that is past its deadline is finished first, which fires an absence sequence.
+func (t *Tracker) Observe(ctx context.Context, trigger *data.Trigger, event *data.Event) ([]*data.Event, error) {
+	if trigger.Sequence == nil || !trigger.Enabled {
+		return nil, nil
+	}
+
+	expression := trigger.Sequence.Key
+	if expression == "" {
+		expression = DefaultKey
+	}
+	value, err := triggers.EvaluateExpression(expression, event)
+	if err != nil {
+		return nil, fmt.Errorf("key: %w", err)
+	}
+	if value == nil || value == "" {
+		return nil, nil
+	}
+	id := InstanceID(trigger.Namespace, trigger.ID, fmt.Sprint(value))
+
+	for attempt := 0; attempt < maxConflictRetries; attempt++ {
+		fired, err := t.observe(ctx, trigger, event, id, fmt.Sprint(value))
+		if errors.Is(err, ErrConflict) || errors.Is(err, ErrExists) {
+			continue
+		}
+		return fired, err
+	}
+	return nil, fmt.Errorf("too many concurrent updates of sequence %s", id)
+}
+
+func (t *Tracker) observe(ctx context.Context, trigger *data.Trigger, event *data.Event, id, key string) ([]*data.Event, error) {
+	seq := trigger.Sequence
+	at := event.Timestamp
+	if at.IsZero() {
+		at = t.now()
+	}
+
+	instance, err := t.store.Get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		instance = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var fired []*data.Event
+
+	// Instances of an older definition or past their deadline no longer progress
+	if instance != nil && (!reflect.DeepEqual(instance.Sequence, *seq) || !at.Before(instance.Deadline)) {
+		deleted, err := t.store.Delete(ctx, instance)
+		if err != nil {
+			return nil, err
+		}
+		if !deleted {
+			return nil, ErrConflict
+		}
+		if absence := absenceEvent(trigger, instance); absence != nil {
+			fired = append(fired, absence)
+		}
+		instance = nil
+	}
+
+	if instance == nil {
+		first := seq.Steps[0]
+		matched, err := matchStep(first, event, nil)
+		if err != nil || !matched {
+			return fired, err
+		}
+
+		timeout, err := time.ParseDuration(seq.Timeout)
+		if err != nil {
+			return fired, fmt.Errorf("invalid timeout: %w", err)
+		}
+		instance = &Instance{
+			ID:        id,
+			Namespace: trigger.Namespace,
+			TriggerID: trigger.ID,
+			Key:       key,
+			Sequence:  *seq,
+			Steps:     map[string]*data.Event{first.Name: event},
+			Next:      1,
+			StartedAt: at,
+			Deadline:  at.Add(timeout),
+		}
+
+		if len(seq.Steps) == 1 {
+			return append(fired, sequenceEvent(data.SequenceMatchedEventType, trigger, instance, at)), nil
+		}
+		if err := t.store.Create(ctx, instance); err != nil {
+			return nil, err
+		}
+		return fired, nil
+	}
+
+	step := seq.Steps[instance.Next]
+	matched, err := matchStep(step, event, instance.Steps)
+	if err != nil || !matched {
+		return fired, err
+	}
+	instance.Steps[step.Name] = event
+	instance.Next++
+
+	if instance.Next < len(seq.Steps) {
+		if err := t.store.Update(ctx, instance); err != nil {
+			return nil, err
+		}
+		return fired, nil
+	}
+
+	// The last step occurred: the sequence is complete, or the absence did not happen
+	deleted, err := t.store.Delete(ctx, instance)
+	if err != nil {
+		return nil, err
+	}
+	if !deleted {
+		return nil, ErrConflict
+	}
+	if !seq.Absent {
+		fired = append(fired, sequenceEvent(data.SequenceMatchedEventType, trigger, instance, at))
+	}
+	return fired, nil
+}
+
+// Expire finishes the instances whose deadline has passed and returns the absence
+// sequences to fire. Each instance is finished by one replica only.
+func (t *Tracker) Expire(ctx context.Context) ([]Firing, error) {
+	var firings []Firing
+	for {
+		expired, err := t.store.ListExpired(ctx, t.now(), expireBatch)
+		if err != nil {
+			return firings, err
+		}
+
+		for _, instance := range expired {
+			deleted, err := t.store.Delete(ctx, instance)
+			if err != nil {
+				return firings, err
+			}
+			if !deleted {
+				continue
+			}
+
+			trigger := t.lookup(instance.Namespace, instance.TriggerID)
+			if trigger == nil || !trigger.Enabled || trigger.Sequence == nil || !reflect.DeepEqual(*trigger.Sequence, instance.Sequence) {
+				continue
+			}
+			if event := absenceEvent(trigger, instance); event != nil {
+				firings = append(firings, Firing{Trigger: trigger, Event: event})
+			}
+		}
+
+		if len(expired) < expireBatch {
+			return firings, nil
+		}
+	}
+}
+
+// lookup returns a trigger by namespace and ID, or nil if it does not exist
+func (t *Tracker) lookup(namespace, id string) *data.Trigger {
+	for _, trigger := range t.triggers.GetTriggers(namespace) {
+		if trigger.ID == id {
+			return trigger
+		}
+	}
+	return nil
+}
+
+// matchStep evaluates a step's criteria against an event and the events of earlier steps
+func matchStep(step data.SequenceStep, event *data.Event, steps map[string]*data.Event) (bool, error) {
+	stepMaps := make(map[string]interface{}, len(steps))
+	for name, e := range steps {
+		stepMaps[name] = e.ToMap()
+	}
+
+	output, err := triggers.EvaluateEnv(step.Criteria, map[string]interface{}{
+		"event": event.ToMap(),
+		"steps": stepMaps,
+	})
+	if err != nil {
+		return false, fmt.Errorf("step %s: %w", step.Name, err)
+	}
+	matched, ok := output.(bool)
+	if !ok {
+		return false, fmt.Errorf("step %s: criteria did not return a boolean", step.Name)
+	}
+	return matched, nil
+}
+
+// absenceEvent returns the event that fires an expired absence sequence, or nil if the
+// instance does not describe a missing last step of the trigger's current definition
+func absenceEvent(trigger *data.Trigger, instance *Instance) *data.Event {
+	seq := instance.Sequence
+	if !seq.Absent || instance.Next != len(seq.Steps)-1 || trigger.Sequence == nil || !reflect.DeepEqual(*trigger.Sequence, seq) {
+		return nil
+	}
+	return sequenceEvent(data.SequenceAbsentEventType, trigger, instance, instance.Deadline)
+}
+
+// sequenceEvent returns the synthetic event that fires a sequence trigger
+func sequenceEvent(eventType string, trigger *data.Trigger, instance *Instance, at time.Time) *data.Event {
+	last := instance.Steps[instance.Sequence.Steps[instance.Next-1].Name]
+
+	steps := make(map[string]interface{}, len(instance.Steps))
+	for name, e := range instance.Steps {
+		steps[name] = e.ToMap()
+	}
+
+	event := &data.Event{
+		ID:           data.NewEventID(),
+		EventType:    eventType,
+		EventVersion: "1.0.0",
+		Namespace:    trigger.Namespace,
+		ObjectType:   last.ObjectType,
+		ObjectID:     last.ObjectID,
+		Timestamp:    at.UTC(),
+	}
+	event.Actor.Type = "system"
+	event.Actor.ID = ActorID
+	event.Context = last.Context
+	event.Payload.After = map[string]interface{}{
+		"trigger_id": trigger.ID,
+		"key":        instance.Key,
+		"steps":      steps,
+		"started_at": instance.StartedAt.UTC().Format(time.RFC3339),
+		"deadline":   instance.Deadline.UTC().Format(time.RFC3339),
+	}
+	return event
+}
@@ -0,0 +1,180 @@
+package sequence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"event/data"
+	"event/handlers/triggers"
+)
+
+var base = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// staticStore is a trigger store with a fixed set of triggers
+type staticStore struct {
+	triggers.TriggerStore
+	triggers []*data.Trigger
+}
+
+func (s *staticStore) GetTriggers(namespace string) []*data.Trigger {
+	var result []*data.Trigger
+	for _, trigger := range s.triggers {
+		if trigger.Namespace == namespace {
+			result = append(result, trigger)
+		}
+	}
+	return result
+}
+
+func newEvent(eventType, objectID, actor string, at time.Duration) *data.Event {
+	event := &data.Event{
+		ID:         data.NewEventID(),
+		EventType:  eventType,
+		Namespace:  "shop",
+		ObjectType: "order",
+		ObjectID:   objectID,
+		Timestamp:  base.Add(at),
+	}
+	event.Actor.Type = "user"
+	event.Actor.ID = actor
+	return event
+}
+
+func newTracker(trigger *data.Trigger, now time.Duration) (*Tracker, *MemoryStore) {
+	store := NewMemoryStore()
+	tracker := NewTracker(store, &staticStore{triggers: []*data.Trigger{trigger}})
+	tracker.now = func() time.Time { return base.Add(now) }
+	return tracker, store
+}
+
+func TestTracker_Sequence(t *testing.T) {
+	ctx := context.Background()
+	trigger := &data.Trigger{ID: "reset-then-login", Namespace: "shop", Enabled: true, Sequence: &data.Sequence{
+		Steps: []data.SequenceStep{
+			{Name: "reset", Criteria: `event.event_type == "user.password_reset"`},
+			{Name: "login", Criteria: `event.event_type == "user.login" && event.actor.id != steps.reset.actor.id`},
+		},
+		Key:     "event.object_id",
+		Timeout: "5m",
+	}}
+	tracker, _ := newTracker(trigger, 0)
+
+	steps := []struct {
+		event *data.Event
+		fires bool
+	}{
+		{newEvent("user.password_reset", "u1", "alice", 0), false},
+		{newEvent("user.login", "u1", "alice", time.Minute), false},   // same actor
+		{newEvent("user.login", "u2", "mallory", time.Minute), false}, // other key
+		{newEvent("user.login", "u1", "mallory", 2*time.Minute), true},
+		{newEvent("user.login", "u1", "mallory", 3*time.Minute), false}, // sequence finished
+	}
+
+	for i, step := range steps {
+		fired, err := tracker.Observe(ctx, trigger, step.event)
+		if err != nil {
+			t.Fatalf("step %d: Observe() error = %v", i, err)
+		}
+		if (len(fired) == 1) != step.fires {
+			t.Fatalf("step %d: fired %d events, want fires=%v", i, len(fired), step.fires)
+		}
+		if step.fires {
+			event := fired[0]
+			if event.EventType != data.SequenceMatchedEventType || event.Payload.After["key"] != "u1" {
+				t.Errorf("sequence event = %s %v", event.EventType, event.Payload.After)
+			}
+			stepEvents := event.Payload.After["steps"].(map[string]interface{})
+			if len(stepEvents) != 2 {
+				t.Errorf("sequence event steps = %v, want reset and login", stepEvents)
+			}
+		}
+	}
+}
+
+func TestTracker_Timeout(t *testing.T) {
+	ctx := context.Background()
+	trigger := &data.Trigger{ID: "quick-login", Namespace: "shop", Enabled: true, Sequence: &data.Sequence{
+		Steps: []data.SequenceStep{
+			{Name: "reset", Criteria: `event.event_type == "user.password_reset"`},
+			{Name: "login", Criteria: `event.event_type == "user.login"`},
+		},
+		Timeout: "5m",
+	}}
+	tracker, _ := newTracker(trigger, 0)
+
+	tracker.Observe(ctx, trigger, newEvent("user.password_reset", "u1", "alice", 0))
+	fired, err := tracker.Observe(ctx, trigger, newEvent("user.login", "u1", "alice", 6*time.Minute))
+	if err != nil || len(fired) != 0 {
+		t.Errorf("Observe() after the timeout = %v, %v, want no events", fired, err)
+	}
+}
+
+func TestTracker_Absence(t *testing.T) {
+	ctx := context.Background()
+	trigger := &data.Trigger{ID: "unpaid", Namespace: "shop", Enabled: true, Sequence: &data.Sequence{
+		Steps: []data.SequenceStep{
+			{Name: "created", Criteria: `event.event_type == "order.created"`},
+			{Name: "paid", Criteria: `event.event_type == "order.paid"`},
+		},
+		Timeout: "24h",
+		Absent:  true,
+	}}
+	tracker, store := newTracker(trigger, 0)
+
+	tracker.Observe(ctx, trigger, newEvent("order.created", "o1", "alice", 0))
+	tracker.Observe(ctx, trigger, newEvent("order.created", "o2", "bob", time.Hour))
+	if fired, _ := tracker.Observe(ctx, trigger, newEvent("order.paid", "o2", "bob", 2*time.Hour)); len(fired) != 0 {
+		t.Fatalf("paid order fired the absence trigger")
+	}
+
+	// Nothing has expired yet
+	if firings, err := tracker.Expire(ctx); err != nil || len(firings) != 0 {
+		t.Fatalf("Expire() before the deadline = %v, %v, want none", firings, err)
+	}
+
+	// A second replica sharing the store expires the same instances; only one of them fires
+	tracker.now = func() time.Time { return base.Add(25 * time.Hour) }
+	other := NewTracker(store, tracker.triggers)
+	other.now = tracker.now
+
+	firings, err := tracker.Expire(ctx)
+	if err != nil || len(firings) != 1 {
+		t.Fatalf("Expire() = %v, %v, want one firing", firings, err)
+	}
+	if event := firings[0].Event; event.EventType != data.SequenceAbsentEventType || event.ObjectID != "o1" {
+		t.Errorf("absence event = %s %s, want %s for o1", event.EventType, event.ObjectID, data.SequenceAbsentEventType)
+	}
+	if again, _ := other.Expire(ctx); len(again) != 0 {
+		t.Errorf("second Expire() fired %d more times", len(again))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	step := func(name, criteria string) data.SequenceStep {
+		return data.SequenceStep{Name: name, Criteria: criteria}
+	}
+
+	tests := []struct {
+		name     string
+		sequence data.Sequence
+		wantErr  bool
+	}{
+		{"valid", data.Sequence{Steps: []data.SequenceStep{step("a", "true"), step("b", "event.actor.id != steps.a.actor.id")}, Timeout: "5m"}, false},
+		{"missing timeout", data.Sequence{Steps: []data.SequenceStep{step("a", "true")}}, true},
+		{"no steps", data.Sequence{Timeout: "5m"}, true},
+		{"absence with one step", data.Sequence{Steps: []data.SequenceStep{step("a", "true")}, Timeout: "5m", Absent: true}, true},
+		{"invalid name", data.Sequence{Steps: []data.SequenceStep{step("a-b", "true")}, Timeout: "5m"}, true},
+		{"duplicate name", data.Sequence{Steps: []data.SequenceStep{step("a", "true"), step("a", "true")}, Timeout: "5m"}, true},
+		{"missing criteria", data.Sequence{Steps: []data.SequenceStep{step("a", "")}, Timeout: "5m"}, true},
+		{"bad key", data.Sequence{Steps: []data.SequenceStep{step("a", "true")}, Timeout: "5m", Key: "event.("}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(&tt.sequence); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
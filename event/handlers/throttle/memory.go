@@ -0,0 +1,132 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"event/data"
+)
+
+// Compile-time checks to ensure the memory stores implement their interfaces
+var (
+	_ Store      = (*MemoryStore)(nil)
+	_ StatsStore = (*MemoryStatsStore)(nil)
+)
+
+// MemoryStore is an in-memory throttle store, intended for tests and a single triggerd instance.
+// Ended claims and counters are dropped whenever a key is claimed.
+type MemoryStore struct {
+	claims   map[string]time.Time
+	counters map[string]*counter
+	mu       sync.Mutex
+}
+
+type counter struct {
+	count   int
+	expires time.Time
+}
+
+// NewMemoryStore creates a new in-memory throttle store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		claims:   make(map[string]time.Time),
+		counters: make(map[string]*counter),
+	}
+}
+
+// Claim takes a key until the given time if it is free
+func (s *MemoryStore) Claim(ctx context.Context, key string, now, until time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current, ok := s.claims[key]; ok && current.After(now) {
+		return false, nil
+	}
+	s.claims[key] = until
+	s.expire(now)
+	return true, nil
+}
+
+// Release frees a key if it is still claimed until the given time
+func (s *MemoryStore) Release(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current, ok := s.claims[key]; ok && current.Equal(until) {
+		delete(s.claims, key)
+	}
+	return nil
+}
+
+// Increment increments a counter if it is below limit
+func (s *MemoryStore) Increment(ctx context.Context, key string, limit int, expires time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.counters[key]
+	if !ok {
+		c = &counter{expires: expires}
+		s.counters[key] = c
+	}
+	if c.count >= limit {
+		return false, nil
+	}
+	c.count++
+	return true, nil
+}
+
+// expire drops the claims and counters that ended before now
+func (s *MemoryStore) expire(now time.Time) {
+	for key, until := range s.claims {
+		if !until.After(now) {
+			delete(s.claims, key)
+		}
+	}
+	for key, c := range s.counters {
+		if !c.expires.After(now) {
+			delete(s.counters, key)
+		}
+	}
+}
+
+// MemoryStatsStore is an in-memory trigger stats store, intended for tests and local development
+type MemoryStatsStore struct {
+	stats map[string]*data.TriggerStats
+	mu    sync.RWMutex
+}
+
+// NewMemoryStatsStore creates a new in-memory trigger stats store
+func NewMemoryStatsStore() *MemoryStatsStore {
+	return &MemoryStatsStore{
+		stats: make(map[string]*data.TriggerStats),
+	}
+}
+
+// Add adds the counters of stats to the stored stats of the same trigger
+func (s *MemoryStatsStore) Add(ctx context.Context, stats *data.TriggerStats) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := statsID(stats.Namespace, stats.TriggerID)
+	current, ok := s.stats[key]
+	if !ok {
+		current = &data.TriggerStats{Namespace: stats.Namespace, TriggerID: stats.TriggerID}
+		s.stats[key] = current
+	}
+	merge(current, stats)
+	return nil
+}
+
+// Get returns the stats of a trigger
+func (s *MemoryStatsStore) Get(ctx context.Context, namespace, triggerID string) (*data.TriggerStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats, ok := s.stats[statsID(namespace, triggerID)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *stats
+	return &c, nil
+}
@@ -0,0 +1,166 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"event/data"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// DefaultCollection is the default MongoDB collection for throttle claims and counters
+	DefaultCollection = "throttles"
+	// DefaultStatsCollection is the default MongoDB collection for trigger stats
+	DefaultStatsCollection = "trigger_stats"
+)
+
+// Compile-time checks to ensure the MongoDB stores implement their interfaces
+var (
+	_ Store      = (*MongoStore)(nil)
+	_ StatsStore = (*MongoStatsStore)(nil)
+)
+
+// MongoStore is a throttle store backed by a MongoDB collection. Claims and counters are
+// single-document updates, and a conflicting upsert fails on the unique _id, which makes
+// them atomic across replicas.
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoStore creates a new MongoDB-backed throttle store and ensures its indexes exist
+func NewMongoStore(ctx context.Context, db *mongo.Database, collectionName string) (*MongoStore, error) {
+	if collectionName == "" {
+		collectionName = DefaultCollection
+	}
+	collection := db.Collection(collectionName)
+
+	// Ended claims and counters are removed by MongoDB; until then they are ignored or reused
+	indexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	if _, err := collection.Indexes().CreateMany(ctx, indexModels); err != nil {
+		return nil, fmt.Errorf("failed to create throttle indexes: %w", err)
+	}
+
+	return &MongoStore{
+		collection: collection,
+	}, nil
+}
+
+// Claim takes a key until the given time if it is free
+func (s *MongoStore) Claim(ctx context.Context, key string, now, until time.Time) (bool, error) {
+	// A claimed key does not match the filter, so the upsert inserts a duplicate _id and fails
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"_id": key, "expires_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"expires_at": until}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to claim throttle key: %w", err)
+	}
+	return true, nil
+}
+
+// Release frees a key if it is still claimed until the given time
+func (s *MongoStore) Release(ctx context.Context, key string, until time.Time) error {
+	if _, err := s.collection.DeleteOne(ctx, bson.M{"_id": key, "expires_at": until}); err != nil {
+		return fmt.Errorf("failed to release throttle key: %w", err)
+	}
+	return nil
+}
+
+// Increment increments a counter if it is below limit
+func (s *MongoStore) Increment(ctx context.Context, key string, limit int, expires time.Time) (bool, error) {
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"_id": key, "count": bson.M{"$lt": limit}},
+		bson.M{
+			"$inc":         bson.M{"count": 1},
+			"$setOnInsert": bson.M{"expires_at": expires},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to increment throttle counter: %w", err)
+	}
+	return true, nil
+}
+
+// MongoStatsStore is a trigger stats store backed by a MongoDB collection, with one
+// document per trigger
+type MongoStatsStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoStatsStore creates a new MongoDB-backed trigger stats store
+func NewMongoStatsStore(ctx context.Context, db *mongo.Database, collectionName string) (*MongoStatsStore, error) {
+	if collectionName == "" {
+		collectionName = DefaultStatsCollection
+	}
+	return &MongoStatsStore{
+		collection: db.Collection(collectionName),
+	}, nil
+}
+
+// Add adds the counters of stats to the stored stats of the same trigger
+func (s *MongoStatsStore) Add(ctx context.Context, stats *data.TriggerStats) error {
+	update := bson.M{
+		"$set": bson.M{
+			"namespace":  stats.Namespace,
+			"trigger_id": stats.TriggerID,
+		},
+		"$inc": bson.M{
+			"fired":        stats.Fired,
+			"rate_limited": stats.RateLimited,
+			"deduplicated": stats.Deduplicated,
+			"cooling_down": stats.CoolingDown,
+		},
+	}
+	latest := bson.M{}
+	if !stats.LastFiredAt.IsZero() {
+		latest["last_fired_at"] = stats.LastFiredAt
+	}
+	if !stats.LastSuppressedAt.IsZero() {
+		latest["last_suppressed_at"] = stats.LastSuppressedAt
+	}
+	if len(latest) > 0 {
+		update["$max"] = latest
+	}
+
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"_id": statsID(stats.Namespace, stats.TriggerID)},
+		update,
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add trigger stats: %w", err)
+	}
+	return nil
+}
+
+// Get returns the stats of a trigger
+func (s *MongoStatsStore) Get(ctx context.Context, namespace, triggerID string) (*data.TriggerStats, error) {
+	var stats data.TriggerStats
+	err := s.collection.FindOne(ctx, bson.M{"_id": statsID(namespace, triggerID)}).Decode(&stats)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trigger stats: %w", err)
+	}
+	return &stats, nil
+}
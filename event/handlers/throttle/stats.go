@@ -0,0 +1,102 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"event/data"
+)
+
+// Recorder counts firings and suppressions in memory and adds them to a stats store when
+// flushed, so that counting does not cost a write per event
+type Recorder struct {
+	store   StatsStore
+	pending map[string]*data.TriggerStats
+	mu      sync.Mutex
+}
+
+// NewRecorder creates a recorder that flushes to store
+func NewRecorder(store StatsStore) *Recorder {
+	return &Recorder{
+		store:   store,
+		pending: make(map[string]*data.TriggerStats),
+	}
+}
+
+// Record counts a firing of a trigger at the given time, or a suppression if reason is set
+func (r *Recorder) Record(trigger *data.Trigger, reason Reason, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := statsID(trigger.Namespace, trigger.ID)
+	stats, ok := r.pending[key]
+	if !ok {
+		stats = &data.TriggerStats{Namespace: trigger.Namespace, TriggerID: trigger.ID}
+		r.pending[key] = stats
+	}
+
+	switch reason {
+	case "":
+		stats.Fired++
+		stats.LastFiredAt = latest(stats.LastFiredAt, at)
+		return
+	case RateLimited:
+		stats.RateLimited++
+	case Deduplicated:
+		stats.Deduplicated++
+	case CoolingDown:
+		stats.CoolingDown++
+	}
+	stats.LastSuppressedAt = latest(stats.LastSuppressedAt, at)
+}
+
+// Flush adds the counts recorded since the last flush to the store. Counts that could not
+// be added are kept for the next flush.
+func (r *Recorder) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	pending := r.pending
+	r.pending = make(map[string]*data.TriggerStats)
+	r.mu.Unlock()
+
+	var firstErr error
+	for key, stats := range pending {
+		if err := r.store.Add(ctx, stats); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			r.requeue(key, stats)
+		}
+	}
+	return firstErr
+}
+
+// requeue merges stats that failed to flush back into the pending stats
+func (r *Recorder) requeue(key string, stats *data.TriggerStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if current, ok := r.pending[key]; ok {
+		merge(current, stats)
+		return
+	}
+	r.pending[key] = stats
+}
+
+// merge adds the counters of stats to into and keeps the later times
+func merge(into, stats *data.TriggerStats) {
+	into.Fired += stats.Fired
+	into.RateLimited += stats.RateLimited
+	into.Deduplicated += stats.Deduplicated
+	into.CoolingDown += stats.CoolingDown
+	into.LastFiredAt = latest(into.LastFiredAt, stats.LastFiredAt)
+	into.LastSuppressedAt = latest(into.LastSuppressedAt, stats.LastSuppressedAt)
+}
+
+// latest returns the later of two times
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
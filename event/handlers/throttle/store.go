@@ -0,0 +1,37 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"event/data"
+)
+
+// Store keeps the claims and counters that throttles are enforced with. Every operation
+// is atomic, so that replicas sharing a store enforce the same limits.
+type Store interface {
+	// Claim takes a key until the given time if it is free, i.e. not claimed or claimed
+	// until at most now. It reports whether the key was taken by this call.
+	Claim(ctx context.Context, key string, now, until time.Time) (bool, error)
+
+	// Release frees a key if it is still claimed until the given time
+	Release(ctx context.Context, key string, until time.Time) error
+
+	// Increment increments a counter if it is below limit and reports whether it did.
+	// A new counter is removed after expires.
+	Increment(ctx context.Context, key string, limit int, expires time.Time) (bool, error)
+}
+
+// ErrNotFound is returned when a trigger has no stats
+var ErrNotFound = errors.New("trigger stats not found")
+
+// StatsStore accumulates trigger stats from every triggerd instance
+type StatsStore interface {
+	// Add adds the counters of stats to the stored stats of the same trigger and keeps the
+	// later of the last fired and last suppressed times
+	Add(ctx context.Context, stats *data.TriggerStats) error
+
+	// Get returns the stats of a trigger, or ErrNotFound if it never fired or was suppressed
+	Get(ctx context.Context, namespace, triggerID string) (*data.TriggerStats, error)
+}
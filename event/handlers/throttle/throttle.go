@@ -0,0 +1,189 @@
+package throttle
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	"event/data"
+	"event/handlers/triggers"
+)
+
+// Reason is why a firing was suppressed
+type Reason string
+
+const (
+	// RateLimited means the trigger already fired max_fires times in the current interval
+	RateLimited Reason = "rate_limit"
+	// Deduplicated means the trigger fired for the same dedup key within the dedup window
+	Deduplicated Reason = "dedup"
+	// CoolingDown means the trigger fired less than the cooldown ago
+	CoolingDown Reason = "cooldown"
+)
+
+// Validate checks a throttle before its trigger is saved
+func Validate(throttle *data.Throttle) error {
+	if throttle.MaxFires == 0 && throttle.Interval == "" && throttle.DedupKey == "" && throttle.DedupWindow == "" && throttle.Cooldown == "" {
+		return fmt.Errorf("at least one of max_fires, dedup_key and cooldown is required")
+	}
+
+	if throttle.MaxFires != 0 || throttle.Interval != "" {
+		if throttle.MaxFires <= 0 {
+			return fmt.Errorf("max_fires must be positive")
+		}
+		if _, err := positiveDuration("interval", throttle.Interval); err != nil {
+			return err
+		}
+	}
+
+	if throttle.DedupKey != "" || throttle.DedupWindow != "" {
+		if throttle.DedupKey == "" {
+			return fmt.Errorf("dedup_key is required with dedup_window")
+		}
+		if err := triggers.CompileExpression(throttle.DedupKey); err != nil {
+			return fmt.Errorf("dedup_key: %w", err)
+		}
+		if _, err := positiveDuration("dedup_window", throttle.DedupWindow); err != nil {
+			return err
+		}
+	}
+
+	if throttle.Cooldown != "" {
+		if _, err := positiveDuration("cooldown", throttle.Cooldown); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// positiveDuration parses a duration setting that must be positive
+func positiveDuration(name, value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", name, value, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s must be positive", name)
+	}
+	return d, nil
+}
+
+// Limiter decides whether a trigger may fire under its throttle
+type Limiter struct {
+	store Store
+	now   func() time.Time
+}
+
+// NewLimiter creates a limiter that keeps its claims and counters in store
+func NewLimiter(store Store) *Limiter {
+	return &Limiter{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// Allow reports why the trigger may not fire with the event, or "" if it may. A firing that
+// is allowed counts towards the trigger's limits; one that is suppressed does not.
+// The dedup key is evaluated against the event that fires the trigger.
+func (l *Limiter) Allow(ctx context.Context, trigger *data.Trigger, event *data.Event) (Reason, error) {
+	throttle := trigger.Throttle
+	if throttle == nil {
+		return "", nil
+	}
+	now := l.now()
+	prefix := statsID(trigger.Namespace, trigger.ID)
+
+	// Undo the claims taken so far when a later limit suppresses the firing
+	var undo []func()
+	rollback := func() {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+	}
+
+	if throttle.DedupKey != "" {
+		window, err := positiveDuration("dedup_window", throttle.DedupWindow)
+		if err != nil {
+			return "", err
+		}
+		value, err := triggers.EvaluateExpression(throttle.DedupKey, event)
+		if err != nil {
+			return "", fmt.Errorf("dedup_key: %w", err)
+		}
+		// Events without a key are not deduplicated
+		if value != nil && value != "" {
+			key := prefix + "/dedup/" + hashKey(fmt.Sprint(value))
+			until := now.Add(window)
+			claimed, err := l.store.Claim(ctx, key, now, until)
+			if err != nil {
+				return "", err
+			}
+			if !claimed {
+				return Deduplicated, nil
+			}
+			undo = append(undo, func() { l.release(ctx, key, until) })
+		}
+	}
+
+	if throttle.Cooldown != "" {
+		cooldown, err := positiveDuration("cooldown", throttle.Cooldown)
+		if err != nil {
+			rollback()
+			return "", err
+		}
+		key := prefix + "/cooldown"
+		until := now.Add(cooldown)
+		claimed, err := l.store.Claim(ctx, key, now, until)
+		if err != nil {
+			rollback()
+			return "", err
+		}
+		if !claimed {
+			rollback()
+			return CoolingDown, nil
+		}
+		undo = append(undo, func() { l.release(ctx, key, until) })
+	}
+
+	if throttle.MaxFires > 0 {
+		interval, err := positiveDuration("interval", throttle.Interval)
+		if err != nil {
+			rollback()
+			return "", err
+		}
+		start := now.Truncate(interval)
+		key := fmt.Sprintf("%s/rate/%d", prefix, start.Unix())
+		counted, err := l.store.Increment(ctx, key, throttle.MaxFires, start.Add(interval))
+		if err != nil {
+			rollback()
+			return "", err
+		}
+		if !counted {
+			rollback()
+			return RateLimited, nil
+		}
+	}
+	return "", nil
+}
+
+// release frees a claim of a suppressed firing. A claim that cannot be freed only
+// suppresses firings until it ends.
+func (l *Limiter) release(ctx context.Context, key string, until time.Time) {
+	if err := l.store.Release(ctx, key, until); err != nil {
+		log.Printf("Failed to release throttle key %s: %v", key, err)
+	}
+}
+
+// hashKey shortens dedup key values to a fixed length
+func hashKey(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:16])
+}
+
+// statsID returns the ID of a trigger's stats and the prefix of its throttle keys
+func statsID(namespace, triggerID string) string {
+	return namespace + "/" + triggerID
+}
@@ -0,0 +1,211 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"event/data"
+)
+
+var base = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newEvent(customer string) *data.Event {
+	event := &data.Event{ID: data.NewEventID(), EventType: "payment.failed", Namespace: "shop"}
+	event.Payload.After = map[string]interface{}{"customer_id": customer}
+	return event
+}
+
+// fire calls Allow at the given offset from base and returns the reason
+func fire(t *testing.T, limiter *Limiter, trigger *data.Trigger, event *data.Event, at time.Duration) Reason {
+	t.Helper()
+	limiter.now = func() time.Time { return base.Add(at) }
+	reason, err := limiter.Allow(context.Background(), trigger, event)
+	if err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+	return reason
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	type firing struct {
+		customer string
+		at       time.Duration
+		want     Reason
+	}
+
+	tests := []struct {
+		name     string
+		throttle *data.Throttle
+		firings  []firing
+	}{
+		{
+			name:     "no throttle",
+			throttle: nil,
+			firings:  []firing{{"a", 0, ""}, {"a", 0, ""}},
+		},
+		{
+			name:     "rate limit per fixed interval",
+			throttle: &data.Throttle{MaxFires: 2, Interval: "1m"},
+			firings: []firing{
+				{"a", 0, ""},
+				{"b", 10 * time.Second, ""},
+				{"c", 59 * time.Second, RateLimited},
+				{"d", 60 * time.Second, ""},
+			},
+		},
+		{
+			name:     "dedup per key within window",
+			throttle: &data.Throttle{DedupKey: "event.payload.after.customer_id", DedupWindow: "1h"},
+			firings: []firing{
+				{"a", 0, ""},
+				{"b", time.Minute, ""},
+				{"a", 30 * time.Minute, Deduplicated},
+				{"a", time.Hour, ""},
+				{"", time.Hour, ""},
+				{"", time.Hour, ""},
+			},
+		},
+		{
+			name:     "cooldown after each fire",
+			throttle: &data.Throttle{Cooldown: "30s"},
+			firings: []firing{
+				{"a", 0, ""},
+				{"b", 29 * time.Second, CoolingDown},
+				{"c", 30 * time.Second, ""},
+				{"d", 45 * time.Second, CoolingDown},
+			},
+		},
+		{
+			name:     "suppressed firings do not count",
+			throttle: &data.Throttle{DedupKey: "event.payload.after.customer_id", DedupWindow: "1h", Cooldown: "10s", MaxFires: 2, Interval: "1h"},
+			firings: []firing{
+				{"a", 0, ""},
+				// Cooling down: the dedup claim for b is released
+				{"b", 5 * time.Second, CoolingDown},
+				{"b", 10 * time.Second, ""},
+				// Rate limited: the dedup claim for c and the cooldown are released
+				{"c", 20 * time.Second, RateLimited},
+				{"d", 21 * time.Second, RateLimited},
+				{"c", time.Hour, ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewLimiter(NewMemoryStore())
+			trigger := &data.Trigger{ID: "alert", Namespace: "shop", Throttle: tt.throttle}
+			for i, f := range tt.firings {
+				if got := fire(t, limiter, trigger, newEvent(f.customer), f.at); got != f.want {
+					t.Errorf("firing %d: Allow() = %q, want %q", i, got, f.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLimiter_SharedStore(t *testing.T) {
+	// Two replicas sharing a store enforce one limit
+	store := NewMemoryStore()
+	replicas := []*Limiter{NewLimiter(store), NewLimiter(store)}
+	trigger := &data.Trigger{ID: "alert", Namespace: "shop", Throttle: &data.Throttle{MaxFires: 3, Interval: "1m"}}
+
+	fired := 0
+	for i := 0; i < 10; i++ {
+		if fire(t, replicas[i%2], trigger, newEvent("a"), time.Duration(i)*time.Second) == "" {
+			fired++
+		}
+	}
+	if fired != 3 {
+		t.Errorf("fired %d times, want 3", fired)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		throttle data.Throttle
+		wantErr  bool
+	}{
+		{"rate limit", data.Throttle{MaxFires: 10, Interval: "1m"}, false},
+		{"dedup", data.Throttle{DedupKey: "event.object_id", DedupWindow: "1h"}, false},
+		{"cooldown", data.Throttle{Cooldown: "30s"}, false},
+		{"empty", data.Throttle{}, true},
+		{"max fires without interval", data.Throttle{MaxFires: 10}, true},
+		{"interval without max fires", data.Throttle{Interval: "1m"}, true},
+		{"dedup window without key", data.Throttle{DedupWindow: "1h"}, true},
+		{"dedup key without window", data.Throttle{DedupKey: "event.object_id"}, true},
+		{"invalid dedup key", data.Throttle{DedupKey: "event.object_id ==", DedupWindow: "1h"}, true},
+		{"negative cooldown", data.Throttle{Cooldown: "-5s"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(&tt.throttle); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// failingStatsStore fails every Add
+type failingStatsStore struct {
+	StatsStore
+}
+
+func (failingStatsStore) Add(ctx context.Context, stats *data.TriggerStats) error {
+	return errors.New("unavailable")
+}
+
+func TestRecorder(t *testing.T) {
+	ctx := context.Background()
+	trigger := &data.Trigger{ID: "alert", Namespace: "shop"}
+
+	// Counts that fail to flush are kept for the next flush
+	store := NewMemoryStatsStore()
+	recorder := NewRecorder(failingStatsStore{})
+	recorder.Record(trigger, "", base)
+	recorder.Record(trigger, RateLimited, base.Add(time.Second))
+	if err := recorder.Flush(ctx); err == nil {
+		t.Fatal("Flush() error = nil, want an error")
+	}
+	recorder.store = store
+
+	recorder.Record(trigger, "", base.Add(2*time.Second))
+	recorder.Record(trigger, Deduplicated, base.Add(3*time.Second))
+	recorder.Record(trigger, CoolingDown, base.Add(time.Second))
+	if err := recorder.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	recorder.Record(trigger, RateLimited, base)
+	if err := recorder.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	stats, err := store.Get(ctx, "shop", "alert")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	want := data.TriggerStats{
+		Namespace:        "shop",
+		TriggerID:        "alert",
+		Fired:            2,
+		RateLimited:      2,
+		Deduplicated:     1,
+		CoolingDown:      1,
+		LastFiredAt:      base.Add(2 * time.Second),
+		LastSuppressedAt: base.Add(3 * time.Second),
+	}
+	if *stats != want {
+		t.Errorf("Get() = %+v, want %+v", *stats, want)
+	}
+	if stats.Suppressed() != 4 {
+		t.Errorf("Suppressed() = %d, want 4", stats.Suppressed())
+	}
+
+	if _, err := store.Get(ctx, "shop", "other"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,213 @@
+package triggers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"event/data"
+)
+
+// minutesPerDay is the end of a window that lasts until midnight
+const minutesPerDay = 24 * 60
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// Compile-time check to ensure period implements data.ActivePeriod
+var _ data.ActivePeriod = (*period)(nil)
+
+// window is a parsed active window
+type window struct {
+	days       [7]bool
+	start, end int // minutes of the day
+	location   *time.Location
+}
+
+// period is the parsed active time of a trigger
+type period struct {
+	from, until time.Time
+	windows     []*window
+}
+
+// ParseActive parses the active_from, active_until and active_windows of a trigger and sets
+// its ActivePeriod, so that Active does not parse them again
+func ParseActive(trigger *data.Trigger) error {
+	p := &period{}
+	var err error
+	if p.from, p.until, err = activeRange(trigger); err != nil {
+		return err
+	}
+	if !p.from.IsZero() && !p.until.IsZero() && !p.until.After(p.from) {
+		return fmt.Errorf("active_until must be after active_from")
+	}
+	for i := range trigger.ActiveWindows {
+		w, err := parseWindow(&trigger.ActiveWindows[i])
+		if err != nil {
+			return fmt.Errorf("active_windows[%d]: %w", i, err)
+		}
+		p.windows = append(p.windows, w)
+	}
+	trigger.ActivePeriod = p
+	return nil
+}
+
+// ValidateActive checks the active_from, active_until and active_windows of a trigger and
+// sets its ActivePeriod. A trigger whose active_until has passed at now is invalid, as it
+// would never fire.
+func ValidateActive(trigger *data.Trigger, now time.Time) error {
+	if err := ParseActive(trigger); err != nil {
+		return err
+	}
+	if until := trigger.ActivePeriod.Until(); !until.IsZero() && !until.After(now) {
+		return fmt.Errorf("active_until %s has passed", trigger.ActiveUntil)
+	}
+	return nil
+}
+
+// Active reports whether a trigger is evaluated at a time, that is whether the time is
+// between its active_from and active_until and within one of its active windows. Triggers
+// without an ActivePeriod are parsed on every call.
+func Active(trigger *data.Trigger, at time.Time) (bool, error) {
+	p := trigger.ActivePeriod
+	if p == nil {
+		if trigger.ActiveFrom == "" && trigger.ActiveUntil == "" && len(trigger.ActiveWindows) == 0 {
+			return true, nil
+		}
+		// Parse a copy, as the trigger may be shared with concurrent evaluations
+		parsed := *trigger
+		if err := ParseActive(&parsed); err != nil {
+			return false, err
+		}
+		p = parsed.ActivePeriod
+	}
+	return p.Contains(at), nil
+}
+
+// ExpiresAt returns the active_until of a trigger, or the zero time if it does not expire
+func ExpiresAt(trigger *data.Trigger) (time.Time, error) {
+	if trigger.ActivePeriod != nil {
+		return trigger.ActivePeriod.Until(), nil
+	}
+	_, until, err := activeRange(trigger)
+	return until, err
+}
+
+// Contains reports whether a time is between from and until and within one of the windows
+func (p *period) Contains(at time.Time) bool {
+	if (!p.from.IsZero() && at.Before(p.from)) || (!p.until.IsZero() && !at.Before(p.until)) {
+		return false
+	}
+	if len(p.windows) == 0 {
+		return true
+	}
+	for _, w := range p.windows {
+		if w.contains(at) {
+			return true
+		}
+	}
+	return false
+}
+
+// Until returns the active_until of the trigger, or the zero time if it does not expire
+func (p *period) Until() time.Time {
+	return p.until
+}
+
+func activeRange(trigger *data.Trigger) (from, until time.Time, err error) {
+	if trigger.ActiveFrom != "" {
+		if from, err = time.Parse(time.RFC3339, trigger.ActiveFrom); err != nil {
+			return from, until, fmt.Errorf("invalid active_from %q: %w", trigger.ActiveFrom, err)
+		}
+	}
+	if trigger.ActiveUntil != "" {
+		if until, err = time.Parse(time.RFC3339, trigger.ActiveUntil); err != nil {
+			return from, until, fmt.Errorf("invalid active_until %q: %w", trigger.ActiveUntil, err)
+		}
+	}
+	return from, until, nil
+}
+
+func parseWindow(w *data.ActiveWindow) (*window, error) {
+	parsed := &window{end: minutesPerDay, location: time.UTC}
+
+	if len(w.Days) == 0 {
+		parsed.days = [7]bool{true, true, true, true, true, true, true}
+	}
+	for _, day := range w.Days {
+		first, last, isRange := strings.Cut(strings.ToLower(strings.TrimSpace(day)), "-")
+		if !isRange {
+			last = first
+		}
+		from, ok := weekdays[strings.TrimSpace(first)]
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q", day)
+		}
+		to, ok := weekdays[strings.TrimSpace(last)]
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q", day)
+		}
+		// Ranges wrap around the week, so fri-mon is Friday to Monday
+		for d := from; ; d = (d + 1) % 7 {
+			parsed.days[d] = true
+			if d == to {
+				break
+			}
+		}
+	}
+
+	var err error
+	if w.Start != "" {
+		if parsed.start, err = parseTimeOfDay(w.Start); err != nil {
+			return nil, fmt.Errorf("invalid start: %w", err)
+		}
+	}
+	if w.End != "" {
+		if parsed.end, err = parseTimeOfDay(w.End); err != nil {
+			return nil, fmt.Errorf("invalid end: %w", err)
+		}
+	}
+	if parsed.start == parsed.end {
+		return nil, fmt.Errorf("start and end must differ")
+	}
+
+	if w.Timezone != "" {
+		if parsed.location, err = loadLocation(w.Timezone); err != nil {
+			return nil, err
+		}
+	}
+	return parsed, nil
+}
+
+// parseTimeOfDay parses HH:MM into minutes of the day, allowing 24:00 as the end of the day
+func parseTimeOfDay(value string) (int, error) {
+	if value == "24:00" {
+		return minutesPerDay, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// contains reports whether a time is within the window. A window that ends on the next day
+// belongs to the day it starts on.
+func (w *window) contains(at time.Time) bool {
+	at = at.In(w.location)
+	minute := at.Hour()*60 + at.Minute()
+	day := at.Weekday()
+
+	if w.start < w.end {
+		return w.days[day] && minute >= w.start && minute < w.end
+	}
+	yesterday := (day + 6) % 7
+	return (w.days[day] && minute >= w.start) || (w.days[yesterday] && minute < w.end)
+}
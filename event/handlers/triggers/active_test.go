@@ -0,0 +1,250 @@
+package triggers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"event/data"
+)
+
+func TestActive(t *testing.T) {
+	// 2025-06-02 is a Monday
+	monday := func(clock string) time.Time {
+		at, err := time.Parse(time.RFC3339, "2025-06-02T"+clock+":00Z")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return at
+	}
+
+	businessHours := []data.ActiveWindow{{Days: []string{"mon-fri"}, Start: "09:00", End: "17:00"}}
+	tests := []struct {
+		name    string
+		trigger data.Trigger
+		at      time.Time
+		want    bool
+		wantErr bool
+	}{
+		{
+			name: "always active",
+			at:   monday("12:00"),
+			want: true,
+		},
+		{
+			name:    "before active_from",
+			trigger: data.Trigger{ActiveFrom: "2025-06-02T13:00:00Z"},
+			at:      monday("12:00"),
+			want:    false,
+		},
+		{
+			name:    "at active_from",
+			trigger: data.Trigger{ActiveFrom: "2025-06-02T12:00:00Z"},
+			at:      monday("12:00"),
+			want:    true,
+		},
+		{
+			name:    "at active_until",
+			trigger: data.Trigger{ActiveUntil: "2025-06-02T12:00:00Z"},
+			at:      monday("12:00"),
+			want:    false,
+		},
+		{
+			name:    "active_until in another zone",
+			trigger: data.Trigger{ActiveUntil: "2025-06-02T14:30:00+02:00"},
+			at:      monday("12:00"),
+			want:    true,
+		},
+		{
+			name:    "within business hours",
+			trigger: data.Trigger{ActiveWindows: businessHours},
+			at:      monday("09:00"),
+			want:    true,
+		},
+		{
+			name:    "end of business hours",
+			trigger: data.Trigger{ActiveWindows: businessHours},
+			at:      monday("17:00"),
+			want:    false,
+		},
+		{
+			name:    "weekend",
+			trigger: data.Trigger{ActiveWindows: businessHours},
+			at:      monday("12:00").AddDate(0, 0, -1),
+			want:    false,
+		},
+		{
+			name: "business hours in a time zone",
+			trigger: data.Trigger{ActiveWindows: []data.ActiveWindow{
+				{Days: []string{"Monday"}, Start: "09:00", End: "17:00", Timezone: "America/New_York"},
+			}},
+			at:   monday("12:00"),
+			want: false,
+		},
+		{
+			name: "any of several windows",
+			trigger: data.Trigger{ActiveWindows: []data.ActiveWindow{
+				{Days: []string{"sat", "sun"}},
+				{Start: "11:00", End: "13:00"},
+			}},
+			at:   monday("12:00"),
+			want: true,
+		},
+		{
+			name: "overnight window after midnight",
+			trigger: data.Trigger{ActiveWindows: []data.ActiveWindow{
+				{Days: []string{"sun"}, Start: "22:00", End: "06:00"},
+			}},
+			at:   monday("05:59"),
+			want: true,
+		},
+		{
+			name: "overnight window of another day",
+			trigger: data.Trigger{ActiveWindows: []data.ActiveWindow{
+				{Days: []string{"mon"}, Start: "22:00", End: "06:00"},
+			}},
+			at:   monday("05:59"),
+			want: false,
+		},
+		{
+			name: "day range across the weekend",
+			trigger: data.Trigger{ActiveWindows: []data.ActiveWindow{
+				{Days: []string{"fri-mon"}},
+			}},
+			at:   monday("12:00"),
+			want: true,
+		},
+		{
+			name:    "window outside the active range",
+			trigger: data.Trigger{ActiveUntil: "2025-06-01T00:00:00Z", ActiveWindows: businessHours},
+			at:      monday("12:00"),
+			want:    false,
+		},
+		{
+			name:    "invalid active_from",
+			trigger: data.Trigger{ActiveFrom: "tomorrow"},
+			at:      monday("12:00"),
+			wantErr: true,
+		},
+		{
+			name: "invalid day",
+			trigger: data.Trigger{ActiveWindows: []data.ActiveWindow{
+				{Days: []string{"someday"}},
+			}},
+			at:      monday("12:00"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Active(&tt.trigger, tt.at)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Active() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Active() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateActive(t *testing.T) {
+	now := time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		trigger data.Trigger
+		wantErr bool
+	}{
+		{name: "no limits"},
+		{
+			name: "valid",
+			trigger: data.Trigger{
+				ActiveFrom:    "2025-06-01T00:00:00Z",
+				ActiveUntil:   "2025-07-01T00:00:00Z",
+				ActiveWindows: []data.ActiveWindow{{Days: []string{"mon-fri"}, Start: "09:00", End: "24:00", Timezone: "Europe/Berlin"}},
+			},
+		},
+		{
+			name:    "active_until passed",
+			trigger: data.Trigger{ActiveUntil: "2025-06-01T00:00:00Z"},
+			wantErr: true,
+		},
+		{
+			name:    "active_until before active_from",
+			trigger: data.Trigger{ActiveFrom: "2025-08-01T00:00:00Z", ActiveUntil: "2025-07-01T00:00:00Z"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid start",
+			trigger: data.Trigger{ActiveWindows: []data.ActiveWindow{{Start: "9am"}}},
+			wantErr: true,
+		},
+		{
+			name:    "empty window",
+			trigger: data.Trigger{ActiveWindows: []data.ActiveWindow{{Start: "09:00", End: "09:00"}}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid timezone",
+			trigger: data.Trigger{ActiveWindows: []data.ActiveWindow{{Timezone: "Mars/Olympus"}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateActive(&tt.trigger, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateActive() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadTrigger_ParsesActive(t *testing.T) {
+	trigger, err := LoadTrigger(strings.NewReader(`id: business-hours
+active_windows:
+  - days: [mon-fri]
+    start: "09:00"
+    end: "17:00"
+    timezone: Europe/Berlin
+`))
+	if err != nil {
+		t.Fatalf("LoadTrigger() error = %v", err)
+	}
+	if trigger.ActivePeriod == nil {
+		t.Fatal("LoadTrigger() did not set ActivePeriod")
+	}
+
+	// The parsed window is used even if the fields change afterwards
+	trigger.ActiveWindows[0].Timezone = "Mars/Olympus"
+	if active, err := Active(trigger, time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)); err != nil || !active {
+		t.Errorf("Active() = %v, %v, want true", active, err)
+	}
+
+	if _, err := LoadTrigger(strings.NewReader("id: invalid\nactive_windows:\n  - timezone: Mars/Olympus\n")); err == nil {
+		t.Error("LoadTrigger() accepted an invalid active window")
+	}
+}
+
+func TestLeaseTTL(t *testing.T) {
+	now := time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		until time.Time
+		want  int64
+	}{
+		{until: now.Add(time.Hour), want: 3600},
+		{until: now.Add(1500 * time.Millisecond), want: 2},
+		{until: now.Add(time.Millisecond), want: 1},
+		{until: now.Add(-time.Minute), want: 1},
+	}
+
+	for _, tt := range tests {
+		if got := leaseTTL(tt.until, now); got != tt.want {
+			t.Errorf("leaseTTL(%v) = %d, want %d", tt.until.Sub(now), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package triggers
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/expr-lang/expr"
+)
+
+// changeFunctions returns the change-detection functions for the event in env.
+// They compare event.payload.before with event.payload.after; a missing before or
+// after is treated as an empty object. Paths are dotted and may index arrays, e.g.
+// "address.city" or "items.0.sku".
+//
+//	changed("status")                            the value at the path differs
+//	changed_from_to("status", "pending", "paid") the value changed from one value to another
+//	changed_fields()                             the sorted paths of all changed values
+//	added("discount"), removed("discount")       the path was added or removed
+//	added(), removed()                           the sorted paths of all added or removed values
+func changeFunctions(env map[string]interface{}) []expr.Option {
+	before, after := payloads(env)
+
+	return []expr.Option{
+		expr.Function("changed", func(params ...any) (any, error) {
+			path, err := pathArg("changed", params, 1)
+			if err != nil {
+				return false, err
+			}
+			old, hadOld := lookup(before, path)
+			current, hasCurrent := lookup(after, path)
+			return hadOld != hasCurrent || !equalValues(old, current), nil
+		}, new(func(string) bool)),
+
+		expr.Function("changed_from_to", func(params ...any) (any, error) {
+			path, err := pathArg("changed_from_to", params, 3)
+			if err != nil {
+				return false, err
+			}
+			old, hadOld := lookup(before, path)
+			current, hasCurrent := lookup(after, path)
+			return hadOld && hasCurrent && equalValues(old, params[1]) && equalValues(current, params[2]) && !equalValues(old, current), nil
+		}, new(func(string, any, any) bool)),
+
+		expr.Function("changed_fields", func(params ...any) (any, error) {
+			var paths []string
+			diff(before, after, "", func(path string, inBefore, inAfter bool) {
+				paths = append(paths, path)
+			})
+			return sortedPaths(paths), nil
+		}, new(func() []string)),
+
+		expr.Function("added", func(params ...any) (any, error) {
+			return presence("added", params, before, after)
+		}, new(func(string) bool), new(func() []string)),
+
+		expr.Function("removed", func(params ...any) (any, error) {
+			return presence("removed", params, after, before)
+		}, new(func(string) bool), new(func() []string)),
+	}
+}
+
+// payloads returns the before and after payloads of the event in env
+func payloads(env map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	event, _ := env["event"].(map[string]interface{})
+	payload, _ := event["payload"].(map[string]interface{})
+	before, _ := payload["before"].(map[string]interface{})
+	after, _ := payload["after"].(map[string]interface{})
+	return before, after
+}
+
+// presence implements added and removed: with a path, whether it exists in to but not in from;
+// without one, every such path
+func presence(name string, params []any, from, to map[string]interface{}) (any, error) {
+	if len(params) == 0 {
+		var paths []string
+		diff(from, to, "", func(path string, inFrom, inTo bool) {
+			if !inFrom && inTo {
+				paths = append(paths, path)
+			}
+		})
+		return sortedPaths(paths), nil
+	}
+
+	path, err := pathArg(name, params, 1)
+	if err != nil {
+		return false, err
+	}
+	_, inFrom := lookup(from, path)
+	_, inTo := lookup(to, path)
+	return !inFrom && inTo, nil
+}
+
+// pathArg returns the path argument of a change function called with n arguments
+func pathArg(name string, params []any, n int) (string, error) {
+	if len(params) != n {
+		return "", fmt.Errorf("%s() expects %d arguments", name, n)
+	}
+	path, ok := params[0].(string)
+	if !ok {
+		return "", fmt.Errorf("%s() expects a string path", name)
+	}
+	return path, nil
+}
+
+// lookup returns the value at a dotted path and whether it exists
+func lookup(root map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = root
+	for _, part := range strings.Split(path, ".") {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			next, ok := v[part]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			current = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// diff calls report for every path whose value differs between before and after.
+// Objects present on both sides are compared field by field; other values are compared whole.
+func diff(before, after map[string]interface{}, prefix string, report func(path string, inBefore, inAfter bool)) {
+	for key, old := range before {
+		path := prefix + key
+		current, ok := after[key]
+		if !ok {
+			report(path, true, false)
+			continue
+		}
+		oldMap, oldIsMap := old.(map[string]interface{})
+		currentMap, currentIsMap := current.(map[string]interface{})
+		if oldIsMap && currentIsMap {
+			diff(oldMap, currentMap, path+".", report)
+			continue
+		}
+		if !equalValues(old, current) {
+			report(path, true, true)
+		}
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			report(prefix+key, false, true)
+		}
+	}
+}
+
+// equalValues compares two decoded JSON values, treating numbers of any type as equal if
+// their values are
+func equalValues(a, b interface{}) bool {
+	if isNumber(a) && isNumber(b) {
+		x, _ := ToNumber(a)
+		y, _ := ToNumber(b)
+		return x == y
+	}
+
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, value := range x {
+			other, ok := y[key]
+			if !ok || !equalValues(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equalValues(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+// isNumber reports whether v is a number. Strings and booleans, which ToNumber also
+// converts, are compared as they are.
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case string, bool:
+		return false
+	}
+	_, ok := ToNumber(v)
+	return ok
+}
+
+// sortedPaths sorts paths and returns an empty list rather than nil
+func sortedPaths(paths []string) []string {
+	if paths == nil {
+		return []string{}
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,80 @@
+package triggers
+
+import (
+	"reflect"
+	"testing"
+
+	"event/data"
+)
+
+func TestChangeFunctions(t *testing.T) {
+	update := &data.Event{EventType: "order.updated"}
+	update.Payload.Before = map[string]interface{}{
+		"status":   "pending",
+		"amount":   100,
+		"discount": 5,
+		"address":  map[string]interface{}{"city": "Berlin", "zip": "10115"},
+		"items":    []interface{}{map[string]interface{}{"sku": "a"}},
+	}
+	update.Payload.After = map[string]interface{}{
+		"status":  "shipped",
+		"amount":  100.0,
+		"note":    "fragile",
+		"address": map[string]interface{}{"city": "Hamburg", "zip": "10115"},
+		"items":   []interface{}{map[string]interface{}{"sku": "b"}},
+	}
+
+	create := &data.Event{EventType: "order.created"}
+	create.Payload.After = map[string]interface{}{"status": "pending"}
+
+	tests := []struct {
+		name     string
+		event    *data.Event
+		criteria string
+		want     bool
+	}{
+		{"changed", update, `changed("status")`, true},
+		{"unchanged number of another type", update, `changed("amount")`, false},
+		{"changed nested", update, `changed("address.city")`, true},
+		{"unchanged nested", update, `changed("address.zip")`, false},
+		{"changed array element", update, `changed("items.0.sku")`, true},
+		{"changed missing on both sides", update, `changed("missing")`, false},
+		{"changed_from_to", update, `changed_from_to("status", "pending", "shipped")`, true},
+		{"changed_from_to other values", update, `changed_from_to("status", "new", "shipped")`, false},
+		{"changed_fields", update, `changed_fields() == ["address.city", "discount", "items", "note", "status"]`, true},
+		{"added", update, `added("note") && !added("status")`, true},
+		{"removed", update, `removed("discount") && !removed("note")`, true},
+		{"added list", update, `added() == ["note"]`, true},
+		{"removed list", update, `removed() == ["discount"]`, true},
+		{"nil before changed", create, `changed("status")`, true},
+		{"nil before changed_from_to", create, `changed_from_to("status", nil, "pending")`, false},
+		{"nil before added", create, `added("status") && len(removed()) == 0`, true},
+		{"nil before changed_fields", create, `changed_fields() == ["status"]`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := evaluateTriggerCriteria(tt.event, tt.criteria)
+			if err != nil {
+				t.Fatalf("evaluateTriggerCriteria() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("evaluateTriggerCriteria(%s) = %v, want %v", tt.criteria, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangeFunctions_Arguments(t *testing.T) {
+	event := &data.Event{}
+	for _, criteria := range []string{`changed()`, `changed(1)`, `changed_from_to("status")`} {
+		if _, err := evaluateTriggerCriteria(event, criteria); err == nil {
+			t.Errorf("evaluateTriggerCriteria(%s) error = nil, want error", criteria)
+		}
+	}
+
+	paths, err := EvaluateExpression(`changed_fields()`, event)
+	if err != nil || !reflect.DeepEqual(paths, []string{}) {
+		t.Errorf("changed_fields() without payload = %v, %v, want []", paths, err)
+	}
+}
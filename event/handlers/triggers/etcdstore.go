@@ -3,10 +3,13 @@ package triggers
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"path"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"event/data"
@@ -29,10 +32,58 @@ type EtcdStore struct {
 	triggers    map[string]map[string]*data.Trigger // namespace -> triggerName -> Trigger
 	mu          sync.RWMutex
 	watchCancel context.CancelFunc
+	config      clientv3.Config
+	observer    WatchObserver
+	// loaded and watching report whether the triggers were loaded and are kept up to date
+	loaded          atomic.Bool
+	watching        atomic.Bool
+	watchGeneration atomic.Uint64
+}
+
+var (
+	_ HealthChecker = (*EtcdStore)(nil)
+	_ LimitedSaver  = (*EtcdStore)(nil)
+)
+
+// Watch changes reported to a WatchObserver
+const (
+	WatchPut    = "put"
+	WatchDelete = "delete"
+	// WatchError reports that the watch itself failed, e.g. because etcd compacted the revision
+	WatchError = "error"
+)
+
+// WatchObserver is called for every change the store receives from its watch, with the
+// error applying the change, if any
+type WatchObserver func(change string, err error)
+
+// EtcdOption is a function that configures an EtcdStore
+type EtcdOption func(*EtcdStore)
+
+// WithTLS connects to etcd over TLS
+func WithTLS(config *tls.Config) EtcdOption {
+	return func(s *EtcdStore) {
+		s.config.TLS = config
+	}
+}
+
+// WithCredentials authenticates to etcd with a username and password
+func WithCredentials(username, password string) EtcdOption {
+	return func(s *EtcdStore) {
+		s.config.Username = username
+		s.config.Password = password
+	}
+}
+
+// WithWatchObserver reports the changes received from the watch to observer
+func WithWatchObserver(observer WatchObserver) EtcdOption {
+	return func(s *EtcdStore) {
+		s.observer = observer
+	}
 }
 
 // NewEtcdStore creates a new etcd-backed trigger store
-func NewEtcdStore(endpoints []string, prefix string) (*EtcdStore, error) {
+func NewEtcdStore(endpoints []string, prefix string, options ...EtcdOption) (*EtcdStore, error) {
 	if prefix == "" {
 		prefix = DefaultTriggerPrefix
 	}
@@ -42,28 +93,61 @@ func NewEtcdStore(endpoints []string, prefix string) (*EtcdStore, error) {
 		prefix = prefix + "/"
 	}
 
+	s := &EtcdStore{
+		prefix:   prefix,
+		triggers: make(map[string]map[string]*data.Trigger),
+		config: clientv3.Config{
+			Endpoints:   endpoints,
+			DialTimeout: 5 * time.Second,
+		},
+	}
+	for _, option := range options {
+		option(s)
+	}
+
 	// Create etcd client
-	client, err := clientv3.New(clientv3.Config{
-		Endpoints:   endpoints,
-		DialTimeout: 5 * time.Second,
-	})
+	client, err := clientv3.New(s.config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create etcd client: %w", err)
 	}
+	s.client = client
 
-	return &EtcdStore{
-		client:   client,
-		prefix:   prefix,
-		triggers: make(map[string]map[string]*data.Trigger),
-	}, nil
+	return s, nil
+}
+
+// Client returns the underlying etcd client so other stores can share the connection
+func (s *EtcdStore) Client() *clientv3.Client {
+	return s.client
 }
 
 // Close closes the etcd client and stops watching for changes
 func (s *EtcdStore) Close() error {
+	s.StopWatch()
+	return s.client.Close()
+}
+
+// StopWatch stops watching for changes, leaving the client open for others that share it
+func (s *EtcdStore) StopWatch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.watchCancel != nil {
 		s.watchCancel()
 	}
-	return s.client.Close()
+}
+
+// Healthy returns an error if etcd cannot be reached, or if the triggers were not loaded or
+// are no longer watched, so that changes may have been missed
+func (s *EtcdStore) Healthy(ctx context.Context) error {
+	if !s.loaded.Load() {
+		return fmt.Errorf("triggers have not been loaded")
+	}
+	if !s.watching.Load() {
+		return fmt.Errorf("triggers are not being watched")
+	}
+	if _, err := s.client.Get(ctx, s.prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly(), clientv3.WithLimit(1)); err != nil {
+		return fmt.Errorf("failed to reach etcd: %w", err)
+	}
+	return nil
 }
 
 // LoadAll loads all triggers from etcd
@@ -86,12 +170,14 @@ func (s *EtcdStore) LoadAll(ctx context.Context) error {
 		}
 	}
 
+	s.loaded.Store(true)
 	return nil
 }
 
 // Watch starts watching for changes to triggers in etcd
 func (s *EtcdStore) Watch(ctx context.Context) {
 	// Cancel any existing watch
+	s.mu.Lock()
 	if s.watchCancel != nil {
 		s.watchCancel()
 	}
@@ -99,32 +185,57 @@ func (s *EtcdStore) Watch(ctx context.Context) {
 	// Create a new context with cancel function
 	watchCtx, cancel := context.WithCancel(ctx)
 	s.watchCancel = cancel
+	s.mu.Unlock()
 
 	// Start watching for changes
 	watchChan := s.client.Watch(watchCtx, s.prefix, clientv3.WithPrefix())
+	generation := s.watchGeneration.Add(1)
+	s.watching.Store(true)
 
 	// Process watch events in a goroutine
 	go func() {
+		// The channel closes when the watch is cancelled or fails for good, e.g. because
+		// its revision was compacted. A watch that was replaced leaves the state to its successor.
+		defer func() {
+			if s.watchGeneration.Load() == generation {
+				s.watching.Store(false)
+			}
+		}()
 		for watchResp := range watchChan {
+			if err := watchResp.Err(); err != nil {
+				fmt.Printf("Error watching triggers: %v\n", err)
+				s.observe(WatchError, err)
+			}
 			for _, event := range watchResp.Events {
 				switch event.Type {
 				case clientv3.EventTypePut:
 					// Process updated or new trigger
-					if err := s.processTrigger(event.Kv.Key, event.Kv.Value); err != nil {
+					err := s.processTrigger(event.Kv.Key, event.Kv.Value)
+					if err != nil {
 						fmt.Printf("Error processing trigger update: %v\n", err)
 					}
+					s.observe(WatchPut, err)
 				case clientv3.EventTypeDelete:
 					// Remove deleted trigger
-					if err := s.removeTrigger(event.Kv.Key); err != nil {
+					err := s.removeTrigger(event.Kv.Key)
+					if err != nil {
 						fmt.Printf("Error removing trigger: %v\n", err)
 					}
+					s.observe(WatchDelete, err)
 				}
 			}
 		}
 	}()
 }
 
-// GetTriggers returns all triggers for a namespace
+// observe reports a watch change to the observer, if any
+func (s *EtcdStore) observe(change string, err error) {
+	if s.observer != nil {
+		s.observer(change, err)
+	}
+}
+
+// GetTriggers returns all triggers for a namespace in evaluation order
 func (s *EtcdStore) GetTriggers(namespace string) []*data.Trigger {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -138,6 +249,7 @@ func (s *EtcdStore) GetTriggers(namespace string) []*data.Trigger {
 	for _, trigger := range namespaceTriggers {
 		triggers = append(triggers, trigger)
 	}
+	SortTriggers(triggers)
 
 	return triggers
 }
@@ -234,33 +346,152 @@ func (s *EtcdStore) parseKey(key []byte) (namespace, triggerName string, err err
 	return namespace, triggerName, nil
 }
 
-// SaveTrigger saves a trigger to etcd
+// SaveTrigger saves a trigger to etcd. A trigger with active_until is attached to a lease
+// that expires then, so that etcd deletes it.
 func (s *EtcdStore) SaveTrigger(ctx context.Context, namespace, name string, trigger *data.Trigger) error {
-	// Marshal trigger to YAML
-	yamlData, err := trigger.ToYAML()
+	yamlData, until, err := marshalTrigger(trigger)
 	if err != nil {
-		return fmt.Errorf("failed to marshal trigger to YAML: %w", err)
+		return err
+	}
+	putOptions, release, err := s.grantLease(ctx, until)
+	if err != nil {
+		return err
 	}
-
-	// Create key
-	key := s.prefix + namespace + "/" + name + ".yaml"
 
 	// Save to etcd
-	_, err = s.client.Put(ctx, key, string(yamlData))
+	_, err = s.client.Put(ctx, s.triggerKey(namespace, name), string(yamlData), putOptions...)
 	if err != nil {
-		return fmt.Errorf("failed to save trigger to etcd: %w", err)
+		return errors.Join(fmt.Errorf("failed to save trigger to etcd: %w", err), release())
 	}
 
 	return nil
 }
 
+// revokeTimeout bounds revoking the lease of a trigger that could not be saved
+const revokeTimeout = 5 * time.Second
+
+// maxLimitAttempts is how often SaveTriggerWithLimit counts again after a concurrent change
+// to the namespace
+const maxLimitAttempts = 5
+
+// SaveTriggerWithLimit saves a trigger to etcd like SaveTrigger, unless the trigger is new and
+// its namespace already has maxTriggers triggers. The triggers are counted in etcd, and the
+// put only succeeds if no trigger of the namespace changed since, so concurrent saves cannot
+// exceed the limit.
+func (s *EtcdStore) SaveTriggerWithLimit(ctx context.Context, namespace, name string, trigger *data.Trigger, maxTriggers int) (err error) {
+	yamlData, until, err := marshalTrigger(trigger)
+	if err != nil {
+		return err
+	}
+	prefix := s.prefix + namespace + "/"
+	key := s.triggerKey(namespace, name)
+
+	// The lease is granted once the limit allows the save, and revoked if it is not saved
+	var (
+		putOptions []clientv3.OpOption
+		granted    bool
+	)
+	release := func() error { return nil }
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, release())
+		}
+	}()
+
+	for attempt := 0; attempt < maxLimitAttempts; attempt++ {
+		// Count the namespace and look for the trigger at one revision
+		resp, err := s.client.Txn(ctx).Then(
+			clientv3.OpGet(prefix, clientv3.WithPrefix(), clientv3.WithCountOnly()),
+			clientv3.OpGet(key, clientv3.WithCountOnly()),
+		).Commit()
+		if err != nil {
+			return fmt.Errorf("failed to count triggers in etcd: %w", err)
+		}
+		count := resp.Responses[0].GetResponseRange().Count
+		exists := resp.Responses[1].GetResponseRange().Count > 0
+		if !exists && count >= int64(maxTriggers) {
+			return fmt.Errorf("%w: namespace %s already has %d triggers, the limit is %d", ErrTriggerLimit, namespace, count, maxTriggers)
+		}
+
+		if !granted {
+			if putOptions, release, err = s.grantLease(ctx, until); err != nil {
+				return err
+			}
+			granted = true
+		}
+
+		// Deleted triggers only lower the count, so only puts since the count conflict
+		put, err := s.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(prefix).WithPrefix(), "<", resp.Header.Revision+1)).
+			Then(clientv3.OpPut(key, string(yamlData), putOptions...)).
+			Commit()
+		if err != nil {
+			return fmt.Errorf("failed to save trigger to etcd: %w", err)
+		}
+		if put.Succeeded {
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to save trigger to etcd: triggers of namespace %s changed concurrently %d times", namespace, maxLimitAttempts)
+}
+
+// marshalTrigger marshals a trigger and returns when it expires, or the zero time if it
+// does not
+func marshalTrigger(trigger *data.Trigger) ([]byte, time.Time, error) {
+	yamlData, err := trigger.ToYAML()
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to marshal trigger to YAML: %w", err)
+	}
+	until, err := ExpiresAt(trigger)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return yamlData, until, nil
+}
+
+// grantLease grants the lease of a trigger that expires at until and returns the options to
+// put the trigger with, and a function that revokes the lease if the trigger is not saved.
+// A trigger that does not expire is put without a lease, which detaches it from the lease
+// of an earlier active_until.
+func (s *EtcdStore) grantLease(ctx context.Context, until time.Time) ([]clientv3.OpOption, func() error, error) {
+	if until.IsZero() {
+		return nil, func() error { return nil }, nil
+	}
+	lease, err := s.client.Grant(ctx, leaseTTL(until, time.Now()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to grant lease for trigger: %w", err)
+	}
+	release := func() error {
+		// The lease is revoked even if the save failed because ctx was cancelled
+		revokeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), revokeTimeout)
+		defer cancel()
+		if _, err := s.client.Revoke(revokeCtx, lease.ID); err != nil {
+			return fmt.Errorf("failed to revoke lease of unsaved trigger: %w", err)
+		}
+		return nil
+	}
+	return []clientv3.OpOption{clientv3.WithLease(lease.ID)}, release, nil
+}
+
+// triggerKey returns the etcd key of a trigger
+func (s *EtcdStore) triggerKey(namespace, name string) string {
+	return s.prefix + namespace + "/" + name + ".yaml"
+}
+
+// leaseTTL returns the TTL in seconds of the lease of a trigger that expires at until,
+// rounded up so that the trigger is not deleted before it expires
+func leaseTTL(until, now time.Time) int64 {
+	ttl := int64((until.Sub(now) + time.Second - 1) / time.Second)
+	if ttl < 1 {
+		ttl = 1
+	}
+	return ttl
+}
+
 // DeleteTrigger deletes a trigger from etcd
 func (s *EtcdStore) DeleteTrigger(ctx context.Context, namespace, name string) error {
-	// Create key
-	key := s.prefix + namespace + "/" + name + ".yaml"
-
 	// Delete from etcd
-	_, err := s.client.Delete(ctx, key)
+	_, err := s.client.Delete(ctx, s.triggerKey(namespace, name))
 	if err != nil {
 		return fmt.Errorf("failed to delete trigger from etcd: %w", err)
 	}
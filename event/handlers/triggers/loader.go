@@ -8,7 +8,7 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-// LoadTrigger loads a trigger definition from a YAML reader
+// LoadTrigger loads a trigger definition from a YAML reader and parses its active time
 func LoadTrigger(r io.Reader) (*data.Trigger, error) {
 	// Read all content from the reader
 	content, err := io.ReadAll(r)
@@ -23,5 +23,10 @@ func LoadTrigger(r io.Reader) (*data.Trigger, error) {
 		return nil, err
 	}
 
+	// Parse the active time once, rather than on every event
+	if err := ParseActive(&trigger); err != nil {
+		return nil, err
+	}
+
 	return &trigger, nil
 }
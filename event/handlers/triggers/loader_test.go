@@ -70,3 +70,46 @@ invalid yaml format
 		t.Fatal("Expected error for invalid YAML, got nil")
 	}
 }
+
+func TestLoadTrigger_Actions(t *testing.T) {
+	yamlContent := `
+id: order-alerts
+name: Order Alerts
+namespace: sales
+enabled: true
+criteria: event.payload.after.amount > 1000
+actions:
+  - type: webhook
+    retry_count: 3
+    config:
+      url: https://example.com/hook
+      headers:
+        X-Team: sales
+  - type: nats
+    config:
+      subject: alerts.sales
+`
+
+	trigger, err := LoadTrigger(strings.NewReader(yamlContent))
+	if err != nil {
+		t.Fatalf("Failed to load trigger: %v", err)
+	}
+
+	if len(trigger.Actions) != 2 {
+		t.Fatalf("Expected 2 actions, got %d", len(trigger.Actions))
+	}
+	webhook := trigger.Actions[0]
+	if webhook.Type != "webhook" || webhook.RetryCount != 3 {
+		t.Errorf("Expected webhook action with 3 retries, got %+v", webhook)
+	}
+	if webhook.Config["url"] != "https://example.com/hook" {
+		t.Errorf("Expected webhook url, got %v", webhook.Config["url"])
+	}
+	headers, ok := webhook.Config["headers"].(map[string]interface{})
+	if !ok || headers["X-Team"] != "sales" {
+		t.Errorf("Expected nested headers map, got %v", webhook.Config["headers"])
+	}
+	if trigger.Actions[1].Type != "nats" || trigger.Actions[1].Config["subject"] != "alerts.sales" {
+		t.Errorf("Expected nats action, got %+v", trigger.Actions[1])
+	}
+}
@@ -23,6 +23,19 @@ func MatchTrigger(trigger *data.Trigger, event *data.Event) (bool, error) {
 		return false, nil
 	}
 
+	// Sequence triggers are evaluated step by step by the sequence tracker
+	if trigger.Sequence != nil {
+		return false, nil
+	}
+
+	// Scheduled triggers only fire on their own ticks
+	if trigger.Schedule != nil {
+		if event.EventType != data.ScheduleTickEventType || event.Namespace != trigger.Namespace || event.ObjectID != trigger.ID {
+			return false, nil
+		}
+		return evaluateTriggerCriteria(event, trigger.Criteria)
+	}
+
 	// If criteria is empty, match based on event type and namespace
 	if trigger.Criteria == "" {
 		return (trigger.EventType == "" || trigger.EventType == event.EventType) &&
@@ -68,6 +81,54 @@ func has(args ...any) (any, error) {
 	return true, nil
 }
 
+// exprOptions returns the compile options shared by criteria and other expressions over events
+func exprOptions(env map[string]interface{}) []expr.Option {
+	options := []expr.Option{
+		expr.Env(env),
+		expr.Function("has", has),
+	}
+	options = append(options, stdlib...)
+	return append(options, changeFunctions(env)...)
+}
+
+// CompileExpression checks that an expression over `event` and the given additional
+// variables compiles, without evaluating it
+func CompileExpression(expression string, vars ...string) error {
+	env := map[string]interface{}{
+		"event": map[string]interface{}{},
+	}
+	for _, name := range vars {
+		env[name] = map[string]interface{}{}
+	}
+	if _, err := expr.Compile(expression, exprOptions(env)...); err != nil {
+		return fmt.Errorf("failed to compile expression: %w", err)
+	}
+	return nil
+}
+
+// EvaluateExpression evaluates an expression over `event` with the same variables and
+// functions as trigger criteria, and returns its result
+func EvaluateExpression(expression string, event *data.Event) (interface{}, error) {
+	return EvaluateEnv(expression, map[string]interface{}{
+		"event": event.ToMap(),
+	})
+}
+
+// EvaluateEnv evaluates an expression with the functions of trigger criteria and the
+// variables in env, and returns its result
+func EvaluateEnv(expression string, env map[string]interface{}) (interface{}, error) {
+	program, err := expr.Compile(expression, exprOptions(env)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile expression: %w", err)
+	}
+
+	output, err := expr.Run(program, env)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate expression: %w", err)
+	}
+	return output, nil
+}
+
 // EvaluateTriggerCriteria safely evaluates a criteria string against the given event
 func evaluateTriggerCriteria(event *data.Event, criteria string) (bool, error) {
 	// If criteria is empty, match based on event type and namespace
@@ -78,32 +139,7 @@ func evaluateTriggerCriteria(event *data.Event, criteria string) (bool, error) {
 	}
 
 	// Create a map representation of the event that matches JSON field names
-	eventMap := map[string]interface{}{
-		"event_id":      event.ID,
-		"event_type":    event.EventType,
-		"event_version": event.EventVersion,
-		"namespace":     event.Namespace,
-		"object_type":   event.ObjectType,
-		"object_id":     event.ObjectID,
-		"timestamp":     event.Timestamp,
-		"actor": map[string]interface{}{
-			"type": event.Actor.Type,
-			"id":   event.Actor.ID,
-		},
-		"context": map[string]interface{}{
-			"request_id": event.Context.RequestID,
-			"trace_id":   event.Context.TraceID,
-		},
-		"payload": map[string]interface{}{
-			"before": event.Payload.Before,
-			"after":  event.Payload.After,
-		},
-		"nats_meta": map[string]interface{}{
-			"stream":      event.NatsMeta.Stream,
-			"sequence":    event.NatsMeta.Sequence,
-			"received_at": event.NatsMeta.ReceivedAt,
-		},
-	}
+	eventMap := event.ToMap()
 
 	// Create environment with event as the root variable
 	env := map[string]interface{}{
@@ -111,12 +147,7 @@ func evaluateTriggerCriteria(event *data.Event, criteria string) (bool, error) {
 	}
 
 	// Compile the expression with custom functions
-	options := []expr.Option{
-		expr.Env(env),
-		expr.Function("has", has),
-	}
-
-	program, err := expr.Compile(criteria, options...)
+	program, err := expr.Compile(criteria, exprOptions(env)...)
 	if err != nil {
 		return false, fmt.Errorf("failed to compile criteria: %w", err)
 	}
@@ -52,6 +52,15 @@ func TestMatchTrigger(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name: "scheduled trigger ignores regular events",
+			trigger: data.Trigger{
+				Enabled:   true,
+				Namespace: "core",
+				Schedule:  &data.Schedule{Cron: "@daily"},
+			},
+			want: false,
+		},
 		{
 			name: "basic matching - namespace only",
 			trigger: data.Trigger{
@@ -0,0 +1,22 @@
+package triggers
+
+import (
+	"sort"
+
+	"event/data"
+)
+
+// SortTriggers orders triggers for evaluation: higher priority first, then by ID and name, so
+// that every triggerd instance evaluates the triggers of a namespace in the same order
+func SortTriggers(triggers []*data.Trigger) {
+	sort.SliceStable(triggers, func(i, j int) bool {
+		a, b := triggers[i], triggers[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if a.ID != b.ID {
+			return a.ID < b.ID
+		}
+		return a.Name < b.Name
+	})
+}
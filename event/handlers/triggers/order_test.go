@@ -0,0 +1,84 @@
+package triggers
+
+import (
+	"reflect"
+	"testing"
+
+	"event/data"
+)
+
+func TestSortTriggers(t *testing.T) {
+	tests := []struct {
+		name     string
+		triggers []*data.Trigger
+		want     []string
+	}{
+		{
+			name: "by ID without priorities",
+			triggers: []*data.Trigger{
+				{ID: "c"}, {ID: "a"}, {ID: "b"},
+			},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "higher priority first",
+			triggers: []*data.Trigger{
+				{ID: "a"}, {ID: "b", Priority: 10}, {ID: "c", Priority: -1}, {ID: "d", Priority: 5},
+			},
+			want: []string{"b", "d", "a", "c"},
+		},
+		{
+			name: "by ID within a priority",
+			triggers: []*data.Trigger{
+				{ID: "z", Priority: 1}, {ID: "y", Priority: 2}, {ID: "x", Priority: 1},
+			},
+			want: []string{"y", "x", "z"},
+		},
+		{
+			name: "by name for equal IDs",
+			triggers: []*data.Trigger{
+				{ID: "a", Name: "second"}, {ID: "a", Name: "first"},
+			},
+			want: []string{"first", "second"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SortTriggers(tt.triggers)
+			got := make([]string, len(tt.triggers))
+			for i, trigger := range tt.triggers {
+				got[i] = trigger.ID
+				if trigger.Name != "" {
+					got[i] = trigger.Name
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SortTriggers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEtcdStore_GetTriggersOrder(t *testing.T) {
+	store := &EtcdStore{
+		triggers: map[string]map[string]*data.Trigger{
+			"namespace1": {
+				"a": &data.Trigger{ID: "a"},
+				"b": &data.Trigger{ID: "b", Priority: 1},
+				"c": &data.Trigger{ID: "c"},
+			},
+		},
+	}
+
+	// Map iteration is random, so repeat to catch an unordered result
+	for i := 0; i < 10; i++ {
+		var got []string
+		for _, trigger := range store.GetTriggers("namespace1") {
+			got = append(got, trigger.ID)
+		}
+		if want := []string{"b", "a", "c"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("GetTriggers() = %v, want %v", got, want)
+		}
+	}
+}
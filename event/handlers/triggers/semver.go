@@ -0,0 +1,118 @@
+package triggers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// version is a parsed semantic version (https://semver.org)
+type version struct {
+	major, minor, patch uint64
+	prerelease          []string
+}
+
+// parseVersion parses MAJOR[.MINOR[.PATCH]][-PRERELEASE][+BUILD] with an optional "v" prefix.
+// Missing minor and patch numbers are 0, so "1.2" is 1.2.0.
+func parseVersion(v any) (version, error) {
+	s, ok := v.(string)
+	if !ok {
+		return version{}, fmt.Errorf("%v (%T) is not a version", v, v)
+	}
+
+	rest := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	rest, _, _ = strings.Cut(rest, "+")
+	rest, pre, hasPre := strings.Cut(rest, "-")
+
+	parts := strings.Split(rest, ".")
+	if len(parts) > 3 {
+		return version{}, fmt.Errorf("invalid version %q", s)
+	}
+	var numbers [3]uint64
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return version{}, fmt.Errorf("invalid version %q", s)
+		}
+		numbers[i] = n
+	}
+
+	parsed := version{major: numbers[0], minor: numbers[1], patch: numbers[2]}
+	if hasPre {
+		parsed.prerelease = strings.Split(pre, ".")
+		for _, id := range parsed.prerelease {
+			if id == "" {
+				return version{}, fmt.Errorf("invalid version %q", s)
+			}
+		}
+	}
+	return parsed, nil
+}
+
+// compareVersions returns -1, 0 or 1 as version a is lower than, equal to or higher than b.
+// Build metadata is ignored and a pre-release is lower than its release.
+func compareVersions(a, b any) (int, error) {
+	x, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	y, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, pair := range [][2]uint64{{x.major, y.major}, {x.minor, y.minor}, {x.patch, y.patch}} {
+		if c := compareUint(pair[0], pair[1]); c != 0 {
+			return c, nil
+		}
+	}
+
+	switch {
+	case len(x.prerelease) == 0 && len(y.prerelease) == 0:
+		return 0, nil
+	case len(x.prerelease) == 0:
+		return 1, nil
+	case len(y.prerelease) == 0:
+		return -1, nil
+	}
+	for i := 0; i < len(x.prerelease) && i < len(y.prerelease); i++ {
+		if c := comparePrerelease(x.prerelease[i], y.prerelease[i]); c != 0 {
+			return c, nil
+		}
+	}
+	return compareUint(uint64(len(x.prerelease)), uint64(len(y.prerelease))), nil
+}
+
+// comparePrerelease compares pre-release identifiers: numeric ones by value and lower than
+// alphanumeric ones, which compare lexically
+func comparePrerelease(a, b string) int {
+	x, errA := strconv.ParseUint(a, 10, 64)
+	y, errB := strconv.ParseUint(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		return compareUint(x, y)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// CompareVersions returns -1, 0 or 1 as version a is lower than, equal to or higher than b,
+// with the rules of the semver_compare criteria function
+func CompareVersions(a, b string) (int, error) {
+	return compareVersions(a, b)
+}
@@ -0,0 +1,372 @@
+package triggers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/expr-lang/expr"
+)
+
+// maxCachedPatterns bounds the number of compiled regular expressions kept in memory
+const maxCachedPatterns = 512
+
+// stdlib holds the standard criteria functions. They complement expr's builtins such as
+// now(), duration(), date(), timezone(), abs() and round().
+var stdlib = []expr.Option{
+	// Regular expressions
+	expr.Function("regex_match", regexMatch, new(func(any, string) bool)),
+	expr.Function("regex_find", regexFind, new(func(any, string) string)),
+
+	// Time, evaluated in an IANA time zone
+	expr.Function("to_time", func(params ...any) (any, error) {
+		return toTime(params[0])
+	}, new(func(any) time.Time)),
+	expr.Function("local_hour", localPart(func(t time.Time) any { return t.Hour() }), new(func(any, string) int)),
+	expr.Function("local_weekday", localPart(func(t time.Time) any { return int(t.Weekday()) }), new(func(any, string) int)),
+	expr.Function("local_date", localPart(func(t time.Time) any { return t.Format(time.DateOnly) }), new(func(any, string) string)),
+	expr.Function("local_time", localPart(func(t time.Time) any { return t.Format("15:04") }), new(func(any, string) string)),
+	expr.Function("between_times", betweenTimes, new(func(any, string, string, string) bool)),
+	expr.Function("since", func(params ...any) (any, error) {
+		t, err := toTime(params[0])
+		if err != nil {
+			return nil, err
+		}
+		return time.Since(t), nil
+	}, new(func(any) time.Duration)),
+
+	// Networks
+	expr.Function("ip_in_cidr", ipInCIDR, new(func(any, any) bool)),
+	expr.Function("is_private_ip", func(params ...any) (any, error) {
+		addr, ok := parseIP(params[0])
+		return ok && (addr.IsPrivate() || addr.IsLoopback()), nil
+	}, new(func(any) bool)),
+
+	// Semantic versions, e.g. of event.event_version
+	expr.Function("semver_compare", func(params ...any) (any, error) {
+		return compareVersions(params[0], params[1])
+	}, new(func(any, any) int)),
+	expr.Function("semver_satisfies", semverSatisfies, new(func(any, string) bool)),
+
+	// Numbers
+	expr.Function("to_number", toNumberOr, new(func(any) float64), new(func(any, float64) float64)),
+	expr.Function("to_int", func(params ...any) (any, error) {
+		n, err := toNumberOr(params...)
+		if err != nil {
+			return nil, err
+		}
+		return int(math.Trunc(n.(float64))), nil
+	}, new(func(any) int), new(func(any, float64) int)),
+	expr.Function("bucket", bucket, new(func(any, []any) int)),
+	expr.Function("clamp", func(params ...any) (any, error) {
+		n, _ := ToNumber(params[0])
+		low, _ := ToNumber(params[1])
+		high, _ := ToNumber(params[2])
+		return math.Min(math.Max(n, low), high), nil
+	}, new(func(any, float64, float64) float64)),
+}
+
+// patternCache caches compiled regular expressions by pattern
+var patternCache = struct {
+	patterns map[string]*regexp.Regexp
+	mu       sync.Mutex
+}{patterns: make(map[string]*regexp.Regexp)}
+
+// compilePattern returns the compiled pattern, compiling it at most once while it is cached
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	patternCache.mu.Lock()
+	defer patternCache.mu.Unlock()
+
+	if re, ok := patternCache.patterns[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+	}
+	if len(patternCache.patterns) >= maxCachedPatterns {
+		// Criteria use a small, fixed set of patterns; a full cache means patterns are built
+		// from event data, so start over rather than track recency
+		patternCache.patterns = make(map[string]*regexp.Regexp)
+	}
+	patternCache.patterns[pattern] = re
+	return re, nil
+}
+
+// regexMatch reports whether a string value matches a pattern. Non-strings never match.
+func regexMatch(params ...any) (any, error) {
+	re, err := compilePattern(params[1].(string))
+	if err != nil {
+		return false, err
+	}
+	s, ok := params[0].(string)
+	return ok && re.MatchString(s), nil
+}
+
+// regexFind returns the first submatch of a pattern in a string value, or the whole match if
+// the pattern has no groups. It returns "" if there is no match.
+func regexFind(params ...any) (any, error) {
+	re, err := compilePattern(params[1].(string))
+	if err != nil {
+		return "", err
+	}
+	s, ok := params[0].(string)
+	if !ok {
+		return "", nil
+	}
+	match := re.FindStringSubmatch(s)
+	switch {
+	case match == nil:
+		return "", nil
+	case len(match) > 1:
+		return match[1], nil
+	default:
+		return match[0], nil
+	}
+}
+
+// locationCache caches loaded time zones by name
+var locationCache sync.Map
+
+// loadLocation returns a time zone by IANA name
+func loadLocation(name string) (*time.Location, error) {
+	if loc, ok := locationCache.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", name, err)
+	}
+	locationCache.Store(name, loc)
+	return loc, nil
+}
+
+// toTime converts a time, an RFC 3339 string or Unix seconds to a time
+func toTime(v any) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, t)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q: %w", t, err)
+		}
+		return parsed, nil
+	}
+	if n, ok := ToNumber(v); ok {
+		sec, frac := math.Modf(n)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("%v (%T) is not a time", v, v)
+}
+
+// localPart returns a function that converts its first argument to a time in the zone
+// given as second argument and extracts a part of it
+func localPart(part func(time.Time) any) func(params ...any) (any, error) {
+	return func(params ...any) (any, error) {
+		t, err := toTime(params[0])
+		if err != nil {
+			return nil, err
+		}
+		loc, err := loadLocation(params[1].(string))
+		if err != nil {
+			return nil, err
+		}
+		return part(t.In(loc)), nil
+	}
+}
+
+// betweenTimes reports whether the wall-clock time of t in a time zone is in [from, to).
+// from and to are "15:04" times; a range that wraps midnight, like "22:00"-"06:00", is allowed.
+func betweenTimes(params ...any) (any, error) {
+	t, err := toTime(params[0])
+	if err != nil {
+		return false, err
+	}
+	loc, err := loadLocation(params[1].(string))
+	if err != nil {
+		return false, err
+	}
+	from, err := minuteOfDay(params[2].(string))
+	if err != nil {
+		return false, err
+	}
+	to, err := minuteOfDay(params[3].(string))
+	if err != nil {
+		return false, err
+	}
+
+	local := t.In(loc)
+	now := local.Hour()*60 + local.Minute()
+	if from <= to {
+		return now >= from && now < to, nil
+	}
+	return now >= from || now < to, nil
+}
+
+// minuteOfDay parses a "15:04" time into minutes since midnight
+func minuteOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, want HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// parseIP parses a string value as an IP address
+func parseIP(v any) (netip.Addr, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return netip.Addr{}, false
+	}
+	addr, err := netip.ParseAddr(strings.TrimSpace(s))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// ipInCIDR reports whether an IP address is in a CIDR range or in any of a list of ranges.
+// Values that are not IP addresses are in no range; invalid ranges are an error.
+func ipInCIDR(params ...any) (any, error) {
+	var ranges []string
+	switch r := params[1].(type) {
+	case string:
+		ranges = []string{r}
+	case []any:
+		for _, item := range r {
+			s, ok := item.(string)
+			if !ok {
+				return false, fmt.Errorf("ip_in_cidr() expects CIDR strings, got %T", item)
+			}
+			ranges = append(ranges, s)
+		}
+	default:
+		return false, fmt.Errorf("ip_in_cidr() expects a CIDR string or a list of them, got %T", params[1])
+	}
+
+	addr, ok := parseIP(params[0])
+	for _, cidr := range ranges {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return false, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+		if ok && prefix.Contains(addr) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// semverSatisfies reports whether a version satisfies every space-separated constraint,
+// e.g. ">=1.2.0 <2.0.0". Operators are =, !=, >, >=, < and <=; = is the default.
+func semverSatisfies(params ...any) (any, error) {
+	for _, constraint := range strings.Fields(params[1].(string)) {
+		op := strings.TrimRight(constraint, "0123456789.-+abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZv")
+		if len(op) == len(constraint) {
+			return false, fmt.Errorf("invalid version constraint %q", constraint)
+		}
+		cmp, err := compareVersions(params[0], constraint[len(op):])
+		if err != nil {
+			return false, err
+		}
+
+		var ok bool
+		switch op {
+		case "", "=", "==":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		default:
+			return false, fmt.Errorf("invalid version constraint operator %q", op)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ToNumber converts Go numbers, JSON numbers, numeric strings and booleans to a float.
+// Criteria functions, change detection and aggregations all read numbers with it.
+func ToNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+	case bool:
+		if n {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// toNumberOr converts a value to a number, returning the default (0 unless given) if it
+// is not numeric
+func toNumberOr(params ...any) (any, error) {
+	if n, ok := ToNumber(params[0]); ok {
+		return n, nil
+	}
+	if len(params) > 1 {
+		def, _ := ToNumber(params[1])
+		return def, nil
+	}
+	return 0.0, nil
+}
+
+// bucket returns how many of the ascending bounds the value is greater than or equal to,
+// e.g. bucket(250, [100, 1000, 10000]) is 1. Non-numeric values are in bucket 0.
+func bucket(params ...any) (any, error) {
+	n, ok := ToNumber(params[0])
+	if !ok {
+		return 0, nil
+	}
+	bounds := params[1].([]any)
+	i := 0
+	for _, b := range bounds {
+		bound, ok := ToNumber(b)
+		if !ok {
+			return 0, fmt.Errorf("bucket() bounds must be numbers, got %T", b)
+		}
+		if n < bound {
+			break
+		}
+		i++
+	}
+	return i, nil
+}
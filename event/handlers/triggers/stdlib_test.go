@@ -0,0 +1,158 @@
+package triggers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"event/data"
+)
+
+func TestStdlibFunctions(t *testing.T) {
+	event := &data.Event{
+		EventType:    "user.login",
+		EventVersion: "1.4.2",
+		// 2024-03-04 is a Monday; 10:30 in Asia/Taipei
+		Timestamp: time.Date(2024, 3, 4, 2, 30, 0, 0, time.UTC),
+	}
+	event.Payload.After = map[string]interface{}{
+		"email":      "ada@example.com",
+		"ip":         "10.1.2.3",
+		"public_ip":  "8.8.8.8",
+		"amount":     json.Number("1250.50"),
+		"amount_str": " 42 ",
+		"count":      3,
+		"note":       "n/a",
+		"created_at": "2024-03-04T23:15:00Z",
+		"unix":       1709519400,
+		"client":     "v2.0.0-rc.1",
+	}
+
+	tests := []struct {
+		name     string
+		criteria string
+		want     bool
+	}{
+		{"regex_match", `regex_match(event.payload.after.email, "^[^@]+@example\\.com$")`, true},
+		{"regex_match no match", `regex_match(event.payload.after.email, "@other\\.org$")`, false},
+		{"regex_match non-string", `regex_match(event.payload.after.count, ".*")`, false},
+		{"regex_find group", `regex_find(event.payload.after.email, "@(.+)$") == "example.com"`, true},
+		{"regex_find whole match", `regex_find(event.payload.after.email, "[a-z]+") == "ada"`, true},
+		{"regex_find no match", `regex_find(event.payload.after.email, "[0-9]+") == ""`, true},
+
+		{"local_hour", `local_hour(event.timestamp, "Asia/Taipei") == 10`, true},
+		{"local_weekday", `local_weekday(event.timestamp, "Asia/Taipei") == 1`, true},
+		{"local_weekday crosses midnight", `local_weekday(event.payload.after.created_at, "Asia/Taipei") == 2`, true},
+		{"local_date", `local_date(event.payload.after.created_at, "Asia/Taipei") == "2024-03-05"`, true},
+		{"local_time", `local_time(event.timestamp, "Asia/Taipei") == "10:30"`, true},
+		{"between_times inside", `between_times(event.timestamp, "Asia/Taipei", "09:00", "18:00")`, true},
+		{"between_times outside", `between_times(event.timestamp, "UTC", "09:00", "18:00")`, false},
+		{"between_times wrapping midnight", `between_times(event.timestamp, "UTC", "22:00", "06:00")`, true},
+		{"to_time from unix seconds", `to_time(event.payload.after.unix) == event.timestamp`, true},
+		{"since with duration", `since(event.timestamp) > duration("24h")`, true},
+		{"since with now", `now() - to_time(event.payload.after.created_at) > duration("1h")`, true},
+
+		{"ip_in_cidr", `ip_in_cidr(event.payload.after.ip, "10.0.0.0/8")`, true},
+		{"ip_in_cidr list", `ip_in_cidr(event.payload.after.public_ip, ["10.0.0.0/8", "8.8.8.0/24"])`, true},
+		{"ip_in_cidr outside", `ip_in_cidr(event.payload.after.public_ip, "10.0.0.0/8")`, false},
+		{"ip_in_cidr not an ip", `ip_in_cidr(event.payload.after.email, "0.0.0.0/0")`, false},
+		{"ip_in_cidr mapped ipv4", `ip_in_cidr("::ffff:10.1.2.3", "10.0.0.0/8")`, true},
+		{"is_private_ip", `is_private_ip(event.payload.after.ip) && !is_private_ip(event.payload.after.public_ip)`, true},
+
+		{"semver_compare lower", `semver_compare(event.event_version, "1.10.0") == -1`, true},
+		{"semver_compare equal", `semver_compare(event.event_version, "v1.4.2+build.5") == 0`, true},
+		{"semver_compare short", `semver_compare("2", "1.9.9") == 1`, true},
+		{"semver_compare prerelease", `semver_compare(event.payload.after.client, "2.0.0") == -1`, true},
+		{"semver_compare prerelease identifiers", `semver_compare("1.0.0-alpha.2", "1.0.0-alpha.10") == -1 && semver_compare("1.0.0-alpha.1", "1.0.0-alpha") == 1`, true},
+		{"semver_satisfies range", `semver_satisfies(event.event_version, ">=1.2.0 <2.0.0")`, true},
+		{"semver_satisfies outside range", `semver_satisfies(event.event_version, ">=1.5")`, false},
+		{"semver_satisfies exact", `semver_satisfies(event.event_version, "1.4.2") && semver_satisfies(event.event_version, "!=1.4.1")`, true},
+
+		{"to_number json number", `to_number(event.payload.after.amount) > 1000`, true},
+		{"to_number string", `to_number(event.payload.after.amount_str) == 42`, true},
+		{"to_number int", `to_number(event.payload.after.count) == 3`, true},
+		{"to_number default", `to_number(event.payload.after.note, -1) == -1 && to_number(event.payload.after.missing) == 0`, true},
+		{"to_int", `to_int(event.payload.after.amount) == 1250`, true},
+		{"bucket", `bucket(event.payload.after.amount, [100, 1000, 10000]) == 2`, true},
+		{"bucket below first", `bucket(5, [100, 1000]) == 0 && bucket("n/a", [100]) == 0`, true},
+		{"clamp", `clamp(event.payload.after.amount, 0, 1000) == 1000`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := evaluateTriggerCriteria(event, tt.criteria)
+			if err != nil {
+				t.Fatalf("evaluateTriggerCriteria() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("evaluateTriggerCriteria() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStdlibErrors(t *testing.T) {
+	event := &data.Event{EventVersion: "1.0.0", Timestamp: time.Now()}
+
+	tests := []struct {
+		name     string
+		criteria string
+	}{
+		{"invalid regex", `regex_match("a", "(")`},
+		{"invalid time zone", `local_hour(event.timestamp, "Mars/Olympus")`},
+		{"invalid time", `local_hour("yesterday", "UTC") == 1`},
+		{"invalid time of day", `between_times(event.timestamp, "UTC", "9am", "18:00")`},
+		{"invalid cidr", `ip_in_cidr("10.0.0.1", "10.0.0.0/33")`},
+		{"invalid version", `semver_compare(event.event_version, "one") == 0`},
+		{"invalid constraint", `semver_satisfies(event.event_version, "~>1.0")`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := evaluateTriggerCriteria(event, tt.criteria); err == nil {
+				t.Error("evaluateTriggerCriteria() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestToNumber(t *testing.T) {
+	tests := []struct {
+		value  any
+		want   float64
+		wantOK bool
+	}{
+		{42, 42, true},
+		{int64(-7), -7, true},
+		{uint32(3), 3, true},
+		{float32(1.5), 1.5, true},
+		{json.Number("1250.50"), 1250.5, true},
+		{" 12.5 ", 12.5, true},
+		{true, 1, true},
+		{"twelve", 0, false},
+		{"NaN", 0, false},
+		{nil, 0, false},
+		{[]any{1}, 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ToNumber(tt.value)
+		if ok != tt.wantOK || (ok && got != tt.want) {
+			t.Errorf("ToNumber(%#v) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestCompilePatternCache(t *testing.T) {
+	first, err := compilePattern("^a+$")
+	if err != nil {
+		t.Fatalf("compilePattern() error = %v", err)
+	}
+	second, err := compilePattern("^a+$")
+	if err != nil {
+		t.Fatalf("compilePattern() error = %v", err)
+	}
+	if first != second {
+		t.Error("compilePattern() compiled a cached pattern again")
+	}
+}
@@ -2,6 +2,7 @@ package triggers
 
 import (
 	"context"
+	"errors"
 
 	"event/data"
 )
@@ -14,7 +15,7 @@ type TriggerStore interface {
 	// Watch starts watching for changes to triggers
 	Watch(ctx context.Context)
 
-	// GetTriggers returns all triggers for a namespace
+	// GetTriggers returns all triggers for a namespace in evaluation order, see SortTriggers
 	GetTriggers(namespace string) []*data.Trigger
 
 	// GetAllTriggers returns all triggers from all namespaces
@@ -29,3 +30,22 @@ type TriggerStore interface {
 	// Close closes the store
 	Close() error
 }
+
+// HealthChecker is implemented by trigger stores that can report whether they reach their
+// backend and are in sync with it
+type HealthChecker interface {
+	// Healthy returns an error if the backend cannot be reached or the triggers may be stale
+	Healthy(ctx context.Context) error
+}
+
+// ErrTriggerLimit is returned when a namespace has no room for another trigger
+var ErrTriggerLimit = errors.New("namespace trigger limit reached")
+
+// LimitedSaver is implemented by trigger stores that can enforce a namespace's trigger limit
+// atomically with saving, so that concurrent saves cannot exceed it
+type LimitedSaver interface {
+	// SaveTriggerWithLimit saves a trigger like SaveTrigger unless the trigger is new and its
+	// namespace already has maxTriggers triggers, in which case it returns an error wrapping
+	// ErrTriggerLimit
+	SaveTriggerWithLimit(ctx context.Context, namespace, name string, trigger *data.Trigger, maxTriggers int) error
+}
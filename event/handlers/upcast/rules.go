@@ -0,0 +1,149 @@
+package upcast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rule declares an upcaster in configuration. Paths are dotted paths into the event's JSON,
+// e.g. payload.after.amount_cents. Paths are lists rather than map keys, as configuration
+// map keys lose their case.
+// Example: renaming payload.after.total to amount in Order events from 1.0 to 1.1 is
+// {ObjectType: "Order", From: "1.0", To: "1.1", Rename: [{From: "payload.after.total", To: "payload.after.amount"}]}.
+type Rule struct {
+	ObjectType string `mapstructure:"object_type" yaml:"object_type,omitempty"`
+	EventType  string `mapstructure:"event_type" yaml:"event_type,omitempty"`
+	From       string `mapstructure:"from" yaml:"from"`
+	To         string `mapstructure:"to" yaml:"to"`
+	// Rename moves values from one path to another. All values are read before any is
+	// written, so renames can swap paths. Missing paths are skipped.
+	Rename []Rename `mapstructure:"rename" yaml:"rename,omitempty"`
+	// Set sets paths to fixed values, e.g. defaults for fields the version added
+	Set []Assignment `mapstructure:"set" yaml:"set,omitempty"`
+	// Remove deletes paths
+	Remove []string `mapstructure:"remove" yaml:"remove,omitempty"`
+}
+
+// Rename moves the value at one path to another
+type Rename struct {
+	From string `mapstructure:"from" yaml:"from"`
+	To   string `mapstructure:"to" yaml:"to"`
+}
+
+// Assignment sets a path to a value
+type Assignment struct {
+	Path  string      `mapstructure:"path" yaml:"path"`
+	Value interface{} `mapstructure:"value" yaml:"value"`
+}
+
+// Upcaster returns the upcaster of the rule. Its example holds a value at every path the rule
+// renames or removes, so that Verify runs the rule and the rules after it.
+func (r Rule) Upcaster() (Upcaster, error) {
+	for _, path := range r.paths() {
+		if path == "" || strings.HasPrefix(path, ".") || strings.HasSuffix(path, ".") || strings.Contains(path, "..") {
+			return Upcaster{}, fmt.Errorf("invalid path %q", path)
+		}
+		if path == "event_version" || strings.HasPrefix(path, "event_version.") {
+			return Upcaster{}, fmt.Errorf("event_version is set by the registry")
+		}
+	}
+
+	example := Document{"event_version": r.From}
+	if r.ObjectType != "" && r.ObjectType != Wildcard {
+		example["object_type"] = r.ObjectType
+	}
+	if r.EventType != "" && r.EventType != Wildcard {
+		example["event_type"] = r.EventType
+	}
+	paths := r.Remove
+	for _, rename := range r.Rename {
+		paths = append(paths, rename.From)
+	}
+	for _, path := range paths {
+		if _, ok := example[path]; !ok {
+			setPath(example, path, "example "+path)
+		}
+	}
+
+	return Upcaster{
+		ObjectType: r.ObjectType,
+		EventType:  r.EventType,
+		From:       r.From,
+		To:         r.To,
+		Upcast:     r.apply,
+		Example:    example,
+	}, nil
+}
+
+func (r Rule) paths() []string {
+	var paths []string
+	for _, rename := range r.Rename {
+		paths = append(paths, rename.From, rename.To)
+	}
+	for _, assignment := range r.Set {
+		paths = append(paths, assignment.Path)
+	}
+	return append(paths, r.Remove...)
+}
+
+func (r Rule) apply(doc Document) error {
+	values := make([]interface{}, len(r.Rename))
+	found := make([]bool, len(r.Rename))
+	for i, rename := range r.Rename {
+		values[i], found[i] = deletePath(doc, rename.From)
+	}
+	for i, rename := range r.Rename {
+		if !found[i] {
+			continue
+		}
+		if err := setPath(doc, rename.To, values[i]); err != nil {
+			return err
+		}
+	}
+	for _, assignment := range r.Set {
+		if err := setPath(doc, assignment.Path, assignment.Value); err != nil {
+			return err
+		}
+	}
+	for _, path := range r.Remove {
+		deletePath(doc, path)
+	}
+	return nil
+}
+
+// setPath sets the value at a dotted path, creating the objects along it
+func setPath(doc Document, path string, value interface{}) error {
+	keys := strings.Split(path, ".")
+	node := doc
+	for _, key := range keys[:len(keys)-1] {
+		switch child := node[key].(type) {
+		case map[string]interface{}:
+			node = child
+		case nil:
+			created := map[string]interface{}{}
+			node[key] = created
+			node = created
+		default:
+			return fmt.Errorf("cannot set %s: %s is not an object", path, key)
+		}
+	}
+	node[keys[len(keys)-1]] = value
+	return nil
+}
+
+// deletePath removes the value at a dotted path and returns it
+func deletePath(doc Document, path string) (interface{}, bool) {
+	keys := strings.Split(path, ".")
+	node := doc
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := node[key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		node = child
+	}
+	last := keys[len(keys)-1]
+	value, ok := node[last]
+	delete(node, last)
+	return value, ok
+}
@@ -0,0 +1,237 @@
+// Package upcast transforms events of older event_versions into the current shape, so that
+// trigger criteria and stored events see one shape per event type however old the producer.
+package upcast
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"event/data"
+	"event/handlers/triggers"
+)
+
+// Wildcard matches any object type or event type
+const Wildcard = "*"
+
+// Document is an event decoded from JSON, keyed by the event's JSON field names
+type Document = map[string]interface{}
+
+// Func transforms an event from the From version of its upcaster to the To version.
+// The registry sets event_version afterwards.
+type Func func(doc Document) error
+
+// Upcaster transforms the events of an object type and event type from one version to the next
+type Upcaster struct {
+	// ObjectType and EventType select the events, Wildcard or "" matches any. An upcaster for
+	// an exact object type is preferred over one for an exact event type, and both over
+	// wildcards.
+	ObjectType string
+	EventType  string
+	// From and To are the versions the upcaster converts between. To must be higher than From.
+	From string
+	To   string
+	// Upcast transforms the event
+	Upcast Func
+	// Example is an event at version From that Verify upcasts along every path through this
+	// upcaster. Want, if set, is the expected event after this upcaster.
+	Example Document
+	Want    Document
+}
+
+func (u *Upcaster) String() string {
+	return fmt.Sprintf("%s/%s %s -> %s", u.ObjectType, u.EventType, u.From, u.To)
+}
+
+// specificity orders the upcasters that apply to the same event, higher first
+func (u *Upcaster) specificity() int {
+	s := 0
+	if u.ObjectType != Wildcard {
+		s += 2
+	}
+	if u.EventType != Wildcard {
+		s++
+	}
+	return s
+}
+
+func (u *Upcaster) selects(objectType, eventType string) bool {
+	return (u.ObjectType == Wildcard || u.ObjectType == objectType) &&
+		(u.EventType == Wildcard || u.EventType == eventType)
+}
+
+// Registry holds upcasters and chains them, so that an event is upcast version by version
+// until no upcaster applies to its version
+type Registry struct {
+	mu        sync.RWMutex
+	upcasters []*Upcaster
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds an upcaster. Only one upcaster may convert from a version for the same
+// object type and event type.
+func (r *Registry) Register(u Upcaster) error {
+	if u.ObjectType == "" {
+		u.ObjectType = Wildcard
+	}
+	if u.EventType == "" {
+		u.EventType = Wildcard
+	}
+	if u.Upcast == nil {
+		return fmt.Errorf("upcaster %s has no upcast function", &u)
+	}
+	cmp, err := triggers.CompareVersions(u.From, u.To)
+	if err != nil {
+		return fmt.Errorf("upcaster %s: %w", &u, err)
+	}
+	if cmp >= 0 {
+		return fmt.Errorf("upcaster %s must upcast to a higher version", &u)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.upcasters {
+		if existing.ObjectType != u.ObjectType || existing.EventType != u.EventType {
+			continue
+		}
+		if cmp, _ := triggers.CompareVersions(existing.From, u.From); cmp == 0 {
+			return fmt.Errorf("upcaster %s conflicts with %s", &u, existing)
+		}
+	}
+	r.upcasters = append(r.upcasters, &u)
+	return nil
+}
+
+// Len returns the number of registered upcasters. A nil registry has none and upcasts nothing.
+func (r *Registry) Len() int {
+	if r == nil {
+		return 0
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.upcasters)
+}
+
+// next returns the most specific upcaster from version for the object type and event type,
+// or nil if there is none
+func (r *Registry) next(objectType, eventType, version string) *Upcaster {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var best *Upcaster
+	for _, u := range r.upcasters {
+		if !u.selects(objectType, eventType) {
+			continue
+		}
+		if cmp, err := triggers.CompareVersions(u.From, version); err != nil || cmp != 0 {
+			continue
+		}
+		if best == nil || u.specificity() > best.specificity() {
+			best = u
+		}
+	}
+	return best
+}
+
+// Upcast upcasts an event in place until no upcaster applies to its version, and returns the
+// upcasters it applied. Events without a valid event_version are left as they are.
+func (r *Registry) Upcast(doc Document) ([]*Upcaster, error) {
+	var applied []*Upcaster
+	for {
+		objectType, _ := doc["object_type"].(string)
+		eventType, _ := doc["event_type"].(string)
+		version, _ := doc["event_version"].(string)
+
+		// Every step raises the version, so the chain ends
+		u := r.next(objectType, eventType, version)
+		if u == nil {
+			return applied, nil
+		}
+		if err := u.Upcast(doc); err != nil {
+			return applied, fmt.Errorf("upcaster %s failed: %w", u, err)
+		}
+		doc["event_version"] = u.To
+		applied = append(applied, u)
+	}
+}
+
+// header holds the fields that select the upcasters of an event
+type header struct {
+	EventVersion string `json:"event_version"`
+	ObjectType   string `json:"object_type"`
+	EventType    string `json:"event_type"`
+}
+
+// needsUpcast reports whether an upcaster applies to a JSON-encoded event
+func (r *Registry) needsUpcast(raw []byte) (bool, error) {
+	if r.Len() == 0 {
+		return false, nil
+	}
+	var h header
+	if err := json.Unmarshal(raw, &h); err != nil {
+		return false, err
+	}
+	return r.next(h.ObjectType, h.EventType, h.EventVersion) != nil, nil
+}
+
+// UpcastJSON upcasts a JSON-encoded event, e.g. before it is persisted. The event is
+// returned unchanged if no upcaster applies to it.
+func (r *Registry) UpcastJSON(raw []byte) ([]byte, error) {
+	needed, err := r.needsUpcast(raw)
+	if err != nil || !needed {
+		return raw, err
+	}
+	var doc Document
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	if _, err := r.Upcast(doc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// Decode decodes a JSON-encoded event and upcasts it. It also returns the version the event
+// was produced with if it was upcast, and "" otherwise.
+func (r *Registry) Decode(raw []byte) (*data.Event, string, error) {
+	var event data.Event
+	needed, err := r.needsUpcast(raw)
+	if err != nil {
+		return nil, "", err
+	}
+	if !needed {
+		if err := json.Unmarshal(raw, &event); err != nil {
+			return nil, "", err
+		}
+		return &event, "", nil
+	}
+
+	var doc Document
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, "", err
+	}
+	from, _ := doc["event_version"].(string)
+	if _, err := r.Upcast(doc); err != nil {
+		return nil, "", err
+	}
+	if err := decodeDocument(doc, &event); err != nil {
+		return nil, "", err
+	}
+	return &event, from, nil
+}
+
+// decodeDocument decodes an upcast document into an event
+func decodeDocument(doc Document, event *data.Event) error {
+	upcast, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to encode upcast event: %w", err)
+	}
+	if err := json.Unmarshal(upcast, event); err != nil {
+		return fmt.Errorf("upcast event is invalid: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,332 @@
+package upcast
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// renameAmount is the upcaster of Order events from 1.0, which called the amount total
+var renameAmount = Upcaster{
+	ObjectType: "Order",
+	From:       "1.0",
+	To:         "1.1",
+	Upcast: func(doc Document) error {
+		payload, _ := doc["payload"].(map[string]interface{})
+		after, _ := payload["after"].(map[string]interface{})
+		if total, ok := after["total"]; ok {
+			after["amount"] = total
+			delete(after, "total")
+		}
+		return nil
+	},
+	Example: Document{"payload": map[string]interface{}{"after": map[string]interface{}{"total": 10}}},
+	Want: Document{
+		"object_type": "Order",
+		"payload":     map[string]interface{}{"after": map[string]interface{}{"amount": 10}},
+	},
+}
+
+// addCurrency is the upcaster of every event from 1.1, which had no currency
+var addCurrency = Upcaster{
+	From: "1.1.0",
+	To:   "1.3.0",
+	Upcast: func(doc Document) error {
+		payload, _ := doc["payload"].(map[string]interface{})
+		if after, ok := payload["after"].(map[string]interface{}); ok {
+			if _, ok := after["currency"]; !ok {
+				after["currency"] = "USD"
+			}
+		}
+		return nil
+	},
+	Example: Document{"payload": map[string]interface{}{"after": map[string]interface{}{}}},
+}
+
+func newTestRegistry(t *testing.T, upcasters ...Upcaster) *Registry {
+	t.Helper()
+	registry := NewRegistry()
+	for _, u := range upcasters {
+		if err := registry.Register(u); err != nil {
+			t.Fatalf("Register(%s) error = %v", &u, err)
+		}
+	}
+	return registry
+}
+
+func TestRegistry_Register(t *testing.T) {
+	noop := func(Document) error { return nil }
+	tests := []struct {
+		name     string
+		upcaster Upcaster
+		wantErr  string
+	}{
+		{"valid", Upcaster{ObjectType: "Order", From: "1.1", To: "1.2", Upcast: noop}, ""},
+		{"same version for another event type", Upcaster{ObjectType: "Order", EventType: "order.created", From: "1.0", To: "1.1", Upcast: noop}, ""},
+		{"conflict", Upcaster{ObjectType: "Order", From: "1.0.0", To: "1.2", Upcast: noop}, "conflicts"},
+		{"downgrade", Upcaster{From: "1.3", To: "1.1", Upcast: noop}, "higher version"},
+		{"same version", Upcaster{From: "1.3", To: "v1.3.0", Upcast: noop}, "higher version"},
+		{"invalid version", Upcaster{From: "one", To: "1.1", Upcast: noop}, "invalid version"},
+		{"no function", Upcaster{From: "1.0", To: "1.1"}, "no upcast function"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := newTestRegistry(t, renameAmount)
+			err := registry.Register(tt.upcaster)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Register() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Register() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegistry_Upcast(t *testing.T) {
+	registry := newTestRegistry(t, renameAmount, addCurrency)
+
+	tests := []struct {
+		name        string
+		doc         Document
+		wantVersion string
+		wantAfter   map[string]interface{}
+		wantSteps   int
+	}{
+		{
+			name:        "chained",
+			doc:         Document{"object_type": "Order", "event_version": "1.0", "payload": map[string]interface{}{"after": map[string]interface{}{"total": 5.0}}},
+			wantVersion: "1.3.0",
+			wantAfter:   map[string]interface{}{"amount": 5.0, "currency": "USD"},
+			wantSteps:   2,
+		},
+		{
+			name:        "from the middle",
+			doc:         Document{"object_type": "Customer", "event_version": "v1.1", "payload": map[string]interface{}{"after": map[string]interface{}{"currency": "EUR"}}},
+			wantVersion: "1.3.0",
+			wantAfter:   map[string]interface{}{"currency": "EUR"},
+			wantSteps:   1,
+		},
+		{
+			name:        "only other object types upcast from the version",
+			doc:         Document{"object_type": "Customer", "event_version": "1.0", "payload": map[string]interface{}{"after": map[string]interface{}{"total": 5.0}}},
+			wantVersion: "1.0",
+			wantAfter:   map[string]interface{}{"total": 5.0},
+		},
+		{
+			name:        "current",
+			doc:         Document{"object_type": "Order", "event_version": "1.3.0", "payload": map[string]interface{}{"after": map[string]interface{}{"amount": 5.0}}},
+			wantVersion: "1.3.0",
+			wantAfter:   map[string]interface{}{"amount": 5.0},
+		},
+		{
+			name:        "no version",
+			doc:         Document{"object_type": "Order", "payload": map[string]interface{}{"after": map[string]interface{}{"total": 5.0}}},
+			wantVersion: "",
+			wantAfter:   map[string]interface{}{"total": 5.0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applied, err := registry.Upcast(tt.doc)
+			if err != nil {
+				t.Fatalf("Upcast() error = %v", err)
+			}
+			if len(applied) != tt.wantSteps {
+				t.Errorf("Upcast() applied %v, want %d upcasters", applied, tt.wantSteps)
+			}
+			if version, _ := tt.doc["event_version"].(string); version != tt.wantVersion {
+				t.Errorf("event_version = %q, want %q", version, tt.wantVersion)
+			}
+			after := tt.doc["payload"].(map[string]interface{})["after"]
+			if !reflect.DeepEqual(after, tt.wantAfter) {
+				t.Errorf("payload.after = %v, want %v", after, tt.wantAfter)
+			}
+		})
+	}
+}
+
+func TestRegistry_Specificity(t *testing.T) {
+	mark := func(name string) Func {
+		return func(doc Document) error {
+			doc["upcast_by"] = name
+			return nil
+		}
+	}
+	registry := newTestRegistry(t,
+		Upcaster{From: "1.0", To: "1.1", Upcast: mark("any")},
+		Upcaster{EventType: "order.created", From: "1.0", To: "1.1", Upcast: mark("event type")},
+		Upcaster{ObjectType: "Order", From: "1.0", To: "1.1", Upcast: mark("object type")},
+		Upcaster{ObjectType: "Order", EventType: "order.created", From: "1.0", To: "1.1", Upcast: mark("both")},
+	)
+
+	tests := []struct {
+		objectType string
+		eventType  string
+		want       string
+	}{
+		{"Order", "order.created", "both"},
+		{"Order", "order.paid", "object type"},
+		{"Invoice", "order.created", "event type"},
+		{"Invoice", "invoice.paid", "any"},
+	}
+
+	for _, tt := range tests {
+		doc := Document{"object_type": tt.objectType, "event_type": tt.eventType, "event_version": "1.0"}
+		if _, err := registry.Upcast(doc); err != nil {
+			t.Fatalf("Upcast() error = %v", err)
+		}
+		if doc["upcast_by"] != tt.want {
+			t.Errorf("%s/%s upcast by %v, want %s", tt.objectType, tt.eventType, doc["upcast_by"], tt.want)
+		}
+	}
+}
+
+func TestRegistry_Decode(t *testing.T) {
+	registry := newTestRegistry(t, renameAmount, addCurrency)
+
+	event, from, err := registry.Decode([]byte(`{"event_id": "e1", "object_type": "Order", "event_version": "1.0", "payload": {"after": {"total": 12.5}}}`))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if from != "1.0" || event.EventVersion != "1.3.0" || event.ID != "e1" {
+		t.Errorf("Decode() = version %q from %q, id %q; want 1.3.0 from 1.0, e1", event.EventVersion, from, event.ID)
+	}
+	if event.Payload.After["amount"] != 12.5 || event.Payload.After["currency"] != "USD" {
+		t.Errorf("payload.after = %v, want amount and currency", event.Payload.After)
+	}
+
+	event, from, err = registry.Decode([]byte(`{"event_id": "e2", "object_type": "Order", "event_version": "1.3.0"}`))
+	if err != nil || from != "" || event.ID != "e2" {
+		t.Errorf("Decode() of a current event = %v, %q, %v", event, from, err)
+	}
+
+	if _, _, err := registry.Decode([]byte(`{"object_type": "Order", "event_version": "1.0", "timestamp": "yesterday"}`)); err == nil {
+		t.Error("Decode() of an invalid event error = nil")
+	}
+}
+
+func TestRegistry_UpcastJSON(t *testing.T) {
+	registry := newTestRegistry(t, renameAmount)
+
+	current := []byte(`{"object_type": "Order", "event_version": "1.1"}`)
+	if got, err := registry.UpcastJSON(current); err != nil || string(got) != string(current) {
+		t.Errorf("UpcastJSON() of a current event = %s, %v; want it unchanged", got, err)
+	}
+
+	got, err := registry.UpcastJSON([]byte(`{"object_type": "Order", "event_version": "1.0", "payload": {"after": {"total": 3}}}`))
+	if err != nil {
+		t.Fatalf("UpcastJSON() error = %v", err)
+	}
+	var doc Document
+	if err := json.Unmarshal(got, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["event_version"] != "1.1" || doc["payload"].(map[string]interface{})["after"].(map[string]interface{})["amount"] != 3.0 {
+		t.Errorf("UpcastJSON() = %s", got)
+	}
+}
+
+func TestRegistry_Verify(t *testing.T) {
+	if err := newTestRegistry(t, renameAmount, addCurrency).Verify(); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	failing := addCurrency
+	failing.Upcast = func(Document) error { return errors.New("boom") }
+	wrongWant := renameAmount
+	wrongWant.Want = Document{"object_type": "Order", "payload": map[string]interface{}{"after": map[string]interface{}{"total": 10}}}
+	noExample := addCurrency
+	noExample.Example = nil
+	shadowed := Upcaster{ObjectType: "Order", EventType: "order.created", From: "1.0", To: "1.1", Upcast: renameAmount.Upcast}
+	shadowing := Upcaster{ObjectType: "Order", From: "1.0", To: "1.1", Upcast: renameAmount.Upcast,
+		Example: Document{"event_type": "order.created"}}
+
+	tests := []struct {
+		name      string
+		upcasters []Upcaster
+		wantErrs  []string
+	}{
+		// The path through renameAmount fails in the next upcaster, so both are reported
+		{"failing step", []Upcaster{renameAmount, failing}, []string{"Order/* 1.0 -> 1.1: upcaster */* 1.1.0 -> 1.3.0 failed: boom", "*/* 1.1.0 -> 1.3.0: failed to upcast example: boom"}},
+		{"wrong want", []Upcaster{wrongWant}, []string{"want map"}},
+		{"no example", []Upcaster{noExample}, []string{ErrNoExample.Error()}},
+		{"example selects another upcaster", []Upcaster{shadowed, shadowing}, []string{"no example", "upcast by Order/order.created"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newTestRegistry(t, tt.upcasters...).Verify()
+			if err == nil {
+				t.Fatal("Verify() error = nil")
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Verify() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRule(t *testing.T) {
+	rule := Rule{
+		ObjectType: "Order",
+		From:       "1.0",
+		To:         "1.1",
+		Rename: []Rename{
+			{From: "payload.after.total", To: "payload.after.amount"},
+			{From: "payload.after.a", To: "payload.after.b"},
+			{From: "payload.after.b", To: "payload.after.a"},
+			{From: "payload.after.custId", To: "payload.after.customer.id"},
+		},
+		Set:    []Assignment{{Path: "payload.after.currency", Value: "USD"}},
+		Remove: []string{"payload.after.legacy", "payload.before"},
+	}
+	u, err := rule.Upcaster()
+	if err != nil {
+		t.Fatalf("Upcaster() error = %v", err)
+	}
+
+	doc := Document{
+		"object_type":   "Order",
+		"event_version": "1.0",
+		"payload": map[string]interface{}{
+			"before": map[string]interface{}{"total": 1},
+			"after":  map[string]interface{}{"total": 2, "a": "x", "b": "y", "custId": "c1", "legacy": true},
+		},
+	}
+	registry := newTestRegistry(t, u)
+	if _, err := registry.Upcast(doc); err != nil {
+		t.Fatalf("Upcast() error = %v", err)
+	}
+	want := Document{
+		"object_type":   "Order",
+		"event_version": "1.1",
+		"payload": map[string]interface{}{
+			"after": map[string]interface{}{"amount": 2, "a": "y", "b": "x", "customer": map[string]interface{}{"id": "c1"}, "currency": "USD"},
+		},
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("Upcast() = %v, want %v", doc, want)
+	}
+	if err := registry.Verify(); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	for _, invalid := range []Rule{
+		{From: "1.0", To: "1.1", Rename: []Rename{{From: "payload..total", To: "amount"}}},
+		{From: "1.0", To: "1.1", Set: []Assignment{{Path: "event_version", Value: "2"}}},
+	} {
+		if _, err := invalid.Upcaster(); err == nil {
+			t.Errorf("Upcaster() of %+v error = nil", invalid)
+		}
+	}
+}
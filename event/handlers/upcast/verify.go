@@ -0,0 +1,111 @@
+package upcast
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"event/data"
+)
+
+// ErrNoExample is returned by Verify for upcasters without an example
+var ErrNoExample = errors.New("upcaster has no example")
+
+// Verify upcasts the example of every upcaster along the whole chain that follows it, so that
+// every path an event can take through the registry is exercised. It checks that
+//   - the example is selected by its own upcaster,
+//   - each step succeeds and the first step produces Want, if set,
+//   - the chain ends at the version of its last upcaster, and
+//   - the result decodes as an event.
+//
+// Tests of packages that register upcasters should call it, and triggerd calls it for the
+// upcasters in its configuration at startup.
+func (r *Registry) Verify() error {
+	r.mu.RLock()
+	upcasters := append([]*Upcaster(nil), r.upcasters...)
+	r.mu.RUnlock()
+
+	var errs []error
+	for _, u := range upcasters {
+		if err := r.verifyPath(u); err != nil {
+			errs = append(errs, fmt.Errorf("upcaster %s: %w", u, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Registry) verifyPath(u *Upcaster) error {
+	if u.Example == nil {
+		return ErrNoExample
+	}
+	doc, err := clone(u.Example)
+	if err != nil {
+		return fmt.Errorf("invalid example: %w", err)
+	}
+	if _, ok := doc["event_version"]; !ok {
+		doc["event_version"] = u.From
+	}
+	if _, ok := doc["object_type"]; !ok && u.ObjectType != Wildcard {
+		doc["object_type"] = u.ObjectType
+	}
+	if _, ok := doc["event_type"]; !ok && u.EventType != Wildcard {
+		doc["event_type"] = u.EventType
+	}
+
+	objectType, _ := doc["object_type"].(string)
+	eventType, _ := doc["event_type"].(string)
+	version, _ := doc["event_version"].(string)
+	if selected := r.next(objectType, eventType, version); selected != u {
+		return fmt.Errorf("example is upcast by %v instead", selected)
+	}
+
+	if err := u.Upcast(doc); err != nil {
+		return fmt.Errorf("failed to upcast example: %w", err)
+	}
+	doc["event_version"] = u.To
+	if u.Want != nil {
+		want, err := clone(u.Want)
+		if err != nil {
+			return fmt.Errorf("invalid want: %w", err)
+		}
+		if _, ok := want["event_version"]; !ok {
+			want["event_version"] = u.To
+		}
+		// Compare the JSON forms, as an event arrives as JSON
+		got, err := clone(doc)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(got, want) {
+			return fmt.Errorf("upcast example = %v, want %v", got, want)
+		}
+	}
+
+	applied, err := r.Upcast(doc)
+	if err != nil {
+		return err
+	}
+	last := u
+	if len(applied) > 0 {
+		last = applied[len(applied)-1]
+	}
+	if version, _ := doc["event_version"].(string); version != last.To {
+		return fmt.Errorf("chain ends at version %q, want %q", version, last.To)
+	}
+	var event data.Event
+	return decodeDocument(doc, &event)
+}
+
+// clone deep-copies a document through JSON, which also gives its values their JSON types
+func clone(doc Document) (Document, error) {
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var copied Document
+	if err := json.Unmarshal(encoded, &copied); err != nil {
+		return nil, err
+	}
+	return copied, nil
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// unknownMethod is the method label of requests for methods the server does not serve
+const unknownMethod = "unknown"
+
+// InitializeGRPC records the methods the server serves, so that only they get their own
+// label value, and creates their series so that they are reported before the first request.
+// It must be called after the services are registered and before the server starts.
+func (m *Metrics) InitializeGRPC(server *grpc.Server) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for service, info := range server.GetServiceInfo() {
+		for _, method := range info.Methods {
+			name := "/" + service + "/" + method.Name
+			m.grpcMethods[name] = struct{}{}
+			m.grpcDuration.WithLabelValues(name)
+		}
+	}
+}
+
+// UnaryServerInterceptor returns an interceptor that measures unary RPCs
+func (m *Metrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		m.observeRPC(info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns an interceptor that measures streaming RPCs
+func (m *Metrics) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		m.observeRPC(info.FullMethod, start, err)
+		return err
+	}
+}
+
+func (m *Metrics) observeRPC(method string, start time.Time, err error) {
+	m.mu.RLock()
+	if _, ok := m.grpcMethods[method]; !ok {
+		method = unknownMethod
+	}
+	m.mu.RUnlock()
+
+	m.grpcRequests.WithLabelValues(method, status.Code(err).String()).Inc()
+	m.grpcDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
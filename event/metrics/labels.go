@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"sync"
+
+	"event/data"
+)
+
+// Other is the label value shared by the namespaces and triggers that did not get their
+// own label value because the limits were reached
+const Other = "_other"
+
+type triggerKey struct {
+	namespace string
+	id        string
+}
+
+// labeler hands out label values for namespaces and triggers up to a limit, so that the
+// number of series stays bounded however many triggers are loaded. Namespaces and triggers
+// keep their label value until they are released.
+type labeler struct {
+	maxNamespaces int
+	maxTriggers   int
+
+	mu         sync.Mutex
+	namespaces map[string]struct{}
+	triggers   map[triggerKey]struct{}
+}
+
+func newLabeler(maxNamespaces, maxTriggers int) *labeler {
+	return &labeler{
+		maxNamespaces: maxNamespaces,
+		maxTriggers:   maxTriggers,
+		namespaces:    make(map[string]struct{}),
+		triggers:      make(map[triggerKey]struct{}),
+	}
+}
+
+// namespace returns the label value of a namespace
+func (l *labeler) namespace(namespace string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.namespaceLocked(namespace)
+}
+
+func (l *labeler) namespaceLocked(namespace string) string {
+	if _, ok := l.namespaces[namespace]; ok {
+		return namespace
+	}
+	if len(l.namespaces) >= l.maxNamespaces {
+		return Other
+	}
+	l.namespaces[namespace] = struct{}{}
+	return namespace
+}
+
+// trigger returns the namespace and trigger label values of a trigger. Triggers of a
+// namespace without its own label value are counted under Other as well.
+func (l *labeler) trigger(trigger *data.Trigger) (string, string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	namespace := l.namespaceLocked(trigger.Namespace)
+	if namespace == Other {
+		return Other, Other
+	}
+	key := triggerKey{namespace: trigger.Namespace, id: trigger.ID}
+	if _, ok := l.triggers[key]; ok {
+		return namespace, trigger.ID
+	}
+	if len(l.triggers) >= l.maxTriggers {
+		return namespace, Other
+	}
+	l.triggers[key] = struct{}{}
+	return namespace, trigger.ID
+}
+
+// release frees the label values of the triggers and namespaces that are not in loaded,
+// and returns them so their series can be deleted
+func (l *labeler) release(loaded []*data.Trigger) (namespaces []string, triggers []triggerKey) {
+	keep := make(map[triggerKey]struct{}, len(loaded))
+	keepNamespaces := make(map[string]struct{})
+	for _, trigger := range loaded {
+		keep[triggerKey{namespace: trigger.Namespace, id: trigger.ID}] = struct{}{}
+		keepNamespaces[trigger.Namespace] = struct{}{}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key := range l.triggers {
+		if _, ok := keep[key]; !ok {
+			delete(l.triggers, key)
+			triggers = append(triggers, key)
+		}
+	}
+	for namespace := range l.namespaces {
+		if _, ok := keepNamespaces[namespace]; !ok {
+			delete(l.namespaces, namespace)
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces, triggers
+}
@@ -0,0 +1,239 @@
+// Package metrics exposes the Prometheus metrics of triggerd: the triggers it loaded, the
+// changes it received from etcd, the evaluation of trigger criteria, the delivery of
+// actions and the gRPC API.
+//
+// Per-trigger series are bounded: only the first namespaces and triggers up to the
+// configured limits get their own label values, the others are counted under Other.
+// Refresh releases the label values of deleted triggers.
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"event/data"
+	"event/handlers/dispatch"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// DefaultMaxNamespaces is the default number of namespaces with their own label value
+	DefaultMaxNamespaces = 100
+	// DefaultMaxTriggers is the default number of triggers with their own label value
+	DefaultMaxTriggers = 1000
+)
+
+// Metrics holds the collectors of triggerd
+type Metrics struct {
+	registry      *prometheus.Registry
+	labels        *labeler
+	maxNamespaces int
+	maxTriggers   int
+
+	loaded             *prometheus.GaugeVec
+	watchEvents        *prometheus.CounterVec
+	watchErrors        *prometheus.CounterVec
+	evaluations        *prometheus.CounterVec
+	matches            *prometheus.CounterVec
+	evaluationErrors   *prometheus.CounterVec
+	evaluationDuration *prometheus.HistogramVec
+	actionAttempts     *prometheus.CounterVec
+	actionDuration     *prometheus.HistogramVec
+	deadLetters        *prometheus.CounterVec
+	grpcRequests       *prometheus.CounterVec
+	grpcDuration       *prometheus.HistogramVec
+
+	// mu guards the gRPC methods and the namespaces of the loaded gauge
+	mu               sync.RWMutex
+	grpcMethods      map[string]struct{}
+	loadedNamespaces map[string]struct{}
+}
+
+var _ dispatch.Observer = (*Metrics)(nil)
+
+// Option is a function that configures Metrics
+type Option func(*Metrics)
+
+// WithMaxNamespaces sets how many namespaces get their own label value
+func WithMaxNamespaces(n int) Option {
+	return func(m *Metrics) {
+		if n > 0 {
+			m.maxNamespaces = n
+		}
+	}
+}
+
+// WithMaxTriggers sets how many triggers, across all namespaces, get their own label value
+func WithMaxTriggers(n int) Option {
+	return func(m *Metrics) {
+		if n > 0 {
+			m.maxTriggers = n
+		}
+	}
+}
+
+// New creates the collectors and registers them, together with the Go runtime and
+// process collectors, in a registry of their own
+func New(options ...Option) *Metrics {
+	m := &Metrics{
+		registry:         prometheus.NewRegistry(),
+		maxNamespaces:    DefaultMaxNamespaces,
+		maxTriggers:      DefaultMaxTriggers,
+		grpcMethods:      make(map[string]struct{}),
+		loadedNamespaces: make(map[string]struct{}),
+	}
+	for _, option := range options {
+		option(m)
+	}
+	m.labels = newLabeler(m.maxNamespaces, m.maxTriggers)
+
+	m.loaded = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "triggerd_triggers_loaded",
+		Help: "Number of triggers loaded from etcd.",
+	}, []string{"namespace"})
+	m.watchEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "triggerd_etcd_watch_events_total",
+		Help: "Trigger changes received from the etcd watch, by change (put, delete or error).",
+	}, []string{"change"})
+	m.watchErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "triggerd_etcd_watch_errors_total",
+		Help: "Trigger changes from the etcd watch that could not be applied, and failures of the watch itself.",
+	}, []string{"change"})
+	m.evaluations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "triggerd_trigger_evaluations_total",
+		Help: "Events evaluated against a trigger.",
+	}, []string{"namespace", "trigger"})
+	m.matches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "triggerd_trigger_matches_total",
+		Help: "Events that matched a trigger.",
+	}, []string{"namespace", "trigger"})
+	m.evaluationErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "triggerd_trigger_evaluation_errors_total",
+		Help: "Events whose evaluation against a trigger failed.",
+	}, []string{"namespace", "trigger"})
+	m.evaluationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "triggerd_criteria_evaluation_seconds",
+		Help:    "Time to evaluate an event against a trigger, including the state lookups of sequence triggers.",
+		Buckets: prometheus.ExponentialBuckets(0.00005, 4, 8),
+	}, []string{"namespace"})
+	m.actionAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "triggerd_action_attempts_total",
+		Help: "Attempts to run an action, including retries, by result (success or failure).",
+	}, []string{"namespace", "trigger", "action", "result"})
+	m.actionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "triggerd_action_duration_seconds",
+		Help:    "Duration of an attempt to run an action.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"action"})
+	m.deadLetters = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "triggerd_dead_letters_total",
+		Help: "Events stored as dead letters after an action failed all its attempts.",
+	}, []string{"namespace", "trigger", "action"})
+	m.grpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "triggerd_grpc_requests_total",
+		Help: "gRPC requests handled, by method and status code.",
+	}, []string{"method", "code"})
+	m.grpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "triggerd_grpc_request_duration_seconds",
+		Help:    "Time to handle a gRPC request; for streams, the lifetime of the stream.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.loaded, m.watchEvents, m.watchErrors,
+		m.evaluations, m.matches, m.evaluationErrors, m.evaluationDuration,
+		m.actionAttempts, m.actionDuration, m.deadLetters,
+		m.grpcRequests, m.grpcDuration,
+	)
+	return m
+}
+
+// Handler returns the HTTP handler that serves the metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Refresh sets the number of loaded triggers per namespace and releases the label values
+// of the namespaces and triggers that are no longer loaded, deleting their series
+func (m *Metrics) Refresh(loaded []*data.Trigger) {
+	namespaces, triggers := m.labels.release(loaded)
+	for _, key := range triggers {
+		labels := prometheus.Labels{"namespace": key.namespace, "trigger": key.id}
+		m.evaluations.DeletePartialMatch(labels)
+		m.matches.DeletePartialMatch(labels)
+		m.evaluationErrors.DeletePartialMatch(labels)
+		m.actionAttempts.DeletePartialMatch(labels)
+		m.deadLetters.DeletePartialMatch(labels)
+	}
+	for _, namespace := range namespaces {
+		labels := prometheus.Labels{"namespace": namespace}
+		m.evaluations.DeletePartialMatch(labels)
+		m.matches.DeletePartialMatch(labels)
+		m.evaluationErrors.DeletePartialMatch(labels)
+		m.evaluationDuration.DeletePartialMatch(labels)
+		m.actionAttempts.DeletePartialMatch(labels)
+		m.deadLetters.DeletePartialMatch(labels)
+	}
+
+	counts := make(map[string]int)
+	for _, trigger := range loaded {
+		counts[m.labels.namespace(trigger.Namespace)]++
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for namespace := range m.loadedNamespaces {
+		if _, ok := counts[namespace]; !ok {
+			m.loaded.DeleteLabelValues(namespace)
+			delete(m.loadedNamespaces, namespace)
+		}
+	}
+	for namespace, count := range counts {
+		m.loaded.WithLabelValues(namespace).Set(float64(count))
+		m.loadedNamespaces[namespace] = struct{}{}
+	}
+}
+
+// WatchEvent counts a change received from the etcd watch. It is a triggers.WatchObserver.
+func (m *Metrics) WatchEvent(change string, err error) {
+	m.watchEvents.WithLabelValues(change).Inc()
+	if err != nil {
+		m.watchErrors.WithLabelValues(change).Inc()
+	}
+}
+
+// TriggerEvaluated counts the evaluation of an event against a trigger
+func (m *Metrics) TriggerEvaluated(trigger *data.Trigger, duration time.Duration, matched bool, err error) {
+	namespace, id := m.labels.trigger(trigger)
+	m.evaluations.WithLabelValues(namespace, id).Inc()
+	m.evaluationDuration.WithLabelValues(namespace).Observe(duration.Seconds())
+	switch {
+	case err != nil:
+		m.evaluationErrors.WithLabelValues(namespace, id).Inc()
+	case matched:
+		m.matches.WithLabelValues(namespace, id).Inc()
+	}
+}
+
+// ActionAttempted counts an attempt to run an action
+func (m *Metrics) ActionAttempted(trigger *data.Trigger, actionType string, duration time.Duration, err error) {
+	namespace, id := m.labels.trigger(trigger)
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.actionAttempts.WithLabelValues(namespace, id, actionType, result).Inc()
+	m.actionDuration.WithLabelValues(actionType).Observe(duration.Seconds())
+}
+
+// DeadLettered counts an event stored as a dead letter
+func (m *Metrics) DeadLettered(trigger *data.Trigger, actionType string) {
+	namespace, id := m.labels.trigger(trigger)
+	m.deadLetters.WithLabelValues(namespace, id, actionType).Inc()
+}
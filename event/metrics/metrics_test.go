@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"event/data"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTriggerEvaluated_BoundedLabels(t *testing.T) {
+	m := New(WithMaxNamespaces(2), WithMaxTriggers(3))
+
+	evaluate := func(namespace, id string) {
+		m.TriggerEvaluated(&data.Trigger{Namespace: namespace, ID: id}, time.Millisecond, true, nil)
+	}
+	evaluate("sales", "t1")
+	evaluate("sales", "t2")
+	evaluate("billing", "t1")
+	evaluate("sales", "t3")   // over the trigger limit
+	evaluate("sales", "t4")   // over the trigger limit
+	evaluate("support", "t1") // over the namespace limit
+	evaluate("sales", "t1")   // keeps its label value
+
+	tests := []struct {
+		namespace, trigger string
+		want               float64
+	}{
+		{"sales", "t1", 2},
+		{"sales", "t2", 1},
+		{"billing", "t1", 1},
+		{"sales", Other, 2},
+		{Other, Other, 1},
+	}
+	for _, tt := range tests {
+		if got := testutil.ToFloat64(m.matches.WithLabelValues(tt.namespace, tt.trigger)); got != tt.want {
+			t.Errorf("matches{namespace=%q, trigger=%q} = %v, want %v", tt.namespace, tt.trigger, got, tt.want)
+		}
+	}
+	if got := testutil.CollectAndCount(m.evaluations); got != 5 {
+		t.Errorf("evaluations has %d series, want 5", got)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	m := New(WithMaxNamespaces(2), WithMaxTriggers(2))
+
+	loaded := []*data.Trigger{
+		{Namespace: "sales", ID: "t1"},
+		{Namespace: "sales", ID: "t2"},
+		{Namespace: "billing", ID: "t1"},
+	}
+	m.Refresh(loaded)
+	for _, trigger := range loaded {
+		m.DeadLettered(trigger, "webhook")
+	}
+
+	want := `
+# HELP triggerd_triggers_loaded Number of triggers loaded from etcd.
+# TYPE triggerd_triggers_loaded gauge
+triggerd_triggers_loaded{namespace="billing"} 1
+triggerd_triggers_loaded{namespace="sales"} 2
+`
+	if err := testutil.CollectAndCompare(m.loaded, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+
+	// Removing a trigger and a namespace releases their label values for new triggers
+	m.Refresh([]*data.Trigger{{Namespace: "sales", ID: "t2"}, {Namespace: "support", ID: "t9"}})
+	m.DeadLettered(&data.Trigger{Namespace: "support", ID: "t9"}, "webhook")
+
+	want = `
+# HELP triggerd_dead_letters_total Events stored as dead letters after an action failed all its attempts.
+# TYPE triggerd_dead_letters_total counter
+triggerd_dead_letters_total{action="webhook",namespace="sales",trigger="t2"} 1
+triggerd_dead_letters_total{action="webhook",namespace="support",trigger="t9"} 1
+`
+	if err := testutil.CollectAndCompare(m.deadLetters, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+	want = `
+# HELP triggerd_triggers_loaded Number of triggers loaded from etcd.
+# TYPE triggerd_triggers_loaded gauge
+triggerd_triggers_loaded{namespace="sales"} 1
+triggerd_triggers_loaded{namespace="support"} 1
+`
+	if err := testutil.CollectAndCompare(m.loaded, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestActionAttempted(t *testing.T) {
+	m := New()
+	trigger := &data.Trigger{Namespace: "sales", ID: "t1"}
+
+	m.ActionAttempted(trigger, "webhook", time.Second, errors.New("503"))
+	m.ActionAttempted(trigger, "webhook", time.Second, nil)
+
+	for _, result := range []string{"success", "failure"} {
+		if got := testutil.ToFloat64(m.actionAttempts.WithLabelValues("sales", "t1", "webhook", result)); got != 1 {
+			t.Errorf("action attempts with result %s = %v, want 1", result, got)
+		}
+	}
+}
+
+func TestWatchEvent(t *testing.T) {
+	m := New()
+	m.WatchEvent("put", nil)
+	m.WatchEvent("put", errors.New("invalid YAML"))
+	m.WatchEvent("delete", nil)
+
+	if got := testutil.ToFloat64(m.watchEvents.WithLabelValues("put")); got != 2 {
+		t.Errorf("put events = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.watchErrors.WithLabelValues("put")); got != 1 {
+		t.Errorf("put errors = %v, want 1", got)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	m := New()
+	m.grpcMethods["/api.TriggerService/ListTriggers"] = struct{}{}
+	interceptor := m.UnaryServerInterceptor()
+
+	call := func(method string, err error) {
+		info := &grpc.UnaryServerInfo{FullMethod: method}
+		interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, err
+		})
+	}
+	call("/api.TriggerService/ListTriggers", nil)
+	call("/api.TriggerService/ListTriggers", status.Error(codes.PermissionDenied, "denied"))
+	call("/random/method", nil)
+
+	tests := []struct {
+		method, code string
+		want         float64
+	}{
+		{"/api.TriggerService/ListTriggers", "OK", 1},
+		{"/api.TriggerService/ListTriggers", "PermissionDenied", 1},
+		{unknownMethod, "OK", 1},
+	}
+	for _, tt := range tests {
+		if got := testutil.ToFloat64(m.grpcRequests.WithLabelValues(tt.method, tt.code)); got != tt.want {
+			t.Errorf("requests{method=%q, code=%q} = %v, want %v", tt.method, tt.code, got, tt.want)
+		}
+	}
+}
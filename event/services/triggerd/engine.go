@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"event/data"
+	"event/handlers/actions"
+	"event/handlers/aggregation"
+	"event/handlers/dispatch"
+	"event/handlers/sequence"
+	"event/handlers/throttle"
+	"event/handlers/triggers"
+	"event/handlers/upcast"
+	"event/metrics"
+	"event/tracing"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("event/services/triggerd")
+
+// engine decides which triggers fire for an event and dispatches their actions
+type engine struct {
+	dispatcher *dispatch.Dispatcher
+	aggregator *aggregation.Aggregator
+	sequences  *sequence.Tracker
+	limiter    *throttle.Limiter
+	stats      *throttle.Recorder
+	metrics    *metrics.Metrics
+	upcasters  *upcast.Registry
+	// firstMatch holds the namespaces in which only the first matching trigger fires
+	firstMatch map[string]bool
+
+	// inflight tracks the deliveries running in the background, so that shutdown waits for
+	// them before it closes the stores they dead-letter to. Deliveries outlive the context
+	// they were fired with and are only cancelled by abort, once drain runs out of time.
+	mu       sync.Mutex
+	draining bool
+	inflight sync.WaitGroup
+	aborted  context.Context
+	abort    context.CancelFunc
+}
+
+// abortGrace is how long drain waits for aborted deliveries to be dead-lettered
+const abortGrace = 10 * time.Second
+
+// handleMessage decodes an event received from NATS, upcasts it to the current version of
+// its type and evaluates it against the triggers of its namespace, continuing the trace of
+// the event's producer
+func (e *engine) handleMessage(ctx context.Context, msg *nats.Msg, candidates func(namespace string) []*data.Trigger) {
+	received := time.Now()
+	event, upcastFrom, decodeErr := e.upcasters.Decode(msg.Data)
+	decoded := time.Now()
+	if event == nil {
+		event = &data.Event{}
+	}
+
+	ctx, span := tracer.Start(tracing.Extract(ctx, msg.Header, event), "process event",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithTimestamp(received),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "nats"),
+			attribute.String("messaging.destination.name", msg.Subject),
+		),
+	)
+	defer span.End()
+
+	_, decodeSpan := tracer.Start(ctx, "decode", trace.WithTimestamp(received))
+	if decodeErr != nil {
+		log.Printf("Failed to decode event on %s: %v", msg.Subject, decodeErr)
+		decodeSpan.RecordError(decodeErr)
+		decodeSpan.SetStatus(codes.Error, decodeErr.Error())
+		decodeSpan.End(trace.WithTimestamp(decoded))
+		span.SetStatus(codes.Error, "failed to decode event")
+		return
+	}
+	if upcastFrom != "" {
+		decodeSpan.SetAttributes(attribute.String("event.upcast_from", upcastFrom))
+	}
+	decodeSpan.End(trace.WithTimestamp(decoded))
+	span.SetAttributes(tracing.EventAttributes(event)...)
+
+	// Actions that send the event pass the trace on even if its producer did not set one
+	if event.Context.TraceID == "" {
+		event.Context.TraceID = tracing.TraceID(ctx)
+	}
+
+	// An event published by a trigger's nats action is not evaluated against that trigger,
+	// which it would otherwise match again and again
+	eligible := candidates(event.Namespace)
+	if causedBy := msg.Header.Get(actions.CausationHeader); causedBy != "" {
+		span.SetAttributes(attribute.String("event.caused_by", causedBy))
+		eligible = withoutTrigger(eligible, causedBy)
+	}
+
+	e.handleEvent(ctx, eligible, event)
+}
+
+// withoutTrigger returns the triggers except the one with the given namespace/id key
+func withoutTrigger(triggers []*data.Trigger, key string) []*data.Trigger {
+	kept := make([]*data.Trigger, 0, len(triggers))
+	for _, trigger := range triggers {
+		if trigger.Namespace+"/"+trigger.ID != key {
+			kept = append(kept, trigger)
+		}
+	}
+	return kept
+}
+
+// handleEvent evaluates the event against the triggers of its namespace in order, until a
+// trigger with stop_on_match matches or, in first-match namespaces, any trigger matches
+func (e *engine) handleEvent(ctx context.Context, candidates []*data.Trigger, event *data.Event) {
+	ctx, span := tracer.Start(ctx, "match", trace.WithAttributes(attribute.Int("triggers.candidates", len(candidates))))
+	defer span.End()
+
+	firstMatch := e.firstMatch[event.Namespace]
+	for _, trigger := range candidates {
+		if !e.fire(ctx, trigger, event) {
+			continue
+		}
+		if trigger.StopOnMatch || firstMatch {
+			span.SetAttributes(attribute.String("triggers.stopped_by", trigger.ID))
+			return
+		}
+	}
+}
+
+// traceEvaluation records the evaluation of a trigger as a span and returns the span's
+// context, so that the actions the trigger runs are traced as its children. Only
+// evaluations that matched or failed are traced, as every event is evaluated against
+// every trigger of its namespace.
+func traceEvaluation(ctx context.Context, trigger *data.Trigger, start time.Time, err error) context.Context {
+	ctx, span := tracer.Start(ctx, "evaluate trigger",
+		trace.WithTimestamp(start),
+		trace.WithAttributes(tracing.TriggerAttributes(trigger)...),
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	return ctx
+}
+
+// fire runs the trigger's actions in the background if the event matches the trigger, and
+// reports whether it matched. Triggers outside their active time never match. For
+// aggregation triggers, a match only counts towards the window, and the event matches
+// when it makes the window cross its threshold; the actions then run with the threshold
+// event. Sequence triggers match when the event completes their sequence.
+func (e *engine) fire(ctx context.Context, trigger *data.Trigger, event *data.Event) bool {
+	// Triggers outside their active time are not evaluated
+	if active, err := triggers.Active(trigger, time.Now()); !active {
+		if err != nil {
+			log.Printf("Error checking the active time of trigger %s/%s: %v", trigger.Namespace, trigger.ID, err)
+		}
+		return false
+	}
+
+	// Sequence triggers fire with sequence events once their steps have matched
+	if trigger.Sequence != nil {
+		start := time.Now()
+		fired, err := e.sequences.Observe(ctx, trigger, event)
+		e.metrics.TriggerEvaluated(trigger, time.Since(start), len(fired) > 0, err)
+		if err != nil || len(fired) > 0 {
+			ctx = traceEvaluation(ctx, trigger, start, err)
+		}
+		if err != nil {
+			log.Printf("Error applying event %s to sequence trigger %s/%s: %v", event.ID, trigger.Namespace, trigger.ID, err)
+		}
+		for _, sequenceEvent := range fired {
+			log.Printf("Event %s completed sequence trigger %s/%s", event.ID, trigger.Namespace, trigger.ID)
+			e.dispatch(ctx, trigger, sequenceEvent)
+		}
+		return len(fired) > 0
+	}
+
+	start := time.Now()
+	matched, err := triggers.MatchTrigger(trigger, event)
+	e.metrics.TriggerEvaluated(trigger, time.Since(start), matched, err)
+	if err != nil {
+		log.Printf("Error matching trigger %s/%s: %v", trigger.Namespace, trigger.ID, err)
+		traceEvaluation(ctx, trigger, start, err)
+		return false
+	}
+	if !matched {
+		return false
+	}
+	ctx = traceEvaluation(ctx, trigger, start, nil)
+
+	if trigger.Aggregation != nil {
+		threshold, err := e.aggregator.Observe(ctx, trigger, event)
+		if err != nil {
+			log.Printf("Error aggregating event %s for trigger %s/%s: %v", event.ID, trigger.Namespace, trigger.ID, err)
+			return false
+		}
+		if threshold == nil {
+			return false
+		}
+		log.Printf("Event %s crossed the threshold of trigger %s/%s", event.ID, trigger.Namespace, trigger.ID)
+		event = threshold
+	} else {
+		log.Printf("Event %s matched trigger %s/%s", event.ID, trigger.Namespace, trigger.ID)
+	}
+
+	e.dispatch(ctx, trigger, event)
+	return true
+}
+
+// dispatch runs the trigger's actions for the event in the background unless the trigger's
+// throttle suppresses the firing
+func (e *engine) dispatch(ctx context.Context, trigger *data.Trigger, event *data.Event) {
+	if len(actions.ForTrigger(trigger)) == 0 {
+		return
+	}
+
+	// A throttle that cannot be checked lets the trigger fire rather than drop the event
+	reason, err := e.limiter.Allow(ctx, trigger, event)
+	if err != nil {
+		log.Printf("Error checking the throttle of trigger %s/%s: %v", trigger.Namespace, trigger.ID, err)
+	}
+	e.stats.Record(trigger, reason, time.Now())
+	if reason != "" {
+		log.Printf("Throttle of trigger %s/%s suppressed event %s: %s", trigger.Namespace, trigger.ID, event.ID, reason)
+		return
+	}
+
+	e.mu.Lock()
+	if e.draining {
+		e.mu.Unlock()
+		log.Printf("Dropping event %s for trigger %s/%s: triggerd is shutting down", event.ID, trigger.Namespace, trigger.ID)
+		return
+	}
+	if e.abort == nil {
+		e.aborted, e.abort = context.WithCancel(context.Background())
+	}
+	aborted := e.aborted
+	e.inflight.Add(1)
+	e.mu.Unlock()
+
+	// The delivery keeps the trace of ctx, but neither a shutdown signal nor a lost
+	// leadership cancels it
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(aborted, cancel)
+	go func() {
+		defer e.inflight.Done()
+		defer cancel()
+		defer stop()
+		if err := e.dispatcher.Dispatch(ctx, trigger, event); err != nil {
+			log.Printf("Delivery of event %s for trigger %s/%s failed: %v", event.ID, trigger.Namespace, trigger.ID, err)
+		}
+	}()
+}
+
+// drain stops starting deliveries and waits until the running ones have finished. When ctx is
+// done first, the running deliveries are cancelled, and drain waits up to abortGrace for
+// them to be dead-lettered before it returns ctx's error.
+func (e *engine) drain(ctx context.Context) error {
+	e.mu.Lock()
+	e.draining = true
+	e.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		e.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	e.mu.Lock()
+	if e.abort != nil {
+		e.abort()
+	}
+	e.mu.Unlock()
+	select {
+	case <-done:
+	case <-time.After(abortGrace):
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"event/data"
+	"event/handlers/actions"
+	"event/handlers/aggregation"
+	"event/handlers/deadletter"
+	"event/handlers/dispatch"
+	"event/handlers/throttle"
+	"event/handlers/upcast"
+	"event/metrics"
+
+	"github.com/nats-io/nats.go"
+)
+
+// blockingAction is a test action that runs until it is released or cancelled, and reports
+// how it ended on finished
+type blockingAction struct {
+	started  chan struct{}
+	release  chan struct{}
+	finished chan error
+}
+
+func newBlockingAction() *blockingAction {
+	return &blockingAction{started: make(chan struct{}, 1), release: make(chan struct{}), finished: make(chan error, 1)}
+}
+
+func (a *blockingAction) Execute(ctx context.Context, trigger *data.Trigger, event *data.Event) (data.DeliveryAttempt, error) {
+	a.started <- struct{}{}
+	var err error
+	select {
+	case <-a.release:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	a.finished <- err
+	return data.DeliveryAttempt{}, err
+}
+
+// newTestEngine returns an engine that runs the actions of registry
+func newTestEngine(registry *actions.Registry) *engine {
+	return &engine{
+		dispatcher: dispatch.NewDispatcher(registry, deadletter.NewMemoryStore(), dispatch.WithBackoff(0, 0)),
+		aggregator: aggregation.NewAggregator(aggregation.NewMemoryStore()),
+		limiter:    throttle.NewLimiter(throttle.NewMemoryStore()),
+		stats:      throttle.NewRecorder(throttle.NewMemoryStatsStore()),
+		metrics:    metrics.New(),
+		upcasters:  upcast.NewRegistry(),
+		firstMatch: map[string]bool{},
+	}
+}
+
+func TestEngine_Drain(t *testing.T) {
+	action := newBlockingAction()
+	registry := actions.NewRegistry()
+	registry.Register("block", func(config map[string]interface{}) (actions.Action, error) {
+		return action, nil
+	})
+	e := newTestEngine(registry)
+
+	trigger := &data.Trigger{ID: "t1", Namespace: "sales", Actions: []data.ActionConfig{{Type: "block"}}}
+	event := &data.Event{ID: "evt1", Namespace: "sales"}
+	signalCtx, signal := context.WithCancel(context.Background())
+	e.dispatch(signalCtx, trigger, event)
+	<-action.started
+
+	// The shutdown signal does not cancel the running delivery
+	signal()
+	select {
+	case err := <-action.finished:
+		t.Fatalf("delivery ended with %v when its context was cancelled", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// The running delivery holds up the drain until its deadline, which cancels it
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := e.drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("drain() error = %v, want context.DeadlineExceeded", err)
+	}
+	select {
+	case err := <-action.finished:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("aborted delivery ended with %v, want context.Canceled", err)
+		}
+	default:
+		t.Error("drain returned before the aborted delivery ended")
+	}
+
+	// Deliveries are not started once draining has begun
+	e.dispatch(context.Background(), trigger, event)
+	if err := e.drain(context.Background()); err != nil {
+		t.Fatalf("drain() error = %v", err)
+	}
+	select {
+	case <-action.started:
+		t.Error("a delivery started while draining")
+	default:
+	}
+}
+
+func TestEngine_DrainLetsDeliveriesFinish(t *testing.T) {
+	action := newBlockingAction()
+	registry := actions.NewRegistry()
+	registry.Register("block", func(config map[string]interface{}) (actions.Action, error) {
+		return action, nil
+	})
+	e := newTestEngine(registry)
+
+	e.dispatch(context.Background(), &data.Trigger{ID: "t1", Namespace: "sales", Actions: []data.ActionConfig{{Type: "block"}}}, &data.Event{ID: "evt1", Namespace: "sales"})
+	<-action.started
+
+	// A delivery that finishes within the shutdown timeout is not cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	time.AfterFunc(20*time.Millisecond, func() { close(action.release) })
+	if err := e.drain(ctx); err != nil {
+		t.Fatalf("drain() error = %v", err)
+	}
+	if err := <-action.finished; err != nil {
+		t.Errorf("delivery ended with %v, want success", err)
+	}
+}
+
+// loopbackPublisher keeps the messages a nats action publishes, to be fed back to the engine
+type loopbackPublisher struct {
+	mu   sync.Mutex
+	msgs []*nats.Msg
+}
+
+func (p *loopbackPublisher) PublishMsg(msg *nats.Msg) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.msgs = append(p.msgs, msg)
+	return nil
+}
+
+func (p *loopbackPublisher) FlushWithContext(ctx context.Context) error {
+	return nil
+}
+
+func (p *loopbackPublisher) published() []*nats.Msg {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*nats.Msg(nil), p.msgs...)
+}
+
+// countingAction is a test action that counts the events it runs for
+type countingAction struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (a *countingAction) Execute(ctx context.Context, trigger *data.Trigger, event *data.Event) (data.DeliveryAttempt, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.events = append(a.events, event.ID)
+	return data.DeliveryAttempt{}, nil
+}
+
+func (a *countingAction) count() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.events)
+}
+
+func TestEngine_DerivedEventDoesNotRefireTrigger(t *testing.T) {
+	publisher := &loopbackPublisher{}
+	counter := &countingAction{}
+	registry := actions.NewRegistry()
+	registry.Register(actions.NATSType, actions.NewNATSFactory(publisher))
+	registry.Register("count", func(config map[string]interface{}) (actions.Action, error) {
+		return counter, nil
+	})
+	e := newTestEngine(registry)
+
+	// The republishing trigger matches the events it publishes, the counting trigger
+	// matches every event
+	candidates := []*data.Trigger{
+		{ID: "republish", Namespace: "sales", Enabled: true, EventType: "order.created", ObjectType: "Order",
+			Actions: []data.ActionConfig{{Type: actions.NATSType, Config: map[string]interface{}{"subject": "event.sales.Order.order.created"}}}},
+		{ID: "count", Namespace: "sales", Enabled: true, EventType: "order.created", ObjectType: "Order",
+			Actions: []data.ActionConfig{{Type: "count"}}},
+	}
+	triggersOf := func(namespace string) []*data.Trigger { return candidates }
+
+	event := &data.Event{ID: "evt1", EventType: "order.created", Namespace: "sales", ObjectType: "Order", ObjectID: "o1"}
+	body, _ := json.Marshal(event)
+	e.handleMessage(context.Background(), &nats.Msg{Subject: "event.sales.Order.order.created", Data: body}, triggersOf)
+
+	// Feed the derived event back in, as the NATS subscription would
+	waitFor(t, func() bool { return len(publisher.published()) == 1 })
+	e.handleMessage(context.Background(), publisher.published()[0], triggersOf)
+	if err := e.drain(context.Background()); err != nil {
+		t.Fatalf("drain() error = %v", err)
+	}
+
+	if n := len(publisher.published()); n != 1 {
+		t.Errorf("republishing trigger published %d events, want 1", n)
+	}
+	// Other triggers still see the derived event
+	if n := counter.count(); n != 2 {
+		t.Errorf("counting trigger fired %d times, want 2", n)
+	}
+}
+
+// waitFor polls condition until it holds, failing the test after a second
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within a second")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestEngine_AggregationStopsOnlyWhenCrossingThreshold(t *testing.T) {
+	thresholds, plain := &countingAction{}, &countingAction{}
+	registry := actions.NewRegistry()
+	registry.Register("threshold", func(config map[string]interface{}) (actions.Action, error) {
+		return thresholds, nil
+	})
+	registry.Register("plain", func(config map[string]interface{}) (actions.Action, error) {
+		return plain, nil
+	})
+	e := newTestEngine(registry)
+
+	// The aggregation trigger is evaluated first and stops the evaluation when it matches
+	candidates := []*data.Trigger{
+		{ID: "failures", Namespace: "billing", Enabled: true, EventType: "payment.failed", ObjectType: "payment",
+			Priority: 10, StopOnMatch: true,
+			Aggregation: &data.Aggregation{Function: aggregation.FunctionSum, Field: "event.payload.after.amount", Window: "10m", Threshold: 2},
+			Actions:     []data.ActionConfig{{Type: "threshold"}}},
+		{ID: "every-failure", Namespace: "billing", Enabled: true, EventType: "payment.failed", ObjectType: "payment",
+			Actions: []data.ActionConfig{{Type: "plain"}}},
+	}
+	failed := func(id string, amount interface{}) *data.Event {
+		event := &data.Event{ID: id, EventType: "payment.failed", Namespace: "billing", ObjectType: "payment", ObjectID: "p1", Timestamp: time.Now()}
+		event.Payload.After = map[string]interface{}{"amount": amount}
+		return event
+	}
+
+	// Below the threshold, and when the event cannot be aggregated, the plain trigger still runs
+	e.handleEvent(context.Background(), candidates, failed("e1", 1))
+	e.handleEvent(context.Background(), candidates, failed("e2", "n/a"))
+	// Crossing the threshold fires the aggregation trigger and stops the evaluation
+	e.handleEvent(context.Background(), candidates, failed("e3", 1))
+	if err := e.drain(context.Background()); err != nil {
+		t.Fatalf("drain() error = %v", err)
+	}
+
+	if n := thresholds.count(); n != 1 {
+		t.Errorf("aggregation trigger fired %d times, want 1", n)
+	}
+	plain.mu.Lock()
+	defer plain.mu.Unlock()
+	slices.Sort(plain.events)
+	if !slices.Equal(plain.events, []string{"e1", "e2"}) {
+		t.Errorf("plain trigger fired for %v, want [e1 e2]", plain.events)
+	}
+}
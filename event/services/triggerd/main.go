@@ -0,0 +1,619 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"event/api/auth"
+	"event/api/gateway"
+	"event/api/server"
+	"event/data"
+	"event/handlers/actions"
+	"event/handlers/aggregation"
+	"event/handlers/deadletter"
+	"event/handlers/dispatch"
+	"event/handlers/jobs"
+	"event/handlers/namespaces"
+	"event/handlers/scheduler"
+	"event/handlers/secrets"
+	"event/handlers/sequence"
+	"event/handlers/throttle"
+	"event/handlers/triggers"
+	"event/handlers/upcast"
+	"event/metrics"
+	"event/tlsconfig"
+	"event/tracing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// triggerd evaluates incoming events against the triggers stored in etcd
+// and delivers matching events to the triggers' actions
+
+func main() {
+	var configFile string
+
+	rootCmd := &cobra.Command{
+		Use:   "triggerd",
+		Short: "Evaluate events against triggers and deliver matches",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := loadConfig(configFile); err != nil {
+				return err
+			}
+			return run()
+		},
+	}
+	rootCmd.Flags().StringVar(&configFile, "config", "config.yaml", "Path to the configuration file")
+
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// loadConfig reads the configuration file and sets defaults
+func loadConfig(configFile string) error {
+	viper.SetDefault("mongo.uri", "mongodb://localhost:27017")
+	viper.SetDefault("mongo.database", "eventstore")
+	viper.SetDefault("nats.url", nats.DefaultURL)
+	viper.SetDefault("etcd.endpoints", []string{"localhost:2379"})
+	viper.SetDefault("etcd.trigger_prefix", triggers.DefaultTriggerPrefix)
+	viper.SetDefault("etcd.secret_prefix", secrets.DefaultSecretPrefix)
+	viper.SetDefault("etcd.namespace_prefix", namespaces.DefaultPrefix)
+	viper.SetDefault("namespaces.default_quota.max_triggers", 0)
+	viper.SetDefault("namespaces.default_quota.max_criteria_length", 0)
+	viper.SetDefault("namespaces.default_quota.max_actions_per_minute", 0)
+	viper.SetDefault("triggerd.grpc_address", ":50051")
+	viper.SetDefault("triggerd.subject", "event.>")
+	viper.SetDefault("triggerd.queue_group", "triggerd-workers")
+	viper.SetDefault("triggerd.dead_letter_collection", deadletter.DefaultCollection)
+	viper.SetDefault("triggerd.notification_url", "http://localhost:3000")
+	viper.SetDefault("triggerd.reflection", false)
+	viper.SetDefault("triggerd.health_interval", server.DefaultHealthInterval)
+	viper.SetDefault("triggerd.shutdown_timeout", 10*time.Second)
+	viper.SetDefault("triggerd.first_match_namespaces", []string{})
+	viper.SetDefault("gateway.enabled", false)
+	viper.SetDefault("gateway.address", ":8080")
+	if hostname, err := os.Hostname(); err == nil {
+		viper.SetDefault("triggerd.instance_id", hostname)
+	} else {
+		viper.SetDefault("triggerd.instance_id", "triggerd")
+	}
+	viper.SetDefault("jobs.collection", jobs.DefaultCollection)
+	viper.SetDefault("jobs.reconcile_interval", time.Minute)
+	viper.SetDefault("etcd.scheduler_prefix", scheduler.DefaultPrefix)
+	viper.SetDefault("scheduler.session_ttl", scheduler.DefaultSessionTTL)
+	viper.SetDefault("scheduler.catch_up", scheduler.DefaultCatchUp)
+	viper.SetDefault("aggregation.collection", aggregation.DefaultCollection)
+	viper.SetDefault("aggregation.buckets", aggregation.DefaultBuckets)
+	viper.SetDefault("sequence.collection", sequence.DefaultCollection)
+	viper.SetDefault("sequence.check_interval", 10*time.Second)
+	viper.SetDefault("auth.enabled", false)
+	viper.SetDefault("auth.groups_claim", auth.DefaultGroupsClaim)
+	viper.SetDefault("auth.leeway", auth.DefaultLeeway)
+	viper.SetDefault("throttle.collection", throttle.DefaultCollection)
+	viper.SetDefault("throttle.stats_collection", throttle.DefaultStatsCollection)
+	viper.SetDefault("throttle.stats_interval", 10*time.Second)
+	viper.SetDefault("metrics.address", ":9090")
+	viper.SetDefault("metrics.max_namespaces", metrics.DefaultMaxNamespaces)
+	viper.SetDefault("metrics.max_triggers", metrics.DefaultMaxTriggers)
+	viper.SetDefault("metrics.refresh_interval", 15*time.Second)
+	viper.SetDefault("tracing.exporter", tracing.ExporterNone)
+	viper.SetDefault("tracing.sample_ratio", 1.0)
+
+	viper.SetConfigFile(configFile)
+	viper.AutomaticEnv()
+	if err := viper.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	return nil
+}
+
+func run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Spans continue the traces of the events' producers and follow the events to the actions
+	var tracingConfig tracing.Config
+	if err := viper.UnmarshalKey("tracing", &tracingConfig); err != nil {
+		return fmt.Errorf("failed to read tracing: %w", err)
+	}
+	shutdownTracing, err := tracing.Setup(ctx, "triggerd", tracingConfig)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			log.Printf("Failed to flush spans: %v", err)
+		}
+	}()
+
+	// Per-trigger series are limited so that large namespaces do not overwhelm Prometheus
+	m := metrics.New(
+		metrics.WithMaxNamespaces(viper.GetInt("metrics.max_namespaces")),
+		metrics.WithMaxTriggers(viper.GetInt("metrics.max_triggers")),
+	)
+
+	// Load triggers from etcd and keep them up to date
+	etcdOptions, err := etcdOptions()
+	if err != nil {
+		return err
+	}
+	etcdOptions = append(etcdOptions, triggers.WithWatchObserver(m.WatchEvent))
+	store, err := triggers.NewEtcdStore(viper.GetStringSlice("etcd.endpoints"), viper.GetString("etcd.trigger_prefix"), etcdOptions...)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := store.LoadAll(ctx); err != nil {
+		return err
+	}
+	store.Watch(ctx)
+	m.Refresh(store.GetAllTriggers())
+
+	// Serve the metrics for Prometheus
+	var metricsServer *http.Server
+	if address := viper.GetString("metrics.address"); address != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", m.Handler())
+		metricsServer = &http.Server{Addr: address, Handler: mux}
+		go func() {
+			log.Printf("Serving metrics on %s", address)
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("Metrics server stopped: %v", err)
+			}
+		}()
+	}
+
+	// Failed deliveries are kept in MongoDB
+	mongoClient, err := mongo.Connect(ctx, options.Client().ApplyURI(viper.GetString("mongo.uri")))
+	if err != nil {
+		return fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+	defer mongoClient.Disconnect(context.Background())
+
+	deadLetters, err := deadletter.NewMongoStore(ctx, mongoClient.Database(viper.GetString("mongo.database")), viper.GetString("triggerd.dead_letter_collection"))
+	if err != nil {
+		return err
+	}
+	// NATS delivers events and is used by the nats action
+	nc, err := nats.Connect(viper.GetString("nats.url"))
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	defer nc.Close()
+
+	// Jobs are kept in MongoDB and their lifecycle events are published on NATS
+	jobStore, err := jobs.NewMongoStore(ctx, mongoClient.Database(viper.GetString("mongo.database")), viper.GetString("jobs.collection"))
+	if err != nil {
+		return err
+	}
+	orchestrator := jobs.NewOrchestrator(jobStore, nc)
+	go reconcileJobs(ctx, orchestrator, viper.GetDuration("jobs.reconcile_interval"))
+
+	// Webhooks are signed with the secrets kept next to (but not inside) the triggers in etcd
+	secretStore := secrets.NewEtcdStore(store.Client(), viper.GetString("etcd.secret_prefix"))
+	registry := actions.NewDefaultRegistry(actions.Dependencies{
+		Secrets:         secretStore,
+		NATS:            nc,
+		NotificationURL: viper.GetString("triggerd.notification_url"),
+		Jobs:            orchestrator,
+	})
+	dispatcher := dispatch.NewDispatcher(registry, deadLetters, dispatch.WithObserver(m))
+
+	// Windows of aggregation triggers are kept in MongoDB so that replicas count events together
+	windows, err := aggregation.NewMongoStore(ctx, mongoClient.Database(viper.GetString("mongo.database")), viper.GetString("aggregation.collection"))
+	if err != nil {
+		return err
+	}
+	aggregator := aggregation.NewAggregator(windows, aggregation.WithBuckets(viper.GetInt("aggregation.buckets")))
+	go refreshTriggers(ctx, store, viper.GetDuration("metrics.refresh_interval"), m.Refresh, aggregator.Prune)
+
+	// Sequence instances are kept in MongoDB so that they complete across restarts and replicas
+	sequences, err := sequence.NewMongoStore(ctx, mongoClient.Database(viper.GetString("mongo.database")), viper.GetString("sequence.collection"))
+	if err != nil {
+		return err
+	}
+	tracker := sequence.NewTracker(sequences, store)
+
+	// Throttles are enforced through MongoDB so that replicas share their limits, and
+	// firings are counted in memory and flushed to the trigger stats periodically
+	throttles, err := throttle.NewMongoStore(ctx, mongoClient.Database(viper.GetString("mongo.database")), viper.GetString("throttle.collection"))
+	if err != nil {
+		return err
+	}
+	statsStore, err := throttle.NewMongoStatsStore(ctx, mongoClient.Database(viper.GetString("mongo.database")), viper.GetString("throttle.stats_collection"))
+	if err != nil {
+		return err
+	}
+	stats := throttle.NewRecorder(statsStore)
+	go flushStats(ctx, stats, viper.GetDuration("throttle.stats_interval"))
+
+	// Events of older versions are upcast before they are matched
+	upcasters, err := newUpcasters()
+	if err != nil {
+		return err
+	}
+
+	engine := &engine{
+		dispatcher: dispatcher,
+		aggregator: aggregator,
+		sequences:  tracker,
+		limiter:    throttle.NewLimiter(throttles),
+		stats:      stats,
+		metrics:    m,
+		upcasters:  upcasters,
+		firstMatch: map[string]bool{},
+	}
+	// In first-match namespaces an event fires only the first trigger it matches
+	for _, namespace := range viper.GetStringSlice("triggerd.first_match_namespaces") {
+		engine.firstMatch[namespace] = true
+	}
+	go expireSequences(ctx, engine, viper.GetDuration("sequence.check_interval"))
+
+	// Quotas of namespaces that were not created, or do not set a limit
+	var defaultQuota data.NamespaceQuota
+	if err := viper.UnmarshalKey("namespaces.default_quota", &defaultQuota); err != nil {
+		return fmt.Errorf("failed to read namespaces.default_quota: %w", err)
+	}
+	if err := namespaces.ValidateQuota(defaultQuota); err != nil {
+		return fmt.Errorf("invalid namespaces.default_quota: %w", err)
+	}
+	namespaceStore := namespaces.NewEtcdStore(store.Client(), viper.GetString("etcd.namespace_prefix"))
+
+	// Serve the trigger management API
+	serverOptions := []server.ServerOption{
+		server.WithNamespaces(namespaceStore, defaultQuota),
+		server.WithDeadLetters(deadLetters, dispatcher),
+		server.WithActionRegistry(registry),
+		server.WithJobService(server.NewJobServer(orchestrator)),
+		server.WithStats(statsStore),
+		server.WithMetrics(m),
+		server.WithHealthInterval(viper.GetDuration("triggerd.health_interval")),
+	}
+	if viper.GetBool("triggerd.reflection") {
+		serverOptions = append(serverOptions, server.WithReflection())
+	}
+	var serverTLS tlsconfig.Config
+	if err := viper.UnmarshalKey("triggerd.tls", &serverTLS); err != nil {
+		return fmt.Errorf("failed to read triggerd.tls: %w", err)
+	}
+	if serverTLS.Enabled {
+		config, err := serverTLS.ServerConfig()
+		if err != nil {
+			return fmt.Errorf("invalid triggerd.tls: %w", err)
+		}
+		serverOptions = append(serverOptions, server.WithTLS(config))
+	}
+	if viper.GetBool("auth.enabled") {
+		guard, err := newGuard()
+		if err != nil {
+			return err
+		}
+		serverOptions = append(serverOptions, server.WithAuth(guard))
+	} else {
+		log.Println("Authentication is disabled; anyone who can reach the gRPC API can manage every namespace")
+	}
+	grpcServer := server.NewTriggerServer(store, serverOptions...)
+	go func() {
+		if err := grpcServer.Start(viper.GetString("triggerd.grpc_address")); err != nil {
+			log.Printf("gRPC server stopped: %v", err)
+			stop()
+		}
+	}()
+
+	// Serve the API as HTTP/JSON for browsers and scripts
+	stopGateway := func(context.Context) {}
+	if viper.GetBool("gateway.enabled") {
+		stopGateway, err = startGateway(serverTLS.Enabled)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Consume events from NATS
+	sub, err := nc.QueueSubscribe(viper.GetString("triggerd.subject"), viper.GetString("triggerd.queue_group"), func(msg *nats.Msg) {
+		engine.handleMessage(ctx, msg, store.GetTriggers)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	// Fire scheduled triggers on whichever triggerd instance is elected leader
+	fireScheduled := func(ctx context.Context, trigger *data.Trigger, event *data.Event) {
+		engine.fire(ctx, trigger, event)
+	}
+	ticks := scheduler.NewScheduler(store, fireScheduled, scheduler.WithCatchUp(viper.GetDuration("scheduler.catch_up")))
+	election := scheduler.NewElection(store.Client(), viper.GetString("etcd.scheduler_prefix"), fmt.Sprintf("%s-%d", viper.GetString("triggerd.instance_id"), os.Getpid()),
+		scheduler.WithSessionTTL(viper.GetInt("scheduler.session_ttl")))
+	go election.Run(ctx, ticks)
+
+	log.Printf("triggerd listening on %s", viper.GetString("triggerd.subject"))
+	<-ctx.Done()
+	log.Println("Shutting down triggerd")
+
+	// Let in-flight API calls finish before the stores they use are closed
+	stopCtx, cancelStop := context.WithTimeout(context.Background(), viper.GetDuration("triggerd.shutdown_timeout"))
+	stopGateway(stopCtx)
+	if err := grpcServer.Stop(stopCtx); err != nil {
+		log.Printf("gRPC server did not stop gracefully: %v", err)
+	}
+	// Deliveries get the rest of the shutdown timeout to finish; those still running then are
+	// cancelled and dead-lettered, which needs MongoDB
+	if err := sub.Unsubscribe(); err != nil {
+		log.Printf("Failed to unsubscribe: %v", err)
+	}
+	if err := engine.drain(stopCtx); err != nil {
+		log.Printf("Deliveries did not finish before the shutdown timeout: %v", err)
+	}
+	cancelStop()
+
+	saveCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := stats.Flush(saveCtx); err != nil {
+		log.Printf("Failed to flush trigger stats: %v", err)
+	}
+	if metricsServer != nil {
+		metricsServer.Shutdown(saveCtx)
+	}
+	return nil
+}
+
+// newUpcasters returns the registry of the upcasters declared in the upcasters config, after
+// verifying every path through them
+func newUpcasters() (*upcast.Registry, error) {
+	var rules []upcast.Rule
+	if err := viper.UnmarshalKey("upcasters", &rules); err != nil {
+		return nil, fmt.Errorf("failed to read upcasters: %w", err)
+	}
+
+	registry := upcast.NewRegistry()
+	for i, rule := range rules {
+		u, err := rule.Upcaster()
+		if err == nil {
+			err = registry.Register(u)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid upcasters[%d]: %w", i, err)
+		}
+	}
+	if err := registry.Verify(); err != nil {
+		return nil, fmt.Errorf("invalid upcasters: %w", err)
+	}
+	if registry.Len() > 0 {
+		log.Printf("Upcasting events with %d upcasters", registry.Len())
+	}
+	return registry, nil
+}
+
+// startGateway serves the HTTP/JSON gateway on gateway.address. The gateway calls the gRPC
+// API at triggerd.grpc_address, over TLS with gateway.grpc_tls if the API is served over TLS.
+// It returns a function that stops the gateway.
+func startGateway(grpcTLS bool) (func(context.Context), error) {
+	target := viper.GetString("triggerd.grpc_address")
+	if strings.HasPrefix(target, ":") {
+		target = "localhost" + target
+	}
+	transport := insecure.NewCredentials()
+	if grpcTLS {
+		var clientTLS tlsconfig.Config
+		if err := viper.UnmarshalKey("gateway.grpc_tls", &clientTLS); err != nil {
+			return nil, fmt.Errorf("failed to read gateway.grpc_tls: %w", err)
+		}
+		config, err := clientTLS.ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("invalid gateway.grpc_tls: %w", err)
+		}
+		transport = credentials.NewTLS(config)
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(transport))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client for the gateway: %w", err)
+	}
+
+	address := viper.GetString("gateway.address")
+	httpServer := &http.Server{
+		Addr:              address,
+		Handler:           gateway.New(conn, gateway.WithAllowedOrigins(viper.GetStringSlice("gateway.allowed_origins")...)),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	var gatewayTLS tlsconfig.Config
+	if err := viper.UnmarshalKey("gateway.tls", &gatewayTLS); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read gateway.tls: %w", err)
+	}
+	if gatewayTLS.Enabled {
+		config, err := gatewayTLS.ServerConfig()
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("invalid gateway.tls: %w", err)
+		}
+		httpServer.TLSConfig = config
+	}
+
+	go func() {
+		log.Printf("Serving the HTTP gateway on %s", address)
+		var err error
+		if httpServer.TLSConfig != nil {
+			err = httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = httpServer.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Printf("HTTP gateway stopped: %v", err)
+		}
+	}()
+
+	return func(ctx context.Context) {
+		if err := httpServer.Shutdown(ctx); err != nil {
+			log.Printf("HTTP gateway did not stop gracefully: %v", err)
+		}
+		conn.Close()
+	}, nil
+}
+
+// etcdOptions returns the TLS and authentication options of the etcd client from the etcd config
+func etcdOptions() ([]triggers.EtcdOption, error) {
+	var options []triggers.EtcdOption
+
+	var etcdTLS tlsconfig.Config
+	if err := viper.UnmarshalKey("etcd.tls", &etcdTLS); err != nil {
+		return nil, fmt.Errorf("failed to read etcd.tls: %w", err)
+	}
+	if etcdTLS.Enabled {
+		config, err := etcdTLS.ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("invalid etcd.tls: %w", err)
+		}
+		options = append(options, triggers.WithTLS(config))
+	}
+	if username := viper.GetString("etcd.username"); username != "" {
+		options = append(options, triggers.WithCredentials(username, viper.GetString("etcd.password")))
+	}
+	return options, nil
+}
+
+// newGuard creates the authentication and authorization of the gRPC API from the auth config
+func newGuard() (*auth.Guard, error) {
+	var bindings []auth.Binding
+	if err := viper.UnmarshalKey("auth.bindings", &bindings); err != nil {
+		return nil, fmt.Errorf("failed to read auth bindings: %w", err)
+	}
+	policy, err := auth.NewPolicy(bindings)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth bindings: %w", err)
+	}
+
+	var authenticators []auth.Authenticator
+	var keys []auth.APIKey
+	if err := viper.UnmarshalKey("auth.api_keys", &keys); err != nil {
+		return nil, fmt.Errorf("failed to read API keys: %w", err)
+	}
+	if len(keys) > 0 {
+		apiKeys, err := auth.NewAPIKeyAuthenticator(keys)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, apiKeys)
+	}
+	if file := viper.GetString("auth.jwks_file"); file != "" {
+		jwts, err := auth.NewJWTAuthenticator(file,
+			auth.WithIssuer(viper.GetString("auth.issuer")),
+			auth.WithAudience(viper.GetString("auth.audience")),
+			auth.WithGroupsClaim(viper.GetString("auth.groups_claim")),
+			auth.WithLeeway(viper.GetDuration("auth.leeway")),
+		)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, jwts)
+	}
+	if len(authenticators) == 0 {
+		return nil, fmt.Errorf("auth is enabled but neither auth.jwks_file nor auth.api_keys is configured")
+	}
+
+	return auth.NewGuard(policy, authenticators...), nil
+}
+
+// expireSequences periodically finishes the sequence instances whose deadline has passed
+// and fires the absence sequences among them
+func expireSequences(ctx context.Context, engine *engine, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		firings, err := engine.sequences.Expire(ctx)
+		if err != nil {
+			log.Printf("Failed to expire sequences: %v", err)
+		}
+		for _, firing := range firings {
+			log.Printf("Sequence trigger %s/%s timed out", firing.Trigger.Namespace, firing.Trigger.ID)
+			engine.dispatch(ctx, firing.Trigger, firing.Event)
+		}
+	}
+}
+
+// flushStats periodically adds the firings counted by this instance to the trigger stats
+func flushStats(ctx context.Context, stats *throttle.Recorder, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := stats.Flush(ctx); err != nil {
+			log.Printf("Failed to flush trigger stats: %v", err)
+		}
+	}
+}
+
+// refreshTriggers periodically passes the loaded triggers to each refresh function, so that
+// the metrics release the series of deleted triggers and the aggregator their parsed
+// aggregations
+func refreshTriggers(ctx context.Context, store triggers.TriggerStore, interval time.Duration, refresh ...func([]*data.Trigger)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		loaded := store.GetAllTriggers()
+		for _, f := range refresh {
+			f(loaded)
+		}
+	}
+}
+
+// reconcileJobs periodically re-queues jobs whose worker lease expired and advances
+// pending jobs whose dependencies finished while triggerd could not advance them
+func reconcileJobs(ctx context.Context, orchestrator *jobs.Orchestrator, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := orchestrator.ExpireLeases(ctx); err != nil {
+			log.Printf("Failed to expire job leases: %v", err)
+		} else if n > 0 {
+			log.Printf("Expired %d job leases", n)
+		}
+		if err := orchestrator.Reconcile(ctx); err != nil {
+			log.Printf("Failed to reconcile jobs: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Package signature signs and verifies trigger webhook deliveries.
+//
+// Every delivery carries a header of the form
+//
+//	X-Trigger-Signature: t=1712406600,v1=5257a869...,v1=8f3c1b2d...
+//
+// where t is the Unix time of the delivery and each v1 is the hex encoded
+// HMAC-SHA256 of "<t>.<body>" under one of the active signing secrets.
+// During secret rotation two v1 values are sent, so receivers can verify with
+// either the old or the new secret.
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Header is the HTTP header that carries the signature
+	Header = "X-Trigger-Signature"
+	// DefaultTolerance is the maximum age of a signature accepted by Verify
+	DefaultTolerance = 5 * time.Minute
+
+	timestampKey = "t"
+	schemeKey    = "v1"
+)
+
+var (
+	// ErrMissingHeader is returned when the request carries no signature header
+	ErrMissingHeader = errors.New("missing signature header")
+	// ErrInvalidHeader is returned when the signature header cannot be parsed
+	ErrInvalidHeader = errors.New("invalid signature header")
+	// ErrExpired is returned when the signature timestamp is outside the tolerance
+	ErrExpired = errors.New("signature timestamp outside tolerance")
+	// ErrMismatch is returned when no signature matches any of the secrets
+	ErrMismatch = errors.New("signature mismatch")
+)
+
+// Sign returns the signature header value for body at time ts, with one v1 entry per secret
+func Sign(body []byte, ts time.Time, secrets ...string) string {
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+
+	parts := make([]string, 0, len(secrets)+1)
+	parts = append(parts, timestampKey+"="+timestamp)
+	for _, secret := range secrets {
+		parts = append(parts, schemeKey+"="+compute(timestamp, body, secret))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// Verify checks a signature header value against body.
+// It succeeds when any v1 signature matches any of the given secrets and
+// the timestamp is no older (or newer) than tolerance. A zero tolerance disables the time check.
+func Verify(header string, body []byte, tolerance time.Duration, secrets ...string) error {
+	if header == "" {
+		return ErrMissingHeader
+	}
+
+	timestamp, signatures, err := parse(header)
+	if err != nil {
+		return err
+	}
+
+	if tolerance > 0 {
+		unix, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: bad timestamp", ErrInvalidHeader)
+		}
+		age := time.Since(time.Unix(unix, 0))
+		if age > tolerance || age < -tolerance {
+			return ErrExpired
+		}
+	}
+
+	for _, secret := range secrets {
+		expected := compute(timestamp, body, secret)
+		for _, sig := range signatures {
+			if hmac.Equal([]byte(expected), []byte(sig)) {
+				return nil
+			}
+		}
+	}
+
+	return ErrMismatch
+}
+
+// VerifyRequest reads the signature header from r and verifies it against body
+// using DefaultTolerance. The caller is responsible for reading the request body.
+func VerifyRequest(r *http.Request, body []byte, secrets ...string) error {
+	return Verify(r.Header.Get(Header), body, DefaultTolerance, secrets...)
+}
+
+// parse splits a header value into its timestamp and v1 signatures
+func parse(header string) (string, []string, error) {
+	var (
+		timestamp  string
+		signatures []string
+	)
+
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return "", nil, ErrInvalidHeader
+		}
+		switch key {
+		case timestampKey:
+			timestamp = value
+		case schemeKey:
+			signatures = append(signatures, value)
+		}
+	}
+
+	if timestamp == "" || len(signatures) == 0 {
+		return "", nil, ErrInvalidHeader
+	}
+	return timestamp, signatures, nil
+}
+
+// compute returns the hex encoded HMAC-SHA256 of "<timestamp>.<body>"
+func compute(timestamp string, body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,129 @@
+package signature
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"event_id":"evt1"}`)
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		header    string
+		body      []byte
+		secrets   []string
+		tolerance time.Duration
+		wantErr   error
+	}{
+		{
+			name:      "single secret",
+			header:    Sign(body, now, "secret1"),
+			body:      body,
+			secrets:   []string{"secret1"},
+			tolerance: DefaultTolerance,
+		},
+		{
+			name:      "rotation - receiver still has old secret",
+			header:    Sign(body, now, "new-secret", "old-secret"),
+			body:      body,
+			secrets:   []string{"old-secret"},
+			tolerance: DefaultTolerance,
+		},
+		{
+			name:      "rotation - receiver already has new secret",
+			header:    Sign(body, now, "new-secret", "old-secret"),
+			body:      body,
+			secrets:   []string{"new-secret"},
+			tolerance: DefaultTolerance,
+		},
+		{
+			name:      "wrong secret",
+			header:    Sign(body, now, "secret1"),
+			body:      body,
+			secrets:   []string{"secret2"},
+			tolerance: DefaultTolerance,
+			wantErr:   ErrMismatch,
+		},
+		{
+			name:      "tampered body",
+			header:    Sign(body, now, "secret1"),
+			body:      []byte(`{"event_id":"evt2"}`),
+			secrets:   []string{"secret1"},
+			tolerance: DefaultTolerance,
+			wantErr:   ErrMismatch,
+		},
+		{
+			name:      "expired",
+			header:    Sign(body, now.Add(-10*time.Minute), "secret1"),
+			body:      body,
+			secrets:   []string{"secret1"},
+			tolerance: DefaultTolerance,
+			wantErr:   ErrExpired,
+		},
+		{
+			name:    "expired but tolerance disabled",
+			header:  Sign(body, now.Add(-10*time.Minute), "secret1"),
+			body:    body,
+			secrets: []string{"secret1"},
+		},
+		{
+			name:      "missing header",
+			header:    "",
+			body:      body,
+			secrets:   []string{"secret1"},
+			tolerance: DefaultTolerance,
+			wantErr:   ErrMissingHeader,
+		},
+		{
+			name:      "malformed header",
+			header:    "garbage",
+			body:      body,
+			secrets:   []string{"secret1"},
+			tolerance: DefaultTolerance,
+			wantErr:   ErrInvalidHeader,
+		},
+		{
+			name:      "no signatures",
+			header:    "t=1712406600",
+			body:      body,
+			secrets:   []string{"secret1"},
+			tolerance: DefaultTolerance,
+			wantErr:   ErrInvalidHeader,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.header, tt.body, tt.tolerance, tt.secrets...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSign_Format(t *testing.T) {
+	header := Sign([]byte("body"), time.Unix(1712406600, 0), "a", "b")
+
+	if !strings.HasPrefix(header, "t=1712406600,v1=") {
+		t.Errorf("Sign() = %s, want prefix t=1712406600,v1=", header)
+	}
+	if n := strings.Count(header, "v1="); n != 2 {
+		t.Errorf("Sign() has %d signatures, want 2", n)
+	}
+}
+
+func TestVerifyRequest(t *testing.T) {
+	body := []byte(`{"event_id":"evt1"}`)
+	req := httptest.NewRequest("POST", "/hook", nil)
+	req.Header.Set(Header, Sign(body, time.Now(), "secret1"))
+
+	if err := VerifyRequest(req, body, "secret1"); err != nil {
+		t.Errorf("VerifyRequest() error = %v", err)
+	}
+}
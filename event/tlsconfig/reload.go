@@ -0,0 +1,87 @@
+package tlsconfig
+
+import (
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// reloading holds a value loaded from files and loads it again when a file's modification
+// time changes. A failed reload keeps the previous value, so that a half-written
+// certificate does not break new connections.
+type reloading[T any] struct {
+	files    []string
+	load     func() (T, error)
+	interval time.Duration
+	now      func() time.Time
+
+	value    T
+	modTimes []time.Time
+	checked  time.Time
+	mu       sync.Mutex
+}
+
+// newReloading loads the value for the first time
+func newReloading[T any](files []string, interval time.Duration, load func() (T, error)) (*reloading[T], error) {
+	r := &reloading[T]{
+		files:    files,
+		load:     load,
+		interval: interval,
+		now:      time.Now,
+	}
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+	r.value = value
+	r.modTimes = r.stat()
+	r.checked = r.now()
+	return r, nil
+}
+
+// get returns the current value, reloading it first if the files changed
+func (r *reloading[T]) get() T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if now.Sub(r.checked) < r.interval {
+		return r.value
+	}
+	r.checked = now
+
+	modTimes := r.stat()
+	if equalTimes(modTimes, r.modTimes) {
+		return r.value
+	}
+	value, err := r.load()
+	if err != nil {
+		log.Printf("Failed to reload %v, keeping the previous version: %v", r.files, err)
+		return r.value
+	}
+	log.Printf("Reloaded %v", r.files)
+	r.value = value
+	r.modTimes = modTimes
+	return r.value
+}
+
+// stat returns the modification times of the files, the zero time for missing files
+func (r *reloading[T]) stat() []time.Time {
+	modTimes := make([]time.Time, len(r.files))
+	for i, file := range r.files {
+		if info, err := os.Stat(file); err == nil {
+			modTimes[i] = info.ModTime()
+		}
+	}
+	return modTimes
+}
+
+func equalTimes(a, b []time.Time) bool {
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+// Package tlsconfig builds TLS configurations from certificate files and reloads the
+// files when they change, so that certificates can be rotated without a restart.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// DefaultReloadInterval is how often certificate files are checked for changes at most.
+// Files are checked during handshakes, so an idle connection does not cause reads.
+const DefaultReloadInterval = 10 * time.Second
+
+// Config describes the certificate files of a TLS server or client
+type Config struct {
+	Enabled bool `mapstructure:"enabled" yaml:"enabled"`
+	// CertFile and KeyFile are the PEM certificate (chain) and key presented to the peer.
+	// A server requires them; a client needs them only if the server requires client certificates.
+	CertFile string `mapstructure:"cert_file" yaml:"cert_file,omitempty"`
+	KeyFile  string `mapstructure:"key_file" yaml:"key_file,omitempty"`
+	// CAFile is the PEM bundle a client verifies the server with, defaults to the system roots.
+	// Unlike certificates, it is read only once.
+	CAFile string `mapstructure:"ca_file" yaml:"ca_file,omitempty"`
+	// ClientCAFile is the PEM bundle a server verifies client certificates with. When it is set,
+	// the server requires client certificates (mutual TLS).
+	ClientCAFile string `mapstructure:"client_ca_file" yaml:"client_ca_file,omitempty"`
+	// ServerName is the name a client verifies the server certificate for, defaults to the
+	// host it connects to
+	ServerName string `mapstructure:"server_name" yaml:"server_name,omitempty"`
+	// ReloadInterval is how often the files are checked for changes, defaults to DefaultReloadInterval
+	ReloadInterval time.Duration `mapstructure:"reload_interval" yaml:"reload_interval,omitempty"`
+}
+
+func (c Config) reloadInterval() time.Duration {
+	if c.ReloadInterval > 0 {
+		return c.ReloadInterval
+	}
+	return DefaultReloadInterval
+}
+
+// ServerConfig returns the TLS configuration of a server. Its certificate and client CAs
+// are reloaded when their files change.
+func (c Config) ServerConfig() (*tls.Config, error) {
+	if c.CertFile == "" || c.KeyFile == "" {
+		return nil, errors.New("cert_file and key_file are required")
+	}
+	cert, err := newReloading([]string{c.CertFile, c.KeyFile}, c.reloadInterval(), func() (*tls.Certificate, error) {
+		return loadKeyPair(c.CertFile, c.KeyFile)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	base := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return cert.get(), nil
+		},
+	}
+	if c.ClientCAFile == "" {
+		return base, nil
+	}
+
+	clientCAs, err := newReloading([]string{c.ClientCAFile}, c.reloadInterval(), func() (*x509.CertPool, error) {
+		return loadPool(c.ClientCAFile)
+	})
+	if err != nil {
+		return nil, err
+	}
+	base.ClientAuth = tls.RequireAndVerifyClientCert
+	// Every handshake gets the current client CAs
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		config := base.Clone()
+		config.GetConfigForClient = nil
+		config.ClientCAs = clientCAs.get()
+		return config, nil
+	}
+	return base, nil
+}
+
+// ClientConfig returns the TLS configuration of a client. Its certificate is reloaded when
+// its files change.
+func (c Config) ClientConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: c.ServerName,
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return nil, errors.New("cert_file and key_file must be set together")
+		}
+		cert, err := newReloading([]string{c.CertFile, c.KeyFile}, c.reloadInterval(), func() (*tls.Certificate, error) {
+			return loadKeyPair(c.CertFile, c.KeyFile)
+		})
+		if err != nil {
+			return nil, err
+		}
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return cert.get(), nil
+		}
+	}
+
+	if c.CAFile == "" {
+		return config, nil
+	}
+	// crypto/tls has no hook to replace the roots per handshake without taking over
+	// verification, so a client's CA bundle is read once
+	roots, err := loadPool(c.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	config.RootCAs = roots
+	return config, nil
+}
+
+func loadKeyPair(certFile, keyFile string) (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	return &cert, nil
+}
+
+func loadPool(file string) (*x509.CertPool, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(content) {
+		return nil, fmt.Errorf("no certificates found in %s", file)
+	}
+	return pool, nil
+}
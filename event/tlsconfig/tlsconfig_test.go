@@ -0,0 +1,207 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// authority issues certificates for tests
+type authority struct {
+	cert   *x509.Certificate
+	key    *ecdsa.PrivateKey
+	serial int64
+}
+
+func newAuthority(t *testing.T, dir, name string) *authority {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	writePEM(t, filepath.Join(dir, name+".pem"), "CERTIFICATE", der)
+	return &authority{cert: cert, key: key, serial: 1}
+}
+
+// issue writes a certificate and key for name to dir and returns the certificate's serial number
+func (a *authority) issue(t *testing.T, dir, name string, usage x509.ExtKeyUsage) int64 {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(a.serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, filepath.Join(dir, name+".pem"), "CERTIFICATE", der)
+	writePEM(t, filepath.Join(dir, name+"-key.pem"), "EC PRIVATE KEY", keyDER)
+	return a.serial
+}
+
+func writePEM(t *testing.T, file, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// Make every write visible as a change, even within the file system's timestamp resolution
+	later := time.Now().Add(time.Duration(time.Now().UnixNano()%1000) * time.Millisecond)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// handshake connects a client to a server over an in-memory connection and returns the
+// serial number of the server's certificate
+func handshake(server, client *tls.Config) (int64, error) {
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- tls.Server(serverConn, server).Handshake()
+	}()
+
+	conn := tls.Client(clientConn, client)
+	if err := conn.Handshake(); err != nil {
+		return 0, err
+	}
+	// With TLS 1.3, a rejected client certificate is reported by the server after the
+	// client finished, so the client keeps reading to receive the alert
+	go io.Copy(io.Discard, conn)
+	if err := <-serverErr; err != nil {
+		return 0, err
+	}
+	return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64(), nil
+}
+
+func TestServerAndClientConfig(t *testing.T) {
+	dir := t.TempDir()
+	ca := newAuthority(t, dir, "ca")
+	ca.issue(t, dir, "triggerd.internal", x509.ExtKeyUsageServerAuth)
+	ca.issue(t, dir, "client", x509.ExtKeyUsageClientAuth)
+	other := newAuthority(t, dir, "other-ca")
+	other.issue(t, dir, "rogue", x509.ExtKeyUsageClientAuth)
+
+	path := func(name string) string { return filepath.Join(dir, name) }
+	server, err := Config{
+		CertFile:     path("triggerd.internal.pem"),
+		KeyFile:      path("triggerd.internal-key.pem"),
+		ClientCAFile: path("ca.pem"),
+	}.ServerConfig()
+	if err != nil {
+		t.Fatalf("ServerConfig() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		client  Config
+		wantErr bool
+	}{
+		{"client certificate", Config{CAFile: path("ca.pem"), CertFile: path("client.pem"), KeyFile: path("client-key.pem"), ServerName: "triggerd.internal"}, false},
+		{"no client certificate", Config{CAFile: path("ca.pem"), ServerName: "triggerd.internal"}, true},
+		{"client certificate from another CA", Config{CAFile: path("ca.pem"), CertFile: path("rogue.pem"), KeyFile: path("rogue-key.pem"), ServerName: "triggerd.internal"}, true},
+		{"untrusted server", Config{CAFile: path("other-ca.pem"), CertFile: path("client.pem"), KeyFile: path("client-key.pem"), ServerName: "triggerd.internal"}, true},
+		{"wrong server name", Config{CAFile: path("ca.pem"), CertFile: path("client.pem"), KeyFile: path("client-key.pem"), ServerName: "evil.internal"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := tt.client.ClientConfig()
+			if err != nil {
+				t.Fatalf("ClientConfig() error = %v", err)
+			}
+			if _, err := handshake(server, client); (err != nil) != tt.wantErr {
+				t.Errorf("handshake() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServerConfig_Reload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newAuthority(t, dir, "ca")
+	first := ca.issue(t, dir, "triggerd.internal", x509.ExtKeyUsageServerAuth)
+
+	server, err := Config{
+		CertFile:       filepath.Join(dir, "triggerd.internal.pem"),
+		KeyFile:        filepath.Join(dir, "triggerd.internal-key.pem"),
+		ReloadInterval: time.Nanosecond,
+	}.ServerConfig()
+	if err != nil {
+		t.Fatalf("ServerConfig() error = %v", err)
+	}
+	client, err := Config{CAFile: filepath.Join(dir, "ca.pem"), ServerName: "triggerd.internal"}.ClientConfig()
+	if err != nil {
+		t.Fatalf("ClientConfig() error = %v", err)
+	}
+
+	if serial, err := handshake(server, client); err != nil || serial != first {
+		t.Fatalf("handshake() = %d, %v, want %d", serial, err, first)
+	}
+
+	// A broken file keeps the previous certificate
+	if err := os.WriteFile(filepath.Join(dir, "triggerd.internal.pem"), []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if serial, err := handshake(server, client); err != nil || serial != first {
+		t.Fatalf("handshake() after broken write = %d, %v, want %d", serial, err, first)
+	}
+
+	rotated := ca.issue(t, dir, "triggerd.internal", x509.ExtKeyUsageServerAuth)
+	if serial, err := handshake(server, client); err != nil || serial != rotated {
+		t.Errorf("handshake() after rotation = %d, %v, want %d", serial, err, rotated)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	if _, err := (Config{}).ServerConfig(); err == nil {
+		t.Error("ServerConfig() without certificate error = nil")
+	}
+	if _, err := (Config{CertFile: "cert.pem"}).ClientConfig(); err == nil {
+		t.Error("ClientConfig() with certificate but no key error = nil")
+	}
+	if _, err := (Config{CAFile: "missing.pem"}).ClientConfig(); err == nil {
+		t.Error("ClientConfig() with missing CA error = nil")
+	}
+}
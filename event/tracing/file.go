@@ -0,0 +1,113 @@
+package tracing
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// fileClient is an OTLP client that appends every export request to a file as a line of
+// OTLP JSON, the format the OpenTelemetry Collector's file exporter writes and its
+// otlpjsonfile receiver reads
+type fileClient struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+var _ otlptrace.Client = (*fileClient)(nil)
+
+func (c *fileClient) Start(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	file, err := os.OpenFile(c.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open trace file: %w", err)
+	}
+	c.file = file
+	return nil
+}
+
+func (c *fileClient) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.file == nil {
+		return nil
+	}
+	err := c.file.Close()
+	c.file = nil
+	return err
+}
+
+func (c *fileClient) UploadTraces(ctx context.Context, spans []*tracepb.ResourceSpans) error {
+	line, err := marshalOTLP(&coltracepb.ExportTraceServiceRequest{ResourceSpans: spans})
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return fmt.Errorf("trace file is closed")
+	}
+	_, err = c.file.Write(append(line, '\n'))
+	return err
+}
+
+// idFields are the fields that OTLP JSON encodes as hex rather than the base64 of protobuf JSON
+var idFields = map[string]bool{"traceId": true, "spanId": true, "parentSpanId": true}
+
+// marshalOTLP encodes an export request as a single line of OTLP JSON
+func marshalOTLP(request *coltracepb.ExportTraceServiceRequest) ([]byte, error) {
+	encoded, err := protojson.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode spans: %w", err)
+	}
+	var doc interface{}
+	if err := json.Unmarshal(encoded, &doc); err != nil {
+		return nil, fmt.Errorf("failed to encode spans: %w", err)
+	}
+	if err := hexIDs(doc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// hexIDs replaces the base64 trace and span IDs in a decoded JSON document with hex
+func hexIDs(node interface{}) error {
+	switch node := node.(type) {
+	case map[string]interface{}:
+		for key, value := range node {
+			if s, ok := value.(string); ok && idFields[key] {
+				raw, err := base64.StdEncoding.DecodeString(s)
+				if err != nil {
+					return fmt.Errorf("invalid %s %q: %w", key, s, err)
+				}
+				node[key] = hex.EncodeToString(raw)
+				continue
+			}
+			if err := hexIDs(value); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, value := range node {
+			if err := hexIDs(value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
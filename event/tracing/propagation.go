@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"context"
+	"hash/fnv"
+	"strings"
+
+	"event/data"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HeaderCarrier adapts the headers of a NATS message to a propagation.TextMapCarrier
+type HeaderCarrier nats.Header
+
+var _ propagation.TextMapCarrier = HeaderCarrier(nil)
+
+// Get returns the first value of a header
+func (c HeaderCarrier) Get(key string) string {
+	return nats.Header(c).Get(key)
+}
+
+// Set sets a header
+func (c HeaderCarrier) Set(key, value string) {
+	nats.Header(c).Set(key, value)
+}
+
+// Keys returns the names of the headers
+func (c HeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Inject adds the trace context of ctx to the headers of a message, for producers of events
+func Inject(ctx context.Context, msg *nats.Msg) {
+	if msg.Header == nil {
+		msg.Header = nats.Header{}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, HeaderCarrier(msg.Header))
+}
+
+// Extract returns ctx with the trace context the producer of an event sent, so that spans
+// started from it continue the producer's trace. The trace context is taken from the
+// message headers, or else from the event's context.trace_id, which may be a W3C
+// traceparent or a bare 32 hex digit trace ID.
+func Extract(ctx context.Context, header nats.Header, event *data.Event) context.Context {
+	propagator := otel.GetTextMapPropagator()
+	if header != nil {
+		ctx = propagator.Extract(ctx, HeaderCarrier(header))
+		if trace.SpanContextFromContext(ctx).IsValid() {
+			return ctx
+		}
+	}
+	if event == nil || event.Context.TraceID == "" {
+		return ctx
+	}
+
+	traceID := strings.TrimSpace(event.Context.TraceID)
+	if strings.Count(traceID, "-") == 3 {
+		return propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{"traceparent": traceID})
+	}
+	id, err := trace.TraceIDFromHex(strings.ToLower(traceID))
+	if err != nil {
+		return ctx
+	}
+	// A bare trace ID names no parent span, so the parent is derived from the event ID;
+	// the spans of the event are grouped into the producer's trace under that parent
+	return trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    id,
+		SpanID:     eventSpanID(event.ID),
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	}))
+}
+
+// eventSpanID derives a span ID from an event ID
+func eventSpanID(eventID string) trace.SpanID {
+	h := fnv.New64a()
+	h.Write([]byte(eventID))
+	var id trace.SpanID
+	copy(id[:], h.Sum(nil))
+	if !id.IsValid() {
+		id[len(id)-1] = 1
+	}
+	return id
+}
+
+// TraceID returns the trace ID of ctx as hex, or "" if ctx has no trace
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
@@ -0,0 +1,135 @@
+// Package tracing sets up OpenTelemetry tracing and carries trace context from the
+// producers of events, through NATS, to the actions of triggers.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"event/data"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Exporters that Config.Exporter selects
+const (
+	// ExporterNone does not export spans, but trace context is still propagated
+	ExporterNone = "none"
+	// ExporterStdout writes spans as indented JSON to stdout, or to Config.File
+	ExporterStdout = "stdout"
+	// ExporterFile appends spans to Config.File as OTLP JSON, one export request per line
+	ExporterFile = "file"
+	// ExporterOTLP sends spans to an OTLP collector over gRPC
+	ExporterOTLP = "otlp"
+)
+
+// Config selects where spans are exported to
+type Config struct {
+	Exporter string `mapstructure:"exporter" yaml:"exporter"`
+	// File is the file of the file exporter, and optionally of the stdout exporter
+	File string `mapstructure:"file" yaml:"file,omitempty"`
+	// Endpoint is the host:port of the OTLP collector
+	Endpoint string `mapstructure:"endpoint" yaml:"endpoint,omitempty"`
+	// Insecure connects to the OTLP collector without TLS
+	Insecure bool `mapstructure:"insecure" yaml:"insecure,omitempty"`
+	// SampleRatio is the fraction of new traces that are recorded, defaults to all. Traces
+	// continued from a producer follow the producer's sampling decision.
+	SampleRatio float64 `mapstructure:"sample_ratio" yaml:"sample_ratio,omitempty"`
+}
+
+// Setup installs the global tracer provider and the W3C trace context propagator, and
+// returns a function that flushes the remaining spans and stops the exporter
+func Setup(ctx context.Context, serviceName string, config Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	exporter, err := newExporter(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+	ratio := config.SampleRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, config Config) (sdktrace.SpanExporter, error) {
+	switch config.Exporter {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterStdout:
+		if config.File == "" {
+			return stdouttrace.New(stdouttrace.WithPrettyPrint())
+		}
+		file, err := os.OpenFile(config.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		return stdouttrace.New(stdouttrace.WithWriter(file))
+	case ExporterFile:
+		if config.File == "" {
+			return nil, fmt.Errorf("the file exporter requires a file")
+		}
+		return otlptrace.New(ctx, &fileClient{path: config.File})
+	case ExporterOTLP:
+		options := []otlptracegrpc.Option{}
+		if config.Endpoint != "" {
+			options = append(options, otlptracegrpc.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			options = append(options, otlptracegrpc.WithInsecure())
+		}
+		exporter, err := otlptracegrpc.New(ctx, options...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		return exporter, nil
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", config.Exporter)
+	}
+}
+
+// EventAttributes returns the span attributes that identify an event
+func EventAttributes(event *data.Event) []attribute.KeyValue {
+	attributes := []attribute.KeyValue{
+		attribute.String("event.id", event.ID),
+		attribute.String("event.type", event.EventType),
+		attribute.String("event.namespace", event.Namespace),
+		attribute.String("event.object_type", event.ObjectType),
+		attribute.String("event.object_id", event.ObjectID),
+	}
+	if event.Context.RequestID != "" {
+		attributes = append(attributes, attribute.String("event.request_id", event.Context.RequestID))
+	}
+	return attributes
+}
+
+// TriggerAttributes returns the span attributes that identify a trigger
+func TriggerAttributes(trigger *data.Trigger) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("trigger.namespace", trigger.Namespace),
+		attribute.String("trigger.id", trigger.ID),
+	}
+}
@@ -0,0 +1,174 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"event/data"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestExtract(t *testing.T) {
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+
+	const (
+		headerTrace = "4bf92f3577b34da6a3ce929d0e0e4736"
+		eventTrace  = "0af7651916cd43dd8448eb211c80319c"
+	)
+	tests := []struct {
+		name      string
+		header    nats.Header
+		traceID   string
+		wantTrace string
+		wantSpan  string
+	}{
+		{
+			name:      "header",
+			header:    nats.Header{"traceparent": {"00-" + headerTrace + "-00f067aa0ba902b7-01"}},
+			traceID:   eventTrace,
+			wantTrace: headerTrace,
+			wantSpan:  "00f067aa0ba902b7",
+		},
+		{
+			name:      "traceparent in the event context",
+			traceID:   "00-" + eventTrace + "-b7ad6b7169203331-01",
+			wantTrace: eventTrace,
+			wantSpan:  "b7ad6b7169203331",
+		},
+		{
+			name:      "trace ID in the event context",
+			header:    nats.Header{"other": {"value"}},
+			traceID:   "0AF7651916CD43DD8448EB211C80319C",
+			wantTrace: eventTrace,
+		},
+		{
+			name:    "invalid trace ID",
+			traceID: "req-42",
+		},
+		{
+			name: "no trace",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &data.Event{ID: "evt1"}
+			event.Context.TraceID = tt.traceID
+
+			sc := trace.SpanContextFromContext(Extract(context.Background(), tt.header, event))
+			if tt.wantTrace == "" {
+				if sc.IsValid() {
+					t.Errorf("Extract() span context = %v, want none", sc)
+				}
+				return
+			}
+			if !sc.IsValid() || !sc.IsRemote() {
+				t.Fatalf("Extract() span context = %v, want a valid remote one", sc)
+			}
+			if got := sc.TraceID().String(); got != tt.wantTrace {
+				t.Errorf("trace ID = %s, want %s", got, tt.wantTrace)
+			}
+			if tt.wantSpan != "" && sc.SpanID().String() != tt.wantSpan {
+				t.Errorf("span ID = %s, want %s", sc.SpanID(), tt.wantSpan)
+			}
+		})
+	}
+}
+
+func TestInject(t *testing.T) {
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	msg := nats.NewMsg("event.sales")
+	Inject(ctx, msg)
+	sc := trace.SpanContextFromContext(Extract(context.Background(), msg.Header, nil))
+	if sc.TraceID() != traceID || sc.SpanID() != spanID {
+		t.Errorf("Extract(Inject()) = %v, want trace %s span %s", sc, traceID, spanID)
+	}
+}
+
+func TestFileExporter(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "traces.jsonl")
+	exporter, err := otlptrace.New(context.Background(), &fileClient{path: file})
+	if err != nil {
+		t.Fatalf("otlptrace.New() error = %v", err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "process event")
+	_, child := provider.Tracer("test").Start(ctx, "decode")
+	child.End()
+	parent.End()
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	type span struct {
+		TraceID      string `json:"traceId"`
+		SpanID       string `json:"spanId"`
+		ParentSpanID string `json:"parentSpanId"`
+		Name         string `json:"name"`
+	}
+	spans := map[string]span{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var request struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []span `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			t.Fatalf("line %q is not JSON: %v", scanner.Text(), err)
+		}
+		for _, rs := range request.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, s := range ss.Spans {
+					spans[s.Name] = s
+				}
+			}
+		}
+	}
+
+	want := parent.SpanContext()
+	if got := spans["process event"]; got.TraceID != want.TraceID().String() || got.SpanID != want.SpanID().String() {
+		t.Errorf("process event span = %+v, want trace %s span %s", got, want.TraceID(), want.SpanID())
+	}
+	if got := spans["decode"]; got.ParentSpanID != want.SpanID().String() {
+		t.Errorf("decode span parent = %q, want %s", got.ParentSpanID, want.SpanID())
+	}
+}
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	if _, err := Setup(context.Background(), "test", Config{Exporter: "zipkin"}); err == nil {
+		t.Error("Setup() with unknown exporter error = nil")
+	}
+}
@@ -5,11 +5,14 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"os"
 	"time"
 
 	pb "event/api/proto"
+	"event/tlsconfig"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
@@ -17,7 +20,13 @@ func main() {
 	// Parse command line flags
 	var (
 		serverAddr = flag.String("server", "localhost:50051", "The server address in the format host:port")
-		command    = flag.String("cmd", "list", "Command to execute: list, add, update, remove")
+		token      = flag.String("token", os.Getenv("TRIGGERD_TOKEN"), "Bearer token or API key, defaults to $TRIGGERD_TOKEN")
+		useTLS     = flag.Bool("tls", false, "Connect over TLS, verifying the server with the system roots or --ca")
+		caFile     = flag.String("ca", "", "PEM bundle to verify the server certificate with (implies --tls)")
+		certFile   = flag.String("cert", "", "PEM client certificate for servers that require mutual TLS (implies --tls)")
+		keyFile    = flag.String("key", "", "PEM key of the client certificate")
+		serverName = flag.String("server-name", "", "Name to verify the server certificate for, defaults to the server host")
+		command    = flag.String("cmd", "list", "Command to execute: list, add, update, remove, dryrun, stats, namespaces, namespace, create-namespace, delete-namespace")
 		namespace  = flag.String("namespace", "sales", "Namespace for triggers")
 		id         = flag.String("id", "", "Trigger ID (required for update and remove)")
 		name       = flag.String("name", "", "Trigger name (required for add and update)")
@@ -29,12 +38,18 @@ func main() {
 		field2     = flag.String("field2", "payload.after.region", "Second condition field")
 		op2        = flag.String("op2", "eq", "Second condition operator")
 		value2     = flag.String("value2", "US", "Second condition value")
+		eventFile  = flag.String("event", "", "Path to a JSON sample event (required for dryrun)")
+		owner      = flag.String("owner", "", "Owner of the namespace (for create-namespace)")
+		maxTrig    = flag.Int("max-triggers", 0, "Maximum number of triggers (for create-namespace)")
+		maxCrit    = flag.Int("max-criteria-length", 0, "Maximum criteria length (for create-namespace)")
+		maxRate    = flag.Int("max-actions-per-minute", 0, "Maximum actions per minute (for create-namespace)")
+		deleteAll  = flag.Bool("delete-triggers", false, "Delete the namespace's triggers too (for delete-namespace)")
 	)
 
 	flag.Parse()
 
 	// Set up a connection to the server
-	conn, err := grpc.Dial(*serverAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(*serverAddr, grpc.WithTransportCredentials(transportCredentials(*useTLS, *caFile, *certFile, *keyFile, *serverName)), grpc.WithPerRPCCredentials(bearerToken(*token)))
 	if err != nil {
 		log.Fatalf("Failed to connect: %v", err)
 	}
@@ -65,6 +80,32 @@ func main() {
 			log.Fatal("Trigger ID is required for remove command")
 		}
 		removeTrigger(ctx, client, *namespace, *id)
+	case "dryrun":
+		if *id == "" || *eventFile == "" {
+			log.Fatal("Trigger ID and event file are required for dryrun command")
+		}
+		dryRunTrigger(ctx, client, *namespace, *id, *eventFile)
+	case "stats":
+		if *id == "" {
+			log.Fatal("Trigger ID is required for stats command")
+		}
+		triggerStats(ctx, client, *namespace, *id)
+	case "namespaces":
+		listNamespaces(ctx, client)
+	case "namespace":
+		getNamespace(ctx, client, *namespace)
+	case "create-namespace":
+		createNamespace(ctx, client, &pb.Namespace{
+			Name:  *namespace,
+			Owner: *owner,
+			Quota: &pb.NamespaceQuota{
+				MaxTriggers:         int32(*maxTrig),
+				MaxCriteriaLength:   int32(*maxCrit),
+				MaxActionsPerMinute: int32(*maxRate),
+			},
+		})
+	case "delete-namespace":
+		deleteNamespace(ctx, client, *namespace, *deleteAll)
 	default:
 		log.Fatalf("Unknown command: %s", *command)
 	}
@@ -92,6 +133,13 @@ func listTriggers(ctx context.Context, client pb.TriggerServiceClient, namespace
 		fmt.Printf("   Event Type: %s\n", trigger.EventType)
 		fmt.Printf("   Enabled: %v\n", trigger.Enabled)
 		fmt.Printf("   Criteria: %s\n", trigger.Criteria)
+		if trigger.Schedule != nil {
+			fmt.Printf("   Schedule: %s %s\n", trigger.Schedule.Cron, trigger.Schedule.Timezone)
+		}
+		if t := trigger.Throttle; t != nil {
+			fmt.Printf("   Throttle: max_fires=%d interval=%s dedup_key=%q dedup_window=%s cooldown=%s\n",
+				t.MaxFires, t.Interval, t.DedupKey, t.DedupWindow, t.Cooldown)
+		}
 		fmt.Println()
 	}
 }
@@ -146,6 +194,112 @@ func removeTrigger(ctx context.Context, client pb.TriggerServiceClient, namespac
 	}
 }
 
+// dryRunTrigger evaluates a saved trigger against a sample event and prints the rendered actions
+func dryRunTrigger(ctx context.Context, client pb.TriggerServiceClient, namespace, id, eventFile string) {
+	event, err := os.ReadFile(eventFile)
+	if err != nil {
+		log.Fatalf("Failed to read event: %v", err)
+	}
+
+	resp, err := client.DryRunTrigger(ctx, &pb.DryRunTriggerRequest{
+		Namespace: namespace,
+		TriggerId: id,
+		Event:     string(event),
+	})
+	if err != nil {
+		log.Fatalf("Failed to dry-run trigger: %v", err)
+	}
+
+	fmt.Printf("Matched: %v\n", resp.Matched)
+	for i, a := range resp.Actions {
+		fmt.Printf("Action %d (%s):\n", i, a.Type)
+		if a.Error != "" {
+			fmt.Printf("  Error: %s\n", a.Error)
+			continue
+		}
+		fmt.Printf("  Target: %s %s\n", a.Method, a.Target)
+		for key, value := range a.Headers {
+			fmt.Printf("  %s: %s\n", key, value)
+		}
+		fmt.Printf("  Body: %s\n", a.Body)
+	}
+}
+
+// triggerStats prints how often a trigger fired and how often its throttle suppressed it
+func triggerStats(ctx context.Context, client pb.TriggerServiceClient, namespace, id string) {
+	resp, err := client.GetTriggerStats(ctx, &pb.GetTriggerStatsRequest{
+		Namespace: namespace,
+		TriggerId: id,
+	})
+	if err != nil {
+		log.Fatalf("Failed to get trigger stats: %v", err)
+	}
+
+	stats := resp.Stats
+	fmt.Printf("Fired: %d\n", stats.Fired)
+	fmt.Printf("Suppressed: rate_limit=%d dedup=%d cooldown=%d\n", stats.RateLimited, stats.Deduplicated, stats.CoolingDown)
+	if stats.LastFiredAt != nil {
+		fmt.Printf("Last fired: %s\n", stats.LastFiredAt.AsTime().Format(time.RFC3339))
+	}
+	if stats.LastSuppressedAt != nil {
+		fmt.Printf("Last suppressed: %s\n", stats.LastSuppressedAt.AsTime().Format(time.RFC3339))
+	}
+}
+
+// listNamespaces lists the created namespaces and the namespaces that have triggers
+func listNamespaces(ctx context.Context, client pb.TriggerServiceClient) {
+	resp, err := client.ListNamespaces(ctx, &pb.ListNamespacesRequest{})
+	if err != nil {
+		log.Fatalf("Failed to list namespaces: %v", err)
+	}
+	if len(resp.Namespaces) == 0 {
+		fmt.Println("No namespaces found")
+		return
+	}
+	for _, namespace := range resp.Namespaces {
+		printNamespace(namespace)
+	}
+}
+
+// getNamespace prints a namespace
+func getNamespace(ctx context.Context, client pb.TriggerServiceClient, name string) {
+	resp, err := client.GetNamespace(ctx, &pb.GetNamespaceRequest{Namespace: name})
+	if err != nil {
+		log.Fatalf("Failed to get namespace: %v", err)
+	}
+	printNamespace(resp.Namespace)
+}
+
+// createNamespace creates a namespace
+func createNamespace(ctx context.Context, client pb.TriggerServiceClient, namespace *pb.Namespace) {
+	resp, err := client.CreateNamespace(ctx, &pb.CreateNamespaceRequest{Namespace: namespace})
+	if err != nil {
+		log.Fatalf("Failed to create namespace: %v", err)
+	}
+	fmt.Println("Namespace created successfully:")
+	printNamespace(resp.Namespace)
+}
+
+// deleteNamespace deletes a namespace
+func deleteNamespace(ctx context.Context, client pb.TriggerServiceClient, name string, deleteTriggers bool) {
+	resp, err := client.DeleteNamespace(ctx, &pb.DeleteNamespaceRequest{Namespace: name, DeleteTriggers: deleteTriggers})
+	if err != nil {
+		log.Fatalf("Failed to delete namespace: %v", err)
+	}
+	fmt.Printf("Namespace '%s' deleted with %d triggers\n", name, resp.DeletedTriggers)
+}
+
+func printNamespace(namespace *pb.Namespace) {
+	fmt.Printf("%s (%d triggers)\n", namespace.Name, namespace.TriggerCount)
+	if namespace.Owner != "" {
+		fmt.Printf("   Owner: %s\n", namespace.Owner)
+	}
+	if q := namespace.EffectiveQuota; q != nil {
+		fmt.Printf("   Quota: max_triggers=%d max_criteria_length=%d max_actions_per_minute=%d\n",
+			q.MaxTriggers, q.MaxCriteriaLength, q.MaxActionsPerMinute)
+	}
+}
+
 // createTrigger creates a trigger with the specified parameters
 func createTrigger(namespace, id, name, objectType, eventType, field1, op1, value1, field2, op2, value2 string) *pb.Trigger {
 	// Create criteria expression
@@ -183,3 +337,35 @@ func convertOperator(op string) string {
 		return op
 	}
 }
+
+// bearerToken sends a bearer token with every RPC
+type bearerToken string
+
+func (t bearerToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	if t == "" {
+		return nil, nil
+	}
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity allows tokens on plaintext connections, e.g. to a local triggerd
+func (bearerToken) RequireTransportSecurity() bool {
+	return false
+}
+
+// transportCredentials returns TLS credentials if any TLS flag is set, and plaintext ones otherwise
+func transportCredentials(useTLS bool, caFile, certFile, keyFile, serverName string) credentials.TransportCredentials {
+	if !useTLS && caFile == "" && certFile == "" {
+		return insecure.NewCredentials()
+	}
+	config, err := tlsconfig.Config{
+		CAFile:     caFile,
+		CertFile:   certFile,
+		KeyFile:    keyFile,
+		ServerName: serverName,
+	}.ClientConfig()
+	if err != nil {
+		log.Fatalf("Invalid TLS flags: %v", err)
+	}
+	return credentials.NewTLS(config)
+}
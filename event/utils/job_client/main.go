@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	pb "event/api/proto"
+	"event/tlsconfig"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func main() {
+	// Parse command line flags
+	var (
+		serverAddr  = flag.String("server", "localhost:50051", "The server address in the format host:port")
+		token       = flag.String("token", os.Getenv("TRIGGERD_TOKEN"), "Bearer token or API key, defaults to $TRIGGERD_TOKEN")
+		useTLS      = flag.Bool("tls", false, "Connect over TLS, verifying the server with the system roots or --ca")
+		caFile      = flag.String("ca", "", "PEM bundle to verify the server certificate with (implies --tls)")
+		certFile    = flag.String("cert", "", "PEM client certificate for servers that require mutual TLS (implies --tls)")
+		keyFile     = flag.String("key", "", "PEM key of the client certificate")
+		serverName  = flag.String("server-name", "", "Name to verify the server certificate for, defaults to the server host")
+		command     = flag.String("cmd", "list", "Command to execute: submit, get, list, cancel, retry, watch")
+		namespace   = flag.String("namespace", "default", "Namespace of the jobs")
+		id          = flag.String("id", "", "Job ID (required for get, cancel, retry and watch)")
+		jobType     = flag.String("type", "", "Job type (required for submit, optional filter for list)")
+		inputs      = flag.String("inputs", "{}", "JSON inputs of the job (for submit)")
+		dependsOn   = flag.String("depends-on", "", "Comma-separated IDs of the jobs this job depends on (for submit)")
+		maxRetries  = flag.Int("max-retries", 0, "Times an expired lease is re-queued (for submit)")
+		statuses    = flag.String("status", "", "Comma-separated statuses to list")
+		triggeredBy = flag.String("triggered-by", "", "Event ID that triggered the jobs to list")
+		reason      = flag.String("reason", "", "Reason for cancelling the job")
+	)
+
+	flag.Parse()
+
+	// Set up a connection to the server
+	conn, err := grpc.Dial(*serverAddr, grpc.WithTransportCredentials(transportCredentials(*useTLS, *caFile, *certFile, *keyFile, *serverName)), grpc.WithPerRPCCredentials(bearerToken(*token)))
+	if err != nil {
+		log.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	client := pb.NewJobServiceClient(conn)
+
+	// Watching runs until the job finishes or the command is interrupted
+	if *command == "watch" {
+		if *id == "" {
+			log.Fatal("Job ID is required for watch command")
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		watchJob(ctx, client, *namespace, *id)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	switch *command {
+	case "submit":
+		if *jobType == "" {
+			log.Fatal("Job type is required for submit command")
+		}
+		submitJob(ctx, client, *namespace, *id, *jobType, *inputs, splitList(*dependsOn), *maxRetries)
+	case "get":
+		if *id == "" {
+			log.Fatal("Job ID is required for get command")
+		}
+		resp, err := client.GetJob(ctx, &pb.GetJobRequest{Namespace: *namespace, JobId: *id})
+		if err != nil {
+			log.Fatalf("Failed to get job: %v", err)
+		}
+		printJob(resp.Job)
+	case "list":
+		listJobs(ctx, client, *namespace, splitList(*statuses), *jobType, *triggeredBy)
+	case "cancel":
+		if *id == "" {
+			log.Fatal("Job ID is required for cancel command")
+		}
+		resp, err := client.CancelJob(ctx, &pb.CancelJobRequest{Namespace: *namespace, JobId: *id, Reason: *reason})
+		if err != nil {
+			log.Fatalf("Failed to cancel job: %v", err)
+		}
+		printJob(resp.Job)
+	case "retry":
+		if *id == "" {
+			log.Fatal("Job ID is required for retry command")
+		}
+		resp, err := client.RetryJob(ctx, &pb.RetryJobRequest{Namespace: *namespace, JobId: *id})
+		if err != nil {
+			log.Fatalf("Failed to retry job: %v", err)
+		}
+		printJob(resp.Job)
+	default:
+		log.Fatalf("Unknown command: %s", *command)
+	}
+}
+
+// submitJob submits a job with JSON inputs
+func submitJob(ctx context.Context, client pb.JobServiceClient, namespace, id, jobType, inputsJSON string, dependsOn []string, maxRetries int) {
+	var inputs map[string]interface{}
+	if err := json.Unmarshal([]byte(inputsJSON), &inputs); err != nil {
+		log.Fatalf("Invalid inputs: %v", err)
+	}
+	pbInputs, err := structpb.NewStruct(inputs)
+	if err != nil {
+		log.Fatalf("Invalid inputs: %v", err)
+	}
+
+	resp, err := client.SubmitJob(ctx, &pb.SubmitJobRequest{
+		Job: &pb.Job{
+			JobId:      id,
+			JobType:    jobType,
+			Namespace:  namespace,
+			Inputs:     pbInputs,
+			DependsOn:  dependsOn,
+			MaxRetries: int32(maxRetries),
+		},
+	})
+	if err != nil {
+		log.Fatalf("Failed to submit job: %v", err)
+	}
+
+	fmt.Println("Submitted job:")
+	printJob(resp.Job)
+}
+
+// listJobs lists the jobs of a namespace
+func listJobs(ctx context.Context, client pb.JobServiceClient, namespace string, statuses []string, jobType, triggeredBy string) {
+	resp, err := client.ListJobs(ctx, &pb.ListJobsRequest{
+		Namespace:   namespace,
+		Statuses:    statuses,
+		JobType:     jobType,
+		TriggeredBy: triggeredBy,
+	})
+	if err != nil {
+		log.Fatalf("Failed to list jobs: %v", err)
+	}
+
+	fmt.Printf("Found %d jobs in namespace %s:\n", len(resp.Jobs), namespace)
+	for i, job := range resp.Jobs {
+		fmt.Printf("%d. %s (%s) %s", i+1, job.JobId, job.JobType, job.Status)
+		if job.Status == "started" {
+			fmt.Printf(" %d%%", job.Progress)
+		}
+		fmt.Println()
+	}
+}
+
+// watchJob prints every change of a job until it finishes
+func watchJob(ctx context.Context, client pb.JobServiceClient, namespace, id string) {
+	stream, err := client.WatchJob(ctx, &pb.WatchJobRequest{Namespace: namespace, JobId: id})
+	if err != nil {
+		log.Fatalf("Failed to watch job: %v", err)
+	}
+
+	for {
+		job, err := stream.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			log.Fatalf("Watch failed: %v", err)
+		}
+		fmt.Printf("[%s] %s %s %d%%\n", time.Now().Format(time.TimeOnly), job.JobId, job.Status, job.Progress)
+	}
+}
+
+// printJob prints the details of a job
+func printJob(job *pb.Job) {
+	fmt.Printf("ID: %s\n", job.JobId)
+	fmt.Printf("Type: %s\n", job.JobType)
+	fmt.Printf("Namespace: %s\n", job.Namespace)
+	fmt.Printf("Status: %s\n", job.Status)
+	if len(job.DependsOn) > 0 {
+		fmt.Printf("Depends on: %s\n", strings.Join(job.DependsOn, ", "))
+	}
+	if job.TriggeredBy != "" {
+		fmt.Printf("Triggered by: %s\n", job.TriggeredBy)
+	}
+	if job.Result != nil {
+		result, _ := json.Marshal(job.Result.AsMap())
+		fmt.Printf("Result: %s\n", result)
+	}
+	if job.Error != nil {
+		fmt.Printf("Error: %s: %s\n", job.Error.Code, job.Error.Message)
+	}
+}
+
+// splitList splits a comma-separated flag value, ignoring empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// bearerToken sends a bearer token with every RPC
+type bearerToken string
+
+func (t bearerToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	if t == "" {
+		return nil, nil
+	}
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity allows tokens on plaintext connections, e.g. to a local triggerd
+func (bearerToken) RequireTransportSecurity() bool {
+	return false
+}
+
+// transportCredentials returns TLS credentials if any TLS flag is set, and plaintext ones otherwise
+func transportCredentials(useTLS bool, caFile, certFile, keyFile, serverName string) credentials.TransportCredentials {
+	if !useTLS && caFile == "" && certFile == "" {
+		return insecure.NewCredentials()
+	}
+	config, err := tlsconfig.Config{
+		CAFile:     caFile,
+		CertFile:   certFile,
+		KeyFile:    keyFile,
+		ServerName: serverName,
+	}.ClientConfig()
+	if err != nil {
+		log.Fatalf("Invalid TLS flags: %v", err)
+	}
+	return credentials.NewTLS(config)
+}
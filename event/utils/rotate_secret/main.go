@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"event/handlers/secrets"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// This utility rotates or revokes webhook signing secrets stored in etcd
+
+func main() {
+	// Parse command line flags
+	var (
+		namespace = flag.String("namespace", "sales", "Namespace of the secret")
+		triggerID = flag.String("trigger", "", "Trigger ID (empty for the namespace secret)")
+		secret    = flag.String("secret", "", "New secret (generated when empty)")
+		revoke    = flag.Bool("revoke", false, "Revoke the previous secret instead of rotating")
+		prefix    = flag.String("prefix", secrets.DefaultSecretPrefix, "etcd key prefix for secrets")
+	)
+
+	flag.Parse()
+
+	// Connect to etcd
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{"localhost:2379"},
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		log.Fatalf("Failed to connect to etcd: %v", err)
+	}
+	defer client.Close()
+
+	store := secrets.NewEtcdStore(client, *prefix)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if *revoke {
+		if err := store.Revoke(ctx, *namespace, *triggerID); err != nil {
+			log.Fatalf("Failed to revoke secret: %v", err)
+		}
+		fmt.Println("Previous secret revoked")
+		return
+	}
+
+	if *secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			log.Fatalf("Failed to generate secret: %v", err)
+		}
+		*secret = hex.EncodeToString(buf)
+	}
+
+	if err := store.Rotate(ctx, *namespace, *triggerID, *secret); err != nil {
+		log.Fatalf("Failed to rotate secret: %v", err)
+	}
+
+	fmt.Printf("New signing secret: %s\n", *secret)
+	fmt.Println("The previous secret stays active until it is revoked with --revoke")
+}
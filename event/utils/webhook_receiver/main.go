@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"event/data"
+	"event/signature"
+)
+
+// This utility runs a local webhook receiver that verifies trigger signatures.
+// Point a trigger's action_url at http://localhost:8090/hook to try it out.
+
+func main() {
+	// Parse command line flags
+	var (
+		addr    = flag.String("addr", ":8090", "Address to listen on")
+		secrets = flag.String("secrets", "", "Comma-separated signing secrets to accept (empty accepts unsigned requests)")
+	)
+
+	flag.Parse()
+
+	var accepted []string
+	if *secrets != "" {
+		accepted = strings.Split(*secrets, ",")
+	}
+
+	http.HandleFunc("/hook", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+
+		if len(accepted) > 0 {
+			if err := signature.VerifyRequest(r, body, accepted...); err != nil {
+				log.Printf("Rejected delivery: %v", err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+
+		var event data.Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			log.Printf("Received non-event payload: %s", string(body))
+		} else {
+			log.Printf("Received event %s (%s) for trigger %s", event.ID, event.EventType, r.Header.Get("X-Trigger-ID"))
+		}
+
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, "ok")
+	})
+
+	log.Printf("Webhook receiver listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
@@ -0,0 +1,160 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	pb "event/api/proto"
+	"event/data"
+	"event/handlers/jobs"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// GRPCBackend is a Backend that talks to a remote JobService
+type GRPCBackend struct {
+	client pb.JobServiceClient
+}
+
+var _ Backend = (*GRPCBackend)(nil)
+
+// NewGRPCBackend creates a Backend calling the JobService on conn. The connection's
+// credentials need the editor role in the namespaces the worker claims jobs from.
+func NewGRPCBackend(conn grpc.ClientConnInterface) *GRPCBackend {
+	return &GRPCBackend{
+		client: pb.NewJobServiceClient(conn),
+	}
+}
+
+// Claim leases the oldest started job of the requested type, returning jobs.ErrNoJobs when none is waiting
+func (b *GRPCBackend) Claim(ctx context.Context, req jobs.ClaimRequest) (*data.Job, error) {
+	resp, err := b.client.ClaimJob(ctx, &pb.ClaimJobRequest{
+		JobType:    req.JobType,
+		Namespace:  req.Namespace,
+		WorkerId:   req.WorkerID,
+		LeaseTtlMs: req.TTL.Milliseconds(),
+	})
+	if err != nil {
+		return nil, leaseError(err)
+	}
+	if resp.Job == nil {
+		return nil, jobs.ErrNoJobs
+	}
+	return fromPbJob(resp.Job), nil
+}
+
+// Heartbeat extends the lease of a claimed job and records its progress
+func (b *GRPCBackend) Heartbeat(ctx context.Context, namespace, id, workerID string, progress int, ttl time.Duration) (*data.Job, error) {
+	resp, err := b.client.HeartbeatJob(ctx, &pb.HeartbeatJobRequest{
+		Namespace:  namespace,
+		JobId:      id,
+		WorkerId:   workerID,
+		Progress:   int32(progress),
+		LeaseTtlMs: ttl.Milliseconds(),
+	})
+	if err != nil {
+		return nil, leaseError(err)
+	}
+	return fromPbJob(resp.Job), nil
+}
+
+// ReportResult completes a claimed job with its result, or fails it with jobErr
+func (b *GRPCBackend) ReportResult(ctx context.Context, namespace, id, workerID string, result map[string]interface{}, jobErr *data.JobError) (*data.Job, error) {
+	req := &pb.ReportJobResultRequest{
+		Namespace: namespace,
+		JobId:     id,
+		WorkerId:  workerID,
+	}
+	var err error
+	if result != nil {
+		if req.Result, err = structpb.NewStruct(result); err != nil {
+			return nil, err
+		}
+	}
+	if jobErr != nil {
+		req.Error = &pb.JobError{Code: jobErr.Code, Message: jobErr.Message}
+		if jobErr.Details != nil {
+			if req.Error.Details, err = structpb.NewStruct(jobErr.Details); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	resp, err := b.client.ReportJobResult(ctx, req)
+	if err != nil {
+		return nil, leaseError(err)
+	}
+	return fromPbJob(resp.Job), nil
+}
+
+// leaseError turns the ABORTED errors of the JobService back into jobs.ErrCanceled and jobs.ErrLeaseLost
+func leaseError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.Aborted {
+		return err
+	}
+	for _, detail := range st.Details() {
+		info, ok := detail.(*errdetails.ErrorInfo)
+		if !ok {
+			continue
+		}
+		switch info.Reason {
+		case jobs.ReasonCanceled:
+			return jobs.ErrCanceled
+		case jobs.ReasonLeaseLost:
+			return jobs.ErrLeaseLost
+		}
+	}
+	return err
+}
+
+// fromPbJob converts a job returned by the JobService
+func fromPbJob(j *pb.Job) *data.Job {
+	job := &data.Job{
+		JobID:          j.JobId,
+		JobType:        j.JobType,
+		Namespace:      j.Namespace,
+		Status:         j.Status,
+		Retries:        int(j.Retries),
+		MaxRetries:     int(j.MaxRetries),
+		DependsOn:      j.DependsOn,
+		TriggeredBy:    j.TriggeredBy,
+		LeaseOwner:     j.LeaseOwner,
+		Progress:       int(j.Progress),
+		CreatedAt:      fromTimestamp(j.CreatedAt),
+		StartedAt:      fromTimestamp(j.StartedAt),
+		CompletedAt:    fromTimestamp(j.CompletedAt),
+		UpdatedAt:      fromTimestamp(j.UpdatedAt),
+		LeaseExpiresAt: fromTimestamp(j.LeaseExpiresAt),
+	}
+	if j.Inputs != nil {
+		job.Inputs = j.Inputs.AsMap()
+	}
+	if j.Result != nil {
+		job.Result = j.Result.AsMap()
+	}
+	if j.Error != nil {
+		job.Error = &data.JobError{
+			Code:      j.Error.Code,
+			Message:   j.Error.Message,
+			Timestamp: fromTimestamp(j.Error.Timestamp),
+		}
+		if j.Error.Details != nil {
+			job.Error.Details = j.Error.Details.AsMap()
+		}
+	}
+	return job
+}
+
+// fromTimestamp converts a Timestamp to a time, returning the zero time for nil
+func fromTimestamp(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
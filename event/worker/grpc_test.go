@@ -0,0 +1,141 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	pb "event/api/proto"
+	"event/api/server"
+	"event/data"
+	"event/handlers/jobs"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newGRPCBackend serves a JobService for o over an in-memory connection and returns a backend calling it
+func newGRPCBackend(t *testing.T, o *jobs.Orchestrator) *GRPCBackend {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	pb.RegisterJobServiceServer(grpcServer, server.NewJobServer(o))
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewGRPCBackend(conn)
+}
+
+func TestGRPCBackend_CompletesJob(t *testing.T) {
+	ctx := context.Background()
+	o := jobs.NewOrchestrator(jobs.NewMemoryStore(), nil)
+	backend := newGRPCBackend(t, o)
+	if _, err := o.Submit(ctx, &data.Job{JobID: "a", JobType: "resize_image", Namespace: "media", Inputs: map[string]interface{}{"size": "800x600"}}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	w := NewWorker(backend, "resize_image", func(ctx context.Context, job *Job) (map[string]interface{}, error) {
+		job.SetProgress(50)
+		// Wait for the progress heartbeat to be stored
+		for i := 0; i < 100; i++ {
+			stored, _ := o.Store().Get(ctx, job.Namespace, job.JobID)
+			if stored.Progress == 50 {
+				return map[string]interface{}{"output": "a-" + job.Inputs["size"].(string) + ".png"}, nil
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		return nil, NewError("NO_PROGRESS", "progress heartbeat was not stored", nil)
+	}, WithWorkerID("w1"), WithPollInterval(10*time.Millisecond))
+	stop := runWorker(t, w)
+	defer stop()
+
+	job := waitForStatus(t, o.Store(), "a", data.JobStatusCompleted)
+	if job.Result["output"] != "a-800x600.png" {
+		t.Errorf("Result = %v", job.Result)
+	}
+}
+
+func TestGRPCBackend_ReportsErrors(t *testing.T) {
+	ctx := context.Background()
+	o := jobs.NewOrchestrator(jobs.NewMemoryStore(), nil)
+	backend := newGRPCBackend(t, o)
+	if _, err := o.Submit(ctx, &data.Job{JobID: "a", JobType: "resize_image", Namespace: "media"}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	w := NewWorker(backend, "resize_image", func(ctx context.Context, job *Job) (map[string]interface{}, error) {
+		return nil, NewError("IMG_TOO_LARGE", "Resize failed", map[string]interface{}{"max_size_mb": 10})
+	}, WithPollInterval(10*time.Millisecond))
+	stop := runWorker(t, w)
+	defer stop()
+
+	job := waitForStatus(t, o.Store(), "a", data.JobStatusFailed)
+	if job.Error.Code != "IMG_TOO_LARGE" || job.Error.Details["max_size_mb"] != float64(10) {
+		t.Errorf("Error = %+v", job.Error)
+	}
+}
+
+func TestGRPCBackend_Cancellation(t *testing.T) {
+	ctx := context.Background()
+	o := jobs.NewOrchestrator(jobs.NewMemoryStore(), nil)
+	backend := newGRPCBackend(t, o)
+	if _, err := o.Submit(ctx, &data.Job{JobID: "a", JobType: "resize_image", Namespace: "media"}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	started := make(chan struct{})
+	stopped := make(chan error, 1)
+	w := NewWorker(backend, "resize_image", func(ctx context.Context, job *Job) (map[string]interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		stopped <- context.Cause(ctx)
+		return nil, ctx.Err()
+	}, WithPollInterval(10*time.Millisecond), WithHeartbeatInterval(10*time.Millisecond))
+	stop := runWorker(t, w)
+	defer stop()
+
+	<-started
+	if _, err := o.Cancel(ctx, "media", "a", "operator request"); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+
+	select {
+	case cause := <-stopped:
+		if !errors.Is(cause, jobs.ErrCanceled) {
+			t.Errorf("handler context cause = %v, want ErrCanceled", cause)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler was not canceled")
+	}
+}
+
+func TestGRPCBackend_LeaseLost(t *testing.T) {
+	ctx := context.Background()
+	o := jobs.NewOrchestrator(jobs.NewMemoryStore(), nil)
+	backend := newGRPCBackend(t, o)
+	if _, err := o.Submit(ctx, &data.Job{JobID: "a", JobType: "resize_image", Namespace: "media"}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	if _, err := backend.Claim(ctx, jobs.ClaimRequest{JobType: "resize_image", WorkerID: "w1", TTL: time.Minute}); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if _, err := backend.Claim(ctx, jobs.ClaimRequest{JobType: "resize_image", WorkerID: "w2", TTL: time.Minute}); !errors.Is(err, jobs.ErrNoJobs) {
+		t.Errorf("Claim() error = %v, want ErrNoJobs", err)
+	}
+	if _, err := backend.Heartbeat(ctx, "media", "a", "w2", 0, time.Minute); !errors.Is(err, jobs.ErrLeaseLost) {
+		t.Errorf("Heartbeat() by another worker error = %v, want ErrLeaseLost", err)
+	}
+}
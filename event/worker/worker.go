@@ -0,0 +1,321 @@
+// Package worker is an SDK for running jobs of one job type. A Worker claims
+// started jobs with a time-limited lease, keeps the lease alive with heartbeats
+// that carry progress, and reports the handler's result or structured error.
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"event/data"
+	"event/handlers/jobs"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// DefaultLeaseTTL is the default duration of a job lease
+	DefaultLeaseTTL = 30 * time.Second
+	// DefaultPollInterval is the default delay between claims when no job is waiting
+	DefaultPollInterval = 5 * time.Second
+	// ErrCodeWorker is the error code reported when a handler returns a plain error
+	ErrCodeWorker = "WORKER_ERROR"
+)
+
+// Backend is the job service a worker talks to. *jobs.Orchestrator implements it in
+// process, and GRPCBackend calls a remote JobService.
+type Backend interface {
+	Claim(ctx context.Context, req jobs.ClaimRequest) (*data.Job, error)
+	Heartbeat(ctx context.Context, namespace, id, workerID string, progress int, ttl time.Duration) (*data.Job, error)
+	ReportResult(ctx context.Context, namespace, id, workerID string, result map[string]interface{}, jobErr *data.JobError) (*data.Job, error)
+}
+
+// Handler runs a job and returns its result. Returning a *data.JobError (see NewError)
+// reports it as is; any other error is reported with code WORKER_ERROR. The context is
+// canceled when the job is canceled or the worker loses the job's lease.
+type Handler func(ctx context.Context, job *Job) (map[string]interface{}, error)
+
+// Job is a claimed job passed to a Handler
+type Job struct {
+	*data.Job
+
+	mu       sync.Mutex
+	progress int
+	beat     chan struct{}
+}
+
+// SetProgress records the job's progress percentage (0-100) and sends it with an immediate heartbeat
+func (j *Job) SetProgress(percent int) {
+	if percent < 0 {
+		percent = 0
+	} else if percent > 100 {
+		percent = 100
+	}
+
+	j.mu.Lock()
+	j.progress = percent
+	j.mu.Unlock()
+
+	select {
+	case j.beat <- struct{}{}:
+	default:
+	}
+}
+
+// Progress returns the last progress percentage set by the handler
+func (j *Job) Progress() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.progress
+}
+
+// NewError returns a structured job error in the spec's error{code,message,details} shape
+func NewError(code, message string, details map[string]interface{}) *data.JobError {
+	return &data.JobError{
+		Code:    code,
+		Message: message,
+		Details: details,
+	}
+}
+
+// Worker claims and runs jobs of a single job type
+type Worker struct {
+	backend           Backend
+	jobType           string
+	handler           Handler
+	id                string
+	namespace         string
+	leaseTTL          time.Duration
+	heartbeatInterval time.Duration
+	pollInterval      time.Duration
+	concurrency       int
+	nc                *nats.Conn
+}
+
+// Option is a function that configures a Worker
+type Option func(*Worker)
+
+// WithWorkerID sets the ID the worker holds leases under. It defaults to <hostname>-<pid>.
+func WithWorkerID(id string) Option {
+	return func(w *Worker) {
+		w.id = id
+	}
+}
+
+// WithNamespace limits the worker to jobs of one namespace
+func WithNamespace(namespace string) Option {
+	return func(w *Worker) {
+		w.namespace = namespace
+	}
+}
+
+// WithLeaseTTL sets the lease duration. Heartbeats are sent every third of it unless
+// WithHeartbeatInterval is used.
+func WithLeaseTTL(ttl time.Duration) Option {
+	return func(w *Worker) {
+		w.leaseTTL = ttl
+	}
+}
+
+// WithHeartbeatInterval sets the delay between heartbeats
+func WithHeartbeatInterval(interval time.Duration) Option {
+	return func(w *Worker) {
+		w.heartbeatInterval = interval
+	}
+}
+
+// WithPollInterval sets the delay between claims when no job is waiting
+func WithPollInterval(interval time.Duration) Option {
+	return func(w *Worker) {
+		w.pollInterval = interval
+	}
+}
+
+// WithConcurrency sets the number of jobs the worker runs at the same time
+func WithConcurrency(n int) Option {
+	return func(w *Worker) {
+		w.concurrency = n
+	}
+}
+
+// WithNATS subscribes the worker to the job.started events of its job type,
+// so that new jobs are claimed immediately instead of on the next poll
+func WithNATS(nc *nats.Conn) Option {
+	return func(w *Worker) {
+		w.nc = nc
+	}
+}
+
+// NewWorker creates a new Worker running handler for jobs of jobType
+func NewWorker(backend Backend, jobType string, handler Handler, options ...Option) *Worker {
+	hostname, _ := os.Hostname()
+	w := &Worker{
+		backend:      backend,
+		jobType:      jobType,
+		handler:      handler,
+		id:           fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		leaseTTL:     DefaultLeaseTTL,
+		pollInterval: DefaultPollInterval,
+		concurrency:  1,
+	}
+
+	for _, option := range options {
+		option(w)
+	}
+
+	if w.heartbeatInterval <= 0 {
+		w.heartbeatInterval = w.leaseTTL / 3
+	}
+	if w.concurrency < 1 {
+		w.concurrency = 1
+	}
+
+	return w
+}
+
+// ID returns the ID the worker holds leases under
+func (w *Worker) ID() string {
+	return w.id
+}
+
+// Run claims and runs jobs until ctx is canceled, then waits for running jobs to finish
+func (w *Worker) Run(ctx context.Context) error {
+	wakeup := make(chan struct{}, 1)
+	if w.nc != nil {
+		namespace := w.namespace
+		if namespace == "" {
+			namespace = "*"
+		}
+		sub, err := w.nc.Subscribe(jobs.Subject(namespace, w.jobType, data.JobStatusStarted), func(*nats.Msg) {
+			select {
+			case wakeup <- struct{}{}:
+			default:
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s jobs: %w", w.jobType, err)
+		}
+		defer sub.Unsubscribe()
+	}
+
+	slots := make(chan struct{}, w.concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		// Wait for a free slot
+		select {
+		case <-ctx.Done():
+			return nil
+		case slots <- struct{}{}:
+		}
+
+		job, err := w.backend.Claim(ctx, jobs.ClaimRequest{
+			JobType:   w.jobType,
+			Namespace: w.namespace,
+			WorkerID:  w.id,
+			TTL:       w.leaseTTL,
+		})
+		if err != nil {
+			<-slots
+			if !errors.Is(err, jobs.ErrNoJobs) && ctx.Err() == nil {
+				log.Printf("Failed to claim %s job: %v", w.jobType, err)
+			}
+
+			timer := time.NewTimer(w.pollInterval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			case <-wakeup:
+				timer.Stop()
+			case <-timer.C:
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			w.process(ctx, job)
+		}()
+	}
+}
+
+// process runs the handler for a claimed job while keeping its lease alive, then reports the outcome
+func (w *Worker) process(ctx context.Context, claimed *data.Job) {
+	job := &Job{Job: claimed, progress: claimed.Progress, beat: make(chan struct{}, 1)}
+
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		w.heartbeat(jobCtx, job, cancel)
+	}()
+
+	result, err := w.run(jobCtx, job)
+	cancel(nil)
+	<-heartbeatDone
+
+	// The job was canceled or taken over by another worker; its outcome is no longer ours to report
+	if cause := context.Cause(jobCtx); errors.Is(cause, jobs.ErrCanceled) || errors.Is(cause, jobs.ErrLeaseLost) {
+		log.Printf("Stopped %s job %s: %v", w.jobType, job.JobID, cause)
+		return
+	}
+	if ctx.Err() != nil {
+		// Shutting down; the lease expires and the job is re-queued
+		return
+	}
+
+	var jobErr *data.JobError
+	if err != nil && !errors.As(err, &jobErr) {
+		jobErr = NewError(ErrCodeWorker, err.Error(), nil)
+	}
+
+	if _, err := w.backend.ReportResult(ctx, job.Namespace, job.JobID, w.id, result, jobErr); err != nil {
+		log.Printf("Failed to report result of %s job %s: %v", w.jobType, job.JobID, err)
+	}
+}
+
+// run calls the handler, turning a panic into a job error
+func (w *Worker) run(ctx context.Context, job *Job) (result map[string]interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = NewError(ErrCodeWorker, fmt.Sprintf("handler panicked: %v", r), nil)
+		}
+	}()
+	return w.handler(ctx, job)
+}
+
+// heartbeat renews the job's lease until ctx is done. If the job was canceled or the
+// lease was lost, the job's context is canceled with that cause.
+func (w *Worker) heartbeat(ctx context.Context, job *Job, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(w.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-job.beat:
+		}
+
+		_, err := w.backend.Heartbeat(ctx, job.Namespace, job.JobID, w.id, job.Progress(), w.leaseTTL)
+		if errors.Is(err, jobs.ErrCanceled) || errors.Is(err, jobs.ErrLeaseLost) {
+			cancel(err)
+			return
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to send heartbeat for %s job %s: %v", w.jobType, job.JobID, err)
+		}
+	}
+}
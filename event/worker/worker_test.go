@@ -0,0 +1,149 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"event/data"
+	"event/handlers/jobs"
+)
+
+// runWorker runs w in the background and returns a function that stops it and waits for it to exit
+func runWorker(t *testing.T, w *Worker) func() {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+
+	return func() {
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("Run() error = %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("worker did not stop")
+		}
+	}
+}
+
+// waitForStatus polls the store until the job reaches status
+func waitForStatus(t *testing.T, store jobs.Store, id, status string) *data.Job {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := store.Get(context.Background(), "media", id)
+		if err == nil && job.Status == status {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not reach status %s", id, status)
+	return nil
+}
+
+func TestWorker_CompletesJob(t *testing.T) {
+	ctx := context.Background()
+	o := jobs.NewOrchestrator(jobs.NewMemoryStore(), nil)
+	if _, err := o.Submit(ctx, &data.Job{JobID: "a", JobType: "resize_image", Namespace: "media", Inputs: map[string]interface{}{"size": "800x600"}}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	progressSeen := make(chan int, 1)
+	w := NewWorker(o, "resize_image", func(ctx context.Context, job *Job) (map[string]interface{}, error) {
+		job.SetProgress(50)
+		// Wait for the progress heartbeat to be stored
+		for i := 0; i < 100; i++ {
+			stored, _ := o.Store().Get(ctx, job.Namespace, job.JobID)
+			if stored.Progress == 50 {
+				progressSeen <- stored.Progress
+				break
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		return map[string]interface{}{"output": "a-" + job.Inputs["size"].(string) + ".png"}, nil
+	}, WithWorkerID("w1"), WithPollInterval(10*time.Millisecond))
+	stop := runWorker(t, w)
+	defer stop()
+
+	job := waitForStatus(t, o.Store(), "a", data.JobStatusCompleted)
+	if job.Result["output"] != "a-800x600.png" {
+		t.Errorf("Result = %v", job.Result)
+	}
+	select {
+	case <-progressSeen:
+	default:
+		t.Error("progress heartbeat was not stored")
+	}
+}
+
+func TestWorker_ReportsErrors(t *testing.T) {
+	ctx := context.Background()
+	o := jobs.NewOrchestrator(jobs.NewMemoryStore(), nil)
+	for _, id := range []string{"structured", "plain"} {
+		if _, err := o.Submit(ctx, &data.Job{JobID: id, JobType: "resize_image", Namespace: "media"}); err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+	}
+
+	w := NewWorker(o, "resize_image", func(ctx context.Context, job *Job) (map[string]interface{}, error) {
+		if job.JobID == "structured" {
+			return nil, NewError("IMG_TOO_LARGE", "Resize failed", map[string]interface{}{"max_size_mb": 10})
+		}
+		return nil, errors.New("disk full")
+	}, WithPollInterval(10*time.Millisecond), WithConcurrency(2))
+	stop := runWorker(t, w)
+	defer stop()
+
+	job := waitForStatus(t, o.Store(), "structured", data.JobStatusFailed)
+	if job.Error.Code != "IMG_TOO_LARGE" || job.Error.Details["max_size_mb"] != 10 {
+		t.Errorf("structured error = %+v", job.Error)
+	}
+	job = waitForStatus(t, o.Store(), "plain", data.JobStatusFailed)
+	if job.Error.Code != ErrCodeWorker || job.Error.Message != "disk full" {
+		t.Errorf("plain error = %+v", job.Error)
+	}
+}
+
+func TestWorker_Cancellation(t *testing.T) {
+	ctx := context.Background()
+	o := jobs.NewOrchestrator(jobs.NewMemoryStore(), nil)
+	if _, err := o.Submit(ctx, &data.Job{JobID: "a", JobType: "resize_image", Namespace: "media"}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	started := make(chan struct{})
+	stopped := make(chan error, 1)
+	w := NewWorker(o, "resize_image", func(ctx context.Context, job *Job) (map[string]interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		stopped <- context.Cause(ctx)
+		return nil, ctx.Err()
+	}, WithPollInterval(10*time.Millisecond), WithHeartbeatInterval(10*time.Millisecond))
+	stop := runWorker(t, w)
+	defer stop()
+
+	<-started
+	if _, err := o.Cancel(ctx, "media", "a", "operator request"); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+
+	select {
+	case cause := <-stopped:
+		if !errors.Is(cause, jobs.ErrCanceled) {
+			t.Errorf("handler context cause = %v, want ErrCanceled", cause)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler was not canceled")
+	}
+
+	job, _ := o.Store().Get(ctx, "media", "a")
+	if job.Status != data.JobStatusCanceled {
+		t.Errorf("status = %s, want canceled", job.Status)
+	}
+}